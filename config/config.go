@@ -1,62 +1,260 @@
 package config
 
 import (
-	"fmt"
-
-	"github.com/ilyakaznacheev/cleanenv"
+	"time"
 )
 
 type (
 	Config struct {
-		App      `yaml:"app"`
-		HTTP     `yaml:"http"`
-		Log      `yaml:"logger"`
-		Database `yaml:"database"`
-		Services `yaml:"services"`
+		App       `yaml:"app"`
+		HTTP      `yaml:"http"`
+		GRPC      `yaml:"grpc"`
+		Log       `yaml:"logger"`
+		Database  `yaml:"database"`
+		Services  `yaml:"services"`
+		Events    `yaml:"events"`
+		Webhooks  `yaml:"webhooks"`
+		Storage   `yaml:"storage"`
+		Uploads   `yaml:"uploads"`
+		PfpQueue  `yaml:"pfp_queue"`
+		Shutdown  `yaml:"shutdown"`
+		Health    `yaml:"health"`
+		Tracing   `yaml:"tracing"`
+		Secrets   `yaml:"secrets"`
+		Reload    `yaml:"reload"`
+		Fixtures  `yaml:"fixtures"`
+		RateLimit `yaml:"rate_limit"`
+
+		//files read by Load, the base one first
+		Sources []string `yaml:"-"`
 	}
 
 	App struct {
-		Name    string `env-required:"true" yaml:"name"    env:"APP_NAME"`
-		Version string `env-required:"true" yaml:"version" env:"APP_VERSION"`
+		Name    string `yaml:"name"    env:"APP_NAME"`
+		Version string `yaml:"version" env:"APP_VERSION"`
+		//profile whose overlay was loaded on top of the base file (dev, staging or prod), set by Load
+		Profile string `yaml:"profile"`
 	}
 
 	HTTP struct {
-		Port      string `env-required:"true" yaml:"port" env:"HTTP_PORT"`
-		JWTSecret string `env-required:"true" yaml:"jwtSecret" env:"JWT_SECRET"`
+		Port      string `yaml:"port"      env:"HTTP_PORT"`
+		JWTSecret string `yaml:"jwtSecret" env:"JWT_SECRET" secret:"true"`
+		//url where the service is reachable by the clients, used to build the urls of the uploaded files
+		PublicUrl string `yaml:"public_url" env:"HTTP_PUBLIC_URL" env-default:"http://localhost:8080"`
+		//the ip of the clients is read from X-Forwarded-For, enable it only behind a proxy that sets it
+		TrustProxy bool `yaml:"trust_proxy" env:"HTTP_TRUST_PROXY"`
+		//cidrs of the proxies whose X-Forwarded-For is trusted, empty trusts the private networks
+		TrustedProxies []string `yaml:"trusted_proxies" env:"HTTP_TRUSTED_PROXIES" env-separator:","`
+	}
+
+	//the users api for the internal services, served over grpc on its own port
+	GRPC struct {
+		Enabled bool   `yaml:"enabled" env:"GRPC_ENABLED"`
+		Port    string `yaml:"port"    env:"GRPC_PORT" env-default:"9090"`
+		//the reflection service lets tools like grpcurl list and call the methods without the protos
+		Reflection bool `yaml:"reflection" env:"GRPC_REFLECTION"`
+	}
+
+	Shutdown struct {
+		//how long the components have to stop (in flight requests, running jobs...) before the service exits anyway
+		DrainTimeout time.Duration `yaml:"drain_timeout" env:"SHUTDOWN_DRAIN_TIMEOUT" env-default:"15s"`
+	}
+
+	Health struct {
+		//how long a single dependency check of the readiness probe can take
+		Timeout time.Duration `yaml:"timeout" env:"HEALTH_TIMEOUT" env-default:"2s"`
+	}
+
+	Tracing struct {
+		//"none", "otlp" (collector over http), "stdout" or "file"
+		Exporter string `yaml:"exporter" env:"TRACING_EXPORTER" env-default:"none"`
+		//host:port of the otlp collector
+		Endpoint string `yaml:"endpoint" env:"TRACING_ENDPOINT" env-default:"localhost:4318"`
+		Insecure bool   `yaml:"insecure" env:"TRACING_INSECURE"`
+		//file where the spans are written by the file exporter
+		File string `yaml:"file" env:"TRACING_FILE" env-default:"./data/traces.json"`
+		//ratio of the traces that are sampled, from 0 to 1
+		SampleRatio float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO" env-default:"1"`
+	}
+
+	//where the references to the secrets (like vault://secret/data/service#jwt_secret) are resolved,
+	//file:// and env: references are always available
+	Secrets struct {
+		Vault VaultSecrets `yaml:"vault"`
+	}
+
+	VaultSecrets struct {
+		//address of the vault server, empty disables the vault:// references
+		Address string `yaml:"address" env:"VAULT_ADDR"`
+		//it can be a file:// or env: reference too
+		Token     string        `yaml:"token"     env:"VAULT_TOKEN" secret:"true"`
+		Namespace string        `yaml:"namespace" env:"VAULT_NAMESPACE"`
+		Timeout   time.Duration `yaml:"timeout"   env:"VAULT_TIMEOUT" env-default:"5s"`
+	}
+
+	//reload of the config without restarting the service, on SIGHUP and when the files change
+	Reload struct {
+		//watch the config files for changes, SIGHUP reloads the config anyway
+		Watch bool `yaml:"watch" env:"RELOAD_WATCH"`
+		//changes closer than this are applied together, the editors write the files in more steps
+		Debounce time.Duration `yaml:"debounce" env:"RELOAD_DEBOUNCE" env-default:"500ms"`
+	}
+
+	//users created from the fixture files (see the fixtures package), never in production
+	Fixtures struct {
+		//yaml or json files, applied in order
+		Files []string `yaml:"files" env:"FIXTURES_FILES" env-separator:","`
+		//upsert the users of the files when the service starts
+		OnStart bool `yaml:"on_start" env:"FIXTURES_ON_START"`
+	}
+
+	//limits of the requests per client, the limits (not the store) are applied without a restart
+	RateLimit struct {
+		Enabled bool `yaml:"enabled" env:"RATE_LIMIT_ENABLED"`
+		//"memory" (every instance has its own limits) or "redis" (the instances share the limits)
+		Store string         `yaml:"store" env:"RATE_LIMIT_STORE" env-default:"memory"`
+		Redis RedisRateLimit `yaml:"redis"`
+		//register and login
+		Auth RateLimitRule `yaml:"auth" env-prefix:"RATE_LIMIT_AUTH_"`
+		//every route of the api
+		API RateLimitRule `yaml:"api" env-prefix:"RATE_LIMIT_API_"`
+		//the keys of the clients grouped by api key, separated by commas, the requests with an unknown key are grouped by ip
+		APIKeys string `yaml:"api_keys" env:"RATE_LIMIT_API_KEYS" secret:"true"`
+	}
+
+	RedisRateLimit struct {
+		Address  string `yaml:"address"  env:"RATE_LIMIT_REDIS_ADDRESS" env-default:"localhost:6379"`
+		Password string `yaml:"password" env:"RATE_LIMIT_REDIS_PASSWORD" secret:"true"`
+		DB       int    `yaml:"db"       env:"RATE_LIMIT_REDIS_DB"`
+		//prepended to the keys, the server can be shared with other services
+		Prefix  string        `yaml:"prefix"  env:"RATE_LIMIT_REDIS_PREFIX"  env-default:"usersvc:ratelimit:"`
+		Timeout time.Duration `yaml:"timeout" env:"RATE_LIMIT_REDIS_TIMEOUT" env-default:"100ms"`
+	}
+
+	RateLimitRule struct {
+		//"token_bucket" or "sliding_window"
+		Algorithm string `yaml:"algorithm" env:"ALGORITHM"`
+		//requests allowed every period, 0 disables the limit
+		Requests int           `yaml:"requests" env:"REQUESTS"`
+		Period   time.Duration `yaml:"period"   env:"PERIOD"`
+		//requests allowed at once by the token bucket, requests if 0
+		Burst int `yaml:"burst" env:"BURST"`
+		//what the requests are grouped by: "ip", "user" (the ip without a valid token) or "api_key" (the X-API-Key header, the ip without one of the api_keys)
+		Key string `yaml:"key" env:"KEY"`
 	}
 
 	Log struct {
-		Level string `env-required:"true" yaml:"level" env:"LOG_LEVEL"`
-		Type  string `env-required:"true" yaml:"type"  env:"LOG_TYPE"`
+		Level string `yaml:"level" env:"LOG_LEVEL"`
+		//"text" or "json"
+		Type string `yaml:"type"  env:"LOG_TYPE"`
+		//where the logs are written: stdout, stderr and/or file
+		Outputs []string  `yaml:"outputs" env:"LOG_OUTPUTS" env-separator:"," env-default:"stderr"`
+		File    LogFile   `yaml:"file"`
+		Redact  LogRedact `yaml:"redact"`
+	}
+
+	//log file rotated by size, the rotated files are deleted by age and number
+	LogFile struct {
+		Path       string `yaml:"path"         env:"LOG_FILE_PATH"         env-default:"./data/logs/service.log"`
+		MaxSizeMB  int    `yaml:"max_size_mb"  env:"LOG_FILE_MAX_SIZE_MB"  env-default:"100"`
+		MaxAgeDays int    `yaml:"max_age_days" env:"LOG_FILE_MAX_AGE_DAYS" env-default:"7"`
+		MaxBackups int    `yaml:"max_backups"  env:"LOG_FILE_MAX_BACKUPS"  env-default:"5"`
+		Compress   bool   `yaml:"compress"     env:"LOG_FILE_COMPRESS"     env-default:"true"`
+	}
+
+	//masking of the sensitive data written in the logs, it can be relaxed in development
+	//(to see the emails...) but it should be always enabled in production
+	LogRedact struct {
+		Enabled bool `yaml:"enabled" env:"LOG_REDACT_ENABLED" env-default:"true"`
+		//fields whose value is always hidden
+		Fields []string `yaml:"fields" env:"LOG_REDACT_FIELDS" env-separator:"," env-default:"password,token,authorization,secret,api_key,cookie"`
+		//patterns masked in the messages and in the fields: jwt, bearer, password, email
+		Patterns []string `yaml:"patterns" env:"LOG_REDACT_PATTERNS" env-separator:"," env-default:"jwt,bearer,password,email"`
 	}
 
 	Database struct {
-		Driver string `env-required:"true"  yaml:"driver" env:"DATABASE_DRIVER"`
-		URI    string `                                   env:"DATABASE_URI"`
+		//"mock" or "mongo"
+		Driver string `yaml:"driver" env:"DATABASE_DRIVER"`
+		URI    string `yaml:"uri"    env:"DATABASE_URI" secret:"true"`
+		//file where the mock driver keeps its data between the restarts, empty keeps it only in memory
+		File string `yaml:"file" env:"DATABASE_FILE"`
+		//apply the pending migrations when the service starts, otherwise they are applied with "migrate up"
+		MigrateOnStart bool `yaml:"migrate_on_start" env:"DATABASE_MIGRATE_ON_START"`
 	}
 
 	Services struct {
 		Logo LogoService `yaml:"logo"`
 	}
 
-	LogoService struct {
-		BaseUrl string `env-required:"true" yaml:"base_url" env:"BASE_URL"`
-		ApiKey  string `env-required:"true" yaml:"api_key"  env:"API_KEY"`
+	Events struct {
+		DispatchInterval time.Duration `yaml:"dispatch_interval" env:"EVENTS_DISPATCH_INTERVAL" env-default:"1s"`
+		BatchSize        int           `yaml:"batch_size"        env:"EVENTS_BATCH_SIZE"        env-default:"100"`
+		LogSink          bool          `yaml:"log_sink"          env:"EVENTS_LOG_SINK"`
 	}
-)
 
-func NewConfig() (*Config, error) {
-	cfg := &Config{}
+	Webhooks struct {
+		Enabled     bool          `yaml:"enabled"      env:"WEBHOOKS_ENABLED"`
+		Timeout     time.Duration `yaml:"timeout"      env:"WEBHOOKS_TIMEOUT"      env-default:"10s"`
+		MaxAttempts int           `yaml:"max_attempts" env:"WEBHOOKS_MAX_ATTEMPTS" env-default:"8"`
+	}
 
-	err := cleanenv.ReadConfig("./config/config.yml", cfg)
-	if err != nil {
-		return nil, fmt.Errorf("config error: %w", err)
+	Storage struct {
+		//"local" or "s3"
+		Driver string       `yaml:"driver" env:"STORAGE_DRIVER" env-default:"local"`
+		Local  LocalStorage `yaml:"local"`
+		S3     S3Storage    `yaml:"s3"`
 	}
 
-	err = cleanenv.ReadEnv(cfg)
-	if err != nil {
-		return nil, err
+	LocalStorage struct {
+		Path string `yaml:"path" env:"STORAGE_LOCAL_PATH" env-default:"./data/blobs"`
 	}
 
-	return cfg, nil
-}
+	S3Storage struct {
+		Endpoint  string `yaml:"endpoint"   env:"STORAGE_S3_ENDPOINT"`
+		Region    string `yaml:"region"     env:"STORAGE_S3_REGION"     env-default:"us-east-1"`
+		Bucket    string `yaml:"bucket"     env:"STORAGE_S3_BUCKET"`
+		AccessKey string `yaml:"access_key" env:"STORAGE_S3_ACCESS_KEY" secret:"true"`
+		SecretKey string `yaml:"secret_key" env:"STORAGE_S3_SECRET_KEY" secret:"true"`
+	}
+
+	Uploads struct {
+		//max size in bytes of an uploaded profile picture
+		PfpMaxSize int64 `yaml:"pfp_max_size" env:"UPLOADS_PFP_MAX_SIZE" env-default:"5242880"`
+		//sizes in pixels of the square thumbnails, the profile picture is the biggest
+		PfpSizes []int `yaml:"pfp_sizes" env:"UPLOADS_PFP_SIZES" env-separator:"," env-default:"64,128,256"`
+	}
+
+	//background generation of the profile pictures
+	PfpQueue struct {
+		//when disabled the pfp is generated on the request path
+		Enabled     bool          `yaml:"enabled"      env:"PFP_QUEUE_ENABLED"      env-default:"true"`
+		Workers     int           `yaml:"workers"      env:"PFP_QUEUE_WORKERS"      env-default:"4"`
+		Size        int           `yaml:"size"         env:"PFP_QUEUE_SIZE"         env-default:"1000"`
+		MaxAttempts int           `yaml:"max_attempts" env:"PFP_QUEUE_MAX_ATTEMPTS" env-default:"5"`
+		MinBackoff  time.Duration `yaml:"min_backoff"  env:"PFP_QUEUE_MIN_BACKOFF"  env-default:"1s"`
+		MaxBackoff  time.Duration `yaml:"max_backoff"  env:"PFP_QUEUE_MAX_BACKOFF"  env-default:"1m"`
+	}
+
+	LogoService struct {
+		//"random" doesn't call the provider (useful in development), "http" calls the provider api,
+		//"local" uses the avatars rendered by the service
+		Provider string `yaml:"provider" env:"LOGO_PROVIDER" env-default:"random"`
+		//provider used when the main one fails, empty or "local"
+		Fallback         string        `yaml:"fallback"          env:"LOGO_FALLBACK"`
+		BaseUrl          string        `yaml:"base_url"          env:"LOGO_BASE_URL"`
+		ApiKey           string        `yaml:"api_key"           env:"LOGO_API_KEY" secret:"true"`
+		Timeout          time.Duration `yaml:"timeout"           env:"LOGO_TIMEOUT"           env-default:"5s"`
+		MaxRetries       int           `yaml:"max_retries"       env:"LOGO_MAX_RETRIES"       env-default:"2"`
+		BreakerThreshold int           `yaml:"breaker_threshold" env:"LOGO_BREAKER_THRESHOLD" env-default:"5"`
+		BreakerCooldown  time.Duration `yaml:"breaker_cooldown"  env:"LOGO_BREAKER_COOLDOWN"  env-default:"30s"`
+		Local            LocalLogo     `yaml:"local"`
+	}
+
+	//avatars rendered by the service itself
+	LocalLogo struct {
+		//url where the service is reachable by the clients
+		PublicUrl string `yaml:"public_url" env:"LOGO_LOCAL_PUBLIC_URL" env-default:"http://localhost:8080"`
+		Style     string `yaml:"style"      env:"LOGO_LOCAL_STYLE"      env-default:"identicon"`
+		Format    string `yaml:"format"     env:"LOGO_LOCAL_FORMAT"     env-default:"svg"`
+	}
+)
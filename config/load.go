@@ -0,0 +1,212 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/ilyakaznacheev/cleanenv"
+	"github.com/vano2903/service-template/pkg/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	//prefix of every environment variable read by the service, so they don't collide with the ones of other apps
+	EnvPrefix = "USERSVC_"
+	//environment variables used when the path or the profile are not given explicitly
+	EnvConfigPath = EnvPrefix + "CONFIG"
+	EnvProfile    = EnvPrefix + "PROFILE"
+
+	DefaultPath = "./config/config.yml"
+
+	ProfileDev     = "dev"
+	ProfileStaging = "staging"
+	ProfileProd    = "prod"
+)
+
+var ErrUnknownProfile = errors.New("unknown profile")
+
+// Options says which files are loaded, the empty fields are read from the environment
+// (USERSVC_CONFIG and USERSVC_PROFILE) and then fall back to the defaults
+type Options struct {
+	//path of the base file
+	Path string
+	//dev, staging or prod, the overlay config.<profile>.yml next to the base file is loaded on top of it if it exists
+	Profile string
+	//providers of the secret references by scheme, they are added to (or replace) the file, env and vault ones
+	Providers map[string]secrets.Provider
+}
+
+// prefixed is used only to read the environment, the prefix is added to every env tag of the config
+type prefixed struct {
+	Config `env-prefix:"USERSVC_"`
+}
+
+func Profiles() []string {
+	return []string{ProfileDev, ProfileStaging, ProfileProd}
+}
+
+// Load applies the defaults (the env-default tags), then reads the base file, the overlay of the profile
+// and the environment variables, each layer overrides only the values it sets (false and 0 too). Then the references in the secret fields
+// (file:///run/secrets/jwt, env:JWT_SECRET, vault://secret/data/service#jwt_secret) are resolved.
+// The config is not validated, see Validate
+func Load(opts Options) (*Config, error) {
+	if opts.Path == "" {
+		opts.Path = os.Getenv(EnvConfigPath)
+	}
+	if opts.Path == "" {
+		opts.Path = DefaultPath
+	}
+	if opts.Profile == "" {
+		opts.Profile = os.Getenv(EnvProfile)
+	}
+	if opts.Profile == "" {
+		opts.Profile = ProfileDev
+	}
+	if !contains(Profiles(), opts.Profile) {
+		return nil, fmt.Errorf("%w %q, it must be one of %s", ErrUnknownProfile, opts.Profile, strings.Join(Profiles(), ", "))
+	}
+
+	cfg, err := defaults()
+	if err != nil {
+		return nil, err
+	}
+	if err := readFile(opts.Path, cfg); err != nil {
+		return nil, err
+	}
+	cfg.Sources = append(cfg.Sources, opts.Path)
+
+	overlay := OverlayPath(opts.Path, opts.Profile)
+	if _, err := os.Stat(overlay); err == nil {
+		if err := readFile(overlay, cfg); err != nil {
+			return nil, err
+		}
+		cfg.Sources = append(cfg.Sources, overlay)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read the overlay %s: %w", overlay, err)
+	}
+
+	if cfg, err = readEnv(cfg); err != nil {
+		return nil, err
+	}
+	cfg.App.Profile = opts.Profile
+
+	if err := resolveSecrets(cfg, opts.Providers); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// envField is a field of the config that can be set by the environment
+type envField struct {
+	index []int
+	//the variables of the field, prefix included
+	names []string
+}
+
+// isSet returns true if one of the variables of the field is in the environment
+func (f envField) isSet() bool {
+	for _, name := range f.names {
+		if _, ok := os.LookupEnv(name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// envFields lists the fields with an env or env-default tag, following the env-prefix of the
+// nested structs like cleanenv does
+func envFields(t reflect.Type, prefix string, index []int) []envField {
+	var fields []envField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		fieldIndex := append(append([]int(nil), index...), i)
+		env, hasEnv := f.Tag.Lookup("env")
+		_, hasDefault := f.Tag.Lookup("env-default")
+		switch {
+		case hasEnv || hasDefault:
+			field := envField{index: fieldIndex}
+			if env != "" {
+				for _, name := range strings.Split(env, ",") {
+					field.names = append(field.names, prefix+name)
+				}
+			}
+			fields = append(fields, field)
+		case f.Type.Kind() == reflect.Struct:
+			fields = append(fields, envFields(f.Type, prefix+f.Tag.Get("env-prefix"), fieldIndex)...)
+		}
+	}
+	return fields
+}
+
+// defaults returns the config with only the values of the env-default tags. They are applied
+// before the files: cleanenv applies them to every zero field, so after the files it would
+// turn back to the default a field the files set to false or 0
+func defaults() (*Config, error) {
+	env := &prefixed{}
+	if err := cleanenv.ReadEnv(env); err != nil {
+		return nil, fmt.Errorf("unable to read the environment variables: %w", err)
+	}
+	//the environment is read again on top of the files
+	cfg := reflect.ValueOf(&env.Config).Elem()
+	for _, f := range envFields(cfg.Type(), EnvPrefix, nil) {
+		if f.isSet() {
+			field := cfg.FieldByIndex(f.index)
+			field.Set(reflect.Zero(field.Type()))
+		}
+	}
+	return &env.Config, nil
+}
+
+// readEnv returns the config with the values of the environment variables on top of cfg,
+// the fields without a variable keep the value of cfg even if zero
+func readEnv(cfg *Config) (*Config, error) {
+	env := &prefixed{Config: *cfg}
+	if err := cleanenv.ReadEnv(env); err != nil {
+		return nil, fmt.Errorf("unable to read the environment variables: %w", err)
+	}
+	before := reflect.ValueOf(cfg).Elem()
+	after := reflect.ValueOf(&env.Config).Elem()
+	for _, f := range envFields(after.Type(), EnvPrefix, nil) {
+		if !f.isSet() {
+			after.FieldByIndex(f.index).Set(before.FieldByIndex(f.index))
+		}
+	}
+	return &env.Config, nil
+}
+
+// OverlayPath returns the overlay of the profile, config.yml with the prod profile is config.prod.yml
+func OverlayPath(base, profile string) string {
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "." + profile + ext
+}
+
+// readFile decodes the file in cfg, the fields not in the file keep their value.
+// Unknown keys are an error so a typo doesn't silently leave the default value
+func readFile(path string, cfg *Config) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("unable to open the config file: %w", err)
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && err != io.EOF {
+		return fmt.Errorf("unable to parse the config file %s: %w", path, err)
+	}
+	return nil
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
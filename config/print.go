@@ -0,0 +1,85 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted is written instead of the fields tagged with secret:"true"
+const Redacted = "[REDACTED]"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// WriteRedacted writes the effective config as yaml (with the same keys of the config file),
+// the secrets are replaced by [REDACTED] and the durations are written as 1m30s
+func (c *Config) WriteRedacted(w io.Writer) error {
+	if len(c.Sources) > 0 {
+		if _, err := fmt.Fprintf(w, "# profile: %s\n# files: %s\n", c.App.Profile, strings.Join(c.Sources, ", ")); err != nil {
+			return err
+		}
+	}
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(toNode(reflect.ValueOf(*c), false)); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// toNode builds the yaml node of the value, yaml.Marshal can't be used as it writes
+// the durations as nanoseconds and it doesn't know about the secrets
+func toNode(v reflect.Value, secret bool) *yaml.Node {
+	if secret {
+		if v.IsZero() {
+			return scalar("!!str", "")
+		}
+		return scalar("!!str", Redacted)
+	}
+	if v.Type() == durationType {
+		return scalar("!!str", time.Duration(v.Int()).String())
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if key == "-" || !field.IsExported() {
+				continue
+			}
+			if key == "" {
+				key = strings.ToLower(field.Name)
+			}
+			node.Content = append(node.Content, scalar("!!str", key), toNode(v.Field(i), field.Tag.Get("secret") == "true"))
+		}
+		return node
+	case reflect.Slice:
+		node := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+		for i := 0; i < v.Len(); i++ {
+			node.Content = append(node.Content, toNode(v.Index(i), false))
+		}
+		return node
+	case reflect.String:
+		return scalar("!!str", v.String())
+	case reflect.Bool:
+		return scalar("", strconv.FormatBool(v.Bool()))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return scalar("", strconv.FormatInt(v.Int(), 10))
+	case reflect.Float32, reflect.Float64:
+		return scalar("", strconv.FormatFloat(v.Float(), 'g', -1, 64))
+	default:
+		return scalar("!!str", fmt.Sprint(v.Interface()))
+	}
+}
+
+func scalar(tag, value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: tag, Value: value}
+}
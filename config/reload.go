@@ -0,0 +1,191 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/secrets"
+)
+
+// Subscriber is notified after a reload changed the config, prev and next must not be modified.
+// The components check if their section changed, an error is logged and doesn't stop the others
+type Subscriber func(prev, next *Config) error
+
+// Reloader keeps the current config and reloads it on SIGHUP and (if enabled) when the files change.
+// The new config is validated and only the reloadable sections (see applyReloadable) are swapped,
+// an invalid config is rejected and the service keeps running with the old one
+type Reloader struct {
+	opts Options
+	l    *logrus.Logger
+
+	current atomic.Pointer[Config]
+	//one reload at a time
+	mu          sync.Mutex
+	subscribers []subscriber
+}
+
+type subscriber struct {
+	name string
+	fn   Subscriber
+}
+
+// NewReloader reloads the files and the profile cfg was loaded from, with the given secret providers
+func NewReloader(cfg *Config, providers map[string]secrets.Provider, l *logrus.Logger) *Reloader {
+	opts := Options{Profile: cfg.App.Profile, Providers: providers}
+	if len(cfg.Sources) > 0 {
+		opts.Path = cfg.Sources[0]
+	}
+	r := &Reloader{opts: opts, l: l}
+	r.current.Store(cfg)
+	return r
+}
+
+// Current returns the config in use, it must not be modified
+func (r *Reloader) Current() *Config {
+	return r.current.Load()
+}
+
+// Subscribe adds a component notified after every reload, in the order they are added
+func (r *Reloader) Subscribe(name string, fn Subscriber) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscribers = append(r.subscribers, subscriber{name: name, fn: fn})
+}
+
+// applyReloadable copies from src to dst the sections that are applied without a restart
+func applyReloadable(dst, src *Config) {
+	dst.Log.Level = src.Log.Level
+	dst.Services = src.Services
+	//the store needs a restart, it keeps the state of the limits
+	dst.RateLimit.Enabled = src.RateLimit.Enabled
+	dst.RateLimit.Auth = src.RateLimit.Auth
+	dst.RateLimit.API = src.RateLimit.API
+}
+
+// Reload loads and validates the config again and swaps the reloadable sections, the changes
+// of the other sections are reported as they need a restart. If the config can't be loaded or
+// is invalid the current one is kept and the error is returned
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	loaded, err := Load(r.opts)
+	if err == nil {
+		err = loaded.Validate()
+	}
+	if err != nil {
+		r.l.Errorf("config: reload rejected, the service keeps the current config: %v", err)
+		return err
+	}
+
+	prev := r.Current()
+	next := *prev
+	next.Sources = loaded.Sources
+	applyReloadable(&next, loaded)
+	if restart := changedSections(&next, loaded); len(restart) > 0 {
+		r.l.Warnf("config: the changes to %s need a restart, they are ignored", strings.Join(restart, ", "))
+	}
+	if len(changedSections(prev, &next)) == 0 {
+		r.l.Info("config: reloaded, nothing to apply")
+		return nil
+	}
+
+	r.current.Store(&next)
+	var failed []string
+	for _, s := range r.subscribers {
+		if err := s.fn(prev, &next); err != nil {
+			r.l.Errorf("config: %s failed to apply the new config: %v", s.name, err)
+			failed = append(failed, s.name)
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("the new config was not applied by %s", strings.Join(failed, ", "))
+	}
+	r.l.Infof("config: reloaded, changed %s", strings.Join(changedSections(prev, &next), ", "))
+	return nil
+}
+
+// changedSections returns the yaml keys of the top level sections that are different
+func changedSections(a, b *Config) []string {
+	var changed []string
+	va, vb := reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem()
+	t := va.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := strings.Split(t.Field(i).Tag.Get("yaml"), ",")[0]
+		if key == "-" {
+			continue
+		}
+		if !reflect.DeepEqual(va.Field(i).Interface(), vb.Field(i).Interface()) {
+			changed = append(changed, key)
+		}
+	}
+	return changed
+}
+
+// Run reloads the config on SIGHUP and, if reload.watch is enabled, when the base file or the overlay
+// of the profile change, until ctx is canceled. The directory is watched instead of the files
+// as the editors and kubernetes replace the files instead of writing them
+func (r *Reloader) Run(ctx context.Context) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	cfg := r.Current()
+	var events <-chan fsnotify.Event
+	var errs <-chan error
+	var watched map[string]bool
+	if cfg.Reload.Watch && r.opts.Path != "" {
+		watcher, err := fsnotify.NewWatcher()
+		if err != nil {
+			r.l.Errorf("config: unable to watch the config files, only SIGHUP reloads the config: %v", err)
+		} else {
+			defer watcher.Close()
+			base, _ := filepath.Abs(r.opts.Path)
+			overlay := OverlayPath(base, r.opts.Profile)
+			watched = map[string]bool{base: true, overlay: true}
+			if err := watcher.Add(filepath.Dir(base)); err != nil {
+				r.l.Errorf("config: unable to watch %s, only SIGHUP reloads the config: %v", filepath.Dir(base), err)
+			} else {
+				events, errs = watcher.Events, watcher.Errors
+				r.l.Debugf("config: watching %s and %s", base, overlay)
+			}
+		}
+	}
+
+	//the timer starts stopped, the file events (re)start it
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	defer debounce.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sig:
+			r.l.Info("config: SIGHUP received, reloading the config")
+			r.Reload()
+		case e := <-events:
+			name, _ := filepath.Abs(e.Name)
+			//kubernetes swaps the ..data symlink of the mounted config maps
+			if watched[name] || filepath.Base(name) == "..data" {
+				debounce.Reset(cfg.Reload.Debounce)
+			}
+		case err := <-errs:
+			r.l.Warnf("config: error watching the config files: %v", err)
+		case <-debounce.C:
+			r.l.Info("config: the config files changed, reloading the config")
+			r.Reload()
+		}
+	}
+}
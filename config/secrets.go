@@ -0,0 +1,94 @@
+package config
+
+import (
+	"context"
+	"reflect"
+	"strings"
+
+	"github.com/vano2903/service-template/pkg/secrets"
+)
+
+// secretField is a field tagged with secret:"true", key is the one of the yaml file
+type secretField struct {
+	key   string
+	value reflect.Value
+}
+
+// secretFields returns the secret fields of the config, they can be set
+func secretFields(c *Config) []secretField {
+	var fields []secretField
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			key := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if key == "-" || !field.IsExported() {
+				continue
+			}
+			if key == "" {
+				key = strings.ToLower(field.Name)
+			}
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			switch {
+			case field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String:
+				fields = append(fields, secretField{key: key, value: v.Field(i)})
+			case field.Type.Kind() == reflect.Struct:
+				walk(v.Field(i), key)
+			}
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return fields
+}
+
+// resolveSecrets replaces the references in the secret fields with the secrets, the vault
+// token is resolved first so it can be read from a file too. All the failures are returned together
+func resolveSecrets(c *Config, providers map[string]secrets.Provider) error {
+	//the vault client has its own timeout
+	ctx := context.Background()
+	v := &validator{}
+	resolver := secrets.NewResolver()
+	for scheme, p := range providers {
+		resolver.Register(scheme, p)
+	}
+
+	vault := &c.Secrets.Vault
+	token, err := resolver.Resolve(ctx, vault.Token)
+	if err != nil {
+		v.addf("secrets.vault.token", "%v", err)
+	}
+	vault.Token = token
+	if _, custom := providers["vault"]; !custom && vault.Address != "" && err == nil {
+		client, err := secrets.NewVault(secrets.VaultOptions{
+			Address:   vault.Address,
+			Token:     vault.Token,
+			Namespace: vault.Namespace,
+			Timeout:   vault.Timeout,
+		})
+		if err != nil {
+			v.addf("secrets.vault", "%v", err)
+		} else {
+			resolver.Register("vault", client)
+		}
+	}
+
+	for _, field := range secretFields(c) {
+		if field.key == "secrets.vault.token" {
+			continue
+		}
+		secret, err := resolver.Resolve(ctx, field.value.String())
+		if err != nil {
+			v.addf(field.key, "%v", err)
+			continue
+		}
+		field.value.SetString(secret)
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
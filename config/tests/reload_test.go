@@ -0,0 +1,171 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// recorder keeps the configs received by a subscriber
+type recorder struct {
+	mu    sync.Mutex
+	calls [][2]*config.Config
+}
+
+func (r *recorder) notify(prev, next *config.Config) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, [2]*config.Config{prev, next})
+	return nil
+}
+
+func (r *recorder) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.calls)
+}
+
+func (r *recorder) last() (*config.Config, *config.Config) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	call := r.calls[len(r.calls)-1]
+	return call[0], call[1]
+}
+
+func newReloader(t *testing.T, content string) (*config.Reloader, string, *bytes.Buffer) {
+	t.Helper()
+	path := writeFile(t, t.TempDir(), "config.yml", content)
+	cfg, err := config.Load(config.Options{Path: path, Profile: config.ProfileDev})
+	assert.NilError(t, err)
+	assert.NilError(t, cfg.Validate())
+
+	out := &bytes.Buffer{}
+	l := logrus.New()
+	l.SetOutput(out)
+	return config.NewReloader(cfg, nil, l), path, out
+}
+
+// reload, cases:
+// [x] the reloadable sections are swapped and the subscribers notified
+// [x] the changes that need a restart are ignored and reported
+// [x] an invalid config is rejected and the current one kept
+// [x] the subscribers are not notified if nothing changed
+func TestReload(t *testing.T) {
+	reloader, path, out := newReloader(t, base)
+	first := reloader.Current()
+	rec := &recorder{}
+	reloader.Subscribe("recorder", rec.notify)
+
+	changed := strings.Replace(base, `level: "debug"`, `level: "warn"`, 1)
+	changed = strings.Replace(changed, `provider: "random"`, `provider: "local"`, 1)
+	changed = strings.Replace(changed, `port: "8080"`, `port: "9090"`, 1)
+	writeFile(t, "", path, changed)
+	assert.NilError(t, reloader.Reload())
+
+	assert.Equal(t, rec.count(), 1)
+	prev, next := rec.last()
+	assert.Equal(t, prev, first)
+	assert.Equal(t, next, reloader.Current())
+	assert.Equal(t, next.Log.Level, "warn")
+	assert.Equal(t, next.Services.Logo.Provider, "local")
+	assert.Equal(t, next.HTTP.Port, "8080")
+	assert.Assert(t, strings.Contains(out.String(), "the changes to http need a restart"), out.String())
+	//the previous config is never modified
+	assert.Equal(t, first.Log.Level, "debug")
+
+	writeFile(t, "", path, strings.Replace(changed, `level: "warn"`, `level: "loud"`, 1))
+	err := reloader.Reload()
+	assert.ErrorContains(t, err, "logger.level")
+	assert.Equal(t, reloader.Current(), next)
+	assert.Equal(t, rec.count(), 1)
+	assert.Assert(t, strings.Contains(out.String(), "reload rejected"))
+
+	writeFile(t, "", path, "app: [")
+	assert.Assert(t, reloader.Reload() != nil)
+	assert.Equal(t, reloader.Current(), next)
+
+	writeFile(t, "", path, changed)
+	assert.NilError(t, reloader.Reload())
+	assert.Equal(t, rec.count(), 1)
+}
+
+// run runs the reloader until the end of the test
+func run(t *testing.T, reloader *config.Reloader) {
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reloader.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+}
+
+// waitLevel runs change until the reloader has the level
+func waitLevel(t *testing.T, reloader *config.Reloader, level string, change func()) {
+	t.Helper()
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		change()
+		if reloader.Current().Log.Level == level {
+			return poll.Success()
+		}
+		return poll.Continue("level is still %s", reloader.Current().Log.Level)
+	}, poll.WithTimeout(5*time.Second), poll.WithDelay(50*time.Millisecond))
+}
+
+// the config is reloaded when the file changes
+func TestReloadWatch(t *testing.T) {
+	watch := base + "reload:\n  watch: true\n  debounce: \"20ms\"\n"
+	reloader, path, _ := newReloader(t, watch)
+	rec := &recorder{}
+	reloader.Subscribe("recorder", rec.notify)
+	run(t, reloader)
+
+	//the watcher could not be ready yet, the file is written until the change is seen
+	waitLevel(t, reloader, "error", func() {
+		writeFile(t, "", path, strings.Replace(watch, `level: "debug"`, `level: "error"`, 1))
+	})
+	assert.Equal(t, rec.count(), 1)
+}
+
+// the config is reloaded on SIGHUP
+func TestReloadSighup(t *testing.T) {
+	//without a handler SIGHUP would kill the test before Run handles it
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	defer signal.Stop(sig)
+
+	reloader, path, _ := newReloader(t, base)
+	run(t, reloader)
+	writeFile(t, "", path, strings.Replace(base, `level: "debug"`, `level: "trace"`, 1))
+	waitLevel(t, reloader, "trace", func() {
+		syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	})
+}
+
+// the rate limits are applied without a restart, their store needs it
+func TestReloadRateLimit(t *testing.T) {
+	limited := base + "rate_limit:\n  enabled: true\n  api:\n    algorithm: \"token_bucket\"\n    requests: 10\n    period: \"1m\"\n    key: \"ip\"\n"
+	reloader, path, out := newReloader(t, limited)
+
+	changed := strings.Replace(limited, "requests: 10", "requests: 20", 1)
+	changed = strings.Replace(changed, "enabled: true\n", "enabled: true\n  store: \"redis\"\n", 1)
+	writeFile(t, "", path, changed)
+	assert.NilError(t, reloader.Reload())
+	assert.Equal(t, reloader.Current().RateLimit.API.Requests, 20)
+	assert.Equal(t, reloader.Current().RateLimit.Store, "memory")
+	assert.Assert(t, strings.Contains(out.String(), "the changes to rate_limit need a restart"), out.String())
+}
@@ -0,0 +1,307 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/avatar"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"github.com/vano2903/service-template/pkg/tracing"
+)
+
+// MinJWTSecretLength is the minimum length of the secret used to sign the jwts (256 bits for HS256)
+const MinJWTSecretLength = 32
+
+// values shipped in the example files or left from the tutorials, they are refused outside the dev profile
+var insecureSecrets = []string{
+	"dev-only-jwt-secret-change-me-0123456789",
+	"aSuperSecretKey",
+	"secret",
+	"password",
+	"changeme",
+	"change-me",
+	"minioadmin",
+}
+
+var (
+	databaseDrivers  = []string{"mock"}
+	rateLimitStores  = []string{"memory", "redis"}
+	rateLimitAlgos   = []string{ratelimit.TokenBucket, ratelimit.SlidingWindow}
+	rateLimitKeys    = []string{ratelimit.KeyIP, ratelimit.KeyUser, ratelimit.KeyAPIKey}
+	storageDrivers   = []string{"local", "s3"}
+	logoProviders    = []string{"random", "http", "local"}
+	logoFallbacks    = []string{"", "local"}
+	logTypes         = []string{"text", "json"}
+	logOutputs       = []string{logger.OutputStdout, logger.OutputStderr, logger.OutputFile}
+	avatarStyles     = []string{avatar.StyleIdenticon, avatar.StyleInitials}
+	avatarFormats    = []string{"svg", "png"}
+	tracingExporters = []string{tracing.ExporterNone, tracing.ExporterOTLP, tracing.ExporterStdout, tracing.ExporterFile}
+)
+
+// ValidationError has every problem found in the config, so they can be fixed all at once
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid config:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
+
+// validator collects the problems, the keys are the ones of the yaml file
+type validator struct {
+	problems []string
+}
+
+func (v *validator) addf(key, format string, args ...interface{}) {
+	v.problems = append(v.problems, key+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *validator) required(key, value string) bool {
+	if value == "" {
+		v.addf(key, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) oneOf(key, value string, allowed []string) {
+	if !contains(allowed, value) {
+		v.addf(key, "unknown value %q, it must be one of %s", value, quoteAll(allowed))
+	}
+}
+
+func (v *validator) positive(key string, value int64) {
+	if value <= 0 {
+		v.addf(key, "must be greater than 0, got %d", value)
+	}
+}
+
+func (v *validator) positiveDuration(key string, value time.Duration) {
+	if value <= 0 {
+		v.addf(key, "must be a positive duration, got %s", value)
+	}
+}
+
+func (v *validator) url(key, value string) {
+	u, err := url.Parse(value)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		v.addf(key, "%q is not a valid http(s) url", value)
+	}
+}
+
+func (v *validator) port(key, value string) {
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		v.addf(key, "%q is not a valid port, it must be a number from 1 to 65535", value)
+	}
+}
+
+// rateLimit checks a rule, the rules with 0 requests are disabled
+func (v *validator) rateLimit(key string, rule RateLimitRule) {
+	if rule.Requests == 0 {
+		return
+	}
+	v.oneOf(key+".algorithm", rule.Algorithm, rateLimitAlgos)
+	v.positive(key+".requests", int64(rule.Requests))
+	v.positiveDuration(key+".period", rule.Period)
+	if rule.Period > 0 && rule.Period < time.Duration(rule.Requests) {
+		v.addf(key+".period", "is too short for %d requests, it must be at least 1ns per request", rule.Requests)
+	}
+	if rule.Burst < 0 {
+		v.addf(key+".burst", "can't be negative, 0 uses the requests")
+	}
+	v.oneOf(key+".key", rule.Key, rateLimitKeys)
+}
+
+// Validate checks the values of the config (not only that they are set but also that they make sense),
+// it returns a *ValidationError with all the problems found
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	v.required("app.name", c.App.Name)
+	v.required("app.version", c.App.Version)
+
+	if v.required("http.port", c.HTTP.Port) {
+		v.port("http.port", c.HTTP.Port)
+	}
+	if v.required("http.jwtSecret", c.HTTP.JWTSecret) && len(c.HTTP.JWTSecret) < MinJWTSecretLength {
+		v.addf("http.jwtSecret", "is too weak, it must be at least %d characters long", MinJWTSecretLength)
+	}
+	v.url("http.public_url", c.HTTP.PublicUrl)
+	for _, cidr := range c.HTTP.TrustedProxies {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			v.addf("http.trusted_proxies", "%q is not a cidr (like 10.0.0.0/8)", cidr)
+		}
+	}
+	if c.App.Profile != ProfileDev {
+		for _, field := range secretFields(c) {
+			if isInsecure(field.value.String()) {
+				v.addf(field.key, "is a known insecure default, it can be used only with the %s profile", ProfileDev)
+			}
+		}
+	}
+
+	if c.GRPC.Enabled && v.required("grpc.port", c.GRPC.Port) {
+		v.port("grpc.port", c.GRPC.Port)
+		if c.GRPC.Port == c.HTTP.Port {
+			v.addf("grpc.port", "must be different from http.port, the two servers listen on their own port")
+		}
+	}
+
+	v.positiveDuration("shutdown.drain_timeout", c.Shutdown.DrainTimeout)
+	v.positiveDuration("health.timeout", c.Health.Timeout)
+
+	if c.Reload.Watch {
+		v.positiveDuration("reload.debounce", c.Reload.Debounce)
+	}
+
+	v.oneOf("tracing.exporter", c.Tracing.Exporter, tracingExporters)
+	if c.Tracing.Exporter == tracing.ExporterOTLP {
+		v.required("tracing.endpoint", c.Tracing.Endpoint)
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile {
+		v.required("tracing.file", c.Tracing.File)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.addf("tracing.sample_ratio", "must be between 0 and 1, got %v", c.Tracing.SampleRatio)
+	}
+
+	if v.required("logger.level", c.Log.Level) {
+		if _, err := logrus.ParseLevel(c.Log.Level); err != nil {
+			v.addf("logger.level", "unknown level %q, it must be one of trace, debug, info, warn, error, fatal or panic", c.Log.Level)
+		}
+	}
+	v.oneOf("logger.type", c.Log.Type, logTypes)
+	if len(c.Log.Outputs) == 0 {
+		v.addf("logger.outputs", "at least an output is needed")
+	}
+	for _, output := range c.Log.Outputs {
+		v.oneOf("logger.outputs", output, logOutputs)
+		if output == logger.OutputFile {
+			v.required("logger.file.path", c.Log.File.Path)
+			v.positive("logger.file.max_size_mb", int64(c.Log.File.MaxSizeMB))
+		}
+	}
+	if c.Log.File.MaxAgeDays < 0 {
+		v.addf("logger.file.max_age_days", "can't be negative, 0 keeps the files")
+	}
+	if c.Log.File.MaxBackups < 0 {
+		v.addf("logger.file.max_backups", "can't be negative, 0 keeps all the files")
+	}
+	for _, pattern := range c.Log.Redact.Patterns {
+		v.oneOf("logger.redact.patterns", pattern, logger.RedactPatterns())
+	}
+
+	v.oneOf("database.driver", c.Database.Driver, databaseDrivers)
+
+	if c.RateLimit.Enabled {
+		v.oneOf("rate_limit.store", c.RateLimit.Store, rateLimitStores)
+		if c.RateLimit.Store == "redis" {
+			v.required("rate_limit.redis.address", c.RateLimit.Redis.Address)
+			v.positiveDuration("rate_limit.redis.timeout", c.RateLimit.Redis.Timeout)
+		}
+		v.rateLimit("rate_limit.auth", c.RateLimit.Auth)
+		v.rateLimit("rate_limit.api", c.RateLimit.API)
+		if (c.RateLimit.Auth.Key == ratelimit.KeyAPIKey || c.RateLimit.API.Key == ratelimit.KeyAPIKey) && c.RateLimit.APIKeys == "" {
+			v.addf("rate_limit.api_keys", "is required to group the requests by api key")
+		}
+	}
+
+	if c.Fixtures.OnStart {
+		//the fixtures have well known passwords
+		if c.App.Profile == ProfileProd {
+			v.addf("fixtures.on_start", "the fixtures can't be loaded in production")
+		}
+		if len(c.Fixtures.Files) == 0 {
+			v.addf("fixtures.files", "at least a file is needed to load the fixtures on start")
+		}
+	}
+
+	logo := c.Services.Logo
+	v.oneOf("services.logo.provider", logo.Provider, logoProviders)
+	v.oneOf("services.logo.fallback", logo.Fallback, logoFallbacks)
+	if logo.Provider == "http" {
+		if v.required("services.logo.base_url", logo.BaseUrl) {
+			v.url("services.logo.base_url", logo.BaseUrl)
+		}
+		v.required("services.logo.api_key", logo.ApiKey)
+		v.positiveDuration("services.logo.timeout", logo.Timeout)
+		if logo.MaxRetries < 0 {
+			v.addf("services.logo.max_retries", "can't be negative")
+		}
+		v.positive("services.logo.breaker_threshold", int64(logo.BreakerThreshold))
+		v.positiveDuration("services.logo.breaker_cooldown", logo.BreakerCooldown)
+	}
+	if logo.Provider == "local" || logo.Fallback == "local" {
+		v.url("services.logo.local.public_url", logo.Local.PublicUrl)
+		v.oneOf("services.logo.local.style", logo.Local.Style, avatarStyles)
+		v.oneOf("services.logo.local.format", logo.Local.Format, avatarFormats)
+	}
+
+	v.positiveDuration("events.dispatch_interval", c.Events.DispatchInterval)
+	v.positive("events.batch_size", int64(c.Events.BatchSize))
+
+	if c.Webhooks.Enabled {
+		v.positiveDuration("webhooks.timeout", c.Webhooks.Timeout)
+		v.positive("webhooks.max_attempts", int64(c.Webhooks.MaxAttempts))
+	}
+
+	v.oneOf("storage.driver", c.Storage.Driver, storageDrivers)
+	switch c.Storage.Driver {
+	case "local":
+		v.required("storage.local.path", c.Storage.Local.Path)
+	case "s3":
+		v.required("storage.s3.bucket", c.Storage.S3.Bucket)
+		v.required("storage.s3.region", c.Storage.S3.Region)
+		v.required("storage.s3.access_key", c.Storage.S3.AccessKey)
+		v.required("storage.s3.secret_key", c.Storage.S3.SecretKey)
+		if c.Storage.S3.Endpoint != "" {
+			v.url("storage.s3.endpoint", c.Storage.S3.Endpoint)
+		}
+	}
+
+	v.positive("uploads.pfp_max_size", c.Uploads.PfpMaxSize)
+	if len(c.Uploads.PfpSizes) == 0 {
+		v.addf("uploads.pfp_sizes", "at least a size is needed")
+	}
+	for _, size := range c.Uploads.PfpSizes {
+		v.positive("uploads.pfp_sizes", int64(size))
+	}
+
+	if c.PfpQueue.Enabled {
+		v.positive("pfp_queue.workers", int64(c.PfpQueue.Workers))
+		v.positive("pfp_queue.size", int64(c.PfpQueue.Size))
+		v.positive("pfp_queue.max_attempts", int64(c.PfpQueue.MaxAttempts))
+		v.positiveDuration("pfp_queue.min_backoff", c.PfpQueue.MinBackoff)
+		if c.PfpQueue.MaxBackoff < c.PfpQueue.MinBackoff {
+			v.addf("pfp_queue.max_backoff", "must not be shorter than min_backoff (%s)", c.PfpQueue.MinBackoff)
+		}
+	}
+
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+func isInsecure(secret string) bool {
+	for _, insecure := range insecureSecrets {
+		if strings.EqualFold(secret, insecure) {
+			return true
+		}
+	}
+	return false
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = strconv.Quote(value)
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+)
+
+var _ AdminControllerer = new(Admin)
+
+var (
+	ErrInvalidLogLevel  = errors.New("invalid log level")
+	ErrUnknownComponent = errors.New("unknown log component")
+)
+
+// LogLeveler changes the level of the logs of the components at runtime (see logger.Levels)
+type LogLeveler interface {
+	Levels() []logger.ComponentLevel
+	SetLevel(component string, level logrus.Level, ttl time.Duration) error
+	ResetLevel(component string) error
+}
+
+// Admin has the operations on the service itself, they are all only for admins
+type Admin struct {
+	users repo.UserRepoer
+	logs  LogLeveler
+	l     *logrus.Logger
+}
+
+func NewAdminController(users repo.UserRepoer, logs LogLeveler, log *logrus.Logger) *Admin {
+	return &Admin{
+		users: users,
+		logs:  logs,
+		l:     log,
+	}
+}
+
+// log returns the logger of the request (or of the job) that is running
+func (c *Admin) log(ctx context.Context) *logrus.Entry {
+	return logger.FromContext(ctx, c.l)
+}
+
+// checkAdmin returns nil only if the requester exists and is an admin
+func checkAdmin(ctx context.Context, users repo.UserRepoer, log *logrus.Entry, requesterId int) error {
+	requester, err := users.Get(ctx, requesterId)
+	if err != nil {
+		re, ok := err.(*mock.ErrUserNotFound)
+		if ok {
+			log.Errorf("requester with id %d not found", re.ID)
+			return ErrUserNotFound
+		} else {
+			log.Errorf("controller.checkAdmin: unexpected error in repo.Get: %v", err)
+			return ErrUnexpected
+		}
+	}
+	if requester.Role != model.RoleAdmin {
+		return ErrNotAdmin
+	}
+	return nil
+}
+
+func (c *Admin) GetLogLevels(ctx context.Context, requesterId int) ([]logger.ComponentLevel, error) {
+	ctx, span := tracer.Start(ctx, "controller.Admin.GetLogLevels")
+	defer span.End()
+	if err := checkAdmin(ctx, c.users, c.log(ctx), requesterId); err != nil {
+		return nil, err
+	}
+	return c.logs.Levels(), nil
+}
+
+// SetLogLevel changes the level of the component (logger.RootComponent for every component without
+// a level of its own), if ttl is positive the level goes back to the default one after ttl
+func (c *Admin) SetLogLevel(ctx context.Context, requesterId int, component, level string, ttl time.Duration) error {
+	ctx, span := tracer.Start(ctx, "controller.Admin.SetLogLevel")
+	defer span.End()
+	if err := checkAdmin(ctx, c.users, c.log(ctx), requesterId); err != nil {
+		return err
+	}
+
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil || ttl < 0 {
+		return ErrInvalidLogLevel
+	}
+	if err := c.logs.SetLevel(component, lvl, ttl); err != nil {
+		if err == logger.ErrUnknownComponent {
+			return ErrUnknownComponent
+		}
+		c.log(ctx).Errorf("controller.SetLogLevel: unexpected error in logs.SetLevel: %v", err)
+		return ErrUnexpected
+	}
+
+	if ttl > 0 {
+		c.log(ctx).Warnf("log level of %s set to %s for %s by user %d", component, lvl, ttl, requesterId)
+	} else {
+		c.log(ctx).Warnf("log level of %s set to %s by user %d", component, lvl, requesterId)
+	}
+	return nil
+}
+
+// ResetLogLevel brings the component back to its default level
+func (c *Admin) ResetLogLevel(ctx context.Context, requesterId int, component string) error {
+	ctx, span := tracer.Start(ctx, "controller.Admin.ResetLogLevel")
+	defer span.End()
+	if err := checkAdmin(ctx, c.users, c.log(ctx), requesterId); err != nil {
+		return err
+	}
+
+	if err := c.logs.ResetLevel(component); err != nil {
+		if err == logger.ErrUnknownComponent {
+			return ErrUnknownComponent
+		}
+		c.log(ctx).Errorf("controller.ResetLogLevel: unexpected error in logs.ResetLevel: %v", err)
+		return ErrUnexpected
+	}
+	c.log(ctx).Warnf("log level of %s reset by user %d", component, requesterId)
+	return nil
+}
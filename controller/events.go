@@ -0,0 +1,48 @@
+package controller
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/repo"
+)
+
+// EventUser is the snapshot of the user stored as payload of the domain events.
+// The events leave the service (sinks, webhooks...) so it never contains the password.
+type EventUser struct {
+	ID        int    `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Pfp       string `json:"pfp"`
+	PfpStatus string `json:"pfp_status"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	IsBanned  bool   `json:"is_banned"`
+}
+
+// publishEvent adds the event to the outbox, it must be called inside a repo transaction
+// so that the event is stored only if the change it describes is stored as well
+func publishEvent(outbox repo.OutboxRepoer, eventType string, u *model.User) error {
+	payload, err := json.Marshal(EventUser{
+		ID:        u.ID,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Pfp:       u.Pfp,
+		PfpStatus: u.PfpStatus,
+		Email:     u.Email,
+		Role:      u.Role,
+		IsBanned:  u.IsBanned,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = outbox.AddEvent(&model.Event{
+		Type:       eventType,
+		UserID:     u.ID,
+		Payload:    payload,
+		OccurredAt: time.Now(),
+	})
+	return err
+}
@@ -7,17 +7,47 @@ package controller
 //because it is the business logic. (for example there shouldn't be a mock for the controller)
 
 import (
+	"context"
+	"io"
+	"time"
+
 	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/providers/blob"
 )
 
 type (
 	UserControllerer interface {
-		CreateUser(firstName, lastName, email, password, role string) (int, error)
-		GetUser(id int) (*model.User, error)
-		GetAllUsers() []*model.User
-		UpdateUser(requesterId int, u *model.User) error
-		DeleteUser(requesterId int, id int) error
-		RegeneratePfp(id int) error
-		CheckCredentials(email, password string) (int, error)
+		CreateUser(ctx context.Context, firstName, lastName, email, password, role string) (int, error)
+		GetUser(ctx context.Context, id int) (*model.User, error)
+		GetAllUsers(ctx context.Context) []*model.User
+		GetUsers(ctx context.Context, ids []int) ([]*model.User, error)
+		UpdateUser(ctx context.Context, requesterId int, u *model.User) error
+		DeleteUser(ctx context.Context, requesterId int, id int) error
+		RegeneratePfp(ctx context.Context, id int) error
+		CheckCredentials(ctx context.Context, email, password string) (int, error)
+		//operations run by the operators, without a requester
+		SetBanned(ctx context.Context, id int, banned bool) error
+		SetRole(ctx context.Context, id int, role string) error
+		UpsertUser(ctx context.Context, u *model.User) (int, string, error)
+	}
+
+	WebhookControllerer interface {
+		CreateWebhook(ctx context.Context, requesterId int, url string, events []string, secret string) (*model.Webhook, error)
+		GetAllWebhooks(ctx context.Context, requesterId int) ([]*model.Webhook, error)
+		DeleteWebhook(ctx context.Context, requesterId, id int) error
+		GetDeliveries(ctx context.Context, requesterId, webhookId int, status string) ([]*model.WebhookDelivery, error)
+		Redeliver(ctx context.Context, requesterId, deliveryId int) error
+	}
+
+	AdminControllerer interface {
+		GetLogLevels(ctx context.Context, requesterId int) ([]logger.ComponentLevel, error)
+		SetLogLevel(ctx context.Context, requesterId int, component, level string, ttl time.Duration) error
+		ResetLogLevel(ctx context.Context, requesterId int, component string) error
+	}
+
+	PfpControllerer interface {
+		UploadPfp(ctx context.Context, requesterId, userId int, r io.Reader) ([]PfpThumbnail, error)
+		GetPfp(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error)
 	}
 )
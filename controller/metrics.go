@@ -0,0 +1,50 @@
+package controller
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Business metrics of the user controller. They are counted even if they are not registered
+// so the controller works the same way in the tests, RegisterMetrics exposes them.
+var (
+	registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "users",
+		Name:      "registrations_total",
+		Help:      "User registrations by outcome (success, already_exists, error).",
+	}, []string{"outcome"})
+	logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "users",
+		Name:      "logins_total",
+		Help:      "Logins by outcome (success, not_found, wrong_password, error).",
+	}, []string{"outcome"})
+	updates = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "users",
+		Name:      "updates_total",
+		Help:      "User updates by outcome (success, forbidden, not_found, unupdatable, error).",
+	}, []string{"outcome"})
+	deletions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "users",
+		Name:      "deletions_total",
+		Help:      "User deletions by outcome (success, forbidden, not_found, error).",
+	}, []string{"outcome"})
+)
+
+const (
+	outcomeSuccess       = "success"
+	outcomeError         = "error"
+	outcomeNotFound      = "not_found"
+	outcomeForbidden     = "forbidden"
+	outcomeAlreadyExists = "already_exists"
+	outcomeWrongPassword = "wrong_password"
+	outcomeUnupdatable   = "unupdatable"
+)
+
+// RegisterMetrics registers the metrics of the controllers, it must be called once
+func RegisterMetrics(reg prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{registrations, logins, updates, deletions} {
+		if err := reg.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,214 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/imageproc"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/providers/blob"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+)
+
+var (
+	_ PfpControllerer = new(Pfp)
+	_ PfpRemover      = new(Pfp)
+)
+
+const pfpKeyPrefix = "pfp/"
+
+var (
+	ErrPfpTooLarge = errors.New("profile picture too large")
+	ErrInvalidPfp  = errors.New("invalid profile picture")
+	ErrPfpNotFound = errors.New("profile picture not found")
+)
+
+// PfpThumbnail is a size of an uploaded profile picture
+type PfpThumbnail struct {
+	Size int
+	URL  string
+}
+
+// Pfp handles the profile pictures uploaded by the users.
+// Every upload is stored as a new version (pfp/<user id>/<version>/<size>.<ext>)
+// so the urls never change content and can be cached forever.
+type Pfp struct {
+	repo      repo.UserRepoer
+	blob      blob.BlobStorer
+	l         *logrus.Logger
+	publicUrl string
+	sizes     []int
+	maxBytes  int64
+}
+
+func NewPfpController(repo repo.UserRepoer, blob blob.BlobStorer, log *logrus.Logger, publicUrl string, sizes []int, maxBytes int64) *Pfp {
+	sorted := append([]int(nil), sizes...)
+	sort.Ints(sorted)
+	return &Pfp{
+		repo:      repo,
+		blob:      blob,
+		l:         log,
+		publicUrl: strings.TrimRight(publicUrl, "/"),
+		sizes:     sorted,
+		maxBytes:  maxBytes,
+	}
+}
+
+// log returns the logger of the request (or of the job) that is running
+func (c *Pfp) log(ctx context.Context) *logrus.Entry {
+	return logger.FromContext(ctx, c.l)
+}
+
+// UploadPfp processes the image and sets it as profile picture of the user, a user can upload only
+// his own picture while admins can upload it for anyone.
+// It returns the thumbnails from the smallest, the profile picture of the user is the biggest one.
+// The files of the picture uploaded before are deleted once the user is updated.
+func (c *Pfp) UploadPfp(ctx context.Context, requesterId, userId int, r io.Reader) ([]PfpThumbnail, error) {
+	ctx, span := tracer.Start(ctx, "controller.Pfp.UploadPfp")
+	defer span.End()
+	requester, err := c.repo.Get(ctx, requesterId)
+	if err != nil {
+		re, ok := err.(*mock.ErrUserNotFound)
+		if ok {
+			c.log(ctx).Errorf("upload requester with id %d not found", re.ID)
+			return nil, ErrUserNotFound
+		} else {
+			c.log(ctx).Errorf("controller.UploadPfp: unexpected error in repo.Get: %v", err)
+			return nil, ErrUnexpected
+		}
+	}
+	if requester.ID != userId && requester.Role != model.RoleAdmin {
+		return nil, ErrNotAdmin
+	}
+
+	//reading one byte more than the limit to know if the limit was exceeded
+	data, err := io.ReadAll(io.LimitReader(r, c.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPfp, err)
+	}
+	if int64(len(data)) > c.maxBytes {
+		return nil, ErrPfpTooLarge
+	}
+
+	thumbnails, err := imageproc.Thumbnails(data, c.sizes)
+	if err != nil {
+		if errors.Is(err, imageproc.ErrUnsupportedType) || errors.Is(err, imageproc.ErrInvalidImage) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPfp, err)
+		}
+		c.log(ctx).Errorf("controller.UploadPfp: unexpected error in imageproc.Thumbnails: %v", err)
+		return nil, ErrUnexpected
+	}
+
+	version := make([]byte, 8)
+	if _, err := rand.Read(version); err != nil {
+		c.log(ctx).Errorf("controller.UploadPfp: unable to generate version: %v", err)
+		return nil, ErrUnexpected
+	}
+
+	urls := make([]PfpThumbnail, 0, len(thumbnails))
+	keys := make([]string, 0, len(thumbnails))
+	for _, t := range thumbnails {
+		key := fmt.Sprintf("%s%d/%s/%d.%s", pfpKeyPrefix, userId, hex.EncodeToString(version), t.Size, t.Ext)
+		if err := c.blob.Put(ctx, key, bytes.NewReader(t.Data), int64(len(t.Data)), t.ContentType); err != nil {
+			c.log(ctx).Errorf("controller.UploadPfp: unexpected error in blob.Put: %v", err)
+			c.deleteBlobs(ctx, keys)
+			return nil, ErrUnexpected
+		}
+		keys = append(keys, key)
+		urls = append(urls, PfpThumbnail{Size: t.Size, URL: c.publicUrl + "/" + key})
+	}
+	sort.Slice(urls, func(i, j int) bool { return urls[i].Size < urls[j].Size })
+
+	var previous string
+	err = c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+		u, err := users.Get(ctx, userId)
+		if err != nil {
+			return err
+		}
+		previous = u.Pfp
+		u.Pfp = urls[len(urls)-1].URL
+		u.PfpStatus = model.PfpStatusReady
+		if err := users.Update(ctx, u); err != nil {
+			return err
+		}
+		return publishEvent(outbox, model.EventPfpUploaded, u)
+	})
+	if err != nil {
+		//the images are useless if the user was not updated
+		c.deleteBlobs(ctx, keys)
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			return nil, ErrUserNotFound
+		} else if err == mock.ErrUserUnapdatable {
+			return nil, ErrUnupdatableUser
+		}
+		c.log(ctx).Errorf("controller.UploadPfp: unexpected error updating the user: %v", err)
+		return nil, ErrUnexpected
+	}
+	c.DeleteUploaded(ctx, userId, previous)
+	return urls, nil
+}
+
+// DeleteUploaded deletes the files of the picture uploaded by the user with the url, nothing if
+// the url is not of an upload. It's called once the picture is no longer the one of the user
+func (c *Pfp) DeleteUploaded(ctx context.Context, userId int, url string) {
+	c.deleteBlobs(ctx, c.uploadedKeys(userId, url))
+}
+
+// uploadedKeys returns the keys of every size of the uploaded picture with the url, none if the url
+// is not of an upload (a generated logo...). The sizes are the configured ones, the files of the sizes
+// removed from the config since the upload are left in the storage
+func (c *Pfp) uploadedKeys(userId int, url string) []string {
+	prefix := c.publicUrl + "/" + pfpKeyPrefix + strconv.Itoa(userId) + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return nil
+	}
+	version, _, found := strings.Cut(strings.TrimPrefix(url, prefix), "/")
+	if !found || version == "" || strings.Contains(version, "..") {
+		return nil
+	}
+	keys := make([]string, 0, len(c.sizes))
+	for _, size := range c.sizes {
+		keys = append(keys, fmt.Sprintf("%s%d/%s/%d%s", pfpKeyPrefix, userId, version, size, path.Ext(url)))
+	}
+	return keys
+}
+
+func (c *Pfp) deleteBlobs(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		//the request could be canceled, the blobs must be deleted anyway
+		if err := c.blob.Delete(context.Background(), key); err != nil {
+			c.log(ctx).Errorf("controller.Pfp: unable to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+// GetPfp returns the uploaded image with the given key (the path of the url after the public url),
+// the caller must close the reader
+func (c *Pfp) GetPfp(ctx context.Context, key string) (io.ReadCloser, *blob.Info, error) {
+	ctx, span := tracer.Start(ctx, "controller.Pfp.GetPfp")
+	defer span.End()
+	if !strings.HasPrefix(key, pfpKeyPrefix) || strings.Contains(key, "..") {
+		return nil, nil, ErrPfpNotFound
+	}
+	r, info, err := c.blob.Get(ctx, key)
+	if err != nil {
+		if err == blob.ErrNotFound {
+			return nil, nil, ErrPfpNotFound
+		}
+		c.log(ctx).Errorf("controller.GetPfp: unexpected error in blob.Get: %v", err)
+		return nil, nil, ErrUnexpected
+	}
+	return r, info, nil
+}
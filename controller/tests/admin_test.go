@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// runtime log levels, cases:
+// [x] only admins can read and change the levels
+// [x] an invalid level or a negative ttl is rejected
+// [x] an unknown component is rejected
+// [x] an admin changes and resets the level of a component
+func TestLogLevels(t *testing.T) {
+	repo := mock.NewRepo()
+	uc := controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+
+	root := logger.NewLogger("info", "json")
+	root.SetOutput(&bytes.Buffer{})
+	levels := logger.NewLevels(root)
+	repoLog := levels.Component("repo")
+	ac := controller.NewAdminController(repo, levels, l)
+
+	adminID, err := uc.CreateUser(context.Background(), "admin", "admin", "admin@levels.com", "password", model.RoleAdmin)
+	assert.NilError(t, err)
+	userID, err := uc.CreateUser(context.Background(), "user", "user", "user@levels.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+
+	_, err = ac.GetLogLevels(context.Background(), userID)
+	assert.Equal(t, err, controller.ErrNotAdmin)
+	err = ac.SetLogLevel(context.Background(), userID, "repo", "debug", 0)
+	assert.Equal(t, err, controller.ErrNotAdmin)
+
+	err = ac.SetLogLevel(context.Background(), adminID, "repo", "verbose", 0)
+	assert.Equal(t, err, controller.ErrInvalidLogLevel)
+	err = ac.SetLogLevel(context.Background(), adminID, "repo", "debug", -time.Minute)
+	assert.Equal(t, err, controller.ErrInvalidLogLevel)
+	err = ac.SetLogLevel(context.Background(), adminID, "graphql", "debug", 0)
+	assert.Equal(t, err, controller.ErrUnknownComponent)
+
+	err = ac.SetLogLevel(context.Background(), adminID, "repo", "debug", time.Hour)
+	assert.NilError(t, err)
+	assert.Equal(t, repoLog.GetLevel(), logrus.DebugLevel)
+	current, err := ac.GetLogLevels(context.Background(), adminID)
+	assert.NilError(t, err)
+	assert.Equal(t, len(current), 2)
+	assert.Equal(t, current[1].Component, "repo")
+	assert.Equal(t, current[1].Level, "debug")
+	assert.Assert(t, current[1].ExpiresAt != nil)
+
+	assert.NilError(t, ac.ResetLogLevel(context.Background(), adminID, "repo"))
+	assert.Equal(t, repoLog.GetLevel(), logrus.InfoLevel)
+}
@@ -1,12 +1,14 @@
 package controller
 
 import (
+	"context"
 	"log"
 	"testing"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vano2903/service-template/config"
 	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
 	"github.com/vano2903/service-template/model"
 	"github.com/vano2903/service-template/pkg/logger"
 	"github.com/vano2903/service-template/providers/logo"
@@ -50,14 +52,14 @@ func TestCreateUser(t *testing.T) {
 	var id int
 	t.Run("create user", func(t *testing.T) {
 
-		id, err := c.CreateUser(firstName, lastName, email, password, model.RoleUser)
+		id, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUser)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
 
 		//we get the user from the repo directly to make sure
 		//it's actually been created correctly
-		user, err := r.Get(id)
+		user, err := r.Get(context.Background(), id)
 		if err != nil {
 			re, ok := err.(*mock.ErrUserNotFound)
 			if ok {
@@ -76,7 +78,7 @@ func TestCreateUser(t *testing.T) {
 
 	t.Run("create duplicate user", func(t *testing.T) {
 		var err error
-		id, err = c.CreateUser(firstName, lastName, email, password, model.RoleUser)
+		id, err = c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUser)
 		if err == nil {
 			t.Error("duplicate user was created, should not happen")
 		}
@@ -84,7 +86,7 @@ func TestCreateUser(t *testing.T) {
 	})
 
 	t.Cleanup(func() {
-		if err := r.Delete(id); err != nil {
+		if err := r.Delete(context.Background(), id); err != nil {
 			t.Errorf("error cleaning up test case: %v", err)
 		}
 	})
@@ -102,12 +104,12 @@ func TestUpdateUser(t *testing.T) {
 	email := "test@test.com"
 	password := "password"
 	t.Run("update roleUser user", func(t *testing.T) {
-		id, err := c.CreateUser(firstName, lastName, email, password, model.RoleUser)
+		id, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUser)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
 
-		updatedUser, err := r.Get(id)
+		updatedUser, err := r.Get(context.Background(), id)
 		if err != nil {
 			t.Errorf("unable to get user from repo: %v", err)
 		}
@@ -118,11 +120,11 @@ func TestUpdateUser(t *testing.T) {
 		updatedUser.FirstName = updatedFirstName
 		updatedUser.Password = updatedPassword
 
-		if err := c.UpdateUser(id, updatedUser); err != nil {
+		if err := c.UpdateUser(context.Background(), id, updatedUser); err != nil {
 			t.Errorf("unable to update user: %v", err)
 		}
 
-		user, err := r.Get(id)
+		user, err := r.Get(context.Background(), id)
 		t.Log(user)
 		if err != nil {
 			t.Error("unable to get user from repo")
@@ -131,14 +133,14 @@ func TestUpdateUser(t *testing.T) {
 		assert.Equal(t, user.FirstName, updatedFirstName)
 		assert.Equal(t, user.Password, updatedPassword)
 		t.Cleanup(func() {
-			if err := r.Delete(id); err != nil {
+			if err := r.Delete(context.Background(), id); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
 		})
 	})
 
 	t.Run("unable to update roleUnupdatable user", func(t *testing.T) {
-		id, err := c.CreateUser(firstName, lastName, email, password, model.RoleUnupdatable)
+		id, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUnupdatable)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
@@ -152,18 +154,18 @@ func TestUpdateUser(t *testing.T) {
 			Password:  updatedPassword,
 			Role:      model.RoleUser,
 		}
-		if err := c.UpdateUser(id, &updatedUser); err == nil {
+		if err := c.UpdateUser(context.Background(), id, &updatedUser); err == nil {
 			t.Error("unupdatable user was updated, should not happen")
 		}
 		t.Cleanup(func() {
-			if err := r.Delete(id); err != nil {
+			if err := r.Delete(context.Background(), id); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
 		})
 	})
 
 	t.Run("block roleUser user to update another user", func(t *testing.T) {
-		idUpdater, err := c.CreateUser(firstName, lastName, email, password, model.RoleUser)
+		idUpdater, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUser)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
@@ -176,27 +178,27 @@ func TestUpdateUser(t *testing.T) {
 			Role:      model.RoleUser,
 		}
 
-		idUpdated, err := c.CreateUser(toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
+		idUpdated, err := c.CreateUser(context.Background(), toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
 
-		if err := c.UpdateUser(idUpdater, &toUpdate); err == nil {
+		if err := c.UpdateUser(context.Background(), idUpdater, &toUpdate); err == nil {
 			t.Error("roleUser user was able to update another user, should not happen")
 		}
 
 		t.Cleanup(func() {
-			if err := r.Delete(idUpdater); err != nil {
+			if err := r.Delete(context.Background(), idUpdater); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
-			if err := r.Delete(idUpdated); err != nil {
+			if err := r.Delete(context.Background(), idUpdated); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
 		})
 	})
 
 	t.Run("allow roleAdmin user to update any user", func(t *testing.T) {
-		idUpdater, err := c.CreateUser(firstName, lastName, email, password, model.RoleAdmin)
+		idUpdater, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleAdmin)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
@@ -209,32 +211,32 @@ func TestUpdateUser(t *testing.T) {
 			Role:      model.RoleUser,
 		}
 
-		idUpdated, err := c.CreateUser(toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
+		idUpdated, err := c.CreateUser(context.Background(), toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
 
-		toUpdate, err = r.Get(idUpdated)
+		toUpdate, err = r.Get(context.Background(), idUpdated)
 		if err != nil {
 			t.Errorf("unable to get user from repo: %v", err)
 		}
 
-		if err := c.UpdateUser(idUpdater, toUpdate); err != nil {
+		if err := c.UpdateUser(context.Background(), idUpdater, toUpdate); err != nil {
 			t.Errorf("roleAdmin user was not able to update another user: %v", err)
 		}
 
 		t.Cleanup(func() {
-			if err := r.Delete(idUpdater); err != nil {
+			if err := r.Delete(context.Background(), idUpdater); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
-			if err := r.Delete(idUpdated); err != nil {
+			if err := r.Delete(context.Background(), idUpdated); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
 		})
 	})
 
 	t.Run("block roleAdmin user from updating roleUnupdatable user", func(t *testing.T) {
-		idUpdater, err := c.CreateUser(firstName, lastName, email, password, model.RoleAdmin)
+		idUpdater, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleAdmin)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
@@ -247,20 +249,20 @@ func TestUpdateUser(t *testing.T) {
 			Role:      model.RoleUnupdatable,
 		}
 
-		idUpdated, err := c.CreateUser(toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
+		idUpdated, err := c.CreateUser(context.Background(), toUpdate.FirstName, toUpdate.LastName, toUpdate.Email, toUpdate.Password, toUpdate.Role)
 		if err != nil {
 			t.Errorf("unable to create user: %v", err)
 		}
 
-		if err := c.UpdateUser(idUpdater, &toUpdate); err == nil {
+		if err := c.UpdateUser(context.Background(), idUpdater, &toUpdate); err == nil {
 			t.Error("roleAdmin user was able to update roleUnupdatable user, should not happen")
 		}
 
 		t.Cleanup(func() {
-			if err := r.Delete(idUpdater); err != nil {
+			if err := r.Delete(context.Background(), idUpdater); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
-			if err := r.Delete(idUpdated); err != nil {
+			if err := r.Delete(context.Background(), idUpdated); err != nil {
 				t.Errorf("error cleaning up test case: %v", err)
 			}
 		})
@@ -274,22 +276,22 @@ func TestRegenerateLogo(t *testing.T) {
 	email := "test@test.com"
 	password := "password"
 
-	id, err := c.CreateUser(firstName, lastName, email, password, model.RoleUser)
+	id, err := c.CreateUser(context.Background(), firstName, lastName, email, password, model.RoleUser)
 	if err != nil {
 		t.Errorf("unable to create user: %v", err)
 	}
 
-	u, err := r.Get(id)
+	u, err := r.Get(context.Background(), id)
 	if err != nil {
 		t.Errorf("unable to get user from repo: %v", err)
 	}
 	prevLogo := u.Pfp
 
-	if err := c.RegeneratePfp(id); err != nil {
+	if err := c.RegeneratePfp(context.Background(), id); err != nil {
 		t.Errorf("unable to regenerate logo: %v", err)
 	}
 
-	u, err = r.Get(id)
+	u, err = r.Get(context.Background(), id)
 	if err != nil {
 		t.Errorf("unable to get user from repo: %v", err)
 	}
@@ -299,8 +301,75 @@ func TestRegenerateLogo(t *testing.T) {
 	}
 
 	t.Cleanup(func() {
-		if err := r.Delete(id); err != nil {
+		if err := r.Delete(context.Background(), id); err != nil {
 			t.Errorf("error cleaning up test case: %v", err)
 		}
 	})
 }
+
+// operator updates, cases:
+// [x] a user is banned and unbanned
+// [x] the role is changed, an invalid role is rejected
+// [x] a missing user is not found
+func TestSetBannedAndRole(t *testing.T) {
+	ctx := context.Background()
+	id, err := c.CreateUser(ctx, "oper", "ator", "operator@gmail.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+
+	assert.NilError(t, c.SetBanned(ctx, id, true))
+	u, err := c.GetUser(ctx, id)
+	assert.NilError(t, err)
+	assert.Assert(t, u.IsBanned)
+	assert.NilError(t, c.SetBanned(ctx, id, false))
+	u, err = c.GetUser(ctx, id)
+	assert.NilError(t, err)
+	assert.Assert(t, !u.IsBanned)
+
+	assert.NilError(t, c.SetRole(ctx, id, model.RoleAdmin))
+	u, err = c.GetUser(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Role, model.RoleAdmin)
+	assert.Equal(t, c.SetRole(ctx, id, "root"), controller.ErrInvalidRole)
+
+	assert.Equal(t, c.SetBanned(ctx, 4242, true), controller.ErrUserNotFound)
+}
+
+// upsert users, cases:
+// [x] the users of the fixtures are created with their role and ban, a banned user in a single transaction
+// [x] an unupdatable user is unchanged if the fixture is the same and can't be changed otherwise
+// [x] an invalid role or locale is rejected
+func TestUpsertUser(t *testing.T) {
+	ctx := context.Background()
+	repo := mock.NewRepo()
+	uc := controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+	_, err := fixtures.Test().Apply(ctx, uc)
+	assert.NilError(t, err)
+
+	banned, err := repo.GetByEmail(ctx, "banned@fixtures.test")
+	assert.NilError(t, err)
+	assert.Assert(t, banned.IsBanned)
+	types := []string{}
+	for _, e := range repo.GetEvents() {
+		if e.UserID == banned.ID {
+			types = append(types, e.Type)
+		}
+	}
+	assert.DeepEqual(t, types, []string{model.EventUserCreated, model.EventUserBanned})
+
+	fixture, _ := fixtures.Test().Find("unupdatable@fixtures.test")
+	frozen := &model.User{FirstName: fixture.FirstName, LastName: fixture.LastName, Email: fixture.Email, Password: fixture.Password, Role: fixture.Role}
+	id, outcome, err := uc.UpsertUser(ctx, frozen)
+	assert.NilError(t, err)
+	assert.Equal(t, outcome, controller.UpsertUnchanged)
+	frozen.IsBanned = true
+	_, _, err = uc.UpsertUser(ctx, frozen)
+	assert.Equal(t, err, controller.ErrUnupdatableUser)
+	u, err := uc.GetUser(ctx, id)
+	assert.NilError(t, err)
+	assert.Assert(t, !u.IsBanned)
+
+	_, _, err = uc.UpsertUser(ctx, &model.User{Email: "root@fixtures.test", Role: "root"})
+	assert.Equal(t, err, controller.ErrInvalidRole)
+	_, _, err = uc.UpsertUser(ctx, &model.User{Email: "klingon@fixtures.test", Role: model.RoleUser, Locale: "not a locale"})
+	assert.ErrorIs(t, err, controller.ErrInvalidLocale)
+}
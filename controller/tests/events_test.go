@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/events"
+	"gotest.tools/v3/assert"
+)
+
+type recordingSink struct {
+	fail      bool
+	delivered []*model.Event
+}
+
+func (s *recordingSink) Name() string {
+	return "recording"
+}
+
+func (s *recordingSink) Deliver(_ context.Context, e *model.Event) error {
+	if s.fail {
+		return errors.New("sink is down")
+	}
+	s.delivered = append(s.delivered, e)
+	return nil
+}
+
+func userEvents(userID int) []*model.Event {
+	var userEvents []*model.Event
+	for _, e := range r.GetEvents() {
+		if e.UserID == userID {
+			userEvents = append(userEvents, e)
+		}
+	}
+	return userEvents
+}
+
+// events, cases:
+// [x] every change of the user is published in the outbox
+// [x] the payload never contains the password
+// [x] banning a user publishes a banned event
+// [x] a failed delivery is retried, not lost
+func TestUserEvents(t *testing.T) {
+	id, err := c.CreateUser(context.Background(), "name", "lastname", "events@test.com", "password", model.RoleUser)
+	if err != nil {
+		t.Fatalf("unable to create user: %v", err)
+	}
+
+	t.Run("lifecycle events", func(t *testing.T) {
+		u, err := r.Get(context.Background(), id)
+		if err != nil {
+			t.Fatalf("unable to get user from repo: %v", err)
+		}
+		u.IsBanned = true
+		if err := c.UpdateUser(context.Background(), id, u); err != nil {
+			t.Fatalf("unable to update user: %v", err)
+		}
+		if err := c.RegeneratePfp(context.Background(), id); err != nil {
+			t.Fatalf("unable to regenerate pfp: %v", err)
+		}
+		if err := c.DeleteUser(context.Background(), id, id); err != nil {
+			t.Fatalf("unable to delete user: %v", err)
+		}
+
+		published := userEvents(id)
+		types := make([]string, 0, len(published))
+		for _, e := range published {
+			types = append(types, e.Type)
+		}
+		assert.DeepEqual(t, types, []string{
+			model.EventUserCreated,
+			model.EventUserUpdated,
+			model.EventUserBanned,
+			model.EventPfpRegenerated,
+			model.EventUserDeleted,
+		})
+
+		payload := map[string]interface{}{}
+		if err := json.Unmarshal(published[0].Payload, &payload); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		_, hasPassword := payload["password"]
+		assert.Assert(t, !hasPassword)
+		assert.Equal(t, payload["email"], "events@test.com")
+
+		deleted := controller.EventUser{}
+		if err := json.Unmarshal(published[len(published)-1].Payload, &deleted); err != nil {
+			t.Fatalf("invalid payload: %v", err)
+		}
+		assert.Equal(t, deleted.ID, id)
+	})
+
+	t.Run("at-least-once delivery", func(t *testing.T) {
+		sink := &recordingSink{fail: true}
+		d := events.NewDispatcher(r, l, 0, 0, sink)
+
+		delivered, err := d.DispatchPending(context.Background())
+		assert.NilError(t, err)
+		assert.Equal(t, delivered, 0)
+		for _, e := range userEvents(id) {
+			assert.Assert(t, !e.IsDelivered())
+			assert.Equal(t, e.Attempts, 1)
+			assert.Equal(t, e.LastError, "recording: sink is down")
+		}
+
+		//the failed events are scheduled in the future, we
+		//make them due again to simulate the passing of time
+		for _, e := range userEvents(id) {
+			assert.NilError(t, r.MarkEventFailed(e.ID, e.LastError, e.OccurredAt))
+		}
+		sink.fail = false
+		_, err = d.DispatchPending(context.Background())
+		assert.NilError(t, err)
+		for _, e := range userEvents(id) {
+			assert.Assert(t, e.IsDelivered())
+		}
+		assert.Assert(t, len(sink.delivered) >= len(userEvents(id)))
+	})
+}
@@ -0,0 +1,67 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"gotest.tools/v3/assert"
+)
+
+func counterValue(t *testing.T, reg *prometheus.Registry, name, outcome string) float64 {
+	families, err := reg.Gather()
+	assert.NilError(t, err)
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "outcome" && l.GetValue() == outcome {
+					return m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// controller metrics, cases:
+// [x] registrations are counted by outcome
+// [x] logins are counted by outcome
+// [x] forbidden updates and deletions are counted
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	assert.NilError(t, controller.RegisterMetrics(reg))
+
+	registered := counterValue(t, reg, "users_registrations_total", "success")
+	duplicated := counterValue(t, reg, "users_registrations_total", "already_exists")
+	id, err := c.CreateUser(context.Background(), "name", "lastname", "metrics@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	_, err = c.CreateUser(context.Background(), "name", "lastname", "metrics@test.com", "password", model.RoleUser)
+	assert.Equal(t, err, controller.ErrUserAlreadyExists)
+	assert.Equal(t, counterValue(t, reg, "users_registrations_total", "success"), registered+1)
+	assert.Equal(t, counterValue(t, reg, "users_registrations_total", "already_exists"), duplicated+1)
+
+	logged := counterValue(t, reg, "users_logins_total", "success")
+	wrong := counterValue(t, reg, "users_logins_total", "wrong_password")
+	_, err = c.CheckCredentials(context.Background(), "metrics@test.com", "password")
+	assert.NilError(t, err)
+	_, err = c.CheckCredentials(context.Background(), "metrics@test.com", "wrong")
+	assert.Equal(t, err, controller.ErrWrongPassword)
+	assert.Equal(t, counterValue(t, reg, "users_logins_total", "success"), logged+1)
+	assert.Equal(t, counterValue(t, reg, "users_logins_total", "wrong_password"), wrong+1)
+
+	otherID, err := c.CreateUser(context.Background(), "name", "lastname", "metrics-other@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	forbiddenUpdates := counterValue(t, reg, "users_updates_total", "forbidden")
+	forbiddenDeletions := counterValue(t, reg, "users_deletions_total", "forbidden")
+	other, err := r.Get(context.Background(), otherID)
+	assert.NilError(t, err)
+	assert.NilError(t, c.UpdateUser(context.Background(), id, other))
+	assert.NilError(t, c.DeleteUser(context.Background(), id, otherID))
+	assert.Equal(t, counterValue(t, reg, "users_updates_total", "forbidden"), forbiddenUpdates+1)
+	assert.Equal(t, counterValue(t, reg, "users_deletions_total", "forbidden"), forbiddenDeletions+1)
+}
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/blob"
+	"github.com/vano2903/service-template/providers/logo"
+	"gotest.tools/v3/assert"
+)
+
+func testImage(t *testing.T, w, h int, encode func(io.Writer, image.Image) error) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 100, A: 255})
+		}
+	}
+	buf := &bytes.Buffer{}
+	assert.NilError(t, encode(buf, img))
+	return buf.Bytes()
+}
+
+// upload profile picture, cases:
+// [x] upload a png, every thumbnail is a square of the right size (from the smallest) and the pfp is the biggest
+// [x] jpeg stays jpeg, the files of the picture uploaded before are deleted
+// [x] files that are not images or are too large are refused
+// [x] a user can't upload the picture of another user, an admin can
+func TestUploadPfp(t *testing.T) {
+	store, err := blob.NewLocal(t.TempDir())
+	assert.NilError(t, err)
+	pc := controller.NewPfpController(r, store, l, "http://localhost:8080/", []int{128, 64}, 1<<20)
+
+	id, err := c.CreateUser(context.Background(), "name", "lastname", "pfp@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	adminID, err := c.CreateUser(context.Background(), "name", "lastname", "pfpadmin@test.com", "password", model.RoleAdmin)
+	assert.NilError(t, err)
+
+	t.Run("upload png", func(t *testing.T) {
+		urls, err := pc.UploadPfp(context.Background(), id, id, bytes.NewReader(testImage(t, 300, 200, png.Encode)))
+		assert.NilError(t, err)
+		assert.Equal(t, len(urls), 2)
+		assert.Equal(t, urls[0].Size, 64)
+		assert.Equal(t, urls[1].Size, 128)
+
+		u, err := r.Get(context.Background(), id)
+		assert.NilError(t, err)
+		assert.Equal(t, u.Pfp, urls[1].URL)
+
+		for _, thumbnail := range urls {
+			size, url := thumbnail.Size, thumbnail.URL
+			assert.Assert(t, strings.HasSuffix(url, ".png"))
+			rc, info, err := pc.GetPfp(context.Background(), strings.TrimPrefix(url, "http://localhost:8080/"))
+			assert.NilError(t, err)
+			img, err := png.Decode(rc)
+			rc.Close()
+			assert.NilError(t, err)
+			assert.Equal(t, info.ContentType, "image/png")
+			assert.Equal(t, img.Bounds().Dx(), size)
+			assert.Equal(t, img.Bounds().Dy(), size)
+		}
+	})
+
+	t.Run("upload jpeg", func(t *testing.T) {
+		previous, err := r.Get(context.Background(), id)
+		assert.NilError(t, err)
+		encode := func(w io.Writer, img image.Image) error { return jpeg.Encode(w, img, nil) }
+		urls, err := pc.UploadPfp(context.Background(), id, id, bytes.NewReader(testImage(t, 100, 150, encode)))
+		assert.NilError(t, err)
+		rc, _, err := pc.GetPfp(context.Background(), strings.TrimPrefix(urls[0].URL, "http://localhost:8080/"))
+		assert.NilError(t, err)
+		defer rc.Close()
+		_, err = jpeg.Decode(rc)
+		assert.NilError(t, err)
+
+		//the files of the png are deleted, every size
+		old := strings.TrimPrefix(previous.Pfp, "http://localhost:8080/")
+		for _, key := range []string{old, strings.Replace(old, "/128.png", "/64.png", 1)} {
+			_, _, err = pc.GetPfp(context.Background(), key)
+			assert.Equal(t, err, controller.ErrPfpNotFound, key)
+		}
+	})
+
+	t.Run("invalid files", func(t *testing.T) {
+		_, err := pc.UploadPfp(context.Background(), id, id, strings.NewReader("<html>definitely not an image</html>"))
+		assert.Assert(t, errors.Is(err, controller.ErrInvalidPfp))
+
+		_, err = pc.UploadPfp(context.Background(), id, id, bytes.NewReader(make([]byte, 1<<20+1)))
+		assert.Equal(t, err, controller.ErrPfpTooLarge)
+
+		_, _, err = pc.GetPfp(context.Background(), "pfp/../../config.yml")
+		assert.Equal(t, err, controller.ErrPfpNotFound)
+		_, _, err = pc.GetPfp(context.Background(), "pfp/1/none/64.png")
+		assert.Equal(t, err, controller.ErrPfpNotFound)
+	})
+
+	t.Run("permissions", func(t *testing.T) {
+		_, err := pc.UploadPfp(context.Background(), id, adminID, bytes.NewReader(testImage(t, 10, 10, png.Encode)))
+		assert.Equal(t, err, controller.ErrNotAdmin)
+
+		before := len(userEvents(id))
+		_, err = pc.UploadPfp(context.Background(), adminID, id, bytes.NewReader(testImage(t, 10, 10, png.Encode)))
+		assert.NilError(t, err)
+		published := userEvents(id)
+		assert.Equal(t, len(published), before+1)
+		assert.Equal(t, published[len(published)-1].Type, model.EventPfpUploaded)
+	})
+}
+
+// uploadedKeys returns the keys of every size of the uploaded picture
+func uploadedKeys(url string) []string {
+	key := strings.TrimPrefix(url, "http://localhost:8080/")
+	return []string{key, strings.Replace(key, "/128.png", "/64.png", 1)}
+}
+
+// files of the uploaded profile pictures, cases:
+// [x] a generated picture that replaces an upload deletes its files
+// [x] deleting the user deletes the files of its upload
+func TestUploadedPfpCleanup(t *testing.T) {
+	store, err := blob.NewLocal(t.TempDir())
+	assert.NilError(t, err)
+	pc := controller.NewPfpController(r, store, l, "http://localhost:8080/", []int{128, 64}, 1<<20)
+	uc := controller.NewUserController(r, logo.NewServiceLogo("", ""), l)
+	uc.SetPfpRemover(pc)
+
+	assertDeleted := func(t *testing.T, url string) {
+		t.Helper()
+		for _, key := range uploadedKeys(url) {
+			_, _, err := pc.GetPfp(context.Background(), key)
+			assert.Equal(t, err, controller.ErrPfpNotFound, key)
+		}
+	}
+
+	id, err := uc.CreateUser(context.Background(), "name", "lastname", "pfpcleanup@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	urls, err := pc.UploadPfp(context.Background(), id, id, bytes.NewReader(testImage(t, 10, 10, png.Encode)))
+	assert.NilError(t, err)
+	assert.NilError(t, uc.RegeneratePfp(context.Background(), id))
+	assertDeleted(t, urls[1].URL)
+
+	urls, err = pc.UploadPfp(context.Background(), id, id, bytes.NewReader(testImage(t, 10, 10, png.Encode)))
+	assert.NilError(t, err)
+	for _, key := range uploadedKeys(urls[1].URL) {
+		rc, _, err := pc.GetPfp(context.Background(), key)
+		assert.NilError(t, err)
+		rc.Close()
+	}
+	assert.NilError(t, uc.DeleteUser(context.Background(), id, id))
+	assertDeleted(t, urls[1].URL)
+}
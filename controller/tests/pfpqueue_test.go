@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/workqueue"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// flakyLogo fails the first failures calls for every user, if failures is negative it always fails
+type flakyLogo struct {
+	mu       sync.Mutex
+	failures int
+	calls    map[int]int
+}
+
+func (s *flakyLogo) GenerateLogo(_ context.Context, u *model.User) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[u.ID]++
+	if s.failures < 0 || s.calls[u.ID] <= s.failures {
+		return "", errors.New("provider is down")
+	}
+	return fmt.Sprintf("https://logo.example.com/%d-%d.png", u.ID, s.calls[u.ID]), nil
+}
+
+func waitPfpStatus(t *testing.T, repo *mock.RepoMock, id int, status string) *model.User {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		u, err := repo.Get(context.Background(), id)
+		assert.NilError(t, err)
+		if u.PfpStatus == status {
+			return u
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("pfp of user %d is %q, expected %q", id, u.PfpStatus, status)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// pfp queue, cases:
+// [x] creating a user doesn't wait for the provider, the pfp is pending until generated
+// [x] a failed generation is retried
+// [x] after every attempt fails the pfp is failed and the user can regenerate it
+// [x] an uploaded picture is not overwritten by a pending generation
+func TestPfpQueue(t *testing.T) {
+	repo := mock.NewRepo()
+	logoService := &flakyLogo{failures: 2, calls: make(map[int]int)}
+	uc := controller.NewUserController(repo, logoService, l)
+	queue := workqueue.New("pfp", l, uc.GeneratePfp, uc.PfpGenerationFailed, workqueue.Options{
+		Workers:     2,
+		MaxAttempts: 3,
+		MinBackoff:  time.Millisecond,
+		MaxBackoff:  10 * time.Millisecond,
+	})
+	uc.SetPfpQueue(queue)
+
+	//the user is created before the workers start to check the status before the generation
+	id, err := uc.CreateUser(context.Background(), "name", "lastname", "queue@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	u, err := repo.Get(context.Background(), id)
+	assert.NilError(t, err)
+	assert.Equal(t, u.PfpStatus, model.PfpStatusPending)
+	assert.Equal(t, u.Pfp, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		queue.Run(ctx)
+		close(done)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	t.Run("retried until ready", func(t *testing.T) {
+		u := waitPfpStatus(t, repo, id, model.PfpStatusReady)
+		assert.Equal(t, u.Pfp, fmt.Sprintf("https://logo.example.com/%d-3.png", id))
+
+		var types []string
+		for _, e := range repo.GetEvents() {
+			if e.UserID == id {
+				types = append(types, e.Type)
+			}
+		}
+		assert.DeepEqual(t, types, []string{model.EventUserCreated, model.EventPfpRegenerated})
+	})
+
+	t.Run("failed and regenerated", func(t *testing.T) {
+		logoService.mu.Lock()
+		logoService.failures = -1
+		logoService.mu.Unlock()
+
+		assert.NilError(t, uc.RegeneratePfp(context.Background(), id))
+		u := waitPfpStatus(t, repo, id, model.PfpStatusFailed)
+		//the previous picture is kept
+		assert.Equal(t, u.Pfp, fmt.Sprintf("https://logo.example.com/%d-3.png", id))
+
+		logoService.mu.Lock()
+		logoService.failures = 0
+		logoService.mu.Unlock()
+
+		assert.NilError(t, uc.RegeneratePfp(context.Background(), id))
+		u = waitPfpStatus(t, repo, id, model.PfpStatusReady)
+		assert.Equal(t, u.Pfp, fmt.Sprintf("https://logo.example.com/%d-7.png", id))
+	})
+
+	t.Run("upload wins", func(t *testing.T) {
+		u, err := repo.Get(context.Background(), id)
+		assert.NilError(t, err)
+		u.PfpStatus = model.PfpStatusPending
+		assert.NilError(t, repo.Update(context.Background(), u))
+
+		//the picture is uploaded before the job runs
+		u.Pfp = "http://localhost:8080/pfp/uploaded.png"
+		u.PfpStatus = model.PfpStatusReady
+		assert.NilError(t, repo.Update(context.Background(), u))
+		assert.NilError(t, uc.GeneratePfp(context.Background(), id))
+
+		u, err = repo.Get(context.Background(), id)
+		assert.NilError(t, err)
+		assert.Equal(t, u.Pfp, "http://localhost:8080/pfp/uploaded.png")
+	})
+}
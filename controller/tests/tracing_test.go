@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/instrumented"
+	"github.com/vano2903/service-template/repo/mock"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"gotest.tools/v3/assert"
+)
+
+// tracing, cases:
+// [x] the span of the controller is a child of the span in the context
+// [x] the spans of the repo and of the logo provider are children of the span of the controller
+// [x] the calls made in a transaction are children of the span of the transaction
+func TestTracing(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	reg := prometheus.NewRegistry()
+	users, err := instrumented.NewUserRepo(mock.NewRepo(), reg, l)
+	assert.NilError(t, err)
+	logoMetrics, err := logo.NewServiceMetrics(reg)
+	assert.NilError(t, err)
+	uc := controller.NewUserController(users, logo.NewInstrumented(logo.NewServiceLogo("", ""), "random", logoMetrics), l)
+
+	ctx, request := otel.Tracer("test").Start(context.Background(), "POST /api/v1/user/register")
+	_, err = uc.CreateUser(ctx, "traced", "user", "traced@test.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+	request.End()
+
+	spans := map[string]sdktrace.ReadOnlySpan{}
+	for _, s := range recorder.Ended() {
+		//the workers started by the other tests could still be running
+		if s.SpanContext().TraceID() == request.SpanContext().TraceID() {
+			spans[s.Name()] = s
+		}
+	}
+	parentOf := func(name string) string {
+		s, ok := spans[name]
+		assert.Assert(t, ok, "missing span %s", name)
+		for _, p := range spans {
+			if p.SpanContext().SpanID() == s.Parent().SpanID() {
+				return p.Name()
+			}
+		}
+		return ""
+	}
+
+	assert.Equal(t, parentOf("controller.User.CreateUser"), "POST /api/v1/user/register")
+	assert.Equal(t, parentOf("repo.users.get_by_email"), "controller.User.CreateUser")
+	assert.Equal(t, parentOf("logo.GenerateLogo"), "controller.User.CreateUser")
+	assert.Equal(t, parentOf("repo.users.with_tx"), "controller.User.CreateUser")
+	assert.Equal(t, parentOf("repo.users.create"), "repo.users.with_tx")
+}
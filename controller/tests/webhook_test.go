@@ -0,0 +1,143 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/events"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/providers/webhook"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// receiver is the integrator side of the webhook, it verifies every request
+type receiver struct {
+	mu       sync.Mutex
+	secret   string
+	fail     bool
+	received []webhook.Body
+	invalid  int
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	body, _ := io.ReadAll(req.Body)
+	if err := webhook.Verify(rc.secret, req.Header.Get(webhook.HeaderTimestamp), req.Header.Get(webhook.HeaderSignature), body, time.Minute); err != nil {
+		rc.invalid++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if rc.fail {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	b := webhook.Body{}
+	_ = json.Unmarshal(body, &b)
+	rc.received = append(rc.received, b)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// webhooks, cases:
+// [x] only admins can create webhooks
+// [x] a subscribed event is delivered signed to the receiver
+// [x] an event not in the filter is not delivered
+// [x] a failing receiver exhausts the attempts and the delivery ends up in the dead-letter list
+// [x] a dead delivery can be redelivered
+// [x] a succeeded delivery is not resendable
+func TestWebhooks(t *testing.T) {
+	//a dedicated repo so that the events of the other tests are not delivered
+	repo := mock.NewRepo()
+	uc := controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+	wc := controller.NewWebhookController(repo, repo, l)
+	dispatcher := events.NewDispatcher(repo, l, 0, 0, webhook.NewSink(repo))
+	sender := webhook.NewSender(repo, l, time.Second, 2)
+	sender.SetBackoff(0, 0)
+
+	adminID, err := uc.CreateUser(context.Background(), "admin", "admin", "admin@webhooks.com", "password", model.RoleAdmin)
+	assert.NilError(t, err)
+	userID, err := uc.CreateUser(context.Background(), "user", "user", "user@webhooks.com", "password", model.RoleUser)
+	assert.NilError(t, err)
+
+	rc := &receiver{}
+	srv := httptest.NewServer(rc)
+	defer srv.Close()
+
+	t.Run("only admins can create webhooks", func(t *testing.T) {
+		_, err := wc.CreateWebhook(context.Background(), userID, srv.URL, nil, "")
+		assert.Equal(t, err, controller.ErrNotAdmin)
+	})
+
+	w, err := wc.CreateWebhook(context.Background(), adminID, srv.URL, []string{model.EventUserCreated}, "")
+	assert.NilError(t, err)
+	rc.secret = w.Secret
+
+	t.Run("signed delivery", func(t *testing.T) {
+		newID, err := uc.CreateUser(context.Background(), "new", "user", "new@webhooks.com", "password", model.RoleUser)
+		assert.NilError(t, err)
+		//this event is not in the filter of the webhook
+		assert.NilError(t, uc.RegeneratePfp(context.Background(), newID))
+
+		_, err = dispatcher.DispatchPending(context.Background())
+		assert.NilError(t, err)
+		_, err = sender.SendDue(context.Background())
+		assert.NilError(t, err)
+
+		assert.Equal(t, rc.invalid, 0)
+		//the admin, the user and the new user have been created
+		assert.Equal(t, len(rc.received), 3)
+		last := rc.received[2]
+		assert.Equal(t, last.EventType, model.EventUserCreated)
+		data := controller.EventUser{}
+		assert.NilError(t, json.Unmarshal(last.Data, &data))
+		assert.Equal(t, data.ID, newID)
+
+		deliveries, err := wc.GetDeliveries(context.Background(), adminID, w.ID, model.DeliverySucceeded)
+		assert.NilError(t, err)
+		assert.Equal(t, len(deliveries), 3)
+	})
+
+	t.Run("dead letter and redelivery", func(t *testing.T) {
+		rc.fail = true
+		_, err := uc.CreateUser(context.Background(), "failing", "user", "failing@webhooks.com", "password", model.RoleUser)
+		assert.NilError(t, err)
+		_, err = dispatcher.DispatchPending(context.Background())
+		assert.NilError(t, err)
+
+		//max attempts is 2
+		for i := 0; i < 3; i++ {
+			_, err = sender.SendDue(context.Background())
+			assert.NilError(t, err)
+		}
+
+		dead, err := wc.GetDeliveries(context.Background(), adminID, w.ID, model.DeliveryDead)
+		assert.NilError(t, err)
+		assert.Equal(t, len(dead), 1)
+		assert.Equal(t, len(dead[0].Attempts), 2)
+		assert.Equal(t, dead[0].Attempts[1].StatusCode, http.StatusServiceUnavailable)
+
+		rc.fail = false
+		assert.NilError(t, wc.Redeliver(context.Background(), adminID, dead[0].ID))
+		succeeded, err := sender.SendDue(context.Background())
+		assert.NilError(t, err)
+		assert.Equal(t, succeeded, 1)
+
+		redelivered, err := repo.GetDelivery(dead[0].ID)
+		assert.NilError(t, err)
+		assert.Equal(t, redelivered.Status, model.DeliverySucceeded)
+		assert.Equal(t, len(redelivered.Attempts), 3)
+
+		//only the dead deliveries are resendable
+		err = wc.Redeliver(context.Background(), adminID, dead[0].ID)
+		assert.ErrorIs(t, err, controller.ErrDeliveryNotResendable)
+	})
+}
@@ -0,0 +1,9 @@
+package controller
+
+import (
+	"go.opentelemetry.io/otel"
+)
+
+// tracer creates the spans of the business logic, they are children of the span of the
+// request (or of the job) and parents of the spans of the repo and of the providers
+var tracer = otel.Tracer("github.com/vano2903/service-template/controller")
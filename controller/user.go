@@ -1,13 +1,17 @@
 package controller
 
 import (
+	"context"
 	"errors"
+	"fmt"
 
 	"github.com/sirupsen/logrus"
 	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
 	"github.com/vano2903/service-template/providers/logo"
 	"github.com/vano2903/service-template/repo"
 	"github.com/vano2903/service-template/repo/mock"
+	"golang.org/x/text/language"
 )
 
 var _ UserControllerer = new(User)
@@ -18,12 +22,27 @@ var (
 	ErrWrongPassword     = errors.New("wrong password")
 	ErrUnexpected        = errors.New("unexpected error")
 	ErrUnupdatableUser   = errors.New("user can't be updated")
+	ErrInvalidRole       = errors.New("invalid role")
+	ErrInvalidLocale     = errors.New("invalid locale")
 )
 
+// PfpQueuer schedules the generation of the profile picture of a user in background (see workqueue.Queue),
+// the jobs must be run by calling GeneratePfp
+type PfpQueuer interface {
+	Enqueue(id int) error
+}
+
+// PfpRemover deletes the files of an uploaded profile picture (see Pfp.DeleteUploaded)
+type PfpRemover interface {
+	DeleteUploaded(ctx context.Context, userId int, url string)
+}
+
 type User struct {
-	repo repo.UserRepoer
-	logo logo.LogoServicer
-	l    *logrus.Logger
+	repo       repo.UserRepoer
+	logo       logo.LogoServicer
+	l          *logrus.Logger
+	pfpQueue   PfpQueuer
+	pfpRemover PfpRemover
 }
 
 func NewUserController(repo repo.UserRepoer, logo logo.LogoServicer, log *logrus.Logger) *User {
@@ -34,40 +53,125 @@ func NewUserController(repo repo.UserRepoer, logo logo.LogoServicer, log *logrus
 	}
 }
 
-func (c *User) CreateUser(firstName, lastName, email, password, role string) (int, error) {
+// log returns the logger of the request (or of the job) that is running
+func (c *User) log(ctx context.Context) *logrus.Entry {
+	return logger.FromContext(ctx, c.l)
+}
+
+// SetPfpQueue makes the profile pictures generated in background, without a queue
+// they are generated on the request path (and a provider error makes the request fail)
+func (c *User) SetPfpQueue(q PfpQueuer) {
+	c.pfpQueue = q
+}
+
+// SetPfpRemover deletes the uploaded pictures that are replaced by a generated one or whose
+// user is deleted, without it their files are left in the storage
+func (c *User) SetPfpRemover(r PfpRemover) {
+	c.pfpRemover = r
+}
+
+// deleteUploadedPfp deletes the files of the picture if it was uploaded
+func (c *User) deleteUploadedPfp(ctx context.Context, id int, pfp string) {
+	if c.pfpRemover != nil {
+		c.pfpRemover.DeleteUploaded(ctx, id, pfp)
+	}
+}
+
+func (c *User) CreateUser(ctx context.Context, firstName, lastName, email, password, role string) (int, error) {
 
+	ctx, span := tracer.Start(ctx, "controller.User.CreateUser")
+	defer span.End()
 	//here we check if the user already exists
-	u, err := c.repo.GetByEmail(email)
+	u, err := c.repo.GetByEmail(ctx, email)
 	if err == nil {
+		registrations.WithLabelValues(outcomeAlreadyExists).Inc()
 		return u.ID, ErrUserAlreadyExists
 	}
 
-	m := &model.User{
+	return c.create(ctx, &model.User{
 		FirstName: firstName,
 		LastName:  lastName,
 		Email:     email,
 		Password:  password,
 		Role:      role,
+	})
+}
+
+// create stores the new user with its pfp (generated now or queued) and publishes the events,
+// a banned user is stored and announced as banned in the same transaction
+func (c *User) create(ctx context.Context, m *model.User) (int, error) {
+	var err error
+	m.PfpStatus = model.PfpStatusPending
+	if c.pfpQueue == nil {
+		m.Pfp, err = c.logo.GenerateLogo(ctx, m)
+		if err != nil {
+			c.log(ctx).Errorf("controller.CreateUser: unexpected error in logo.GenerateLogo: %v", err)
+			registrations.WithLabelValues(outcomeError).Inc()
+			return -1, errors.New("unexpected error when generating logo")
+		}
+		m.PfpStatus = model.PfpStatusReady
+	}
+
+	//the user and the events are stored together, if one fails nothing is stored
+	var id int
+	err = c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+		id, err = users.Create(ctx, m)
+		if err != nil {
+			return err
+		}
+		if err := publishEvent(outbox, model.EventUserCreated, m); err != nil {
+			return err
+		}
+		if m.IsBanned {
+			return publishEvent(outbox, model.EventUserBanned, m)
+		}
+		return nil
+	})
+	if err != nil {
+		c.log(ctx).Errorf("controller.CreateUser: unexpected error storing the user: %v", err)
+		registrations.WithLabelValues(outcomeError).Inc()
+		return -1, ErrUnexpected
 	}
+	registrations.WithLabelValues(outcomeSuccess).Inc()
 
-	m.Pfp, err = c.logo.GenerateLogo()
+	if c.pfpQueue != nil {
+		c.enqueuePfp(ctx, id)
+	}
+	return id, nil
+}
+
+func (c *User) GetUser(ctx context.Context, id int) (*model.User, error) {
+	ctx, span := tracer.Start(ctx, "controller.User.GetUser")
+	defer span.End()
+	u, err := c.repo.Get(ctx, id)
 	if err != nil {
-		c.l.Errorf("controller.CreateUser: unexpected error in logo.GenerateLogo: %v", err)
-		return u.ID, errors.New("unexpected error when generating logo")
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			return nil, ErrUserNotFound
+		}
+		c.log(ctx).Errorf("controller.GetUser: unexpected error in repo.Get: %v", err)
+		return nil, ErrUnexpected
 	}
-	return c.repo.Create(m)
+	return u, nil
 }
 
-func (c *User) GetUser(id int) (*model.User, error) {
-	return c.repo.Get(id)
+func (c *User) GetAllUsers(ctx context.Context) []*model.User {
+	ctx, span := tracer.Start(ctx, "controller.User.GetAllUsers")
+	defer span.End()
+	return c.repo.GetAll(ctx)
 }
 
-func (c *User) GetAllUsers() []*model.User {
-	return c.repo.GetAll()
+// GetUsers returns with a single read of the repo the users with the given ids, in no
+// particular order: the ids without a user are skipped
+func (c *User) GetUsers(ctx context.Context, ids []int) ([]*model.User, error) {
+	ctx, span := tracer.Start(ctx, "controller.User.GetUsers")
+	defer span.End()
+	return c.repo.GetMany(ctx, ids)
 }
 
-func (c *User) UpdateUser(requesterId int, u *model.User) error {
-	requester, err := c.repo.Get(requesterId)
+func (c *User) UpdateUser(ctx context.Context, requesterId int, u *model.User) error {
+	ctx, span := tracer.Start(ctx, "controller.User.UpdateUser")
+	defer span.End()
+	requester, err := c.repo.Get(ctx, requesterId)
 	if err != nil {
 		//in this case we check if the error is a error not found and we log it
 		//but not return it cause it could be something important that
@@ -75,116 +179,406 @@ func (c *User) UpdateUser(requesterId int, u *model.User) error {
 		re, ok := err.(*mock.ErrUserNotFound)
 		if ok {
 			//here we log the error and return a generic one
-			c.l.Errorf("update requester with id %d not found", re.ID)
+			c.log(ctx).Errorf("update requester with id %d not found", re.ID)
+			updates.WithLabelValues(outcomeNotFound).Inc()
 			return ErrUserNotFound
 		} else {
-			c.l.Errorf("controller.UpdateUser: unexpected error in repo.Get: %v", err)
+			c.log(ctx).Errorf("controller.UpdateUser: unexpected error in repo.Get: %v", err)
+			updates.WithLabelValues(outcomeError).Inc()
 			return ErrUnexpected
 		}
 	}
 
 	if u.ID <= 0 {
+		updates.WithLabelValues(outcomeError).Inc()
 		return errors.New("missing id from user to update")
 	}
+	if u.Locale, err = normalizeLocale(u.Locale); err != nil {
+		updates.WithLabelValues(outcomeError).Inc()
+		return err
+	}
 
 	if requester.ID == u.ID || requester.Role == model.RoleAdmin {
-		err = c.repo.Update(u)
+		return c.update(ctx, u.ID, func(stored *model.User) {
+			//the pfp is changed only by the pfp queue and the uploads, keeping the stored one
+			//avoids overwriting a picture generated while the caller was editing the user
+			u.Pfp, u.PfpStatus = stored.Pfp, stored.PfpStatus
+			*stored = *u
+		})
+	}
+	updates.WithLabelValues(outcomeForbidden).Inc()
+	return nil
+}
+
+// outcomes of UpsertUser
+const (
+	UpsertCreated   = "created"
+	UpsertUpdated   = "updated"
+	UpsertUnchanged = "unchanged"
+)
+
+// UpsertUser creates the user or, if a user with the same email exists, updates its names, password,
+// locale, role and ban to the given ones
+func (c *User) UpsertUser(ctx context.Context, u *model.User) (int, string, error) {
+	ctx, span := tracer.Start(ctx, "controller.User.UpsertUser")
+	defer span.End()
+	if !model.IsRole(u.Role) {
+		return -1, "", ErrInvalidRole
+	}
+	locale, err := normalizeLocale(u.Locale)
+	if err != nil {
+		return -1, "", err
+	}
+
+	stored, err := c.repo.GetByEmail(ctx, u.Email)
+	if err != nil {
+		if _, ok := err.(*mock.ErrUserNotFound); !ok {
+			c.log(ctx).Errorf("controller.UpsertUser: unexpected error in repo.GetByEmail: %v", err)
+			return -1, "", ErrUnexpected
+		}
+		id, err := c.create(ctx, &model.User{
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Email:     u.Email,
+			Password:  u.Password,
+			Locale:    locale,
+			Role:      u.Role,
+			IsBanned:  u.IsBanned,
+		})
 		if err != nil {
-			re, ok := err.(*mock.ErrUserNotFound)
-			if ok {
-				c.l.Errorf("user to update with id %d not found", re.ID)
-				return ErrUserNotFound
-			} else if err == mock.ErrUserUnapdatable {
-				return ErrUnupdatableUser
-			} else {
-				c.l.Errorf("controller.UpdateUser: unexpected error in repo.Update: %v", err)
-				return ErrUnexpected
-			}
+			return id, "", err
 		}
+		return id, UpsertCreated, nil
 	}
+
+	if stored.FirstName == u.FirstName && stored.LastName == u.LastName && stored.Password == u.Password &&
+		stored.Locale == locale && stored.Role == u.Role && stored.IsBanned == u.IsBanned {
+		return stored.ID, UpsertUnchanged, nil
+	}
+	err = c.update(ctx, stored.ID, func(s *model.User) {
+		s.FirstName, s.LastName, s.Password, s.Locale = u.FirstName, u.LastName, u.Password, locale
+		s.Role, s.IsBanned = u.Role, u.IsBanned
+	})
+	if err != nil {
+		return stored.ID, "", err
+	}
+	return stored.ID, UpsertUpdated, nil
+}
+
+// SetBanned bans (or unbans) the user, it's run by the operators (see the cli) so there is no requester to check
+func (c *User) SetBanned(ctx context.Context, id int, banned bool) error {
+	ctx, span := tracer.Start(ctx, "controller.User.SetBanned")
+	defer span.End()
+	return c.update(ctx, id, func(u *model.User) {
+		u.IsBanned = banned
+	})
+}
+
+// SetRole changes the role of the user, it's run by the operators (see the cli) so there is no requester to check
+func (c *User) SetRole(ctx context.Context, id int, role string) error {
+	ctx, span := tracer.Start(ctx, "controller.User.SetRole")
+	defer span.End()
+	if !model.IsRole(role) {
+		return ErrInvalidRole
+	}
+	return c.update(ctx, id, func(u *model.User) {
+		u.Role = role
+	})
+}
+
+// normalizeLocale returns the canonical form of the preferred locale of a user (it-it -> it-IT),
+// the empty locale means no preference: the one of the client is used
+func normalizeLocale(locale string) (string, error) {
+	if locale == "" {
+		return "", nil
+	}
+	tag, err := language.Parse(locale)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidLocale, err)
+	}
+	return tag.String(), nil
+}
+
+// update applies change to the stored user and publishes the events in the same transaction
+func (c *User) update(ctx context.Context, id int, change func(u *model.User)) error {
+	err := c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+		prev, err := users.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		u := *prev
+		change(&u)
+		if err := users.Update(ctx, &u); err != nil {
+			return err
+		}
+		if err := publishEvent(outbox, model.EventUserUpdated, &u); err != nil {
+			return err
+		}
+		//banning is just an update but other services are usually interested only in this case
+		if !prev.IsBanned && u.IsBanned {
+			return publishEvent(outbox, model.EventUserBanned, &u)
+		}
+		return nil
+	})
+	if err != nil {
+		re, ok := err.(*mock.ErrUserNotFound)
+		if ok {
+			c.log(ctx).Errorf("user to update with id %d not found", re.ID)
+			updates.WithLabelValues(outcomeNotFound).Inc()
+			return ErrUserNotFound
+		} else if err == mock.ErrUserUnapdatable {
+			updates.WithLabelValues(outcomeUnupdatable).Inc()
+			return ErrUnupdatableUser
+		} else {
+			c.log(ctx).Errorf("controller.UpdateUser: unexpected error in repo.Update: %v", err)
+			updates.WithLabelValues(outcomeError).Inc()
+			return ErrUnexpected
+		}
+	}
+	updates.WithLabelValues(outcomeSuccess).Inc()
 	return nil
 }
 
-func (c *User) DeleteUser(requesterId, id int) error {
-	requester, err := c.repo.Get(requesterId)
+func (c *User) DeleteUser(ctx context.Context, requesterId, id int) error {
+	ctx, span := tracer.Start(ctx, "controller.User.DeleteUser")
+	defer span.End()
+	requester, err := c.repo.Get(ctx, requesterId)
 	if err != nil {
 		re, ok := err.(*mock.ErrUserNotFound)
 		if ok {
-			c.l.Errorf("user with id %d not found", re.ID)
+			c.log(ctx).Errorf("user with id %d not found", re.ID)
+			deletions.WithLabelValues(outcomeNotFound).Inc()
 			return ErrUserNotFound
 		} else {
-			c.l.Errorf("controller.DeleteUser: unexpected error in repo.Get: %v", err)
+			c.log(ctx).Errorf("controller.DeleteUser: unexpected error in repo.Get: %v", err)
+			deletions.WithLabelValues(outcomeError).Inc()
 			return ErrUnexpected
 		}
 	}
 
 	if requester.ID == id || requester.Role == model.RoleAdmin {
-		err = c.repo.Delete(id)
+		var pfp string
+		err = c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+			//the event carries the user as it was before being deleted
+			u, err := users.Get(ctx, id)
+			if err != nil {
+				return err
+			}
+			pfp = u.Pfp
+			if err := users.Delete(ctx, id); err != nil {
+				return err
+			}
+			return publishEvent(outbox, model.EventUserDeleted, u)
+		})
 		if err != nil {
 			re, ok := err.(*mock.ErrUserNotFound)
 			if ok {
-				c.l.Errorf("user with id %d not found", re.ID)
+				c.log(ctx).Errorf("user with id %d not found", re.ID)
+				deletions.WithLabelValues(outcomeNotFound).Inc()
 				return ErrUserNotFound
 			} else {
-				c.l.Errorf("controller.DeleteUser: unexpected error in repo.Delete: %v", err)
+				c.log(ctx).Errorf("controller.DeleteUser: unexpected error in repo.Delete: %v", err)
+				deletions.WithLabelValues(outcomeError).Inc()
 				return ErrUnexpected
 			}
 		}
+		c.deleteUploadedPfp(ctx, id, pfp)
+		deletions.WithLabelValues(outcomeSuccess).Inc()
+	} else {
+		deletions.WithLabelValues(outcomeForbidden).Inc()
 	}
 	return nil
 }
 
-func (c *User) RegeneratePfp(id int) error {
-	m, err := c.repo.Get(id)
+// RegeneratePfp generates a new profile picture for the user, with a queue it's generated
+// in background and the pfp status of the user is pending until it's done
+func (c *User) RegeneratePfp(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "controller.User.RegeneratePfp")
+	defer span.End()
+	m, err := c.repo.Get(ctx, id)
 	if err != nil {
 		re, ok := err.(*mock.ErrUserNotFound)
 		if ok {
-			c.l.Errorf("user with id %d not found", re.ID)
+			c.log(ctx).Errorf("user with id %d not found", re.ID)
 			return ErrUserNotFound
 		} else {
-			c.l.Errorf("controller.RegenerateLogo: unexpected error in repo.Get: %v", err)
+			c.log(ctx).Errorf("controller.RegenerateLogo: unexpected error in repo.Get: %v", err)
 			return ErrUnexpected
 		}
 	}
 
-	m.Pfp, err = c.logo.GenerateLogo()
-	if err != nil {
-		c.l.Errorf("controller.RegenerateLogo: unexpected error in logo.GenerateLogo: %v", err)
-		return ErrUnexpected
-	}
+	//the uploaded picture replaced by the generated one, the queue replaces it in GeneratePfp
+	var previous string
+	if c.pfpQueue != nil {
+		//the event is published by GeneratePfp when the new picture is ready
+		m.PfpStatus = model.PfpStatusPending
+		err = c.repo.Update(ctx, m)
+	} else {
+		previous = m.Pfp
+		m.Pfp, err = c.logo.GenerateLogo(ctx, m)
+		if err != nil {
+			c.log(ctx).Errorf("controller.RegenerateLogo: unexpected error in logo.GenerateLogo: %v", err)
+			return ErrUnexpected
+		}
+		m.PfpStatus = model.PfpStatusReady
 
-	err = c.repo.Update(m)
+		err = c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+			if err := users.Update(ctx, m); err != nil {
+				return err
+			}
+			return publishEvent(outbox, model.EventPfpRegenerated, m)
+		})
+	}
 	if err != nil {
 		re, ok := err.(*mock.ErrUserNotFound)
 		if ok {
-			c.l.Errorf("user with id %d not found", re.ID)
+			c.log(ctx).Errorf("user with id %d not found", re.ID)
 			return ErrUserNotFound
 		} else if err == mock.ErrUserUnapdatable {
 			return ErrUnupdatableUser
 		} else {
-			c.l.Errorf("controller.RegenerateLogo: unexpected error in repo.Update: %v", err)
+			c.log(ctx).Errorf("controller.RegenerateLogo: unexpected error in repo.Update: %v", err)
 			return ErrUnexpected
 		}
 	}
+
+	if c.pfpQueue != nil {
+		c.enqueuePfp(ctx, id)
+	} else {
+		c.deleteUploadedPfp(ctx, id, previous)
+	}
+	return nil
+}
+
+func (c *User) enqueuePfp(ctx context.Context, id int) {
+	if err := c.pfpQueue.Enqueue(id); err != nil {
+		//the user can retry with RegeneratePfp
+		c.log(ctx).Errorf("controller.enqueuePfp: unable to enqueue the generation of the pfp of user %d: %v", id, err)
+		c.PfpGenerationFailed(id, err)
+	}
+}
+
+// GeneratePfp is the job of the pfp queue, it generates the profile picture of a user with a pending pfp.
+// The returned error means that the job should be retried.
+func (c *User) GeneratePfp(ctx context.Context, id int) error {
+	ctx, span := tracer.Start(ctx, "controller.User.GeneratePfp")
+	defer span.End()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m, err := c.repo.Get(ctx, id)
+	if err != nil {
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			//the user was deleted in the meantime
+			return nil
+		}
+		return err
+	}
+	//the user uploaded a picture in the meantime
+	if m.PfpStatus != model.PfpStatusPending {
+		return nil
+	}
+
+	pfp, err := c.logo.GenerateLogo(ctx, m)
+	if err != nil {
+		return err
+	}
+
+	//the user may have changed while the picture was generated so it's read again
+	var previous string
+	err = c.repo.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+		u, err := users.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if u.PfpStatus != model.PfpStatusPending {
+			return nil
+		}
+		previous = u.Pfp
+		u.Pfp = pfp
+		u.PfpStatus = model.PfpStatusReady
+		if err := users.Update(ctx, u); err != nil {
+			return err
+		}
+		return publishEvent(outbox, model.EventPfpRegenerated, u)
+	})
+	if err != nil {
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			return nil
+		} else if err == mock.ErrUserUnapdatable {
+			c.log(ctx).Warnf("controller.GeneratePfp: user %d can't be updated, pfp not saved", id)
+			return nil
+		}
+		return err
+	}
+	c.deleteUploadedPfp(ctx, id, previous)
 	return nil
 }
 
-func (c *User) CheckCredentials(email, password string) (int, error) {
-	m, err := c.repo.GetByEmail(email)
+// PfpGenerationFailed marks the pfp of the user as failed, it's called when
+// the queue gives up (or can't even enqueue the job)
+func (c *User) PfpGenerationFailed(id int, cause error) {
+	//the job is over so there is no context to inherit
+	ctx := context.Background()
+	err := c.repo.WithTx(ctx, func(users repo.UserRepoer, _ repo.OutboxRepoer) error {
+		u, err := users.Get(ctx, id)
+		if err != nil {
+			return err
+		}
+		if u.PfpStatus != model.PfpStatusPending {
+			return nil
+		}
+		u.PfpStatus = model.PfpStatusFailed
+		return users.Update(ctx, u)
+	})
+	if err != nil {
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			return
+		}
+		c.log(ctx).Errorf("controller.PfpGenerationFailed: unable to mark the pfp of user %d as failed (%v): %v", id, cause, err)
+	}
+}
+
+// ResumePendingPfps enqueues the generation of every pending pfp, the queue is in memory
+// so it must be called at startup to resume the jobs that were lost by the last shutdown
+func (c *User) ResumePendingPfps(ctx context.Context) int {
+	ctx, span := tracer.Start(ctx, "controller.User.ResumePendingPfps")
+	defer span.End()
+	if c.pfpQueue == nil {
+		return 0
+	}
+	resumed := 0
+	for _, u := range c.repo.GetAll(ctx) {
+		if u.PfpStatus == model.PfpStatusPending {
+			c.enqueuePfp(ctx, u.ID)
+			resumed++
+		}
+	}
+	return resumed
+}
+
+func (c *User) CheckCredentials(ctx context.Context, email, password string) (int, error) {
+	ctx, span := tracer.Start(ctx, "controller.User.CheckCredentials")
+	defer span.End()
+	m, err := c.repo.GetByEmail(ctx, email)
 	if err != nil {
 		_, ok := err.(*mock.ErrUserNotFound)
 		if ok {
-			c.l.Errorf("user with email %s not found", email)
+			c.log(ctx).Errorf("controller.CheckCredentials: no user with the given email")
+			logins.WithLabelValues(outcomeNotFound).Inc()
 			return -1, ErrUserNotFound
 		} else {
-			c.l.Errorf("controller.CheckCredentials: unexpected error in repo.GetByEmail: %v", err)
+			c.log(ctx).Errorf("controller.CheckCredentials: unexpected error in repo.GetByEmail: %v", err)
+			logins.WithLabelValues(outcomeError).Inc()
 			return -1, ErrUnexpected
 		}
 	}
 
 	if m.Password != password {
+		logins.WithLabelValues(outcomeWrongPassword).Inc()
 		return -1, ErrWrongPassword
 	}
 
+	logins.WithLabelValues(outcomeSuccess).Inc()
 	return m.ID, nil
 }
@@ -0,0 +1,200 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+)
+
+var _ WebhookControllerer = new(Webhook)
+
+const (
+	_WEBHOOK_SECRET_BYTES      = 32
+	_WEBHOOK_MIN_SECRET_LENGTH = 16
+)
+
+var (
+	ErrNotAdmin              = errors.New("only admins can perform this action")
+	ErrInvalidWebhook        = errors.New("invalid webhook")
+	ErrWebhookNotFound       = errors.New("webhook not found")
+	ErrDeliveryNotFound      = errors.New("delivery not found")
+	ErrDeliveryNotResendable = errors.New("delivery can't be redelivered")
+)
+
+// Webhooks are managed only by admins, the deliveries are created by the webhook sink
+// and sent by the webhook sender (see providers/webhook)
+type Webhook struct {
+	users repo.UserRepoer
+	repo  repo.WebhookRepoer
+	l     *logrus.Logger
+}
+
+func NewWebhookController(users repo.UserRepoer, webhooks repo.WebhookRepoer, log *logrus.Logger) *Webhook {
+	return &Webhook{
+		users: users,
+		repo:  webhooks,
+		l:     log,
+	}
+}
+
+// log returns the logger of the request (or of the job) that is running
+func (c *Webhook) log(ctx context.Context) *logrus.Entry {
+	return logger.FromContext(ctx, c.l)
+}
+
+// checkAdmin returns nil only if the requester exists and is an admin
+func (c *Webhook) checkAdmin(ctx context.Context, requesterId int) error {
+	return checkAdmin(ctx, c.users, c.log(ctx), requesterId)
+}
+
+// CreateWebhook subscribes the url to the given event types (every event if empty).
+// If the secret is empty a random one is generated, the returned webhook is the only
+// place where the secret can be read so it must be shown to the admin.
+func (c *Webhook) CreateWebhook(ctx context.Context, requesterId int, rawUrl string, events []string, secret string) (*model.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "controller.Webhook.CreateWebhook")
+	defer span.End()
+	if err := c.checkAdmin(ctx, requesterId); err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(rawUrl)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: the url must be an absolute http or https url", ErrInvalidWebhook)
+	}
+	for _, e := range events {
+		if !isKnownEventType(e) {
+			return nil, fmt.Errorf("%w: unknown event type %q", ErrInvalidWebhook, e)
+		}
+	}
+
+	if secret == "" {
+		b := make([]byte, _WEBHOOK_SECRET_BYTES)
+		if _, err := rand.Read(b); err != nil {
+			c.log(ctx).Errorf("controller.CreateWebhook: unable to generate secret: %v", err)
+			return nil, ErrUnexpected
+		}
+		secret = hex.EncodeToString(b)
+	} else if len(secret) < _WEBHOOK_MIN_SECRET_LENGTH {
+		return nil, fmt.Errorf("%w: the secret must be at least %d characters long", ErrInvalidWebhook, _WEBHOOK_MIN_SECRET_LENGTH)
+	}
+
+	w := &model.Webhook{
+		URL:       u.String(),
+		Events:    events,
+		Secret:    secret,
+		CreatedBy: requesterId,
+		CreatedAt: time.Now(),
+	}
+	if _, err := c.repo.CreateWebhook(w); err != nil {
+		c.log(ctx).Errorf("controller.CreateWebhook: unexpected error in repo.CreateWebhook: %v", err)
+		return nil, ErrUnexpected
+	}
+	return w, nil
+}
+
+func isKnownEventType(eventType string) bool {
+	if eventType == "*" {
+		return true
+	}
+	for _, t := range model.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Webhook) GetAllWebhooks(ctx context.Context, requesterId int) ([]*model.Webhook, error) {
+	ctx, span := tracer.Start(ctx, "controller.Webhook.GetAllWebhooks")
+	defer span.End()
+	if err := c.checkAdmin(ctx, requesterId); err != nil {
+		return nil, err
+	}
+	return c.repo.GetAllWebhooks(), nil
+}
+
+func (c *Webhook) DeleteWebhook(ctx context.Context, requesterId, id int) error {
+	ctx, span := tracer.Start(ctx, "controller.Webhook.DeleteWebhook")
+	defer span.End()
+	if err := c.checkAdmin(ctx, requesterId); err != nil {
+		return err
+	}
+	if err := c.repo.DeleteWebhook(id); err != nil {
+		if _, ok := err.(*mock.ErrWebhookNotFound); ok {
+			return ErrWebhookNotFound
+		}
+		c.log(ctx).Errorf("controller.DeleteWebhook: unexpected error in repo.DeleteWebhook: %v", err)
+		return ErrUnexpected
+	}
+	return nil
+}
+
+// GetDeliveries is the delivery log of the webhook, filtering by model.DeliveryDead gives the dead-letter list
+func (c *Webhook) GetDeliveries(ctx context.Context, requesterId, webhookId int, status string) ([]*model.WebhookDelivery, error) {
+	ctx, span := tracer.Start(ctx, "controller.Webhook.GetDeliveries")
+	defer span.End()
+	if err := c.checkAdmin(ctx, requesterId); err != nil {
+		return nil, err
+	}
+	if _, err := c.repo.GetWebhook(webhookId); err != nil {
+		if _, ok := err.(*mock.ErrWebhookNotFound); ok {
+			return nil, ErrWebhookNotFound
+		}
+		c.log(ctx).Errorf("controller.GetDeliveries: unexpected error in repo.GetWebhook: %v", err)
+		return nil, ErrUnexpected
+	}
+
+	deliveries, err := c.repo.GetDeliveries(webhookId, status)
+	if err != nil {
+		c.log(ctx).Errorf("controller.GetDeliveries: unexpected error in repo.GetDeliveries: %v", err)
+		return nil, ErrUnexpected
+	}
+	return deliveries, nil
+}
+
+// Redeliver schedules a dead delivery to be sent again as soon as possible with all the
+// attempts again, the pending and the succeeded ones are not resendable
+func (c *Webhook) Redeliver(ctx context.Context, requesterId, deliveryId int) error {
+	ctx, span := tracer.Start(ctx, "controller.Webhook.Redeliver")
+	defer span.End()
+	if err := c.checkAdmin(ctx, requesterId); err != nil {
+		return err
+	}
+	d, err := c.repo.GetDelivery(deliveryId)
+	if err != nil {
+		if _, ok := err.(*mock.ErrDeliveryNotFound); ok {
+			return ErrDeliveryNotFound
+		}
+		c.log(ctx).Errorf("controller.Redeliver: unexpected error in repo.GetDelivery: %v", err)
+		return ErrUnexpected
+	}
+	if d.Status != model.DeliveryDead {
+		return fmt.Errorf("%w: the delivery is %s, only the dead ones can be sent again", ErrDeliveryNotResendable, d.Status)
+	}
+	if _, err := c.repo.GetWebhook(d.WebhookID); err != nil {
+		if _, ok := err.(*mock.ErrWebhookNotFound); ok {
+			return fmt.Errorf("%w: the webhook was deleted", ErrDeliveryNotResendable)
+		}
+		c.log(ctx).Errorf("controller.Redeliver: unexpected error in repo.GetWebhook: %v", err)
+		return ErrUnexpected
+	}
+
+	d.Status = model.DeliveryPending
+	d.FailedAttempts = 0
+	d.NextAttemptAt = time.Now()
+	if err := c.repo.UpdateDelivery(d); err != nil {
+		c.log(ctx).Errorf("controller.Redeliver: unexpected error in repo.UpdateDelivery: %v", err)
+		return ErrUnexpected
+	}
+	return nil
+}
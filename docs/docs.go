@@ -19,17 +19,30 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/user/all": {
+        "/admin/log-levels": {
             "get": {
-                "description": "Get all user for an unauthorized user",
+                "description": "Level of the logs of every component of the service, only for admins",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor",
+                    "text/csv"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
+                ],
+                "summary": "Get log levels",
+                "operationId": "GetLogLevels",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
                 ],
-                "summary": "Get all user",
-                "operationId": "getAllUnauthorizedUser",
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -47,7 +60,7 @@ const docTemplate = `{
                                         "data": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/httpserver.HttpUnauthenticatedUser"
+                                                "$ref": "#/definitions/logger.ComponentLevel"
                                             }
                                         },
                                         "message": {
@@ -58,40 +71,54 @@ const docTemplate = `{
                             ]
                         }
                     },
-                    "404": {
-                        "description": "Not Found",
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
-            }
-        },
-        "/user/login": {
-            "post": {
-                "description": "Login user given email and password",
+            },
+            "put": {
+                "description": "Change the level of the logs of a component at runtime, only for admins\nWith a ttl the level goes back to the default one automatically, useful to debug without forgetting the service in debug",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Login",
-                "operationId": "LoginUser",
+                "summary": "Set log level",
+                "operationId": "SetLogLevel",
                 "parameters": [
                     {
-                        "description": "email and password",
-                        "name": "credentials",
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "new level",
+                        "name": "level",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpLoginUserPost"
+                            "$ref": "#/definitions/httpserver.HttpLogLevelPut"
                         }
                     }
                 ],
@@ -110,7 +137,10 @@ const docTemplate = `{
                                             "type": "integer"
                                         },
                                         "data": {
-                                            "$ref": "#/definitions/httpserver.LoginUser.HttpLoginUserPostResponse"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/logger.ComponentLevel"
+                                            }
                                         },
                                         "message": {
                                             "type": "string"
@@ -123,41 +153,49 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/me": {
-            "get": {
-                "description": "Get authenticated user info from jwt",
+        "/admin/log-levels/{component}": {
+            "delete": {
+                "description": "Bring the level of the logs of a component back to the default one, only for admins",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
                 ],
                 "tags": [
-                    "users"
+                    "admin"
                 ],
-                "summary": "Get user info",
-                "operationId": "GetUserInfo",
+                "summary": "Reset log level",
+                "operationId": "ResetLogLevel",
                 "parameters": [
                     {
                         "type": "string",
@@ -166,6 +204,13 @@ const docTemplate = `{
                         "name": "Authorization",
                         "in": "header",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Component name",
+                        "name": "component",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -182,9 +227,6 @@ const docTemplate = `{
                                         "code": {
                                             "type": "integer"
                                         },
-                                        "data": {
-                                            "$ref": "#/definitions/model.User"
-                                        },
                                         "message": {
                                             "type": "string"
                                         }
@@ -196,44 +238,1119 @@ const docTemplate = `{
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/register": {
+        "/avatars/{style}/{file}": {
+            "get": {
+                "description": "Deterministic avatar generated by the service (the local logo provider returns these urls)\nThe file is \u003cseed\u003e.png or \u003cseed\u003e.svg, for the initials style the seed is \u003cinitials\u003e-\u003chash\u003e",
+                "produces": [
+                    "image/png",
+                    "image/svg+xml"
+                ],
+                "tags": [
+                    "avatars"
+                ],
+                "summary": "Get avatar",
+                "operationId": "GetAvatar",
+                "parameters": [
+                    {
+                        "enum": [
+                            "identicon",
+                            "initials"
+                        ],
+                        "type": "string",
+                        "description": "avatar style",
+                        "name": "style",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "seed and format",
+                        "name": "file",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "size in pixels (16-512)",
+                        "name": "size",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/errors": {
+            "get": {
+                "description": "Describes every error type the api can return, the type of the errors links here",
+                "produces": [
+                    "text/html"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "Error reference",
+                "operationId": "ErrorReference",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/docs/errors.json": {
+            "get": {
+                "description": "Lists every error type the api can return, for clients that want to generate their error handling",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "docs"
+                ],
+                "summary": "Error catalog",
+                "operationId": "ErrorCatalog",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/httpserver.errorCatalogEntry"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/graphql": {
             "post": {
-                "description": "Register a new user",
+                "description": "GraphQL endpoint of the users, the schema is in handlers/graphqlserver/schema.graphql (introspection is disabled in production).\nThe token is optional: without it the email and the role of the users are null and the mutations but register and login fail.\nThe errors of the resolvers are in the errors of the response with the error type in extensions.code, the status is 200 anyway",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "graphql"
+                ],
+                "summary": "GraphQL",
+                "operationId": "Graphql",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header"
+                    },
+                    {
+                        "description": "query, operationName and variables",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/graphqlserver.Request"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Tells if the process is alive, it doesn't check the dependencies",
                 "produces": [
                     "application/json"
                 ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "operationId": "Liveness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/pfp/{path}": {
+            "get": {
+                "description": "Get an uploaded profile picture, the content of an url never changes so it can be cached forever",
+                "produces": [
+                    "image/png",
+                    "image/jpeg"
+                ],
                 "tags": [
                     "users"
                 ],
-                "summary": "Register a new user",
-                "operationId": "CreateNewUser",
+                "summary": "Get uploaded profile picture",
+                "operationId": "GetPfp",
                 "parameters": [
                     {
-                        "description": "User Informations",
-                        "name": "account",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/httpserver.HttpNewUserPost"
-                        }
+                        "type": "string",
+                        "description": "\u003cuser id\u003e/\u003cversion\u003e/\u003csize\u003e.\u003cext\u003e",
+                        "name": "path",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Checks every dependency and tells if the service can handle requests\nThe status is \"ok\", \"degraded\" (a non critical component is down), \"unavailable\" or \"shutting_down\"",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "operationId": "Readiness",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/health.Report"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/all": {
+            "get": {
+                "description": "Get all user for an unauthorized user",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor",
+                    "text/csv"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get all user",
+                "operationId": "getAllUnauthorizedUser",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/httpserver.HttpUnauthenticatedUser"
+                                            }
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/login": {
+            "post": {
+                "description": "Login user given email and password",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Login",
+                "operationId": "LoginUser",
+                "parameters": [
+                    {
+                        "description": "email and password",
+                        "name": "credentials",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpLoginUserPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.LoginUser.HttpLoginUserPostResponse"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/me": {
+            "get": {
+                "description": "Get authenticated user info from jwt",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user info",
+                "operationId": "GetUserInfo",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/model.User"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/pfp": {
+            "post": {
+                "description": "Upload a new profile picture (jpeg, png or gif) as multipart form with the image in the \"pfp\" field\nThe image is cropped to a square, resized to every thumbnail size (listed from the smallest) and the metadata are removed\nThe files of the picture uploaded before are deleted\nNormal user can only upload their own picture, admins can specify a userid",
+                "consumes": [
+                    "multipart/form-data"
+                ],
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Upload profile picture",
+                "operationId": "UploadPfp",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "file",
+                        "description": "the image",
+                        "name": "pfp",
+                        "in": "formData",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "id of the user to update",
+                        "name": "userid",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/httpserver.HttpPfpThumbnail"
+                                            }
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "413": {
+                        "description": "Request Entity Too Large",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "415": {
+                        "description": "Unsupported Media Type",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/pfp/regenerate/{userid}": {
+            "post": {
+                "description": "Automatically regnerate user's profile picture\nNormal user do not need to specify anything, admins can specify a userid to update\nThe picture is generated in background: the response is 202 with pfp_status \"pending\" and the\nuser's pfp_status becomes \"ready\" (with the new pfp) or \"failed\" when the generation is over",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Regenerate user's pfp",
+                "operationId": "RegeneratePfpUrl",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "id of the user to update",
+                        "name": "userid",
+                        "in": "path"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.RegeneratePfpUrl.HttpNewPfp"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "202": {
+                        "description": "Accepted",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.RegeneratePfpUrl.HttpNewPfp"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/register": {
+            "post": {
+                "description": "Register a new user",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Register a new user",
+                "operationId": "CreateNewUser",
+                "parameters": [
+                    {
+                        "description": "User Informations",
+                        "name": "account",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpNewUserPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.CreateNewUser.HttpNewUserPostResponse"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/update": {
+            "post": {
+                "description": "Update user info from jwt, if you are an admin you can update any user given the id\nA normal user can only update himself, an admin can update any user\nYou don't have to send all the fields, only the ones you want to update with the new values\nIf the ID is not specified the user the update will be applied to the requesting user (only for admins, normal users can't update other users)",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Update user",
+                "operationId": "UpdateUser",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "users information to update",
+                        "name": "user_info",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpUpdateUserPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/user/{id}": {
+            "get": {
+                "description": "Get user from ID for unauthorized users",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "users"
+                ],
+                "summary": "Get user from ID",
+                "operationId": "getUnauthorizedUser",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "User ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.HttpUnauthenticatedUser"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks": {
+            "get": {
+                "description": "Get all the webhooks, only for admins",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor",
+                    "text/csv"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Get all webhooks",
+                "operationId": "GetAllWebhooks",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/httpserver.HttpWebhook"
+                                            }
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Subscribe an url to the domain events, only for admins\nThe deliveries are signed with HMAC-SHA256 of \"\u003ctimestamp\u003e.\u003cbody\u003e\" using the secret (X-Webhook-Signature and X-Webhook-Timestamp headers)\nThe secret is returned only in this response",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Create webhook",
+                "operationId": "CreateWebhook",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "description": "webhook informations",
+                        "name": "webhook",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.HttpNewWebhookPost"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httpserver.HttpSuccess"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "code": {
+                                            "type": "integer"
+                                        },
+                                        "data": {
+                                            "$ref": "#/definitions/httpserver.HttpWebhook"
+                                        },
+                                        "message": {
+                                            "type": "string"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    }
+                }
+            }
+        },
+        "/webhooks/deliveries/{id}/redeliver": {
+            "post": {
+                "description": "Send a dead delivery again as soon as possible with all the attempts again, the pending and the succeeded ones are not resendable, only for admins",
+                "produces": [
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
+                ],
+                "tags": [
+                    "webhooks"
+                ],
+                "summary": "Redeliver",
+                "operationId": "RedeliverWebhookDelivery",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Delivery ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -250,9 +1367,6 @@ const docTemplate = `{
                                         "code": {
                                             "type": "integer"
                                         },
-                                        "data": {
-                                            "$ref": "#/definitions/httpserver.CreateNewUser.HttpNewUserPostResponse"
-                                        },
                                         "message": {
                                             "type": "string"
                                         }
@@ -264,29 +1378,49 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/update": {
-            "post": {
-                "description": "Automatically regnerate user's profile picture\nNormal user do not need to specify anything, admins can specify a userid to update",
+        "/webhooks/{id}": {
+            "delete": {
+                "description": "Delete a webhook, the pending deliveries won't be sent, only for admins",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor"
                 ],
                 "tags": [
-                    "users"
+                    "webhooks"
                 ],
-                "summary": "Regenerate user's pfp",
-                "operationId": "RegeneratePfpUrl",
+                "summary": "Delete webhook",
+                "operationId": "DeleteWebhook",
                 "parameters": [
                     {
                         "type": "string",
@@ -298,9 +1432,10 @@ const docTemplate = `{
                     },
                     {
                         "type": "integer",
-                        "description": "id of the user to update",
-                        "name": "userid",
-                        "in": "path"
+                        "description": "Webhook ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -317,9 +1452,6 @@ const docTemplate = `{
                                         "code": {
                                             "type": "integer"
                                         },
-                                        "data": {
-                                            "$ref": "#/definitions/httpserver.RegeneratePfpUrl.HttpNewPfp"
-                                        },
                                         "message": {
                                             "type": "string"
                                         }
@@ -331,48 +1463,76 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "401": {
                         "description": "Unauthorized",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
             }
         },
-        "/user/{id}": {
+        "/webhooks/{id}/deliveries": {
             "get": {
-                "description": "Get user from ID for unauthorized users",
+                "description": "Delivery log of a webhook with every attempt, only for admins\nUse status=dead to get the dead-letter list (deliveries that exhausted the attempts)",
                 "produces": [
-                    "application/json"
+                    "application/json",
+                    "application/msgpack",
+                    "application/cbor",
+                    "text/csv"
                 ],
                 "tags": [
-                    "users"
+                    "webhooks"
                 ],
-                "summary": "Get user from ID",
-                "operationId": "getUnauthorizedUser",
+                "summary": "Get webhook deliveries",
+                "operationId": "GetWebhookDeliveries",
                 "parameters": [
+                    {
+                        "type": "string",
+                        "default": "Bearer xxx.xxx.xxx",
+                        "description": "jwt token",
+                        "name": "Authorization",
+                        "in": "header",
+                        "required": true
+                    },
                     {
                         "type": "integer",
-                        "description": "User ID",
+                        "description": "Webhook ID",
                         "name": "id",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "enum": [
+                            "pending",
+                            "succeeded",
+                            "dead"
+                        ],
+                        "type": "string",
+                        "description": "filter by status",
+                        "name": "status",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -390,7 +1550,10 @@ const docTemplate = `{
                                             "type": "integer"
                                         },
                                         "data": {
-                                            "$ref": "#/definitions/httpserver.HttpUnauthenticatedUser"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/httpserver.HttpWebhookDelivery"
+                                            }
                                         },
                                         "message": {
                                             "type": "string"
@@ -403,19 +1566,31 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "$ref": "#/definitions/httpserver.HttpError"
+                            "$ref": "#/definitions/httpserver.Problem"
                         }
                     }
                 }
@@ -423,44 +1598,75 @@ const docTemplate = `{
         }
     },
     "definitions": {
-        "httpserver.CreateNewUser.HttpNewUserPostResponse": {
+        "graphqlserver.Request": {
             "type": "object",
             "properties": {
-                "id": {
-                    "type": "integer"
+                "operationName": {
+                    "type": "string"
+                },
+                "query": {
+                    "type": "string"
+                },
+                "variables": {
+                    "type": "object",
+                    "additionalProperties": true
                 }
             }
         },
-        "httpserver.HttpError": {
+        "health.ComponentReport": {
             "type": "object",
             "properties": {
-                "code": {
-                    "type": "integer",
-                    "example": 400
+                "critical": {
+                    "description": "a non critical component being down makes the service degraded but still ready",
+                    "type": "boolean"
                 },
-                "details": {
-                    "type": "string",
-                    "example": "Bad Request With More Info"
+                "duration": {
+                    "type": "string"
                 },
-                "error_doc_url": {
-                    "type": "string",
-                    "example": "https://example.com/docs/errors/invalid_id"
+                "error": {
+                    "type": "string"
                 },
-                "error_type": {
-                    "type": "string",
-                    "example": "invalid_id"
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "health.Report": {
+            "type": "object",
+            "properties": {
+                "components": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/health.ComponentReport"
+                    }
                 },
-                "instance": {
-                    "type": "string",
-                    "example": "/api/v1/users/1"
+                "status": {
+                    "type": "string"
+                }
+            }
+        },
+        "httpserver.CreateNewUser.HttpNewUserPostResponse": {
+            "type": "object",
+            "properties": {
+                "id": {
+                    "type": "integer"
+                }
+            }
+        },
+        "httpserver.HttpLogLevelPut": {
+            "type": "object",
+            "properties": {
+                "component": {
+                    "description": "\"root\" changes every component without a level of its own",
+                    "type": "string"
                 },
-                "is_error": {
-                    "type": "boolean",
-                    "example": true
+                "level": {
+                    "description": "trace, debug, info, warn, error, fatal or panic",
+                    "type": "string"
                 },
-                "message": {
-                    "type": "string",
-                    "example": "Bad Request"
+                "ttl": {
+                    "description": "duration (like 10m or 1h) after which the level goes back to the default one, empty doesn't expire",
+                    "type": "string"
                 }
             }
         },
@@ -492,6 +1698,36 @@ const docTemplate = `{
                 }
             }
         },
+        "httpserver.HttpNewWebhookPost": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "description": "event types to subscribe to, empty means every event",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "secret": {
+                    "description": "if empty a random secret is generated",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "httpserver.HttpPfpThumbnail": {
+            "type": "object",
+            "properties": {
+                "size": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "httpserver.HttpSuccess": {
             "type": "object",
             "properties": {
@@ -527,6 +1763,9 @@ const docTemplate = `{
                 },
                 "pfp": {
                     "type": "string"
+                },
+                "pfp_status": {
+                    "type": "string"
                 }
             }
         },
@@ -545,11 +1784,94 @@ const docTemplate = `{
                 "last_name": {
                     "type": "string"
                 },
+                "locale": {
+                    "description": "BCP 47 tag (en, it-IT...) of the language of the messages sent to the user",
+                    "type": "string",
+                    "example": "it"
+                },
                 "password": {
                     "type": "string"
                 }
             }
         },
+        "httpserver.HttpWebhook": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "created_by": {
+                    "type": "integer"
+                },
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "httpserver.HttpWebhookAttempt": {
+            "type": "object",
+            "properties": {
+                "at": {
+                    "type": "string"
+                },
+                "duration_ms": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "status_code": {
+                    "type": "integer"
+                }
+            }
+        },
+        "httpserver.HttpWebhookDelivery": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/httpserver.HttpWebhookAttempt"
+                    }
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "event_id": {
+                    "type": "integer"
+                },
+                "event_type": {
+                    "type": "string"
+                },
+                "failed_attempts": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_attempt_at": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string"
+                },
+                "webhook_id": {
+                    "type": "integer"
+                }
+            }
+        },
         "httpserver.LoginUser.HttpLoginUserPostResponse": {
             "type": "object",
             "properties": {
@@ -558,11 +1880,89 @@ const docTemplate = `{
                 }
             }
         },
+        "httpserver.Problem": {
+            "type": "object",
+            "properties": {
+                "detail": {
+                    "type": "string",
+                    "example": "id \"abc\" is not a valid id as it is not a number"
+                },
+                "error_type": {
+                    "description": "extension members, the type without the url so clients can switch on it",
+                    "type": "string",
+                    "example": "invalid_id"
+                },
+                "instance": {
+                    "type": "string",
+                    "example": "/api/v1/user/abc"
+                },
+                "request_id": {
+                    "type": "string",
+                    "example": "9b2f6d0c4e8a1f3b5d7c9e0a2b4c6d8e"
+                },
+                "status": {
+                    "type": "integer",
+                    "example": 400
+                },
+                "title": {
+                    "type": "string",
+                    "example": "invalid id"
+                },
+                "type": {
+                    "type": "string",
+                    "example": "http://localhost:8080/docs/errors#invalid_id"
+                }
+            }
+        },
         "httpserver.RegeneratePfpUrl.HttpNewPfp": {
             "type": "object",
             "properties": {
                 "new_pfp": {
                     "type": "string"
+                },
+                "pfp_status": {
+                    "type": "string"
+                }
+            }
+        },
+        "httpserver.errorCatalogEntry": {
+            "type": "object",
+            "properties": {
+                "description": {
+                    "description": "when the error is returned and how the client can fix it",
+                    "type": "string"
+                },
+                "doc_url": {
+                    "type": "string",
+                    "example": "http://localhost:8080/docs/errors#invalid_id"
+                },
+                "status": {
+                    "type": "integer"
+                },
+                "title": {
+                    "type": "string"
+                },
+                "type": {
+                    "type": "string"
+                }
+            }
+        },
+        "logger.ComponentLevel": {
+            "type": "object",
+            "properties": {
+                "component": {
+                    "type": "string"
+                },
+                "expires_at": {
+                    "description": "when the level goes back to the default one, nil if it doesn't expire",
+                    "type": "string"
+                },
+                "level": {
+                    "type": "string"
+                },
+                "overridden": {
+                    "description": "the level was set at runtime, it's not the configured one",
+                    "type": "boolean"
                 }
             }
         },
@@ -589,6 +1989,10 @@ const docTemplate = `{
                     "description": "` + "`" + `json:\"last_name\"` + "`" + `",
                     "type": "string"
                 },
+                "locale": {
+                    "description": "` + "`" + `json:\"locale\"` + "`" + `",
+                    "type": "string"
+                },
                 "password": {
                     "description": "` + "`" + `json:\"password\"` + "`" + `",
                     "type": "string"
@@ -597,6 +2001,10 @@ const docTemplate = `{
                     "description": "` + "`" + `json:\"pfp\"` + "`" + `",
                     "type": "string"
                 },
+                "pfpStatus": {
+                    "description": "` + "`" + `json:\"pfp_status\"` + "`" + `",
+                    "type": "string"
+                },
                 "role": {
                     "description": "` + "`" + `json:\"role\"` + "`" + `",
                     "type": "string"
@@ -617,7 +2025,7 @@ var SwaggerInfo = &swag.Spec{
 	BasePath:         "/api/v1",
 	Schemes:          []string{},
 	Title:            "Go Service Template",
-	Description:      "User Management Service",
+	Description:      "User Management Service\nThe messages and the titles of the errors are translated in the locale preferred by the user (see /user/update) or in the best one of the Accept-Language header\nThe responses are encoded as json, msgpack or cbor following the Accept header and the collections can be exported as text/csv, the other types are refused with 406. The errors are always application/problem+json",
 	InfoInstanceName: "swagger",
 	SwaggerTemplate:  docTemplate,
 }
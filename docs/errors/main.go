@@ -0,0 +1,28 @@
+// Command errors writes the error reference of the api, it is run by `make swag`
+// so the reference is generated together with the swagger docs
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/vano2903/service-template/handlers/httpserver"
+)
+
+func main() {
+	out := flag.String("out", "docs/errors.md", "file where the reference is written")
+	flag.Parse()
+
+	f, err := os.Create(*out)
+	if err != nil {
+		log.Fatalf("unable to create the reference: %v", err)
+	}
+	if err := httpserver.WriteErrorReference(f); err != nil {
+		f.Close()
+		log.Fatalf("unable to write the reference: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("unable to write the reference: %v", err)
+	}
+}
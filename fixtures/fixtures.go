@@ -0,0 +1,176 @@
+package fixtures
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/secrets"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrProduction is returned when the fixtures are loaded with the production profile
+var ErrProduction = errors.New("the fixtures can't be loaded in production")
+
+//go:embed test.yaml
+var testFixtures []byte
+
+type (
+	// Set is the content of one or more fixture files, the files are yaml or json
+	Set struct {
+		Users []User `yaml:"users"`
+	}
+
+	User struct {
+		FirstName string `yaml:"first_name"`
+		LastName  string `yaml:"last_name"`
+		//the users are matched by email, it must be unique
+		Email string `yaml:"email"`
+		//it can be a file:// or env: reference (see pkg/secrets)
+		Password string `yaml:"password"`
+		//the preferred locale (like it-IT), empty to use the one of the client
+		Locale string `yaml:"locale"`
+		//user by default
+		Role   string `yaml:"role"`
+		Banned bool   `yaml:"banned"`
+	}
+
+	// Upserter creates or updates a user by email (see controller.User.UpsertUser)
+	Upserter interface {
+		UpsertUser(ctx context.Context, u *model.User) (int, string, error)
+	}
+
+	// Result counts the users by outcome of the upsert
+	Result struct {
+		Created   int
+		Updated   int
+		Unchanged int
+	}
+)
+
+var _ Upserter = new(controller.User)
+
+// Allowed returns ErrProduction if the profile is production, the fixtures have well known
+// passwords and must never end up in a production database
+func Allowed(profile string) error {
+	if profile == config.ProfileProd {
+		return ErrProduction
+	}
+	return nil
+}
+
+// Load reads and merges the fixture files, the unknown fields, the invalid roles and
+// the emails repeated in the files are errors
+func Load(paths ...string) (*Set, error) {
+	set := &Set{}
+	for _, path := range paths {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the fixtures: %w", err)
+		}
+		if err := set.add(path, content); err != nil {
+			return nil, err
+		}
+	}
+	return set, set.validate()
+}
+
+// Test returns the fixtures shared by the tests of the controllers and of the handlers
+func Test() *Set {
+	set := &Set{}
+	if err := set.add("test.yaml", testFixtures); err != nil {
+		panic(err)
+	}
+	if err := set.validate(); err != nil {
+		panic(err)
+	}
+	return set
+}
+
+func (s *Set) add(name string, content []byte) error {
+	file := Set{}
+	//json is valid yaml so the same decoder reads both
+	dec := yaml.NewDecoder(bytes.NewReader(content))
+	dec.KnownFields(true)
+	if err := dec.Decode(&file); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid fixtures file %s: %w", name, err)
+	}
+	s.Users = append(s.Users, file.Users...)
+	return nil
+}
+
+func (s *Set) validate() error {
+	emails := make(map[string]bool, len(s.Users))
+	for i := range s.Users {
+		u := &s.Users[i]
+		if u.Role == "" {
+			u.Role = model.RoleUser
+		}
+		switch {
+		case u.Email == "":
+			return fmt.Errorf("the user %d of the fixtures has no email", i+1)
+		case emails[strings.ToLower(u.Email)]:
+			return fmt.Errorf("the user %s is in the fixtures more than once", u.Email)
+		case !model.IsRole(u.Role):
+			return fmt.Errorf("the user %s of the fixtures has the invalid role %q", u.Email, u.Role)
+		}
+		emails[strings.ToLower(u.Email)] = true
+	}
+	return nil
+}
+
+// Find returns the user of the fixtures with the email, it's useful in the tests to log in as that user
+func (s *Set) Find(email string) (User, bool) {
+	for _, u := range s.Users {
+		if strings.EqualFold(u.Email, email) {
+			return u, true
+		}
+	}
+	return User{}, false
+}
+
+// Apply upserts the users in order, the passwords that are references are resolved first.
+// It stops at the first error, the users upserted before it are kept
+func (s *Set) Apply(ctx context.Context, upserter Upserter) (Result, error) {
+	result := Result{}
+	resolver := secrets.NewResolver()
+	for _, u := range s.Users {
+		password, err := resolver.Resolve(ctx, u.Password)
+		if err != nil {
+			return result, fmt.Errorf("unable to resolve the password of %s: %w", u.Email, err)
+		}
+		_, outcome, err := upserter.UpsertUser(ctx, &model.User{
+			FirstName: u.FirstName,
+			LastName:  u.LastName,
+			Email:     u.Email,
+			Password:  password,
+			Locale:    u.Locale,
+			Role:      u.Role,
+			IsBanned:  u.Banned,
+		})
+		if err != nil {
+			return result, fmt.Errorf("unable to upsert the user %s: %w", u.Email, err)
+		}
+		switch outcome {
+		case controller.UpsertCreated:
+			result.Created++
+		case controller.UpsertUpdated:
+			result.Updated++
+		default:
+			result.Unchanged++
+		}
+	}
+	return result, nil
+}
+
+func (r Result) String() string {
+	return fmt.Sprintf("%d users created, %d updated, %d unchanged", r.Created, r.Updated, r.Unchanged)
+}
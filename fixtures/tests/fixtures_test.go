@@ -0,0 +1,106 @@
+package fixtures
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+func write(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	assert.NilError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// load, cases:
+// [x] yaml and json files are merged, the role is user by default
+// [x] unknown fields, missing emails, invalid roles and repeated emails are errors
+// [x] the fixtures of the dev profile and of the tests are valid
+func TestLoad(t *testing.T) {
+	yamlFile := write(t, "users.yaml", "users:\n  - email: \"a@fixtures.com\"\n    role: \"admin\"\n")
+	jsonFile := write(t, "users.json", `{"users": [{"email": "b@fixtures.com", "banned": true}]}`)
+	set, err := fixtures.Load(yamlFile, jsonFile)
+	assert.NilError(t, err)
+	assert.Equal(t, len(set.Users), 2)
+	assert.Equal(t, set.Users[0].Role, model.RoleAdmin)
+	assert.Equal(t, set.Users[1].Role, model.RoleUser)
+	assert.Assert(t, set.Users[1].Banned)
+
+	for content, problem := range map[string]string{
+		"users:\n  - email: \"a@fixtures.com\"\n    admin: true\n":               "field admin not found",
+		"users:\n  - first_name: \"nobody\"\n":                                   "has no email",
+		"users:\n  - email: \"a@fixtures.com\"\n    role: \"root\"\n":            "invalid role",
+		"users:\n  - email: \"a@fixtures.com\"\n  - email: \"A@fixtures.com\"\n": "more than once",
+		"users: {}\n": "invalid fixtures file",
+	} {
+		_, err := fixtures.Load(write(t, "invalid.yaml", content))
+		assert.ErrorContains(t, err, problem)
+	}
+	_, err = fixtures.Load(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "unable to read")
+
+	_, err = fixtures.Load("../dev.yaml")
+	assert.NilError(t, err)
+	_, ok := fixtures.Test().Find("admin@fixtures.test")
+	assert.Assert(t, ok)
+}
+
+// apply, cases:
+// [x] the users are created, then updated by email with their password and locale
+// [x] applying the same fixtures again changes nothing
+// [x] the passwords can be references
+// [x] the fixtures are refused in production
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	users := controller.NewUserController(mock.NewRepo(), logo.NewServiceLogo("", ""), logrus.New())
+
+	set := fixtures.Test()
+	result, err := set.Apply(ctx, users)
+	assert.NilError(t, err)
+	assert.Equal(t, result, fixtures.Result{Created: len(set.Users)})
+	result, err = set.Apply(ctx, users)
+	assert.NilError(t, err)
+	assert.Equal(t, result, fixtures.Result{Unchanged: len(set.Users)})
+	for _, u := range users.GetAllUsers(ctx) {
+		assert.Equal(t, u.IsBanned, u.Email == "banned@fixtures.test", u.Email)
+	}
+
+	t.Setenv("FIXTURES_TEST_PASSWORD", "from-env")
+	changed := write(t, "changed.yaml", `users:
+  - email: "user@fixtures.test"
+    password: "changed-password"
+    locale: "it-it"
+    role: "admin"
+  - email: "new@fixtures.test"
+    password: "env:FIXTURES_TEST_PASSWORD"
+`)
+	set, err = fixtures.Load(changed)
+	assert.NilError(t, err)
+	result, err = set.Apply(ctx, users)
+	assert.NilError(t, err)
+	assert.Equal(t, result, fixtures.Result{Created: 1, Updated: 1})
+	_, err = users.CheckCredentials(ctx, "user@fixtures.test", "user-password")
+	assert.Equal(t, err, controller.ErrWrongPassword)
+	id, err := users.CheckCredentials(ctx, "user@fixtures.test", "changed-password")
+	assert.NilError(t, err)
+	u, err := users.GetUser(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Role, model.RoleAdmin)
+	assert.Equal(t, u.Locale, "it-IT")
+	_, err = users.CheckCredentials(ctx, "new@fixtures.test", "from-env")
+	assert.NilError(t, err)
+
+	assert.ErrorIs(t, fixtures.Allowed(config.ProfileProd), fixtures.ErrProduction)
+	assert.NilError(t, fixtures.Allowed(config.ProfileStaging))
+}
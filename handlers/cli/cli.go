@@ -0,0 +1,122 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/migrate"
+)
+
+// ErrUsage is returned when the command or its arguments are wrong, the usage is already printed
+var ErrUsage = errors.New("invalid usage")
+
+// Usage of the commands handled by the cli, serve and config are handled by main
+const Usage = `  migrate up [--steps n]        apply the pending migrations
+  migrate down [--steps n]      revert the last migrations (1 by default)
+  migrate status                list the migrations and when they were applied
+  seed [--file <fixtures.yaml>] upsert the users of the fixtures by email (not in production)
+  user create --email <email> [--first-name ..] [--last-name ..] [--role user]
+                                the password is read from USERSVC_USER_PASSWORD or from stdin
+  user list [--json]            list the users
+  user ban <id|email>           ban the user
+  user unban <id|email>         remove the ban of the user
+  user set-role <id|email> <role>
+  token mint --user <id|email> [--ttl 15m]
+                                print a jwt of the user, to call the api as that user`
+
+// CLI is the view layer for the operators, like the http server it only parses the
+// input and calls the controllers. The commands that change the data must run while the
+// service is stopped if the repo doesn't share its data between processes (like the mock)
+type CLI struct {
+	users    *controller.User
+	jwt      *jwt.JWThandler
+	migrator *migrate.Migrator
+	conf     *config.Config
+	in       io.Reader
+	out      io.Writer
+	errOut   io.Writer
+}
+
+func NewCLI(users *controller.User, jwtHandler *jwt.JWThandler, migrator *migrate.Migrator, conf *config.Config, in io.Reader, out, errOut io.Writer) *CLI {
+	return &CLI{
+		users:    users,
+		jwt:      jwtHandler,
+		migrator: migrator,
+		conf:     conf,
+		in:       in,
+		out:      out,
+		errOut:   errOut,
+	}
+}
+
+// Run runs the command, args starts with the name of the command (like migrate or user)
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return c.usage("missing command")
+	}
+	switch args[0] {
+	case "migrate":
+		return c.migrate(ctx, args[1:])
+	case "seed":
+		return c.seed(ctx, args[1:])
+	case "user":
+		return c.user(ctx, args[1:])
+	case "token":
+		return c.token(ctx, args[1:])
+	default:
+		return c.usage("unknown command %q", args[0])
+	}
+}
+
+func (c *CLI) usage(format string, args ...interface{}) error {
+	fmt.Fprintf(c.errOut, format+"\n\ncommands:\n%s\n", append(args, Usage)...)
+	return ErrUsage
+}
+
+// flags returns the flag set of the command, the errors are printed by parse
+func (c *CLI) flags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.errOut)
+	return fs
+}
+
+// parse parses the flags, they can be before or after the positional arguments
+func parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, ErrUsage
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// findUser finds the user by id or by email
+func (c *CLI) findUser(ctx context.Context, ref string) (*model.User, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		u, err := c.users.GetUser(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("no user with id %d", id)
+		}
+		return u, nil
+	}
+	for _, u := range c.users.GetAllUsers(ctx) {
+		if strings.EqualFold(u.Email, ref) {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("no user with email %s", ref)
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"text/tabwriter"
+	"time"
+)
+
+func (c *CLI) migrate(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return c.usage("missing migrate command: up, down or status")
+	}
+	fs := c.flags("migrate " + args[0])
+	steps := fs.Int("steps", 0, "number of migrations to apply or revert")
+	if _, err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := c.migrator.Up(ctx, *steps)
+		for _, m := range applied {
+			fmt.Fprintf(c.out, "applied %d %s\n", m.Version, m.Name)
+		}
+		if err == nil && len(applied) == 0 {
+			fmt.Fprintln(c.out, "no pending migrations")
+		}
+		return err
+	case "down":
+		reverted, err := c.migrator.Down(ctx, *steps)
+		for _, m := range reverted {
+			fmt.Fprintf(c.out, "reverted %d %s\n", m.Version, m.Name)
+		}
+		if err == nil && len(reverted) == 0 {
+			fmt.Fprintln(c.out, "no migrations to revert")
+		}
+		return err
+	case "status":
+		status, err := c.migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			appliedAt := "pending"
+			if s.AppliedAt != nil {
+				appliedAt = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+		}
+		return w.Flush()
+	default:
+		return c.usage("unknown migrate command %q", args[0])
+	}
+}
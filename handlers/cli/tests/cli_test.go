@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/handlers/cli"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/migrate"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+const secret = "a-secret-long-enough-for-the-tests"
+
+type env struct {
+	cli      *cli.CLI
+	conf     *config.Config
+	repo     *mock.RepoMock
+	users    *controller.User
+	jwt      *jwt.JWThandler
+	in       *bytes.Buffer
+	out, err *bytes.Buffer
+}
+
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	l := logrus.New()
+	l.SetOutput(&bytes.Buffer{})
+	repo := mock.NewRepo()
+	migrator, err := migrate.New(repo, repo.Migrations())
+	assert.NilError(t, err)
+	conf := &config.Config{}
+	conf.App.Profile = config.ProfileDev
+	e := &env{
+		conf: conf,
+		repo: repo,
+		jwt:  jwt.NewJWThandler(secret, "users:test"),
+		in:   &bytes.Buffer{},
+		out:  &bytes.Buffer{},
+		err:  &bytes.Buffer{},
+	}
+	e.users = controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+	e.cli = cli.NewCLI(e.users, e.jwt, migrator, conf, e.in, e.out, e.err)
+	return e
+}
+
+// run runs the command and returns its output
+func (e *env) run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	return e.runWithInput(t, "", args...)
+}
+
+// runWithInput runs the command with the input on stdin
+func (e *env) runWithInput(t *testing.T, in string, args ...string) (string, error) {
+	t.Helper()
+	e.in.Reset()
+	e.in.WriteString(in)
+	e.out.Reset()
+	e.err.Reset()
+	err := e.cli.Run(context.Background(), args)
+	return e.out.String(), err
+}
+
+// migrate, cases:
+// [x] up applies the pending migrations, then there are none
+// [x] status lists the applied and the pending ones
+// [x] down reverts the given steps
+func TestMigrate(t *testing.T) {
+	e := newEnv(t)
+	out, err := e.run(t, "migrate", "up", "--steps", "3")
+	assert.NilError(t, err)
+	assert.Equal(t, strings.Count(out, "applied"), 3)
+
+	out, err = e.run(t, "migrate", "status")
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out, "backfill_pfp_status  pending"), out)
+	assert.Equal(t, strings.Count(out, "pending"), 2)
+
+	out, err = e.run(t, "migrate", "up")
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out, "applied 4 backfill_pfp_status"))
+	out, err = e.run(t, "migrate", "up")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "no pending migrations\n")
+
+	out, err = e.run(t, "migrate", "down", "--steps", "2")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "reverted 5 add_user_locale\nreverted 4 backfill_pfp_status\n")
+}
+
+// users, cases:
+// [x] a user is created with the given role and duplicates are refused
+// [x] the password is read from stdin or from the env, never from a flag
+// [x] the users are listed as a table and as json
+// [x] a user is banned, unbanned and its role is changed by id or by email
+// [x] invalid roles and unknown users are errors
+func TestUsers(t *testing.T) {
+	e := newEnv(t)
+	out, err := e.runWithInput(t, "password\n", "user", "create", "--email", "ops@cli.com", "--first-name", "Op", "--role", model.RoleAdmin)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "user ops@cli.com created with id 1\n")
+	_, err = e.users.CheckCredentials(context.Background(), "ops@cli.com", "password")
+	assert.NilError(t, err)
+	_, err = e.runWithInput(t, "password\n", "user", "create", "--email", "ops@cli.com")
+	assert.ErrorContains(t, err, "already exists")
+	_, err = e.runWithInput(t, "password\n", "user", "create", "--email", "root@cli.com", "--role", "root")
+	assert.ErrorContains(t, err, "invalid role")
+	_, err = e.run(t, "user", "create", "--email", "nopassword@cli.com")
+	assert.ErrorIs(t, err, cli.ErrMissingPassword)
+	_, err = e.run(t, "user", "create", "--email", "flag@cli.com", "--password", "password")
+	assert.ErrorIs(t, err, cli.ErrUsage)
+
+	t.Setenv(cli.EnvUserPassword, "env-password")
+	_, err = e.run(t, "user", "create", "--email", "user@cli.com")
+	assert.NilError(t, err)
+	_, err = e.users.CheckCredentials(context.Background(), "user@cli.com", "env-password")
+	assert.NilError(t, err)
+	out, err = e.run(t, "user", "list")
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(out, "ops@cli.com"))
+	assert.Assert(t, strings.Contains(out, "user@cli.com"))
+
+	out, err = e.run(t, "user", "ban", "user@cli.com")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "user 2 (user@cli.com) banned\n")
+	_, err = e.run(t, "user", "set-role", "2", model.RoleAdmin)
+	assert.NilError(t, err)
+	out, err = e.run(t, "user", "list", "--json")
+	assert.NilError(t, err)
+	var list []cli.CliUser
+	assert.NilError(t, json.Unmarshal([]byte(out), &list))
+	assert.Equal(t, len(list), 2)
+	assert.Assert(t, list[1].IsBanned)
+	assert.Equal(t, list[1].Role, model.RoleAdmin)
+	assert.Assert(t, !strings.Contains(out, "password"))
+
+	_, err = e.run(t, "user", "unban", "2")
+	assert.NilError(t, err)
+	u, err := e.repo.Get(context.Background(), 2)
+	assert.NilError(t, err)
+	assert.Assert(t, !u.IsBanned)
+
+	_, err = e.run(t, "user", "set-role", "2", "root")
+	assert.ErrorContains(t, err, "invalid role")
+	_, err = e.run(t, "user", "ban", "missing@cli.com")
+	assert.ErrorContains(t, err, "no user with email")
+	_, err = e.run(t, "user", "ban", "42")
+	assert.ErrorContains(t, err, "no user with id 42")
+}
+
+// seed, cases:
+// [x] the users of a yaml file are created and the banned ones banned
+// [x] the users are upserted by email (json file), the password is changed too
+// [x] the files of the config are used without --file
+// [x] an invalid file makes the seed fail
+// [x] the seed is refused in production
+func TestSeed(t *testing.T) {
+	e := newEnv(t)
+	dir := t.TempDir()
+	yamlFixtures := filepath.Join(dir, "fixtures.yaml")
+	assert.NilError(t, os.WriteFile(yamlFixtures, []byte(`users:
+  - first_name: "Ada"
+    email: "ada@seed.com"
+    password: "password"
+    role: "admin"
+  - email: "banned@seed.com"
+    password: "password"
+    banned: true
+`), 0o600))
+	out, err := e.run(t, "seed", "--file", yamlFixtures)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "2 users created, 0 updated, 0 unchanged\n")
+	users := e.repo.GetAll(context.Background())
+	assert.Equal(t, len(users), 2)
+	for _, u := range users {
+		assert.Equal(t, u.IsBanned, u.Email == "banned@seed.com")
+	}
+
+	jsonFixtures := filepath.Join(dir, "fixtures.json")
+	assert.NilError(t, os.WriteFile(jsonFixtures, []byte(`{"users": [{"email": "ada@seed.com", "password": "changed", "role": "user"}, {"email": "new@seed.com", "password": "password"}]}`), 0o600))
+	out, err = e.run(t, "seed", "--file", jsonFixtures, "--file", yamlFixtures)
+	assert.ErrorContains(t, err, "more than once")
+	out, err = e.run(t, "seed", "--file", jsonFixtures)
+	assert.NilError(t, err)
+	assert.Equal(t, out, "1 users created, 1 updated, 0 unchanged\n")
+	_, err = e.users.CheckCredentials(context.Background(), "ada@seed.com", "changed")
+	assert.NilError(t, err)
+
+	e.conf.Fixtures.Files = []string{jsonFixtures}
+	out, err = e.run(t, "seed")
+	assert.NilError(t, err)
+	assert.Equal(t, out, "0 users created, 0 updated, 2 unchanged\n")
+
+	assert.NilError(t, os.WriteFile(yamlFixtures, []byte("users:\n  - password: \"password\"\n"), 0o600))
+	_, err = e.run(t, "seed", "--file", yamlFixtures)
+	assert.ErrorContains(t, err, "has no email")
+
+	e.conf.App.Profile = config.ProfileProd
+	_, err = e.run(t, "seed", "--file", jsonFixtures)
+	assert.ErrorIs(t, err, fixtures.ErrProduction)
+}
+
+// token, cases:
+// [x] the token is valid for the handler of the http server and has the claims of the user
+// [x] the ttl changes the expiration
+// [x] banned users get no token
+func TestTokenMint(t *testing.T) {
+	e := newEnv(t)
+	_, err := fixtures.Test().Apply(context.Background(), e.users)
+	assert.NilError(t, err)
+
+	out, err := e.run(t, "token", "mint", "--user", "admin@fixtures.test", "--ttl", "2h")
+	assert.NilError(t, err)
+	claims, err := e.jwt.ValidateToken(strings.TrimSpace(out))
+	assert.NilError(t, err)
+	assert.Equal(t, claims.UserId, 1)
+	assert.Equal(t, claims.UserEmail, "admin@fixtures.test")
+	assert.Equal(t, claims.UserRole, model.RoleAdmin)
+	assert.Equal(t, claims.Issuer, "users:test")
+	assert.Assert(t, claims.ExpiresAt > time.Now().Add(time.Hour).Unix())
+
+	_, err = e.run(t, "token", "mint", "--user", "banned@fixtures.test")
+	assert.ErrorContains(t, err, "banned")
+	_, err = e.run(t, "token", "mint")
+	assert.ErrorIs(t, err, cli.ErrUsage)
+}
+
+// usage, cases:
+// [x] unknown commands and flags are usage errors and print the commands
+func TestUsage(t *testing.T) {
+	e := newEnv(t)
+	for _, args := range [][]string{nil, {"drop"}, {"user"}, {"user", "delete"}, {"migrate", "sideways"}, {"migrate", "up", "--force"}} {
+		_, err := e.run(t, args...)
+		assert.ErrorIs(t, err, cli.ErrUsage, "%v", args)
+		assert.Assert(t, e.err.Len() > 0)
+	}
+	e.run(t, "drop")
+	assert.Assert(t, strings.Contains(e.err.String(), "token mint"))
+}
@@ -0,0 +1,42 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+)
+
+func (c *CLI) token(ctx context.Context, args []string) error {
+	if len(args) == 0 || args[0] != "mint" {
+		return c.usage("missing token command: mint")
+	}
+	fs := c.flags("token mint")
+	user := fs.String("user", "", "id or email of the user")
+	ttl := fs.Duration("ttl", 0, "how long the token is valid, the default is the one of the http server")
+	if _, err := parse(fs, args[1:]); err != nil {
+		return err
+	}
+	if *user == "" {
+		return c.usage("token mint needs --user")
+	}
+	if *ttl < 0 {
+		return c.usage("the ttl can't be negative")
+	}
+
+	u, err := c.findUser(ctx, *user)
+	if err != nil {
+		return err
+	}
+	if u.IsBanned {
+		return fmt.Errorf("user %d (%s) is banned", u.ID, u.Email)
+	}
+	handler := c.jwt
+	if *ttl > 0 {
+		handler = c.jwt.WithExpiration(*ttl)
+	}
+	token, err := handler.GenerateToken(u.ID, u.Email, u.Role)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(c.out, token)
+	return nil
+}
@@ -0,0 +1,230 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/model"
+	"golang.org/x/term"
+)
+
+// EnvUserPassword is read by user create for the password of the new user, for the scripts
+const EnvUserPassword = config.EnvPrefix + "USER_PASSWORD"
+
+var ErrMissingPassword = errors.New("missing password")
+
+type (
+	// CliUser is the user printed by user list --json, without the password
+	CliUser struct {
+		ID        int    `json:"id"`
+		FirstName string `json:"first_name"`
+		LastName  string `json:"last_name"`
+		Email     string `json:"email"`
+		Role      string `json:"role"`
+		IsBanned  bool   `json:"is_banned"`
+		Pfp       string `json:"pfp"`
+		PfpStatus string `json:"pfp_status"`
+	}
+)
+
+func (c *CLI) user(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return c.usage("missing user command: create, list, ban, unban or set-role")
+	}
+	switch args[0] {
+	case "create":
+		return c.createUser(ctx, args[1:])
+	case "list":
+		return c.listUsers(ctx, args[1:])
+	case "ban", "unban":
+		fs := c.flags("user " + args[0])
+		positional, err := parse(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return c.usage("user %s needs the id or the email of the user", args[0])
+		}
+		u, err := c.findUser(ctx, positional[0])
+		if err != nil {
+			return err
+		}
+		if err := c.users.SetBanned(ctx, u.ID, args[0] == "ban"); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.out, "user %d (%s) %sned\n", u.ID, u.Email, args[0])
+		return nil
+	case "set-role":
+		fs := c.flags("user set-role")
+		positional, err := parse(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 2 {
+			return c.usage("user set-role needs the id or the email of the user and the role")
+		}
+		u, err := c.findUser(ctx, positional[0])
+		if err != nil {
+			return err
+		}
+		if err := c.users.SetRole(ctx, u.ID, positional[1]); err != nil {
+			if err == controller.ErrInvalidRole {
+				return fmt.Errorf("invalid role %q, it must be %s, %s or %s", positional[1], model.RoleAdmin, model.RoleUser, model.RoleUnupdatable)
+			}
+			return err
+		}
+		fmt.Fprintf(c.out, "user %d (%s) is now %s\n", u.ID, u.Email, positional[1])
+		return nil
+	default:
+		return c.usage("unknown user command %q", args[0])
+	}
+}
+
+func (c *CLI) createUser(ctx context.Context, args []string) error {
+	fs := c.flags("user create")
+	firstName := fs.String("first-name", "", "first name")
+	lastName := fs.String("last-name", "", "last name")
+	email := fs.String("email", "", "email, it must be unique")
+	role := fs.String("role", model.RoleUser, "role: admin, user or unupdatable")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if *email == "" {
+		return c.usage("user create needs --email")
+	}
+	if !model.IsRole(*role) {
+		return fmt.Errorf("invalid role %q, it must be %s, %s or %s", *role, model.RoleAdmin, model.RoleUser, model.RoleUnupdatable)
+	}
+	password, err := c.readPassword()
+	if err != nil {
+		return err
+	}
+
+	id, err := c.users.CreateUser(ctx, *firstName, *lastName, *email, password, *role)
+	if err == controller.ErrUserAlreadyExists {
+		return fmt.Errorf("user %s already exists with id %d", *email, id)
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(c.out, "user %s created with id %d\n", *email, id)
+	return nil
+}
+
+// readPassword returns the password of EnvUserPassword, otherwise it's asked without echo if stdin is
+// a terminal or read from the first line of stdin. The password is never a flag, the arguments end
+// up in the shell history and in the list of the processes
+func (c *CLI) readPassword() (string, error) {
+	if password, ok := os.LookupEnv(EnvUserPassword); ok && password != "" {
+		return password, nil
+	}
+
+	var password string
+	if f, ok := c.in.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		fmt.Fprint(c.errOut, "password: ")
+		raw, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(c.errOut)
+		if err != nil {
+			return "", fmt.Errorf("unable to read the password: %w", err)
+		}
+		password = string(raw)
+	} else {
+		line, err := bufio.NewReader(c.in).ReadString('\n')
+		if err != nil && line == "" {
+			return "", fmt.Errorf("%w, set %s or write it on stdin", ErrMissingPassword, EnvUserPassword)
+		}
+		password = strings.TrimRight(line, "\r\n")
+	}
+	if password == "" {
+		return "", fmt.Errorf("%w, set %s or write it on stdin", ErrMissingPassword, EnvUserPassword)
+	}
+	return password, nil
+}
+
+func (c *CLI) listUsers(ctx context.Context, args []string) error {
+	fs := c.flags("user list")
+	asJson := fs.Bool("json", false, "print the users as json")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+
+	users := c.users.GetAllUsers(ctx)
+	sortUsers(users)
+	if *asJson {
+		list := make([]CliUser, len(users))
+		for i, u := range users {
+			list[i] = CliUser{
+				ID:        u.ID,
+				FirstName: u.FirstName,
+				LastName:  u.LastName,
+				Email:     u.Email,
+				Role:      u.Role,
+				IsBanned:  u.IsBanned,
+				Pfp:       u.Pfp,
+				PfpStatus: u.PfpStatus,
+			}
+		}
+		enc := json.NewEncoder(c.out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(list)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tNAME\tROLE\tBANNED")
+	for _, u := range users {
+		fmt.Fprintf(w, "%d\t%s\t%s %s\t%s\t%t\n", u.ID, u.Email, u.FirstName, u.LastName, u.Role, u.IsBanned)
+	}
+	return w.Flush()
+}
+
+func (c *CLI) seed(ctx context.Context, args []string) error {
+	fs := c.flags("seed")
+	var files fileList
+	fs.Var(&files, "file", "yaml or json file with the users, it can be repeated (default the fixtures.files of the config)")
+	if _, err := parse(fs, args); err != nil {
+		return err
+	}
+	if err := fixtures.Allowed(c.conf.App.Profile); err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		files = c.conf.Fixtures.Files
+	}
+	if len(files) == 0 {
+		return c.usage("seed needs --file, no fixtures.files in the config")
+	}
+
+	set, err := fixtures.Load(files...)
+	if err != nil {
+		return err
+	}
+	result, err := set.Apply(ctx, c.users)
+	fmt.Fprintln(c.out, result)
+	return err
+}
+
+// fileList is a flag that can be repeated
+type fileList []string
+
+func (f *fileList) String() string {
+	return strings.Join(*f, ",")
+}
+
+func (f *fileList) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+func sortUsers(users []*model.User) {
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+}
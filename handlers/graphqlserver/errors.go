@@ -0,0 +1,70 @@
+package graphqlserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/pkg/dataloader"
+	"github.com/vano2903/service-template/pkg/logger"
+)
+
+// Error is an error sent to the client with its code in the extensions, the codes are
+// the error types of the http api (when there is one) so the clients can share the handling
+type Error struct {
+	Code    string
+	Message string
+}
+
+func NewError(code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Extensions is read by graphql-go and added to the error of the response
+func (e *Error) Extensions() map[string]interface{} {
+	return map[string]interface{}{"code": e.Code}
+}
+
+// controllerCodes maps the sentinel errors of the controller
+var controllerCodes = []struct {
+	err  error
+	code string
+}{
+	{controller.ErrUserAlreadyExists, "user_already_exists"},
+	{controller.ErrUserNotFound, "user_not_found"},
+	{controller.ErrWrongPassword, "wrong_password"},
+	{controller.ErrUnupdatableUser, "unupdatable_user"},
+	{controller.ErrInvalidRole, "invalid_role"},
+	{controller.ErrInvalidLocale, "invalid_locale"},
+	{controller.ErrNotAdmin, "not_admin"},
+}
+
+// errorOf turns the errors of the controller into the errors of the response. The unexpected
+// errors are logged with the request id and their message is replaced so the internals are
+// not sent to the clients
+func errorOf(ctx context.Context, l *logrus.Logger, err error) error {
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	if errors.Is(err, dataloader.ErrNotFound) {
+		return NewError("user_not_found", err.Error())
+	}
+	for _, m := range controllerCodes {
+		if errors.Is(err, m.err) {
+			return NewError(m.code, err.Error())
+		}
+	}
+
+	logger.FromContext(ctx, l).WithError(err).Error("unexpected error resolving the graphql request")
+	return unexpectedError(ctx)
+}
+
+func unexpectedError(ctx context.Context) *Error {
+	return NewError("unexpected_error", "unexpected error, report the request id "+requestFromContext(ctx).RequestID+" if it persists")
+}
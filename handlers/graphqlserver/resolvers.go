@@ -0,0 +1,433 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/dataloader"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+)
+
+const (
+	_DEFAULT_PAGE_SIZE = 20
+	_MAX_PAGE_SIZE     = 100
+	cursorPrefix       = "user:"
+)
+
+// resolver is the root of the schema, the methods are the fields of Query and Mutation
+type resolver struct {
+	controller *controller.User
+	j          *jwt.JWThandler
+	l          *logrus.Logger
+}
+
+// loadUser reads the user through the dataloader of the request
+func loadUser(ctx context.Context, id int) (*model.User, error) {
+	return requestFromContext(ctx).users.Load(ctx, id)
+}
+
+// viewer returns the user of the token, nil without a token or if the user was deleted after the login
+func viewer(ctx context.Context) *model.User {
+	claims := requestFromContext(ctx).Claims
+	if claims == nil {
+		return nil
+	}
+	u, err := loadUser(ctx, claims.UserId)
+	if err != nil {
+		return nil
+	}
+	return u
+}
+
+func isNotFound(err error) bool {
+	return errors.Is(err, dataloader.ErrNotFound) || errors.Is(err, controller.ErrUserNotFound)
+}
+
+func parseID(id graphql.ID) (int, error) {
+	n, err := strconv.Atoi(string(id))
+	if err != nil || n <= 0 {
+		return 0, NewError("invalid_id", fmt.Sprintf("%q is not the id of a user", id))
+	}
+	return n, nil
+}
+
+func encodeCursor(id int) string {
+	return base64.URLEncoding.EncodeToString([]byte(cursorPrefix + strconv.Itoa(id)))
+}
+
+func decodeCursor(cursor string) (int, error) {
+	raw, err := base64.URLEncoding.DecodeString(cursor)
+	if err == nil && strings.HasPrefix(string(raw), cursorPrefix) {
+		if id, err := strconv.Atoi(strings.TrimPrefix(string(raw), cursorPrefix)); err == nil {
+			return id, nil
+		}
+	}
+	return 0, NewError("invalid_cursor", "the cursor is not valid, use the endCursor of a previous page")
+}
+
+func (r *resolver) User(ctx context.Context, args struct{ ID graphql.ID }) (*userResolver, error) {
+	id, err := parseID(args.ID)
+	if err != nil {
+		return nil, err
+	}
+	u, err := loadUser(ctx, id)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, nil
+		}
+		return nil, errorOf(ctx, r.l, err)
+	}
+	return &userResolver{u: u}, nil
+}
+
+func (r *resolver) Users(ctx context.Context, args struct {
+	First int32
+	After *string
+}) (*userConnectionResolver, error) {
+	if args.First < 1 || args.First > _MAX_PAGE_SIZE {
+		return nil, NewError("invalid_pagination", fmt.Sprintf("first must be between 1 and %d", _MAX_PAGE_SIZE))
+	}
+	after := 0
+	if args.After != nil {
+		id, err := decodeCursor(*args.After)
+		if err != nil {
+			return nil, err
+		}
+		after = id
+	}
+
+	users := r.controller.GetAllUsers(ctx)
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	start := sort.Search(len(users), func(i int) bool { return users[i].ID > after })
+	end := start + int(args.First)
+	if end > len(users) {
+		end = len(users)
+	}
+
+	loader := requestFromContext(ctx).users
+	conn := &userConnectionResolver{
+		total:   len(users),
+		hasNext: end < len(users),
+		edges:   make([]*userEdgeResolver, 0, end-start),
+	}
+	for _, u := range users[start:end] {
+		//the users of the page are not read again by the other fields
+		loader.Prime(u.ID, u)
+		conn.edges = append(conn.edges, &userEdgeResolver{node: &userResolver{u: u}})
+	}
+	return conn, nil
+}
+
+func (r *resolver) Me(ctx context.Context) *userResolver {
+	u := viewer(ctx)
+	if u == nil {
+		return nil
+	}
+	return &userResolver{u: u}
+}
+
+// authLimit applies the rate limit of the authentication to register and login
+func authLimit(ctx context.Context) error {
+	if limit := requestFromContext(ctx).AuthLimit; limit != nil {
+		return limit(ctx)
+	}
+	return nil
+}
+
+func (r *resolver) Register(ctx context.Context, args struct {
+	Input struct {
+		FirstName string
+		LastName  string
+		Email     string
+		Password  string
+	}
+}) (graphql.ID, error) {
+	if err := authLimit(ctx); err != nil {
+		return "", err
+	}
+	in := args.Input
+	id, err := r.controller.CreateUser(ctx, in.FirstName, in.LastName, in.Email, in.Password, model.RoleUser)
+	if err != nil {
+		return "", errorOf(ctx, r.l, fmt.Errorf("unable to create user %s: %w", in.Email, err))
+	}
+	return graphql.ID(strconv.Itoa(id)), nil
+}
+
+func (r *resolver) Login(ctx context.Context, args struct {
+	Email    string
+	Password string
+}) (*authPayloadResolver, error) {
+	if err := authLimit(ctx); err != nil {
+		return nil, err
+	}
+	id, err := r.controller.CheckCredentials(ctx, args.Email, args.Password)
+	if err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+	u, err := loadUser(ctx, id)
+	if err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+	token, err := r.j.GenerateToken(u.ID, u.Email, u.Role)
+	if err != nil {
+		return nil, errorOf(ctx, r.l, fmt.Errorf("unexpected error trying to generate the login token: %w", err))
+	}
+	logger.FromContext(ctx, r.l).Debugf("token generated for user %d", u.ID)
+	return &authPayloadResolver{token: token, user: &userResolver{u: u, self: true}}, nil
+}
+
+// requester returns the user of the token, the mutations below need one
+func (r *resolver) requester(ctx context.Context) (*model.User, error) {
+	if requestFromContext(ctx).Claims == nil {
+		return nil, NewError("missing_authorization_header", "the mutation needs the token of the login in the Authorization header")
+	}
+	u := viewer(ctx)
+	if u == nil {
+		return nil, NewError("user_not_found", "the user of the token doesn't exist anymore")
+	}
+	return u, nil
+}
+
+// target returns the id the requester is acting on, the users can act only on themselves
+func target(requester *model.User, id *graphql.ID) (int, error) {
+	if id == nil {
+		return requester.ID, nil
+	}
+	n, err := parseID(*id)
+	if err != nil {
+		return 0, err
+	}
+	if n != requester.ID && requester.Role != model.RoleAdmin {
+		return 0, NewError("unauthorized_update", "only admins can change other users")
+	}
+	return n, nil
+}
+
+// reload returns the user after a change, read again from the repo
+func (r *resolver) reload(ctx context.Context, id int) (*userResolver, error) {
+	requestFromContext(ctx).users.Clear(id)
+	u, err := loadUser(ctx, id)
+	if err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+	return &userResolver{u: u}, nil
+}
+
+func (r *resolver) UpdateUser(ctx context.Context, args struct {
+	Input struct {
+		ID        *graphql.ID
+		FirstName *string
+		LastName  *string
+		Email     *string
+		Password  *string
+		Locale    *string
+	}
+}) (*userResolver, error) {
+	requester, err := r.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	in := args.Input
+	id, err := target(requester, in.ID)
+	if err != nil {
+		return nil, err
+	}
+	//a copy, the one of the loader is shared with the other fields
+	toUpdate, err := r.controller.GetUser(ctx, id)
+	if err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+
+	if in.FirstName != nil {
+		toUpdate.FirstName = *in.FirstName
+	}
+	if in.LastName != nil {
+		toUpdate.LastName = *in.LastName
+	}
+	if in.Email != nil {
+		toUpdate.Email = *in.Email
+	}
+	if in.Password != nil {
+		toUpdate.Password = *in.Password
+	}
+	if in.Locale != nil {
+		toUpdate.Locale = *in.Locale
+	}
+
+	if err := r.controller.UpdateUser(ctx, requester.ID, toUpdate); err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+	return r.reload(ctx, id)
+}
+
+func (r *resolver) DeleteUser(ctx context.Context, args struct{ ID *graphql.ID }) (bool, error) {
+	requester, err := r.requester(ctx)
+	if err != nil {
+		return false, err
+	}
+	id, err := target(requester, args.ID)
+	if err != nil {
+		return false, err
+	}
+	if err := r.controller.DeleteUser(ctx, requester.ID, id); err != nil {
+		return false, errorOf(ctx, r.l, err)
+	}
+	requestFromContext(ctx).users.Clear(id)
+	return true, nil
+}
+
+func (r *resolver) RegeneratePfp(ctx context.Context, args struct{ ID *graphql.ID }) (*userResolver, error) {
+	requester, err := r.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := target(requester, args.ID)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.controller.RegeneratePfp(ctx, id); err != nil {
+		return nil, errorOf(ctx, r.l, err)
+	}
+	//with the pfp queue the status is pending and the pfp is still the old one
+	return r.reload(ctx, id)
+}
+
+// userResolver resolves the fields of a user, the restricted ones depend on the caller
+type userResolver struct {
+	u *model.User
+	//the caller is the user itself, even without a token (the user of the login)
+	self bool
+}
+
+// authenticated returns true if the caller has a token
+func (r *userResolver) authenticated(ctx context.Context) bool {
+	return r.self || requestFromContext(ctx).Claims != nil
+}
+
+// owner returns true if the caller is the user itself or an admin
+func (r *userResolver) owner(ctx context.Context) bool {
+	if r.self {
+		return true
+	}
+	v := viewer(ctx)
+	return v != nil && (v.ID == r.u.ID || v.Role == model.RoleAdmin)
+}
+
+func (r *userResolver) ID() graphql.ID {
+	return graphql.ID(strconv.Itoa(r.u.ID))
+}
+
+func (r *userResolver) FirstName() string {
+	return r.u.FirstName
+}
+
+func (r *userResolver) LastName() string {
+	return r.u.LastName
+}
+
+func (r *userResolver) Pfp() string {
+	return r.u.Pfp
+}
+
+func (r *userResolver) PfpStatus() string {
+	return r.u.PfpStatus
+}
+
+func (r *userResolver) Email(ctx context.Context) *string {
+	if !r.authenticated(ctx) {
+		return nil
+	}
+	return &r.u.Email
+}
+
+func (r *userResolver) Role(ctx context.Context) *string {
+	if !r.authenticated(ctx) {
+		return nil
+	}
+	return &r.u.Role
+}
+
+func (r *userResolver) IsBanned(ctx context.Context) *bool {
+	if !r.owner(ctx) {
+		return nil
+	}
+	return &r.u.IsBanned
+}
+
+func (r *userResolver) Locale(ctx context.Context) *string {
+	if !r.owner(ctx) {
+		return nil
+	}
+	return &r.u.Locale
+}
+
+type userConnectionResolver struct {
+	edges   []*userEdgeResolver
+	total   int
+	hasNext bool
+}
+
+func (r *userConnectionResolver) Edges() []*userEdgeResolver {
+	return r.edges
+}
+
+func (r *userConnectionResolver) PageInfo() *pageInfoResolver {
+	p := &pageInfoResolver{hasNext: r.hasNext}
+	if len(r.edges) > 0 {
+		cursor := r.edges[len(r.edges)-1].Cursor()
+		p.endCursor = &cursor
+	}
+	return p
+}
+
+func (r *userConnectionResolver) TotalCount() int32 {
+	return int32(r.total)
+}
+
+type userEdgeResolver struct {
+	node *userResolver
+}
+
+func (r *userEdgeResolver) Cursor() string {
+	return encodeCursor(r.node.u.ID)
+}
+
+func (r *userEdgeResolver) Node() *userResolver {
+	return r.node
+}
+
+type pageInfoResolver struct {
+	hasNext   bool
+	endCursor *string
+}
+
+func (r *pageInfoResolver) HasNextPage() bool {
+	return r.hasNext
+}
+
+func (r *pageInfoResolver) EndCursor() *string {
+	return r.endCursor
+}
+
+type authPayloadResolver struct {
+	token string
+	user  *userResolver
+}
+
+func (r *authPayloadResolver) Token() string {
+	return r.token
+}
+
+func (r *authPayloadResolver) User() *userResolver {
+	return r.user
+}
@@ -0,0 +1,128 @@
+// Package graphqlserver exposes the user controller as a graphql schema, the transport is left to
+// the callers (the http server mounts it on /api/v1/graphql). The authorization rules are the ones
+// of the http api: the users can change only themselves and the admins anyone, and the restricted
+// fields of a user are null for the callers that can't see them.
+// The reads of the users go through a dataloader per request, so the users requested by the
+// fields resolved in parallel are read from the repo with a single call.
+package graphqlserver
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/graph-gophers/graphql-go"
+	gqlerrors "github.com/graph-gophers/graphql-go/errors"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/dataloader"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+type (
+	Options struct {
+		//allows the __schema and __type queries, it should be off in production
+		Introspection bool
+	}
+
+	// Request is the body of a graphql request, the fields without a json tag are set by the transport
+	Request struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+
+		//the claims of the token, nil if the caller has no token
+		Claims    *jwt.JWTClaims `json:"-"`
+		RequestID string         `json:"-"`
+		//AuthLimit is called before register and login, so they share the rate limit of the
+		//authentication endpoints. A nil AuthLimit means no limit
+		AuthLimit func(ctx context.Context) error `json:"-"`
+	}
+
+	// request is the state of a request shared by its resolvers
+	request struct {
+		*Request
+		users *dataloader.Loader[int, *model.User]
+	}
+
+	requestKey struct{}
+)
+
+func requestFromContext(ctx context.Context) *request {
+	r, _ := ctx.Value(requestKey{}).(*request)
+	if r == nil {
+		return &request{Request: &Request{}}
+	}
+	return r
+}
+
+// Schema executes the graphql requests, it's safe for concurrent use
+type Schema struct {
+	schema     *graphql.Schema
+	controller *controller.User
+}
+
+func NewSchema(users *controller.User, jwtHandler *jwt.JWThandler, l *logrus.Logger, opts Options) *Schema {
+	s := &Schema{controller: users}
+	//no max depth: the types have no cycles so the depth of a query is bounded by the schema
+	schemaOpts := []graphql.SchemaOpt{
+		graphql.UseStringDescriptions(),
+		graphql.Logger(panicLogger{l: l}),
+		graphql.PanicHandler(panicHandler{}),
+	}
+	if !opts.Introspection {
+		schemaOpts = append(schemaOpts, graphql.DisableIntrospection())
+	}
+	root := &resolver{
+		controller: users,
+		j:          jwtHandler,
+		l:          l,
+	}
+	s.schema = graphql.MustParseSchema(schemaSDL, root, schemaOpts...)
+	return s
+}
+
+// Exec runs the request, the errors are in the response
+func (s *Schema) Exec(ctx context.Context, req *Request) *graphql.Response {
+	r := &request{Request: req}
+	r.users = dataloader.New(func(ctx context.Context, ids []int) (map[int]*model.User, error) {
+		found, err := s.controller.GetUsers(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		users := make(map[int]*model.User, len(found))
+		for _, u := range found {
+			users[u.ID] = u
+		}
+		return users, nil
+	}, dataloader.Options{})
+	ctx = context.WithValue(ctx, requestKey{}, r)
+	return s.schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+}
+
+// panicLogger logs the panics of the resolvers, recovered by graphql-go
+type panicLogger struct {
+	l *logrus.Logger
+}
+
+func (p panicLogger) LogPanic(ctx context.Context, value interface{}) {
+	logger.FromContext(ctx, p.l).WithField("stack", string(debug.Stack())).Errorf("panic resolving the graphql request: %v", value)
+}
+
+// panicHandler hides the value of the panic from the clients
+type panicHandler struct{}
+
+func (panicHandler) MakePanicError(ctx context.Context, value interface{}) *gqlerrors.QueryError {
+	e := unexpectedError(ctx)
+	return &gqlerrors.QueryError{
+		Message:    e.Message,
+		Extensions: e.Extensions(),
+		Err:        fmt.Errorf("panic: %v", value),
+	}
+}
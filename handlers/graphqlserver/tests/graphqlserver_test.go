@@ -0,0 +1,330 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/handlers/graphqlserver"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+const jwtSecret = "a-secret-long-enough-for-the-tests"
+
+// countingRepo records the reads of the users
+type countingRepo struct {
+	repo.UserRepoer
+	mu    sync.Mutex
+	gets  int
+	batch [][]int
+}
+
+func (r *countingRepo) Get(ctx context.Context, id int) (*model.User, error) {
+	r.mu.Lock()
+	r.gets++
+	r.mu.Unlock()
+	return r.UserRepoer.Get(ctx, id)
+}
+
+func (r *countingRepo) GetMany(ctx context.Context, ids []int) ([]*model.User, error) {
+	r.mu.Lock()
+	r.batch = append(r.batch, ids)
+	r.mu.Unlock()
+	return r.UserRepoer.GetMany(ctx, ids)
+}
+
+func (r *countingRepo) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.gets = 0
+	r.batch = nil
+}
+
+type env struct {
+	schema *graphqlserver.Schema
+	repo   *countingRepo
+	j      *jwt.JWThandler
+	//ids of the users of the fixtures by email
+	ids map[string]int
+}
+
+// newEnv serves the users of the test fixtures
+func newEnv(t *testing.T) *env {
+	t.Helper()
+	l := logrus.New()
+	l.SetLevel(logrus.PanicLevel)
+	r := &countingRepo{UserRepoer: mock.NewRepo()}
+	users := controller.NewUserController(r, logo.NewServiceLogo("", ""), l)
+	_, err := fixtures.Test().Apply(context.Background(), users)
+	assert.NilError(t, err)
+
+	e := &env{
+		schema: graphqlserver.NewSchema(users, jwt.NewJWThandler(jwtSecret, "users:test"), l, graphqlserver.Options{}),
+		repo:   r,
+		j:      jwt.NewJWThandler(jwtSecret, "users:test"),
+		ids:    map[string]int{},
+	}
+	for _, u := range users.GetAllUsers(context.Background()) {
+		e.ids[u.Email] = u.ID
+	}
+	r.reset()
+	return e
+}
+
+// claims returns the claims of the token of the user, nil for an empty email
+func (e *env) claims(t *testing.T, email string) *jwt.JWTClaims {
+	t.Helper()
+	if email == "" {
+		return nil
+	}
+	token, err := e.j.GenerateToken(e.ids[email], email, "")
+	assert.NilError(t, err)
+	claims, err := e.j.ValidateToken(token)
+	assert.NilError(t, err)
+	return claims
+}
+
+type gqlError struct {
+	Message    string            `json:"message"`
+	Extensions map[string]string `json:"extensions"`
+}
+
+// exec runs the query as the user with the email, anonymously if empty, and decodes the data in data
+func (e *env) exec(t *testing.T, email, query string, vars map[string]interface{}, data interface{}) []gqlError {
+	t.Helper()
+	resp := e.schema.Exec(context.Background(), &graphqlserver.Request{
+		Query:     query,
+		Variables: vars,
+		Claims:    e.claims(t, email),
+		RequestID: "test-request",
+	})
+	var errs []gqlError
+	raw, err := json.Marshal(resp.Errors)
+	assert.NilError(t, err)
+	assert.NilError(t, json.Unmarshal(raw, &errs))
+	if data != nil && len(resp.Data) > 0 {
+		assert.NilError(t, json.Unmarshal(resp.Data, data))
+	}
+	return errs
+}
+
+func codes(errs []gqlError) []string {
+	c := make([]string, 0, len(errs))
+	for _, e := range errs {
+		c = append(c, e.Extensions["code"])
+	}
+	return c
+}
+
+type user struct {
+	ID        string  `json:"id"`
+	FirstName string  `json:"firstName"`
+	Email     *string `json:"email"`
+	Role      *string `json:"role"`
+	IsBanned  *bool   `json:"isBanned"`
+	Locale    *string `json:"locale"`
+}
+
+const userFields = "id firstName email role isBanned locale"
+
+// field visibility, cases:
+// [x] the callers without a token don't see email, role, isBanned and locale
+// [x] the callers with a token see email and role of anyone
+// [x] isBanned and locale are visible to the user itself and to the admins
+// [x] me is null without a token
+// [x] an unknown user is null, an invalid id is an error
+func TestVisibility(t *testing.T) {
+	e := newEnv(t)
+	query := fmt.Sprintf(`query($id: ID!) { user(id: $id) { %s } me { id } }`, userFields)
+	banned := map[string]interface{}{"id": strconv.Itoa(e.ids["banned@fixtures.test"])}
+
+	var data struct {
+		User *user `json:"user"`
+		Me   *user `json:"me"`
+	}
+	assert.Assert(t, len(e.exec(t, "", query, banned, &data)) == 0)
+	assert.Equal(t, data.User.FirstName, "Bob")
+	assert.Assert(t, data.User.Email == nil && data.User.Role == nil)
+	assert.Assert(t, data.User.IsBanned == nil && data.User.Locale == nil)
+	assert.Assert(t, data.Me == nil)
+
+	assert.Assert(t, len(e.exec(t, "user@fixtures.test", query, banned, &data)) == 0)
+	assert.Equal(t, *data.User.Email, "banned@fixtures.test")
+	assert.Equal(t, *data.User.Role, model.RoleUser)
+	assert.Assert(t, data.User.IsBanned == nil && data.User.Locale == nil)
+	assert.Equal(t, data.Me.ID, strconv.Itoa(e.ids["user@fixtures.test"]))
+
+	for _, email := range []string{"banned@fixtures.test", "admin@fixtures.test"} {
+		assert.Assert(t, len(e.exec(t, email, query, banned, &data)) == 0)
+		assert.Assert(t, data.User.IsBanned != nil && *data.User.IsBanned, email)
+		assert.Assert(t, data.User.Locale != nil, email)
+	}
+
+	data.User = nil
+	errs := e.exec(t, "", `{ user(id: "999") { id } }`, nil, &data)
+	assert.Assert(t, len(errs) == 0)
+	assert.Assert(t, data.User == nil)
+	errs = e.exec(t, "", `{ user(id: "abc") { id } }`, nil, nil)
+	assert.DeepEqual(t, codes(errs), []string{"invalid_id"})
+}
+
+// pagination, cases:
+// [x] the users are ordered by id and the pages follow the end cursor
+// [x] the last page has no next page
+// [x] first out of range and invalid cursors are errors
+func TestPagination(t *testing.T) {
+	e := newEnv(t)
+	query := `query($first: Int, $after: String) {
+		users(first: $first, after: $after) { totalCount edges { cursor node { id } } pageInfo { hasNextPage endCursor } }
+	}`
+	type page struct {
+		Users struct {
+			TotalCount int `json:"totalCount"`
+			Edges      []struct {
+				Node user `json:"node"`
+			} `json:"edges"`
+			PageInfo struct {
+				HasNextPage bool    `json:"hasNextPage"`
+				EndCursor   *string `json:"endCursor"`
+			} `json:"pageInfo"`
+		} `json:"users"`
+	}
+
+	var ids []string
+	vars := map[string]interface{}{"first": 3}
+	for i := 0; ; i++ {
+		p := page{}
+		assert.Assert(t, len(e.exec(t, "", query, vars, &p)) == 0)
+		assert.Equal(t, p.Users.TotalCount, len(e.ids))
+		for _, edge := range p.Users.Edges {
+			ids = append(ids, edge.Node.ID)
+		}
+		if !p.Users.PageInfo.HasNextPage {
+			assert.Equal(t, i, 1)
+			break
+		}
+		vars["after"] = *p.Users.PageInfo.EndCursor
+	}
+	assert.Equal(t, len(ids), len(e.ids))
+	for i := 1; i < len(ids); i++ {
+		prev, _ := strconv.Atoi(ids[i-1])
+		next, _ := strconv.Atoi(ids[i])
+		assert.Assert(t, prev < next, ids)
+	}
+
+	errs := e.exec(t, "", query, map[string]interface{}{"first": 101}, nil)
+	assert.DeepEqual(t, codes(errs), []string{"invalid_pagination"})
+	errs = e.exec(t, "", query, map[string]interface{}{"first": 1, "after": "not-a-cursor"}, nil)
+	assert.DeepEqual(t, codes(errs), []string{"invalid_cursor"})
+}
+
+// batching, cases:
+// [x] the users requested by different fields are read with a single call, the duplicated once
+// [x] the users of a page are not read again
+func TestBatching(t *testing.T) {
+	e := newEnv(t)
+	admin, usr := e.ids["admin@fixtures.test"], e.ids["user@fixtures.test"]
+	query := fmt.Sprintf(`{ a: user(id: "%d") { id } b: user(id: "%d") { id } c: user(id: "%d") { id } me { id } }`, admin, usr, admin)
+
+	assert.Assert(t, len(e.exec(t, "user@fixtures.test", query, nil, nil)) == 0)
+	assert.Equal(t, e.repo.gets, 0)
+	assert.Equal(t, len(e.repo.batch), 1)
+	assert.Equal(t, len(e.repo.batch[0]), 2)
+
+	e.repo.reset()
+	assert.Assert(t, len(e.exec(t, "", `{ users { edges { node { id firstName } } } }`, nil, nil)) == 0)
+	assert.Equal(t, e.repo.gets+len(e.repo.batch), 0)
+}
+
+// mutations, cases:
+// [x] register and login need no token, the user of the login sees its own fields
+// [x] the mutations of a user need a token
+// [x] the users change only themselves, the admins anyone
+// [x] the user returned by a mutation is the changed one
+// [x] the errors of the controller have the error type of the http api
+// [x] register and login are limited by AuthLimit
+func TestMutations(t *testing.T) {
+	e := newEnv(t)
+
+	var registered struct {
+		Register string `json:"register"`
+	}
+	register := `mutation($email: String!) { register(input: {firstName: "New", lastName: "User", email: $email, password: "new-password"}) }`
+	assert.Assert(t, len(e.exec(t, "", register, map[string]interface{}{"email": "new@test.com"}, &registered)) == 0)
+	assert.Assert(t, registered.Register != "")
+	errs := e.exec(t, "", register, map[string]interface{}{"email": "new@test.com"}, nil)
+	assert.DeepEqual(t, codes(errs), []string{"user_already_exists"})
+
+	var logged struct {
+		Login struct {
+			Token string `json:"token"`
+			User  user   `json:"user"`
+		} `json:"login"`
+	}
+	login := fmt.Sprintf(`mutation($password: String!) { login(email: "new@test.com", password: $password) { token user { %s } } }`, userFields)
+	assert.Assert(t, len(e.exec(t, "", login, map[string]interface{}{"password": "new-password"}, &logged)) == 0)
+	claims, err := e.j.ValidateToken(logged.Login.Token)
+	assert.NilError(t, err)
+	assert.Equal(t, strconv.Itoa(claims.UserId), registered.Register)
+	assert.Equal(t, *logged.Login.User.Email, "new@test.com")
+	assert.Assert(t, logged.Login.User.IsBanned != nil)
+	errs = e.exec(t, "", login, map[string]interface{}{"password": "wrong"}, nil)
+	assert.DeepEqual(t, codes(errs), []string{"wrong_password"})
+
+	errs = e.exec(t, "", `mutation { deleteUser }`, nil, nil)
+	assert.DeepEqual(t, codes(errs), []string{"missing_authorization_header"})
+
+	var updated struct {
+		UpdateUser user `json:"updateUser"`
+	}
+	update := `mutation($input: UpdateUserInput!) { updateUser(input: $input) { id firstName locale } }`
+	input := map[string]interface{}{"input": map[string]interface{}{"firstName": "Renamed"}}
+	assert.Assert(t, len(e.exec(t, "user@fixtures.test", update, input, &updated)) == 0)
+	assert.Equal(t, updated.UpdateUser.FirstName, "Renamed")
+	assert.Equal(t, updated.UpdateUser.ID, strconv.Itoa(e.ids["user@fixtures.test"]))
+
+	other := map[string]interface{}{"input": map[string]interface{}{"id": strconv.Itoa(e.ids["admin@fixtures.test"]), "firstName": "Hacked"}}
+	errs = e.exec(t, "user@fixtures.test", update, other, nil)
+	assert.DeepEqual(t, codes(errs), []string{"unauthorized_update"})
+	other = map[string]interface{}{"input": map[string]interface{}{"id": strconv.Itoa(e.ids["user@fixtures.test"]), "locale": "klingon-xx-yy"}}
+	errs = e.exec(t, "admin@fixtures.test", update, other, nil)
+	assert.DeepEqual(t, codes(errs), []string{"invalid_locale"})
+
+	var regenerated struct {
+		RegeneratePfp user `json:"regeneratePfp"`
+	}
+	assert.Assert(t, len(e.exec(t, "user@fixtures.test", `mutation { regeneratePfp { id } }`, nil, &regenerated)) == 0)
+	assert.Equal(t, regenerated.RegeneratePfp.ID, strconv.Itoa(e.ids["user@fixtures.test"]))
+
+	var deleted struct {
+		DeleteUser bool `json:"deleteUser"`
+	}
+	deleteAs := fmt.Sprintf(`mutation { deleteUser(id: "%s") }`, registered.Register)
+	assert.Assert(t, len(e.exec(t, "admin@fixtures.test", deleteAs, nil, &deleted)) == 0)
+	assert.Assert(t, deleted.DeleteUser)
+	var found struct {
+		User *user `json:"user"`
+	}
+	assert.Assert(t, len(e.exec(t, "", fmt.Sprintf(`{ user(id: "%s") { id } }`, registered.Register), nil, &found)) == 0)
+	assert.Assert(t, found.User == nil)
+
+	resp := e.schema.Exec(context.Background(), &graphqlserver.Request{
+		Query: `mutation { login(email: "user@fixtures.test", password: "user-password") { token } }`,
+		AuthLimit: func(context.Context) error {
+			return graphqlserver.NewError("rate_limited", "rate limit exceeded")
+		},
+	})
+	assert.Equal(t, len(resp.Errors), 1)
+	assert.Equal(t, resp.Errors[0].Extensions["code"], "rate_limited")
+}
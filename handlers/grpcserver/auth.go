@@ -0,0 +1,96 @@
+package grpcserver
+
+import (
+	"context"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/handlers/grpcserver/userspb"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadataAuthorization has the token as "Bearer <token>", like the Authorization header
+const metadataAuthorization = "authorization"
+
+// publicMethods are the methods of the users service that don't need a token,
+// the health and the reflection services are always public
+var publicMethods = map[string]bool{
+	"/users.v1.UserService/Register":  true,
+	"/users.v1.UserService/Login":     true,
+	"/users.v1.UserService/GetUser":   true,
+	"/users.v1.UserService/ListUsers": true,
+}
+
+type claimsKey struct{}
+
+// claimsFromContext returns the claims of the token of the rpc, nil for the public methods
+func claimsFromContext(ctx context.Context) *jwt.JWTClaims {
+	claims, _ := ctx.Value(claimsKey{}).(*jwt.JWTClaims)
+	return claims
+}
+
+func requiresAuth(method string) bool {
+	return strings.HasPrefix(method, "/"+userspb.UserService_ServiceDesc.ServiceName+"/") && !publicMethods[method]
+}
+
+// authenticate validates the token in the metadata and returns the context with its claims
+func authenticate(ctx context.Context, j *jwt.JWThandler, l *logrus.Logger) (context.Context, error) {
+	values := metadata.ValueFromIncomingContext(ctx, metadataAuthorization)
+	if len(values) == 0 || values[0] == "" {
+		return ctx, newStatus(codes.Unauthenticated, "missing_authorization_header", "missing authorization metadata, it needs to be \"Bearer <token>\"")
+	}
+	if !strings.HasPrefix(values[0], "Bearer ") {
+		return ctx, newStatus(codes.Unauthenticated, "broken_bearer", "authorization metadata malformed, it needs to be \"Bearer <token>\"")
+	}
+	claims, err := j.ValidateToken(strings.TrimPrefix(values[0], "Bearer "))
+	if err != nil {
+		if jwt.IsExpiredError(err) {
+			return ctx, newStatus(codes.Unauthenticated, "token_expired", "your token has expired, please login again")
+		}
+		return ctx, newStatus(codes.Unauthenticated, "invalid_token", "invalid token")
+	}
+	ctx = logger.AddFields(ctx, l, logrus.Fields{"user_id": claims.UserId})
+	return context.WithValue(ctx, claimsKey{}, claims), nil
+}
+
+// authUnaryInterceptor rejects the calls to the authenticated methods without a valid token
+func authUnaryInterceptor(j *jwt.JWThandler, l *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if !requiresAuth(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx, j, l)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// authStreamInterceptor is authUnaryInterceptor for the streams
+func authStreamInterceptor(j *jwt.JWThandler, l *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !requiresAuth(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		ctx, err := authenticate(ss.Context(), j, l)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// contextStream replaces the context of the stream with the one built by the interceptors
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
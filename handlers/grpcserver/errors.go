@@ -0,0 +1,62 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/pkg/logger"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errorDomain is the domain of the ErrorInfo detail of the errors
+const errorDomain = "users.v1"
+
+// controllerCodes maps the sentinel errors of the controller, the reason of the ErrorInfo
+// detail is the error type of the http api so the clients can share the handling
+var controllerCodes = []struct {
+	err    error
+	code   codes.Code
+	reason string
+}{
+	{controller.ErrUserAlreadyExists, codes.AlreadyExists, "user_already_exists"},
+	{controller.ErrUserNotFound, codes.NotFound, "user_not_found"},
+	{controller.ErrWrongPassword, codes.Unauthenticated, "wrong_password"},
+	{controller.ErrUnupdatableUser, codes.FailedPrecondition, "unupdatable_user"},
+	{controller.ErrInvalidRole, codes.InvalidArgument, "invalid_role"},
+	{controller.ErrInvalidLocale, codes.InvalidArgument, "invalid_locale"},
+	{controller.ErrNotAdmin, codes.PermissionDenied, "not_admin"},
+}
+
+// newStatus returns the error of the rpc with the reason as ErrorInfo detail
+func newStatus(code codes.Code, reason, message string) error {
+	st := status.New(code, message)
+	if reason == "" {
+		return st.Err()
+	}
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason, Domain: errorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return withDetails.Err()
+}
+
+// statusOf turns the errors of the controller into the status of the rpc, the errors that
+// are already a status are returned as they are. The unexpected errors are logged with the
+// request id and their message is replaced so the internals are not sent to the clients
+func statusOf(ctx context.Context, l *logrus.Logger, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	for _, m := range controllerCodes {
+		if errors.Is(err, m.err) {
+			return newStatus(m.code, m.reason, err.Error())
+		}
+	}
+
+	logger.FromContext(ctx, l).WithError(err).Error("unexpected error handling the rpc")
+	return newStatus(codes.Internal, "unexpected_error", "unexpected error, report the request id "+requestIDFromContext(ctx)+" if it persists")
+}
@@ -0,0 +1,91 @@
+package grpcserver
+
+import (
+	"context"
+	"time"
+
+	"github.com/vano2903/service-template/handlers/grpcserver/userspb"
+	"github.com/vano2903/service-template/pkg/health"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+const _DEFAULT_WATCH_INTERVAL = 5 * time.Second
+
+// healthService is the grpc health protocol over the readiness checks of the service, the
+// overall status ("") and the one of the users service are the same as the one of /readyz
+type healthService struct {
+	grpc_health_v1.UnimplementedHealthServer
+	checks *health.Registry
+	//how often the watches run the checks
+	interval time.Duration
+	//closed when the server stops, the watches would keep the graceful stop waiting
+	stopping chan struct{}
+}
+
+func newHealthService(checks *health.Registry, interval time.Duration) *healthService {
+	if interval <= 0 {
+		interval = _DEFAULT_WATCH_INTERVAL
+	}
+	return &healthService{
+		checks:   checks,
+		interval: interval,
+		stopping: make(chan struct{}),
+	}
+}
+
+func knownService(service string) bool {
+	return service == "" || service == userspb.UserService_ServiceDesc.ServiceName
+}
+
+func (s *healthService) status(ctx context.Context) grpc_health_v1.HealthCheckResponse_ServingStatus {
+	if s.checks.Ready(ctx).IsReady() {
+		return grpc_health_v1.HealthCheckResponse_SERVING
+	}
+	return grpc_health_v1.HealthCheckResponse_NOT_SERVING
+}
+
+func (s *healthService) Check(ctx context.Context, req *grpc_health_v1.HealthCheckRequest) (*grpc_health_v1.HealthCheckResponse, error) {
+	if !knownService(req.Service) {
+		return nil, status.Errorf(codes.NotFound, "unknown service %q", req.Service)
+	}
+	return &grpc_health_v1.HealthCheckResponse{Status: s.status(ctx)}, nil
+}
+
+// Watch sends the status when it changes, the unknown services are reported as
+// SERVICE_UNKNOWN as required by the protocol
+func (s *healthService) Watch(req *grpc_health_v1.HealthCheckRequest, stream grpc_health_v1.Health_WatchServer) error {
+	if !knownService(req.Service) {
+		return stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN})
+	}
+	ctx := stream.Context()
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	last := grpc_health_v1.HealthCheckResponse_UNKNOWN
+	for {
+		current := s.status(ctx)
+		if current != last {
+			if err := stream.Send(&grpc_health_v1.HealthCheckResponse{Status: current}); err != nil {
+				return err
+			}
+			last = current
+		}
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-s.stopping:
+			if last != grpc_health_v1.HealthCheckResponse_NOT_SERVING {
+				_ = stream.Send(&grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING})
+			}
+			return status.Error(codes.Unavailable, "the server is stopping")
+		case <-ticker.C:
+		}
+	}
+}
+
+// shutdown ends the watches reporting NOT_SERVING
+func (s *healthService) shutdown() {
+	close(s.stopping)
+}
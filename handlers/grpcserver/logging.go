@@ -0,0 +1,152 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/logger"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	//same as the X-Request-ID header of the http api, it's sent back in the header metadata
+	metadataRequestID = "x-request-id"
+	//the id sent by the client ends up in the logs, it must be short and without strange characters
+	maxRequestIDLength = 128
+	//the probes would flood the logs
+	healthMethods = "/grpc.health.v1.Health/"
+)
+
+type requestIDKey struct{}
+
+func requestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	//crypto/rand never fails on the supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// withRequest gives an id to the rpc (keeping the one sent by the client) and puts in the
+// context the entry used by the handlers, the controllers and the repo to log
+func withRequest(ctx context.Context, l *logrus.Logger, method string) (context.Context, string) {
+	id := ""
+	if values := metadata.ValueFromIncomingContext(ctx, metadataRequestID); len(values) > 0 {
+		id = values[0]
+	}
+	if !validRequestID(id) {
+		id = newRequestID()
+	}
+	ctx = context.WithValue(ctx, requestIDKey{}, id)
+	return logger.WithEntry(ctx, l.WithFields(logrus.Fields{
+		"request_id": id,
+		"method":     method,
+	})), id
+}
+
+// logRPC writes the access log of the rpc with the fields of the entry of the context
+func logRPC(ctx context.Context, l *logrus.Logger, method string, begin time.Time, err error) {
+	code := status.Code(err)
+	entry := logger.FromContext(ctx, l).WithFields(logrus.Fields{
+		"code":    code.String(),
+		"latency": time.Since(begin).String(),
+	})
+	if p, ok := peer.FromContext(ctx); ok {
+		entry = entry.WithField("remote_addr", p.Addr.String())
+	}
+	switch code {
+	case codes.OK:
+		if strings.HasPrefix(method, healthMethods) {
+			entry.Debug("rpc handled")
+		} else {
+			entry.Info("rpc handled")
+		}
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable:
+		entry.Error("rpc failed")
+	default:
+		entry.Warn("rpc rejected")
+	}
+}
+
+// loggingUnaryInterceptor is the first interceptor, the others log with the entry it puts in the context
+func loggingUnaryInterceptor(l *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		begin := time.Now()
+		ctx, id := withRequest(ctx, l, info.FullMethod)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(metadataRequestID, id))
+		resp, err := handler(ctx, req)
+		logRPC(ctx, l, info.FullMethod, begin, err)
+		return resp, err
+	}
+}
+
+// loggingStreamInterceptor is loggingUnaryInterceptor for the streams
+func loggingStreamInterceptor(l *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		begin := time.Now()
+		ctx, id := withRequest(ss.Context(), l, info.FullMethod)
+		_ = ss.SetHeader(metadata.Pairs(metadataRequestID, id))
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logRPC(ctx, l, info.FullMethod, begin, err)
+		return err
+	}
+}
+
+// recovered logs the panic with the stack and returns the error of the rpc
+func recovered(ctx context.Context, l *logrus.Logger, r interface{}) error {
+	logger.FromContext(ctx, l).WithField("stack", string(debug.Stack())).Errorf("panic handling the rpc: %v", r)
+	return newStatus(codes.Internal, "unexpected_error", fmt.Sprintf("unexpected error, report the request id %s if it persists", requestIDFromContext(ctx)))
+}
+
+// recoveryUnaryInterceptor turns the panics of the handlers into internal errors
+func recoveryUnaryInterceptor(l *logrus.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ctx, l, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// recoveryStreamInterceptor is recoveryUnaryInterceptor for the streams
+func recoveryStreamInterceptor(l *logrus.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = recovered(ss.Context(), l, r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
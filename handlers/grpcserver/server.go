@@ -0,0 +1,89 @@
+// Package grpcserver serves the user operations over grpc for the internal services, with the
+// same tokens and authorization rules of the http api. The services are defined in
+// proto/users/v1/users.proto and the code in userspb is generated by `make proto`.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/handlers/grpcserver/userspb"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+)
+
+type Options struct {
+	//the reflection service lets tools like grpcurl list and call the methods without the protos
+	Reflection bool
+	//how often the health watches run the checks, 5s if 0
+	WatchInterval time.Duration
+}
+
+// Server has the users, the health and (if enabled) the reflection services
+type Server struct {
+	server   *grpc.Server
+	health   *healthService
+	stopOnce sync.Once
+}
+
+func NewServer(l *logrus.Logger, users *controller.User, jwtHandler *jwt.JWThandler, checks *health.Registry, opts Options) *Server {
+	//the logging interceptor is the first so it logs the rejections and the recovered panics too
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			loggingUnaryInterceptor(l),
+			recoveryUnaryInterceptor(l),
+			authUnaryInterceptor(jwtHandler, l),
+		),
+		grpc.ChainStreamInterceptor(
+			loggingStreamInterceptor(l),
+			recoveryStreamInterceptor(l),
+			authStreamInterceptor(jwtHandler, l),
+		),
+	)
+	s := &Server{
+		server: server,
+		health: newHealthService(checks, opts.WatchInterval),
+	}
+	userspb.RegisterUserServiceServer(server, newUserService(users, jwtHandler, l))
+	grpc_health_v1.RegisterHealthServer(server, s.health)
+	if opts.Reflection {
+		reflection.Register(server)
+	}
+	return s
+}
+
+// Serve accepts the connections of the listener until the server is stopped,
+// it returns nil when stopped by Stop
+func (s *Server) Serve(listener net.Listener) error {
+	if err := s.server.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Stop stops accepting connections and waits for the rpcs in flight, when the
+// context is done the ones still running are cancelled
+func (s *Server) Stop(ctx context.Context) error {
+	s.stopOnce.Do(s.health.shutdown)
+	done := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		<-done
+		return ctx.Err()
+	}
+}
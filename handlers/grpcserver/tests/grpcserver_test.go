@@ -0,0 +1,242 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/handlers/grpcserver"
+	"github.com/vano2903/service-template/handlers/grpcserver/userspb"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"gotest.tools/v3/assert"
+)
+
+const jwtSecret = "a-secret-long-enough-for-the-tests"
+
+// newClient serves the users of the test fixtures over an in memory connection
+func newClient(t *testing.T, checks *health.Registry) *grpc.ClientConn {
+	t.Helper()
+	l := logrus.New()
+	l.SetLevel(logrus.PanicLevel)
+	repo := mock.NewRepo()
+	users := controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+	_, err := fixtures.Test().Apply(context.Background(), users)
+	assert.NilError(t, err)
+
+	server := grpcserver.NewServer(l, users, jwt.NewJWThandler(jwtSecret, "users:test"), checks, grpcserver.Options{
+		Reflection:    true,
+		WatchInterval: 10 * time.Millisecond,
+	})
+	listener := bufconn.Listen(1 << 20)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		assert.NilError(t, server.Stop(ctx))
+	})
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	assert.NilError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// login logs in as the user of the fixtures and returns the context with its token
+func login(t *testing.T, client userspb.UserServiceClient, email string) context.Context {
+	t.Helper()
+	fixture, ok := fixtures.Test().Find(email)
+	assert.Assert(t, ok, email)
+	resp, err := client.Login(context.Background(), &userspb.LoginRequest{Email: fixture.Email, Password: fixture.Password})
+	assert.NilError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+resp.Token)
+}
+
+// assertStatus checks the code of the error and the reason of its ErrorInfo
+func assertStatus(t *testing.T, err error, code codes.Code, reason string) {
+	t.Helper()
+	st, ok := status.FromError(err)
+	assert.Assert(t, ok, err)
+	assert.Equal(t, st.Code(), code, st.Message())
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok {
+			assert.Equal(t, info.Reason, reason)
+			return
+		}
+	}
+	t.Fatalf("no ErrorInfo in %v", st.Details())
+}
+
+// users service, cases:
+// [x] the users log in and read their info with the token
+// [x] the public reads don't need a token and hide role, ban and locale
+// [x] the registered users can log in
+// [x] the errors of the controller are mapped to their code
+func TestUserService(t *testing.T) {
+	client := userspb.NewUserServiceClient(newClient(t, health.NewRegistry()))
+
+	ctx := login(t, client, "admin@fixtures.test")
+	me, err := client.GetMe(ctx, &userspb.GetMeRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, me.Email, "admin@fixtures.test")
+	assert.Equal(t, me.Role, model.RoleAdmin)
+
+	public, err := client.GetUser(context.Background(), &userspb.GetUserRequest{Id: me.Id})
+	assert.NilError(t, err)
+	assert.Equal(t, public.Email, "admin@fixtures.test")
+	assert.Equal(t, public.Role, "")
+	list, err := client.ListUsers(context.Background(), &userspb.ListUsersRequest{})
+	assert.NilError(t, err)
+	assert.Assert(t, len(list.Users) >= 4)
+
+	registered, err := client.Register(context.Background(), &userspb.RegisterRequest{FirstName: "new", Email: "new@grpc.test", Password: "new-password"})
+	assert.NilError(t, err)
+	resp, err := client.Login(context.Background(), &userspb.LoginRequest{Email: "new@grpc.test", Password: "new-password"})
+	assert.NilError(t, err)
+	assert.Assert(t, resp.Token != "")
+
+	_, err = client.Register(context.Background(), &userspb.RegisterRequest{Email: "user@fixtures.test", Password: "password"})
+	assertStatus(t, err, codes.AlreadyExists, "user_already_exists")
+	_, err = client.Login(context.Background(), &userspb.LoginRequest{Email: "user@fixtures.test", Password: "wrong"})
+	assertStatus(t, err, codes.Unauthenticated, "wrong_password")
+	_, err = client.GetUser(context.Background(), &userspb.GetUserRequest{Id: 1000})
+	assertStatus(t, err, codes.NotFound, "user_not_found")
+	_, err = client.SetRole(ctx, &userspb.SetRoleRequest{Id: registered.Id, Role: "owner"})
+	assertStatus(t, err, codes.InvalidArgument, "invalid_role")
+}
+
+// authorization, cases:
+// [x] the authenticated methods need a valid bearer token
+// [x] the users can update themselves but not the others
+// [x] the admins can update, ban and delete anyone
+// [x] the protected users can't be updated
+func TestUserServiceAuth(t *testing.T) {
+	client := userspb.NewUserServiceClient(newClient(t, health.NewRegistry()))
+
+	_, err := client.GetMe(context.Background(), &userspb.GetMeRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "missing_authorization_header")
+	_, err = client.GetMe(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Token abc"), &userspb.GetMeRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "broken_bearer")
+	_, err = client.GetMe(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer abc"), &userspb.GetMeRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "invalid_token")
+	expired, err := jwt.NewJWThandler(jwtSecret, "users:test", -time.Minute).GenerateToken(1, "admin@fixtures.test", model.RoleAdmin)
+	assert.NilError(t, err)
+	_, err = client.GetMe(metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+expired), &userspb.GetMeRequest{})
+	assertStatus(t, err, codes.Unauthenticated, "token_expired")
+
+	userCtx := login(t, client, "user@fixtures.test")
+	user, err := client.UpdateUser(userCtx, &userspb.UpdateUserRequest{FirstName: "renamed", Locale: "it-it"})
+	assert.NilError(t, err)
+	assert.Equal(t, user.FirstName, "renamed")
+	assert.Equal(t, user.Locale, "it-IT")
+
+	adminCtx := login(t, client, "admin@fixtures.test")
+	admin, err := client.GetMe(adminCtx, &userspb.GetMeRequest{})
+	assert.NilError(t, err)
+	_, err = client.UpdateUser(userCtx, &userspb.UpdateUserRequest{Id: admin.Id, FirstName: "hacked"})
+	assertStatus(t, err, codes.PermissionDenied, "unauthorized_update")
+	_, err = client.SetBanned(userCtx, &userspb.SetBannedRequest{Id: admin.Id, Banned: true})
+	assertStatus(t, err, codes.PermissionDenied, "not_admin")
+
+	user, err = client.UpdateUser(adminCtx, &userspb.UpdateUserRequest{Id: user.Id, LastName: "by admin"})
+	assert.NilError(t, err)
+	assert.Equal(t, user.LastName, "by admin")
+	user, err = client.SetBanned(adminCtx, &userspb.SetBannedRequest{Id: user.Id, Banned: true})
+	assert.NilError(t, err)
+	assert.Assert(t, user.IsBanned)
+
+	unupdatable, ok := fixtures.Test().Find("unupdatable@fixtures.test")
+	assert.Assert(t, ok)
+	found, err := client.Login(context.Background(), &userspb.LoginRequest{Email: unupdatable.Email, Password: unupdatable.Password})
+	assert.NilError(t, err)
+	claims, err := jwt.NewJWThandler(jwtSecret, "users:test").ValidateToken(found.Token)
+	assert.NilError(t, err)
+	_, err = client.UpdateUser(adminCtx, &userspb.UpdateUserRequest{Id: int64(claims.UserId), FirstName: "changed"})
+	assertStatus(t, err, codes.FailedPrecondition, "unupdatable_user")
+
+	_, err = client.DeleteUser(adminCtx, &userspb.DeleteUserRequest{Id: user.Id})
+	assert.NilError(t, err)
+	_, err = client.GetUser(context.Background(), &userspb.GetUserRequest{Id: user.Id})
+	assertStatus(t, err, codes.NotFound, "user_not_found")
+	//the token of the deleted user is still valid but it references nobody
+	_, err = client.GetMe(userCtx, &userspb.GetMeRequest{})
+	assertStatus(t, err, codes.NotFound, "user_not_found")
+}
+
+// health and reflection, cases:
+// [x] the status follows the readiness of the critical checks
+// [x] the unknown services are not found
+// [x] the watch sends the changes of the status
+// [x] the reflection lists the services
+func TestHealthAndReflection(t *testing.T) {
+	var down atomic.Bool
+	checks := health.NewRegistry()
+	checks.Register("repo", true, time.Second, func(context.Context) error {
+		if down.Load() {
+			return errors.New("down")
+		}
+		return nil
+	})
+	conn := newClient(t, checks)
+	client := grpc_health_v1.NewHealthClient(conn)
+
+	resp, err := client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{})
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+	resp, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "users.v1.UserService"})
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+	_, err = client.Check(context.Background(), &grpc_health_v1.HealthCheckRequest{Service: "unknown"})
+	assert.Equal(t, status.Code(err), codes.NotFound)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	watch, err := client.Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	assert.NilError(t, err)
+	resp, err = watch.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_SERVING)
+	down.Store(true)
+	resp, err = watch.Recv()
+	assert.NilError(t, err)
+	assert.Equal(t, resp.Status, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+
+	stream, err := grpc_reflection_v1alpha.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	assert.NilError(t, err)
+	assert.NilError(t, stream.Send(&grpc_reflection_v1alpha.ServerReflectionRequest{
+		MessageRequest: &grpc_reflection_v1alpha.ServerReflectionRequest_ListServices{},
+	}))
+	reflected, err := stream.Recv()
+	assert.NilError(t, err)
+	services := map[string]bool{}
+	for _, s := range reflected.GetListServicesResponse().Service {
+		services[s.Name] = true
+	}
+	assert.Assert(t, services["users.v1.UserService"], services)
+	assert.Assert(t, services["grpc.health.v1.Health"], services)
+}
@@ -0,0 +1,238 @@
+package grpcserver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/handlers/grpcserver/userspb"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+var _ userspb.UserServiceServer = new(userService)
+
+// userService exposes the user controller, the authorization rules are the ones of the http api:
+// the users can change only themselves and the admins anyone
+type userService struct {
+	userspb.UnimplementedUserServiceServer
+	controller *controller.User
+	j          *jwt.JWThandler
+	l          *logrus.Logger
+}
+
+func newUserService(c *controller.User, jwtHandler *jwt.JWThandler, l *logrus.Logger) *userService {
+	return &userService{
+		controller: c,
+		j:          jwtHandler,
+		l:          l,
+	}
+}
+
+// publicUser has only the fields visible to anyone, like HttpUnauthenticatedUser
+func publicUser(u *model.User) *userspb.User {
+	return &userspb.User{
+		Id:        int64(u.ID),
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Pfp:       u.Pfp,
+		PfpStatus: u.PfpStatus,
+		Email:     u.Email,
+	}
+}
+
+// fullUser has every field but the password, for the user itself and the admins
+func fullUser(u *model.User) *userspb.User {
+	pb := publicUser(u)
+	pb.Role = u.Role
+	pb.IsBanned = u.IsBanned
+	pb.Locale = u.Locale
+	return pb
+}
+
+// requester returns the user of the token, it could have been deleted after the login
+func (s *userService) requester(ctx context.Context) (*model.User, error) {
+	claims := claimsFromContext(ctx)
+	if claims == nil {
+		//the method is missing from publicMethods or the interceptor is not installed
+		return nil, newStatus(codes.Unauthenticated, "missing_authorization_header", "the method needs a token")
+	}
+	u, err := s.controller.GetUser(ctx, claims.UserId)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return u, nil
+}
+
+// target returns the id the requester is acting on, the users can act only on themselves
+func target(requester *model.User, id int64) (int, error) {
+	if id == 0 || int(id) == requester.ID {
+		return requester.ID, nil
+	}
+	if requester.Role != model.RoleAdmin {
+		return 0, newStatus(codes.PermissionDenied, "unauthorized_update", "only admins can change other users")
+	}
+	return int(id), nil
+}
+
+// getUser returns the user after an operation on it
+func (s *userService) getUser(ctx context.Context, id int) (*userspb.User, error) {
+	u, err := s.controller.GetUser(ctx, id)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return fullUser(u), nil
+}
+
+func (s *userService) Register(ctx context.Context, req *userspb.RegisterRequest) (*userspb.RegisterResponse, error) {
+	id, err := s.controller.CreateUser(ctx, req.FirstName, req.LastName, req.Email, req.Password, model.RoleUser)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, fmt.Errorf("unable to create user %s: %w", req.Email, err))
+	}
+	return &userspb.RegisterResponse{Id: int64(id)}, nil
+}
+
+func (s *userService) Login(ctx context.Context, req *userspb.LoginRequest) (*userspb.LoginResponse, error) {
+	id, err := s.controller.CheckCredentials(ctx, req.Email, req.Password)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	u, err := s.controller.GetUser(ctx, id)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	token, err := s.j.GenerateToken(u.ID, u.Email, u.Role)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, fmt.Errorf("unexpected error trying to generate the login token: %w", err))
+	}
+	logger.FromContext(ctx, s.l).Debugf("token generated for user %d", u.ID)
+	return &userspb.LoginResponse{Token: token}, nil
+}
+
+func (s *userService) GetUser(ctx context.Context, req *userspb.GetUserRequest) (*userspb.User, error) {
+	u, err := s.controller.GetUser(ctx, int(req.Id))
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return publicUser(u), nil
+}
+
+func (s *userService) ListUsers(ctx context.Context, _ *userspb.ListUsersRequest) (*userspb.ListUsersResponse, error) {
+	users := s.controller.GetAllUsers(ctx)
+	resp := &userspb.ListUsersResponse{Users: make([]*userspb.User, 0, len(users))}
+	for _, u := range users {
+		resp.Users = append(resp.Users, publicUser(u))
+	}
+	return resp, nil
+}
+
+func (s *userService) GetMe(ctx context.Context, _ *userspb.GetMeRequest) (*userspb.User, error) {
+	u, err := s.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return fullUser(u), nil
+}
+
+func (s *userService) UpdateUser(ctx context.Context, req *userspb.UpdateUserRequest) (*userspb.User, error) {
+	requester, err := s.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := target(requester, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	toUpdate, err := s.controller.GetUser(ctx, id)
+	if err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+
+	if req.FirstName != "" {
+		toUpdate.FirstName = req.FirstName
+	}
+	if req.LastName != "" {
+		toUpdate.LastName = req.LastName
+	}
+	if req.Email != "" {
+		toUpdate.Email = req.Email
+	}
+	if req.Password != "" {
+		toUpdate.Password = req.Password
+	}
+	if req.Locale != "" {
+		toUpdate.Locale = req.Locale
+	}
+
+	if err := s.controller.UpdateUser(ctx, requester.ID, toUpdate); err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return s.getUser(ctx, id)
+}
+
+func (s *userService) DeleteUser(ctx context.Context, req *userspb.DeleteUserRequest) (*emptypb.Empty, error) {
+	requester, err := s.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := target(requester, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.controller.DeleteUser(ctx, requester.ID, id); err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *userService) RegeneratePfp(ctx context.Context, req *userspb.RegeneratePfpRequest) (*userspb.User, error) {
+	requester, err := s.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := target(requester, req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.controller.RegeneratePfp(ctx, id); err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	//with the pfp queue the status is pending and the pfp is still the old one
+	return s.getUser(ctx, id)
+}
+
+// admin returns the requester if it's an admin
+func (s *userService) admin(ctx context.Context) (*model.User, error) {
+	requester, err := s.requester(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if requester.Role != model.RoleAdmin {
+		return nil, statusOf(ctx, s.l, controller.ErrNotAdmin)
+	}
+	return requester, nil
+}
+
+func (s *userService) SetBanned(ctx context.Context, req *userspb.SetBannedRequest) (*userspb.User, error) {
+	if _, err := s.admin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.controller.SetBanned(ctx, int(req.Id), req.Banned); err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return s.getUser(ctx, int(req.Id))
+}
+
+func (s *userService) SetRole(ctx context.Context, req *userspb.SetRoleRequest) (*userspb.User, error) {
+	if _, err := s.admin(ctx); err != nil {
+		return nil, err
+	}
+	if err := s.controller.SetRole(ctx, int(req.Id), req.Role); err != nil {
+		return nil, statusOf(ctx, s.l, err)
+	}
+	return s.getUser(ctx, int(req.Id))
+}
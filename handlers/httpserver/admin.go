@@ -0,0 +1,173 @@
+package httpserver
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+)
+
+type (
+	HttpLogLevelPut struct {
+		//"root" changes every component without a level of its own
+		Component string `json:"component"`
+		//trace, debug, info, warn, error, fatal or panic
+		Level string `json:"level"`
+		//duration (like 10m or 1h) after which the level goes back to the default one, empty doesn't expire
+		TTL string `json:"ttl,omitempty" validate:"optional"`
+	}
+
+	adminHttpHandler struct {
+		e          *echo.Group
+		controller *controller.Admin
+		l          *logrus.Logger
+		j          *jwt.JWThandler
+	}
+)
+
+func NewAdminHttpHandler(e *echo.Group, c *controller.Admin, l *logrus.Logger, jwtHandler *jwt.JWThandler) *adminHttpHandler {
+	return &adminHttpHandler{
+		e:          e,
+		controller: c,
+		l:          l,
+		j:          jwtHandler,
+	}
+}
+
+// Registers only the routes and links functions,
+// every route requires an admin token
+func (h *adminHttpHandler) RegisterRoutes() {
+	h.e.Use(jwtHeaderChecker(h.j, h.l))
+
+	h.e.GET("/log-levels", h.GetLogLevels)
+	h.e.PUT("/log-levels", h.SetLogLevel)
+	h.e.DELETE("/log-levels/:component", h.ResetLogLevel)
+}
+
+// respControllerError maps the errors shared by all the admin endpoints
+func (h *adminHttpHandler) respControllerError(c echo.Context, err error, action string) error {
+	switch err {
+	case controller.ErrNotAdmin:
+		return respError(c, problemNotAdmin, detailNotAdminService)
+	case controller.ErrUserNotFound:
+		return respError(c, problemUserNotFound, detailTokenUserNotFound)
+	case controller.ErrUnknownComponent:
+		return respError(c, problemComponentNotFound, detailComponentNotFound)
+	case controller.ErrInvalidLogLevel:
+		return respError(c, problemInvalidLogLevel, detailInvalidLogLevel)
+	default:
+		return fmt.Errorf("unexpected error trying to %s: %w", action, err)
+	}
+}
+
+func (h *adminHttpHandler) requesterID(c echo.Context) (int, error) {
+	//it wont panic because the middleware already checked it
+	authHeader := c.Request().Header.Get("Authorization")[bearerHeaderLength:]
+	claims, err := h.j.ValidateToken(authHeader)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserId, nil
+}
+
+// @Summary		Get log levels
+// @Description	Level of the logs of every component of the service, only for admins
+// @ID				GetLogLevels
+// @Tags			admin
+// @Produce		json,application/msgpack,application/cbor,text/csv
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Success		200		{object}	HttpSuccess{data=[]logger.ComponentLevel,code=int,message=string}
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/admin/log-levels [GET]
+func (h *adminHttpHandler) GetLogLevels(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	levels, err := h.controller.GetLogLevels(c.Request().Context(), requesterID)
+	if err != nil {
+		return h.respControllerError(c, err, "retrive the log levels")
+	}
+	return respSuccess(c, 200, msgLogLevelsRetrieved, levels)
+}
+
+// @Summary		Set log level
+// @Description	Change the level of the logs of a component at runtime, only for admins
+// @Description	With a ttl the level goes back to the default one automatically, useful to debug without forgetting the service in debug
+// @ID				SetLogLevel
+// @Tags			admin
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			level	body		HttpLogLevelPut	true	"new level"
+// @Success		200		{object}	HttpSuccess{data=[]logger.ComponentLevel,code=int,message=string}
+// @Failure		400		{object}	Problem
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/admin/log-levels [PUT]
+func (h *adminHttpHandler) SetLogLevel(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	body := HttpLogLevelPut{}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+	if body.Component == "" {
+		body.Component = logger.RootComponent
+	}
+	var ttl time.Duration
+	if body.TTL != "" {
+		ttl, err = time.ParseDuration(body.TTL)
+		if err != nil {
+			return respError(c, problemInvalidTTL, detailInvalidTTL, i18n.Params{"ttl": body.TTL})
+		}
+	}
+
+	if err := h.controller.SetLogLevel(c.Request().Context(), requesterID, body.Component, body.Level, ttl); err != nil {
+		return h.respControllerError(c, err, fmt.Sprintf("set the log level of %s", body.Component))
+	}
+
+	levels, err := h.controller.GetLogLevels(c.Request().Context(), requesterID)
+	if err != nil {
+		return h.respControllerError(c, err, "retrive the log levels")
+	}
+	return respSuccess(c, 200, msgLogLevelSet, levels)
+}
+
+// @Summary		Reset log level
+// @Description	Bring the level of the logs of a component back to the default one, only for admins
+// @ID				ResetLogLevel
+// @Tags			admin
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			component	path		string	true	"Component name"
+// @Success		200		{object}	HttpSuccess{code=int,message=string}
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/admin/log-levels/{component} [DELETE]
+func (h *adminHttpHandler) ResetLogLevel(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	component := c.Param("component")
+	if err := h.controller.ResetLogLevel(c.Request().Context(), requesterID, component); err != nil {
+		return h.respControllerError(c, err, fmt.Sprintf("reset the log level of %s", component))
+	}
+	return respSuccess(c, 200, msgLogLevelReset)
+}
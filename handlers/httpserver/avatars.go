@@ -0,0 +1,117 @@
+package httpserver
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/avatar"
+	"github.com/vano2903/service-template/pkg/i18n"
+)
+
+const (
+	avatarDefaultSize = 128
+	avatarMinSize     = 16
+	avatarMaxSize     = 512
+)
+
+// The avatars are rendered on every request, they are cheap to render and
+// never change so the clients and proxies can cache them forever
+type avatarHttpHandler struct {
+	e *echo.Group
+	l *logrus.Logger
+}
+
+func NewAvatarHttpHandler(e *echo.Group, l *logrus.Logger) *avatarHttpHandler {
+	return &avatarHttpHandler{
+		e: e,
+		l: l,
+	}
+}
+
+// Registers only the routes and links functions
+func (h *avatarHttpHandler) RegisterRoutes() {
+	h.e.GET("/:style/:file", h.GetAvatar)
+}
+
+// @Summary		Get avatar
+// @Description	Deterministic avatar generated by the service (the local logo provider returns these urls)
+// @Description	The file is <seed>.png or <seed>.svg, for the initials style the seed is <initials>-<hash>
+// @ID				GetAvatar
+// @Tags			avatars
+// @Produce		png
+// @Produce		image/svg+xml
+// @Param			style	path		string	true	"avatar style"	Enums(identicon, initials)
+// @Param			file	path		string	true	"seed and format"
+// @Param			size	query		int		false	"size in pixels (16-512)"
+// @Success		200
+// @Failure		400		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/avatars/{style}/{file} [GET]
+func (h *avatarHttpHandler) GetAvatar(c echo.Context) error {
+	style := c.Param("style")
+	if style != avatar.StyleIdenticon && style != avatar.StyleInitials {
+		return respError(c, problemInvalidAvatarStyle, detailInvalidAvatarStyle, i18n.Params{"style": style})
+	}
+
+	file := c.Param("file")
+	dot := strings.LastIndex(file, ".")
+	if dot <= 0 {
+		return respError(c, problemInvalidAvatarFile, detailInvalidAvatarFile)
+	}
+	seed, format := file[:dot], file[dot+1:]
+	if format != "png" && format != "svg" {
+		return respError(c, problemInvalidAvatarFormat, detailInvalidAvatarFormat, i18n.Params{"format": format})
+	}
+
+	initials := ""
+	if style == avatar.StyleInitials {
+		dash := strings.Index(seed, "-")
+		if dash <= 0 || dash > 8 {
+			return respError(c, problemInvalidAvatarSeed, detailInvalidAvatarSeed)
+		}
+		initials = seed[:dash]
+	}
+
+	size := avatarDefaultSize
+	if sizeParam := c.QueryParam("size"); sizeParam != "" {
+		var err error
+		size, err = strconv.Atoi(sizeParam)
+		if err != nil || size < avatarMinSize || size > avatarMaxSize {
+			return respError(c, problemInvalidAvatarSize, detailInvalidAvatarSize, i18n.Params{"min": avatarMinSize, "max": avatarMaxSize})
+		}
+	}
+
+	//the same url always gives the same image
+	etag := fmt.Sprintf("%q", fmt.Sprintf("%s-%s-%d", style, file, size))
+	c.Response().Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	c.Response().Header().Set("ETag", etag)
+	if c.Request().Header.Get("If-None-Match") == etag {
+		return c.NoContent(http.StatusNotModified)
+	}
+
+	if format == "svg" {
+		var svg string
+		if style == avatar.StyleInitials {
+			svg = avatar.InitialsSVG(initials, seed, size)
+		} else {
+			svg = avatar.IdenticonSVG(seed, size)
+		}
+		return c.Blob(http.StatusOK, "image/svg+xml", []byte(svg))
+	}
+
+	img := avatar.Identicon(seed, size)
+	if style == avatar.StyleInitials {
+		img = avatar.InitialsImage(initials, seed, size)
+	}
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return fmt.Errorf("unexpected error trying to render the avatar: %w", err)
+	}
+	return c.Blob(http.StatusOK, "image/png", buf.Bytes())
+}
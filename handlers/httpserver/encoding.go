@@ -0,0 +1,249 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const (
+	MIMEApplicationMsgpack = "application/msgpack"
+	MIMEApplicationCBOR    = "application/cbor"
+	MIMETextCSV            = "text/csv"
+)
+
+var errNotCollection = errors.New("only the collections can be encoded as csv")
+
+// bodyEncoder encodes the successful responses in a media type
+type bodyEncoder struct {
+	mime        string
+	contentType string
+	//other names of the media type used by the clients
+	aliases []string
+	//the encoder gets only the data of the response, it must be a collection
+	rows   bool
+	encode func(v interface{}) ([]byte, error)
+}
+
+// encoders are the media types of the successful responses, json is the
+// default one when the client accepts anything. The errors are always problem+json
+var encoders = []bodyEncoder{
+	{mime: echo.MIMEApplicationJSON, contentType: echo.MIMEApplicationJSONCharsetUTF8, encode: json.Marshal},
+	{mime: MIMEApplicationMsgpack, contentType: MIMEApplicationMsgpack, aliases: []string{"application/x-msgpack", "application/vnd.msgpack"}, encode: encodeMsgpack},
+	{mime: MIMEApplicationCBOR, contentType: MIMEApplicationCBOR, encode: cbor.Marshal},
+	{mime: MIMETextCSV, contentType: MIMETextCSV + "; charset=utf-8", rows: true, encode: encodeCSV},
+}
+
+// encodeMsgpack uses the json names of the fields, so the bodies have the same shape in every format
+func encodeMsgpack(v interface{}) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	enc := msgpack.NewEncoder(buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// acceptedRange is a media range of the Accept header
+type acceptedRange struct {
+	mime string
+	q    float64
+}
+
+// parseAccept returns the media ranges accepted by the client, the preferred first.
+// Without the header the client accepts anything
+func parseAccept(header string) []acceptedRange {
+	if strings.TrimSpace(header) == "" {
+		return []acceptedRange{{mime: "*/*", q: 1}}
+	}
+	ranges := []acceptedRange{}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		ranges = append(ranges, acceptedRange{mime: mediaType, q: q})
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].q > ranges[j].q
+	})
+	return ranges
+}
+
+func (e bodyEncoder) matches(mediaRange string) bool {
+	if mediaRange == "*/*" || mediaRange == e.mime {
+		return true
+	}
+	if strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(e.mime, strings.TrimSuffix(mediaRange, "*")) {
+		return true
+	}
+	for _, alias := range e.aliases {
+		if mediaRange == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// negotiate returns the encoder of the media type preferred by the client, the csv
+// one only if the data is a collection. It's false if no accepted type can be produced
+func negotiate(accept string, data interface{}) (bodyEncoder, bool) {
+	collection := isCollection(data)
+	for _, r := range parseAccept(accept) {
+		for _, e := range encoders {
+			if e.rows && !collection {
+				continue
+			}
+			if e.matches(r.mime) {
+				return e, true
+			}
+		}
+	}
+	return bodyEncoder{}, false
+}
+
+// produced lists the media types for the detail of the 406
+func produced(data interface{}) string {
+	types := []string{}
+	for _, e := range encoders {
+		if !e.rows || isCollection(data) {
+			types = append(types, e.mime)
+		}
+	}
+	return strings.Join(types, ", ")
+}
+
+func isCollection(data interface{}) bool {
+	if data == nil {
+		return false
+	}
+	t := reflect.TypeOf(data)
+	return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() != reflect.Uint8
+}
+
+// csvColumn is an exported field of the elements of the collection
+type csvColumn struct {
+	name  string
+	index []int
+}
+
+// encodeCSV writes a row per element of the collection, the columns are the fields of the
+// elements named as in json. The fields that are not scalars are written as json
+func encodeCSV(v interface{}) ([]byte, error) {
+	if !isCollection(v) {
+		return nil, errNotCollection
+	}
+	rows := reflect.ValueOf(v)
+	elem := rows.Type().Elem()
+	for elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	columns := []csvColumn{{name: "value"}}
+	if elem.Kind() == reflect.Struct {
+		columns = csvColumns(elem)
+	}
+
+	buf := &bytes.Buffer{}
+	w := csv.NewWriter(buf)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.name
+	}
+	if err := w.Write(header); err != nil {
+		return nil, err
+	}
+	record := make([]string, len(columns))
+	for i := 0; i < rows.Len(); i++ {
+		row := reflect.Indirect(rows.Index(i))
+		for j, c := range columns {
+			field := row
+			if c.index != nil && row.IsValid() {
+				field = row.FieldByIndex(c.index)
+			}
+			cell, err := csvCell(field)
+			if err != nil {
+				return nil, fmt.Errorf("column %s of row %d: %w", c.name, i, err)
+			}
+			record[j] = cell
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+func csvColumns(t reflect.Type) []csvColumn {
+	columns := []csvColumn{}
+	for _, f := range reflect.VisibleFields(t) {
+		if !f.IsExported() || f.Anonymous {
+			continue
+		}
+		name := f.Name
+		if tag, ok := f.Tag.Lookup("json"); ok {
+			tagName, _, _ := strings.Cut(tag, ",")
+			if tagName == "-" {
+				continue
+			}
+			if tagName != "" {
+				name = tagName
+			}
+		}
+		columns = append(columns, csvColumn{name: name, index: f.Index})
+	}
+	return columns
+}
+
+func csvCell(v reflect.Value) (string, error) {
+	for v.IsValid() && (v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface) {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t.Format(time.RFC3339Nano), nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64), nil
+	default:
+		b, err := json.Marshal(v.Interface())
+		return string(b), err
+	}
+}
@@ -0,0 +1,117 @@
+package httpserver
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"io"
+	"net/http"
+	"strings"
+	textTemplate "text/template"
+
+	"github.com/labstack/echo/v4"
+)
+
+// The error reference is rendered from the catalog, so it can't drift from what the
+// handlers return. It is served next to swagger and written to docs/errors.md by
+// `make swag`
+type errorDocsHttpHandler struct {
+	e         *echo.Echo
+	publicUrl string
+}
+
+func NewErrorDocsHttpHandler(e *echo.Echo, publicUrl string) *errorDocsHttpHandler {
+	return &errorDocsHttpHandler{
+		e:         e,
+		publicUrl: publicUrl,
+	}
+}
+
+// Registers only the routes and links functions
+func (h *errorDocsHttpHandler) RegisterRoutes() {
+	h.e.GET(ErrorDocsPath, h.Reference)
+	h.e.GET(ErrorDocsPath+".json", h.Catalog)
+}
+
+var errorReferenceHtml = template.Must(template.New("errors").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Error reference</title></head>
+<body>
+<h1>Error reference</h1>
+<p>Every error is returned as <code>application/problem+json</code> (RFC 7807), the <code>type</code> links to its section of this page and <code>error_type</code> holds the bare type.</p>
+{{range .}}<section id="{{.Type}}">
+<h2>{{.Type}}</h2>
+<p><strong>{{.Status}}</strong> {{.Title}}</p>
+<p>{{.Description}}</p>
+</section>
+{{end}}</body>
+</html>
+`))
+
+// @Summary		Error reference
+// @Description	Describes every error type the api can return, the type of the errors links here
+// @ID				ErrorReference
+// @Tags			docs
+// @Produce		html
+// @Success		200	{string}	string
+// @Router			/docs/errors [GET]
+func (h *errorDocsHttpHandler) Reference(c echo.Context) error {
+	buf := &bytes.Buffer{}
+	if err := errorReferenceHtml.Execute(buf, ProblemTypes()); err != nil {
+		return fmt.Errorf("unexpected error trying to render the error reference: %w", err)
+	}
+	return c.HTMLBlob(http.StatusOK, buf.Bytes())
+}
+
+// errorCatalogEntry is a type of the catalog with its doc url
+type errorCatalogEntry struct {
+	ProblemType
+	DocUrl string `json:"doc_url" example:"http://localhost:8080/docs/errors#invalid_id"`
+}
+
+// @Summary		Error catalog
+// @Description	Lists every error type the api can return, for clients that want to generate their error handling
+// @ID				ErrorCatalog
+// @Tags			docs
+// @Produce		json
+// @Success		200	{array}	errorCatalogEntry
+// @Router			/docs/errors.json [GET]
+func (h *errorDocsHttpHandler) Catalog(c echo.Context) error {
+	types := ProblemTypes()
+	entries := make([]errorCatalogEntry, len(types))
+	for i, p := range types {
+		entries[i] = errorCatalogEntry{ProblemType: p, DocUrl: p.DocUrl(h.publicUrl)}
+	}
+	return c.JSON(http.StatusOK, entries)
+}
+
+var errorReferenceMarkdown = textTemplate.Must(textTemplate.New("errors").Funcs(textTemplate.FuncMap{
+	"cell": strings.NewReplacer("|", "\\|", "<", "&lt;", ">", "&gt;").Replace,
+}).Parse(`# Error reference
+
+<!-- generated from handlers/httpserver/problems.go by ` + "`make swag`" + `, do not edit -->
+
+Every error is returned as ` + "`application/problem+json`" + ` ([RFC 7807](https://www.rfc-editor.org/rfc/rfc7807)):
+
+| field | description |
+| --- | --- |
+| type | url of the section of the error reference describing the error |
+| title | short summary of the type, it doesn't change between occurrences |
+| status | http status code |
+| detail | explanation specific to the occurrence |
+| instance | path of the request |
+| error_type | the type without the url, for clients that switch on it |
+| request_id | id of the request, also in the X-Request-ID header |
+
+The errors of the http layer without a type of their own (like 406 or 503) have ` + "`about:blank`" + ` as type, the title of their status and no error_type.
+The reference is served by the service at ` + "`" + ErrorDocsPath + "`" + ` and as json at ` + "`" + ErrorDocsPath + ".json`" + `.
+
+| type | status | title | description |
+| --- | --- | --- | --- |
+{{range .}}| <a id="{{.Type}}"></a>` + "`{{.Type}}`" + ` | {{.Status}} | {{cell .Title}} | {{cell .Description}} |
+{{end}}`))
+
+// WriteErrorReference writes the markdown error reference of the catalog
+func WriteErrorReference(w io.Writer) error {
+	return errorReferenceMarkdown.Execute(w, ProblemTypes())
+}
@@ -0,0 +1,155 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"github.com/vano2903/service-template/pkg/logger"
+)
+
+// controllerProblems maps the sentinel errors of the controllers, the handlers can return
+// them (even wrapped) when they have nothing to add to the default detail
+var controllerProblems = []struct {
+	err     error
+	problem ProblemType
+	detail  problemDetail
+}{
+	{controller.ErrUserAlreadyExists, problemUserAlreadyExists, detailEmailTaken},
+	{controller.ErrUserNotFound, problemUserNotFound, detailUserNotFound},
+	{controller.ErrWrongPassword, problemWrongPassword, detailWrongPassword},
+	{controller.ErrUnupdatableUser, problemUnupdatableUser, detailUnupdatableUser},
+	{controller.ErrInvalidRole, problemInvalidRole, detailInvalidRole},
+	{controller.ErrInvalidLocale, problemInvalidLocale, detailLocaleNotValid},
+	{controller.ErrNotAdmin, problemNotAdmin, detailNotAdmin},
+	{controller.ErrInvalidWebhook, problemInvalidWebhook, detailInvalidWebhook},
+	{controller.ErrWebhookNotFound, problemWebhookNotFound, detailWebhookNotFound},
+	{controller.ErrDeliveryNotFound, problemDeliveryNotFound, detailDeliveryNotFound},
+	{controller.ErrDeliveryNotResendable, problemDeliveryNotResendable, detailDeliveryNotResendable},
+	{controller.ErrPfpTooLarge, problemPfpTooLarge, detailPfpTooLarge},
+	{controller.ErrInvalidPfp, problemUnsupportedPfpFormat, detailUnsupportedPfpFormat},
+	{controller.ErrPfpNotFound, problemPfpNotFound, detailPfpNotFound},
+	{controller.ErrInvalidLogLevel, problemInvalidLogLevel, detailInvalidLogLevel},
+	{controller.ErrUnknownComponent, problemComponentNotFound, detailComponentNotFound},
+}
+
+// echoProblems maps the errors returned by echo itself: the binding, the router and the middlewares
+var echoProblems = map[int]struct {
+	problem ProblemType
+	detail  problemDetail
+}{
+	http.StatusBadRequest:            {problemInvalidBody, detailInvalidBody},
+	http.StatusNotFound:              {problemInvalidEndpoint, detailInvalidEndpoint},
+	http.StatusMethodNotAllowed:      {problemMethodNotAllowed, detailMethodNotAllowed},
+	http.StatusRequestEntityTooLarge: {problemBodyTooLarge, detailBodyTooLarge},
+	http.StatusUnsupportedMediaType:  {problemUnsupportedMediaType, detailUnsupportedMediaType},
+}
+
+// reason returns the message of the error without the one of the sentinel it wraps, like
+// "the url must be an absolute http or https url" of "invalid webhook: the url must be..."
+func reason(err, sentinel error) string {
+	return strings.TrimPrefix(err.Error(), sentinel.Error()+": ")
+}
+
+// panicError is a panic recovered by recoverMiddleware
+type panicError struct {
+	value interface{}
+	stack []byte
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverMiddleware turns the panics of the handlers into errors, so they get to the
+// error handler with the stack of the goroutine that panicked
+func recoverMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					//the standard library uses it to abort the response on purpose
+					if r == http.ErrAbortHandler {
+						panic(r)
+					}
+					err = &panicError{value: r, stack: debug.Stack()}
+				}
+			}()
+			return next(c)
+		}
+	}
+}
+
+// errorHandler writes every error returned by the handlers and the middlewares as a problem.
+// The unexpected errors are logged with the request id, in production their detail
+// is replaced so the internals (queries, paths, panics) are not sent to the clients
+func errorHandler(l *logrus.Logger, production bool) echo.HTTPErrorHandler {
+	return func(err error, c echo.Context) {
+		problem, detail, why := problemOf(err, production)
+
+		if problem.Status >= http.StatusInternalServerError {
+			entry := logger.FromContext(c.Request().Context(), l).WithError(err)
+			var p *panicError
+			if errors.As(err, &p) {
+				entry = entry.WithField("stack", string(p.stack))
+			}
+			entry.Error("unexpected error handling the request")
+			if production {
+				detail = detailUnexpectedHidden
+			}
+		}
+
+		if c.Response().Committed {
+			//the handler already started the response, there is nothing to fix for the client
+			return
+		}
+		var werr error
+		if c.Request().Method == http.MethodHead {
+			werr = c.NoContent(problem.Status)
+		} else {
+			werr = respError(c, problem, detail, i18n.Params{
+				"reason":     why,
+				"method":     c.Request().Method,
+				"path":       c.Request().URL.Path,
+				"request_id": requestID(c),
+			})
+		}
+		if werr != nil {
+			logger.FromContext(c.Request().Context(), l).WithError(werr).Error("unable to write the error response")
+		}
+	}
+}
+
+// problemOf returns the problem type of the error, the detail for the client and the reason
+// of the error that fills its {reason}, it comes from the controllers and from echo so it's in english
+func problemOf(err error, production bool) (ProblemType, problemDetail, string) {
+	for _, m := range controllerProblems {
+		if errors.Is(err, m.err) {
+			return m.problem, m.detail, reason(err, m.err)
+		}
+	}
+
+	var he *echo.HTTPError
+	if errors.As(err, &he) {
+		why := fmt.Sprint(he.Message)
+		//the internal error is the one of the decoder or of the middleware, useful while developing
+		if he.Internal != nil && !production {
+			why += ": " + he.Internal.Error()
+		}
+		if m, ok := echoProblems[he.Code]; ok {
+			return m.problem, m.detail, why
+		}
+		p := problemHttpError
+		p.Status = he.Code
+		p.Title = strings.ToLower(http.StatusText(he.Code))
+		return p, detailHttpError, why
+	}
+
+	return problemUnexpected, detailUnexpected, err.Error()
+}
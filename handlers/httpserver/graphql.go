@@ -0,0 +1,108 @@
+package httpserver
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/handlers/graphqlserver"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+)
+
+type graphqlHttpHandler struct {
+	e         *echo.Group
+	schema    *graphqlserver.Schema
+	l         *logrus.Logger
+	j         *jwt.JWThandler
+	authLimit *ratelimit.Limiter
+	keyer     *rateLimitKeyer
+}
+
+// NewGraphqlHttpHandler serves the schema, the register and login mutations are limited by authLimit
+// like the endpoints of the user api (apiKeys are the known keys, see RateLimiters)
+func NewGraphqlHttpHandler(e *echo.Group, schema *graphqlserver.Schema, l *logrus.Logger, jwtHandler *jwt.JWThandler, authLimit *ratelimit.Limiter, apiKeys []string) *graphqlHttpHandler {
+	return &graphqlHttpHandler{
+		e:         e,
+		schema:    schema,
+		l:         l,
+		j:         jwtHandler,
+		authLimit: authLimit,
+		keyer:     newRateLimitKeyer(jwtHandler, apiKeys),
+	}
+}
+
+func (h *graphqlHttpHandler) RegisterRoutes() {
+	h.e.POST("/graphql", h.Graphql)
+}
+
+// @Summary		GraphQL
+// @Description	GraphQL endpoint of the users, the schema is in handlers/graphqlserver/schema.graphql (introspection is disabled in production).
+// @Description	The token is optional: without it the email and the role of the users are null and the mutations but register and login fail.
+// @Description	The errors of the resolvers are in the errors of the response with the error type in extensions.code, the status is 200 anyway
+// @ID				Graphql
+// @Tags			graphql
+// @Accept			json
+// @Produce		json
+// @Param			Authorization	header		string					false	"jwt token"	default(Bearer xxx.xxx.xxx)
+// @Param			request			body		graphqlserver.Request	true	"query, operationName and variables"
+// @Success		200				{object}	object
+// @Failure		400				{object}	Problem
+// @Failure		401				{object}	Problem
+// @Failure		429				{object}	Problem
+// @Failure		500				{object}	Problem
+// @Router			/graphql [POST]
+func (h *graphqlHttpHandler) Graphql(c echo.Context) error {
+	req := &graphqlserver.Request{}
+	if authHeader := c.Request().Header.Get("Authorization"); authHeader != "" {
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			return respError(c, problemBrokenBearer, detailBrokenBearer)
+		}
+		claims, err := h.j.ValidateToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			if jwt.IsExpiredError(err) {
+				return respError(c, problemTokenExpired, detailTokenExpired)
+			}
+			return respError(c, problemInvalidToken, detailInvalidToken)
+		}
+		setRequestUser(c, h.l, claims.UserId)
+		req.Claims = claims
+	}
+
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	if req.Query == "" {
+		return respError(c, problemInvalidBody, detailMissingQuery)
+	}
+	req.RequestID = requestID(c)
+	req.AuthLimit = func(ctx context.Context) error {
+		return h.allowAuth(ctx, c)
+	}
+
+	return c.JSON(200, h.schema.Exec(c.Request().Context(), req))
+}
+
+// allowAuth counts a register or a login against the limit of the authentication. The errors of the
+// store don't block the request, like in rateLimitMiddleware
+func (h *graphqlHttpHandler) allowAuth(ctx context.Context, c echo.Context) error {
+	if h.authLimit == nil {
+		return nil
+	}
+	limit := h.authLimit.Limit()
+	if !limit.Enabled() {
+		return nil
+	}
+	r, err := h.authLimit.Allow(ctx, h.keyer.key(c, limit.Key))
+	if err != nil {
+		logger.FromContext(ctx, h.l).Errorf("rate limit %s not applied: %v", h.authLimit.Name(), err)
+		return nil
+	}
+	if r.Allowed {
+		return nil
+	}
+	return graphqlserver.NewError(problemRateLimited.Type, fmt.Sprintf("rate limit exceeded, retry in %s seconds", seconds(r.RetryAfter)))
+}
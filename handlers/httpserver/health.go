@@ -0,0 +1,56 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/pkg/health"
+)
+
+// The probes answer with the plain report instead of the usual envelope,
+// orchestrators only look at the status code and humans at the components
+type healthHttpHandler struct {
+	e      *echo.Echo
+	checks *health.Registry
+}
+
+func NewHealthHttpHandler(e *echo.Echo, checks *health.Registry) *healthHttpHandler {
+	return &healthHttpHandler{
+		e:      e,
+		checks: checks,
+	}
+}
+
+// Registers only the routes and links functions
+func (h *healthHttpHandler) RegisterRoutes() {
+	h.e.GET("/healthz", h.Liveness)
+	h.e.GET("/readyz", h.Readiness)
+}
+
+// @Summary		Liveness probe
+// @Description	Tells if the process is alive, it doesn't check the dependencies
+// @ID				Liveness
+// @Tags			health
+// @Produce		json
+// @Success		200	{object}	health.Report
+// @Router			/healthz [GET]
+func (h *healthHttpHandler) Liveness(c echo.Context) error {
+	return c.JSON(http.StatusOK, h.checks.Live())
+}
+
+// @Summary		Readiness probe
+// @Description	Checks every dependency and tells if the service can handle requests
+// @Description	The status is "ok", "degraded" (a non critical component is down), "unavailable" or "shutting_down"
+// @ID				Readiness
+// @Tags			health
+// @Produce		json
+// @Success		200	{object}	health.Report
+// @Failure		503	{object}	health.Report
+// @Router			/readyz [GET]
+func (h *healthHttpHandler) Readiness(c echo.Context) error {
+	report := h.checks.Ready(c.Request().Context())
+	if !report.IsReady() {
+		return c.JSON(http.StatusServiceUnavailable, report)
+	}
+	return c.JSON(http.StatusOK, report)
+}
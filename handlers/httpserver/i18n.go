@@ -0,0 +1,205 @@
+package httpserver
+
+import (
+	"context"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/locales"
+	"github.com/vano2903/service-template/model"
+	"golang.org/x/text/language"
+)
+
+const (
+	localizerKey = "localizer"
+	userIDKey    = "user_id"
+
+	headerAcceptLanguage  = "Accept-Language"
+	headerContentLanguage = "Content-Language"
+)
+
+// successMessage is the key of the message of a successful response in the locales catalogs
+type successMessage string
+
+const (
+	msgLogLevelsRetrieved  successMessage = "log_levels_retrieved"
+	msgLogLevelSet         successMessage = "log_level_set"
+	msgLogLevelReset       successMessage = "log_level_reset"
+	msgPfpUploaded         successMessage = "pfp_uploaded"
+	msgUserRetrieved       successMessage = "user_retrieved"
+	msgUsersRetrieved      successMessage = "users_retrieved"
+	msgUserCreated         successMessage = "user_created"
+	msgUserLoggedIn        successMessage = "user_logged_in"
+	msgUserUpdated         successMessage = "user_updated"
+	msgPfpGenerating       successMessage = "pfp_generating"
+	msgPfpRegenerated      successMessage = "pfp_regenerated"
+	msgWebhookCreated      successMessage = "webhook_created"
+	msgWebhooksRetrieved   successMessage = "webhooks_retrieved"
+	msgWebhookDeleted      successMessage = "webhook_deleted"
+	msgDeliveriesRetrieved successMessage = "deliveries_retrieved"
+	msgDeliveryScheduled   successMessage = "delivery_scheduled"
+)
+
+var successMessages = []successMessage{
+	msgLogLevelsRetrieved, msgLogLevelSet, msgLogLevelReset, msgPfpUploaded,
+	msgUserRetrieved, msgUsersRetrieved, msgUserCreated, msgUserLoggedIn, msgUserUpdated,
+	msgPfpGenerating, msgPfpRegenerated, msgWebhookCreated, msgWebhooksRetrieved,
+	msgWebhookDeleted, msgDeliveriesRetrieved, msgDeliveryScheduled,
+}
+
+func (m successMessage) key() string {
+	return "success." + string(m)
+}
+
+// problemDetail is the key of the detail of a problem in the locales catalogs, the detail can have
+// placeholders like {id} filled by the params of respError
+type problemDetail string
+
+const (
+	detailInvalidBody                problemDetail = "invalid_body"
+	detailMissingQuery               problemDetail = "missing_query"
+	detailInvalidID                  problemDetail = "invalid_id"
+	detailInvalidUserID              problemDetail = "invalid_userid"
+	detailInvalidLocale              problemDetail = "invalid_locale"
+	detailLocaleNotValid             problemDetail = "locale_not_valid"
+	detailUserAlreadyExists          problemDetail = "user_already_exists"
+	detailEmailTaken                 problemDetail = "email_taken"
+	detailInvalidRole                problemDetail = "invalid_role"
+	detailUnupdatableUser            problemDetail = "unupdatable_user"
+	detailInvalidLogLevel            problemDetail = "invalid_log_level"
+	detailInvalidTTL                 problemDetail = "invalid_ttl"
+	detailMissingPfp                 problemDetail = "missing_pfp"
+	detailInvalidPfp                 problemDetail = "invalid_pfp"
+	detailInvalidAvatarStyle         problemDetail = "invalid_avatar_style"
+	detailInvalidAvatarFile          problemDetail = "invalid_avatar_file"
+	detailInvalidAvatarFormat        problemDetail = "invalid_avatar_format"
+	detailInvalidAvatarSeed          problemDetail = "invalid_avatar_seed"
+	detailInvalidAvatarSize          problemDetail = "invalid_avatar_size"
+	detailInvalidWebhook             problemDetail = "invalid_webhook"
+	detailInvalidStatus              problemDetail = "invalid_status"
+	detailDeliveryNotResendable      problemDetail = "delivery_not_resendable"
+	detailMissingAuthorizationHeader problemDetail = "missing_authorization_header"
+	detailBrokenBearer               problemDetail = "broken_bearer"
+	detailBrokenBearerPrefix         problemDetail = "broken_bearer_prefix"
+	detailInvalidToken               problemDetail = "invalid_token"
+	detailTokenExpired               problemDetail = "token_expired"
+	detailWrongPassword              problemDetail = "wrong_password"
+	detailNotAdmin                   problemDetail = "not_admin"
+	detailNotAdminWebhooks           problemDetail = "not_admin_webhooks"
+	detailNotAdminService            problemDetail = "not_admin_service"
+	detailUnauthorizedUpdate         problemDetail = "unauthorized_update"
+	detailInvalidEndpoint            problemDetail = "invalid_endpoint"
+	detailUserNotFound               problemDetail = "user_not_found"
+	detailUserIDNotFound             problemDetail = "user_id_not_found"
+	detailUserEmailNotFound          problemDetail = "user_email_not_found"
+	detailTokenUserNotFound          problemDetail = "token_user_not_found"
+	detailNoUsersFound               problemDetail = "no_users_found"
+	detailComponentNotFound          problemDetail = "component_not_found"
+	detailPfpNotFound                problemDetail = "pfp_not_found"
+	detailWebhookNotFound            problemDetail = "webhook_not_found"
+	detailDeliveryNotFound           problemDetail = "delivery_not_found"
+	detailMethodNotAllowed           problemDetail = "method_not_allowed"
+	detailNotAcceptable              problemDetail = "not_acceptable"
+	detailBodyTooLarge               problemDetail = "body_too_large"
+	detailUnsupportedMediaType       problemDetail = "unsupported_media_type"
+	detailPfpTooLarge                problemDetail = "pfp_too_large"
+	detailPfpMaxSize                 problemDetail = "pfp_max_size"
+	detailUnsupportedPfpFormat       problemDetail = "unsupported_pfp_format"
+	detailRateLimited                problemDetail = "rate_limited"
+	detailHttpError                  problemDetail = "http_error"
+	detailUnexpected                 problemDetail = "unexpected_error"
+	detailUnexpectedHidden           problemDetail = "unexpected_error_hidden"
+)
+
+var problemDetails = []problemDetail{
+	detailInvalidBody, detailMissingQuery, detailInvalidID, detailInvalidUserID, detailInvalidLocale,
+	detailLocaleNotValid, detailUserAlreadyExists, detailEmailTaken, detailInvalidRole, detailUnupdatableUser,
+	detailInvalidLogLevel, detailInvalidTTL, detailMissingPfp, detailInvalidPfp, detailInvalidAvatarStyle,
+	detailInvalidAvatarFile, detailInvalidAvatarFormat, detailInvalidAvatarSeed, detailInvalidAvatarSize,
+	detailInvalidWebhook, detailInvalidStatus, detailDeliveryNotResendable, detailMissingAuthorizationHeader,
+	detailBrokenBearer, detailBrokenBearerPrefix, detailInvalidToken, detailTokenExpired, detailWrongPassword,
+	detailNotAdmin, detailNotAdminWebhooks, detailNotAdminService, detailUnauthorizedUpdate,
+	detailInvalidEndpoint, detailUserNotFound, detailUserIDNotFound, detailUserEmailNotFound,
+	detailTokenUserNotFound, detailNoUsersFound, detailComponentNotFound, detailPfpNotFound,
+	detailWebhookNotFound, detailDeliveryNotFound, detailMethodNotAllowed, detailNotAcceptable,
+	detailBodyTooLarge, detailUnsupportedMediaType, detailPfpTooLarge, detailPfpMaxSize,
+	detailUnsupportedPfpFormat, detailRateLimited, detailHttpError, detailUnexpected, detailUnexpectedHidden,
+}
+
+func (d problemDetail) key() string {
+	return "detail." + string(d)
+}
+
+func problemKey(p ProblemType) string {
+	return "problem." + p.Type
+}
+
+// MessageKeys returns the keys of every message sent by the api, each locale should translate them all
+func MessageKeys() []string {
+	keys := make([]string, 0, len(problemTypes)+len(problemDetails)+len(successMessages))
+	for _, p := range ProblemTypes() {
+		keys = append(keys, problemKey(p))
+	}
+	for _, d := range problemDetails {
+		keys = append(keys, d.key())
+	}
+	for _, m := range successMessages {
+		keys = append(keys, m.key())
+	}
+	return keys
+}
+
+// userGetter returns the user to read its preferred locale (see controller.User)
+type userGetter interface {
+	GetUser(ctx context.Context, id int) (*model.User, error)
+}
+
+// localizer resolves the locale of the request when the first message is translated,
+// after the authentication so the preference of the user wins over the Accept-Language
+type localizer struct {
+	users    userGetter
+	resolved bool
+	tag      language.Tag
+}
+
+func localeMiddleware(users userGetter) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			c.Set(localizerKey, &localizer{users: users})
+			return next(c)
+		}
+	}
+}
+
+// locale returns the locale of the responses of the request: the one preferred by the user,
+// if authenticated and supported, then the best one of the Accept-Language, then the fallback
+func locale(c echo.Context) language.Tag {
+	lz, _ := c.Get(localizerKey).(*localizer)
+	if lz == nil {
+		//the errors of the middlewares before localeMiddleware
+		return locales.Bundle().Match(c.Request().Header.Get(headerAcceptLanguage))
+	}
+	if lz.resolved {
+		return lz.tag
+	}
+
+	preferred := ""
+	if id, ok := c.Get(userIDKey).(int); ok && lz.users != nil {
+		if u, err := lz.users.GetUser(c.Request().Context(), id); err == nil {
+			preferred = u.Locale
+		}
+	}
+	lz.tag = locales.Bundle().Match(preferred, c.Request().Header.Get(headerAcceptLanguage))
+	lz.resolved = true
+	return lz.tag
+}
+
+// localize translates the message in the locale of the request and sets the Content-Language
+func localize(c echo.Context, key, fallback string) string {
+	tag := locale(c)
+	message, found, ok := locales.Bundle().Lookup(tag, key)
+	if !ok {
+		return fallback
+	}
+	c.Response().Header().Set(headerContentLanguage, found.String())
+	return message
+}
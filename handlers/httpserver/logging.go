@@ -0,0 +1,129 @@
+package httpserver
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	headerRequestID = "X-Request-ID"
+	//the id sent by the client ends up in the logs, it must be short and without strange characters
+	maxRequestIDLength = 128
+	//key of the request id in the echo context
+	requestIDKey = "request_id"
+)
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '-', r == '_', r == '.', r == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	id := make([]byte, 16)
+	//crypto/rand never fails on the supported platforms
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// requestIDMiddleware gives an id to every request, the id sent by the client (or by the
+// proxy in front of the service) in the X-Request-ID header is kept so the request can be
+// followed across services. The id is sent back in the response
+func requestIDMiddleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			id := c.Request().Header.Get(headerRequestID)
+			if !validRequestID(id) {
+				id = newRequestID()
+			}
+			c.Set(requestIDKey, id)
+			c.Response().Header().Set(headerRequestID, id)
+			return next(c)
+		}
+	}
+}
+
+// requestID returns the id given to the request by requestIDMiddleware
+func requestID(c echo.Context) string {
+	id, _ := c.Get(requestIDKey).(string)
+	return id
+}
+
+// accessLogMiddleware puts in the context of the request the entry used by the handlers,
+// the controllers and the repo to log, then it writes the access log of the request with the same fields
+func accessLogMiddleware(l *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			begin := time.Now()
+			req := c.Request()
+
+			route := c.Path()
+			if route == "" || route == "/*" {
+				route = unmatchedRoute
+			}
+			fields := logrus.Fields{
+				"request_id": requestID(c),
+				"method":     req.Method,
+				"route":      route,
+			}
+			if span := trace.SpanContextFromContext(req.Context()); span.IsValid() {
+				fields["trace_id"] = span.TraceID().String()
+			}
+			c.SetRequest(req.WithContext(logger.WithEntry(req.Context(), l.WithFields(fields))))
+
+			if err := next(c); err != nil {
+				//same as the metrics, the response is written by the error handler
+				c.Error(err)
+			}
+
+			requestSize := req.ContentLength
+			if requestSize < 0 {
+				requestSize = 0
+			}
+			//the handlers could have added fields (the user id...) to the entry
+			entry := logger.FromContext(c.Request().Context(), l).WithFields(logrus.Fields{
+				"path":      req.URL.Path,
+				"status":    c.Response().Status,
+				"latency":   time.Since(begin).String(),
+				"bytes_in":  requestSize,
+				"bytes_out": c.Response().Size,
+				"remote_ip": c.RealIP(),
+			})
+			switch status := c.Response().Status; {
+			case status >= 500:
+				entry.Error("request failed")
+			case status >= 400:
+				entry.Warn("request rejected")
+			case untracedRoutes[route]:
+				//probes and scraping would flood the logs
+				entry.Debug("request handled")
+			default:
+				entry.Info("request handled")
+			}
+			return nil
+		}
+	}
+}
+
+// setRequestUser adds the id of the authenticated user to the logs of the request
+func setRequestUser(c echo.Context, l *logrus.Logger, userID int) {
+	//read by the localizer for the preferred locale of the user
+	c.Set(userIDKey, userID)
+	ctx := logger.AddFields(c.Request().Context(), l, logrus.Fields{"user_id": userID})
+	c.SetRequest(c.Request().WithContext(ctx))
+}
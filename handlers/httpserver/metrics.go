@@ -0,0 +1,76 @@
+package httpserver
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// requests that didn't match any route share the same label, using the path
+// would let anyone create infinite series by calling random urls
+const unmatchedRoute = "unmatched"
+
+type httpMetrics struct {
+	duration     *prometheus.HistogramVec
+	requestSize  *prometheus.HistogramVec
+	responseSize *prometheus.HistogramVec
+}
+
+// metricsMiddleware records duration, request size and response size of every request
+// by method, route (the pattern, not the path) and status code
+func metricsMiddleware(reg prometheus.Registerer) (echo.MiddlewareFunc, error) {
+	labels := []string{"method", "route", "code"}
+	sizeBuckets := prometheus.ExponentialBuckets(100, 10, 7)
+	m := &httpMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "http",
+			Name:      "request_duration_seconds",
+			Help:      "Duration of the http requests by method, route and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, labels),
+		requestSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "http",
+			Name:      "request_size_bytes",
+			Help:      "Size of the body of the http requests by method, route and status code.",
+			Buckets:   sizeBuckets,
+		}, labels),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "http",
+			Name:      "response_size_bytes",
+			Help:      "Size of the body of the http responses by method, route and status code.",
+			Buckets:   sizeBuckets,
+		}, labels),
+	}
+	for _, c := range []prometheus.Collector{m.duration, m.requestSize, m.responseSize} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			begin := time.Now()
+			if err := next(c); err != nil {
+				//the error handler writes the response, without calling it here the status code would be wrong.
+				//The error is handled so it's not returned
+				c.Error(err)
+			}
+
+			route := c.Path()
+			if route == "" || c.Response().Status == 404 && route == "/*" {
+				route = unmatchedRoute
+			}
+			requestSize := c.Request().ContentLength
+			if requestSize < 0 {
+				requestSize = 0
+			}
+			values := []string{c.Request().Method, route, strconv.Itoa(c.Response().Status)}
+			m.duration.WithLabelValues(values...).Observe(time.Since(begin).Seconds())
+			m.requestSize.WithLabelValues(values...).Observe(float64(requestSize))
+			m.responseSize.WithLabelValues(values...).Observe(float64(c.Response().Size))
+			return nil
+		}
+	}, nil
+}
@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"github.com/vano2903/service-template/pkg/jwt"
+)
+
+// space for the multipart envelope around the image
+const multipartOverhead = 64 << 10
+
+type (
+	HttpPfpThumbnail struct {
+		Size int    `json:"size"`
+		URL  string `json:"url"`
+	}
+
+	pfpHttpHandler struct {
+		user       *echo.Group
+		files      *echo.Group
+		controller *controller.Pfp
+		l          *logrus.Logger
+		j          *jwt.JWThandler
+		maxBytes   int64
+	}
+)
+
+func NewPfpHttpHandler(user, files *echo.Group, c *controller.Pfp, l *logrus.Logger, jwtHandler *jwt.JWThandler, maxBytes int64) *pfpHttpHandler {
+	return &pfpHttpHandler{
+		user:       user,
+		files:      files,
+		controller: c,
+		l:          l,
+		j:          jwtHandler,
+		maxBytes:   maxBytes,
+	}
+}
+
+// Registers only the routes and links functions
+func (h *pfpHttpHandler) RegisterRoutes() {
+	bodyLimit := middleware.BodyLimit(strconv.FormatInt((h.maxBytes+multipartOverhead)/1024, 10) + "K")
+	h.user.POST("/pfp", h.UploadPfp, jwtHeaderChecker(h.j, h.l), bodyLimit)
+
+	h.files.GET("/*", h.GetPfp)
+}
+
+// @Summary		Upload profile picture
+// @Description	Upload a new profile picture (jpeg, png or gif) as multipart form with the image in the "pfp" field
+// @Description	The image is cropped to a square, resized to every thumbnail size (listed from the smallest) and the metadata are removed
+// @Description	The files of the picture uploaded before are deleted
+// @Description	Normal user can only upload their own picture, admins can specify a userid
+// @ID				UploadPfp
+// @Tags			users
+// @Accept			multipart/form-data
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			pfp		formData	file	true	"the image"
+// @Param			userid	query		int		false	"id of the user to update"
+// @Success		200			{object}	HttpSuccess{data=[]HttpPfpThumbnail,code=int,message=string}
+// @Failure		400			{object}	Problem
+// @Failure		401			{object}	Problem
+// @Failure		403			{object}	Problem
+// @Failure		404			{object}	Problem
+// @Failure		413			{object}	Problem
+// @Failure		415			{object}	Problem
+// @Failure		500			{object}	Problem
+// @Router			/user/pfp [POST]
+func (h *pfpHttpHandler) UploadPfp(c echo.Context) error {
+	authHeader := c.Request().Header.Get("Authorization")[bearerHeaderLength:]
+	claims, err := h.j.ValidateToken(authHeader)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	userID := claims.UserId
+	if userIDParam := c.QueryParam("userid"); userIDParam != "" {
+		userID, err = strconv.Atoi(userIDParam)
+		if err != nil {
+			return respError(c, problemInvalidUserID, detailInvalidUserID)
+		}
+	}
+
+	file, err := c.FormFile("pfp")
+	if err != nil {
+		return respError(c, problemMissingPfp, detailMissingPfp)
+	}
+	if file.Size > h.maxBytes {
+		return respError(c, problemPfpTooLarge, detailPfpMaxSize, i18n.Params{"bytes": h.maxBytes})
+	}
+	src, err := file.Open()
+	if err != nil {
+		return respError(c, problemInvalidPfp, detailInvalidPfp, i18n.Params{"reason": err})
+	}
+	defer src.Close()
+
+	urls, err := h.controller.UploadPfp(c.Request().Context(), claims.UserId, userID, src)
+	if err != nil {
+		switch {
+		case err == controller.ErrUserNotFound:
+			return respError(c, problemUserNotFound, detailUserIDNotFound, i18n.Params{"id": userID})
+		case err == controller.ErrNotAdmin:
+			return respError(c, problemUnauthorizedUpdate, detailUnauthorizedUpdate)
+		case err == controller.ErrUnupdatableUser:
+			return respError(c, problemUnupdatableUser, detailUnupdatableUser)
+		case err == controller.ErrPfpTooLarge:
+			return respError(c, problemPfpTooLarge, detailPfpMaxSize, i18n.Params{"bytes": h.maxBytes})
+		case errors.Is(err, controller.ErrInvalidPfp):
+			return respError(c, problemUnsupportedPfpFormat, detailUnsupportedPfpFormat, i18n.Params{"reason": reason(err, controller.ErrInvalidPfp)})
+		default:
+			return fmt.Errorf("unexpected error trying to upload the profile picture of user %d: %w", userID, err)
+		}
+	}
+
+	thumbnails := make([]HttpPfpThumbnail, 0, len(urls))
+	for _, t := range urls {
+		thumbnails = append(thumbnails, HttpPfpThumbnail{Size: t.Size, URL: t.URL})
+	}
+	return respSuccess(c, 200, msgPfpUploaded, thumbnails)
+}
+
+// @Summary		Get uploaded profile picture
+// @Description	Get an uploaded profile picture, the content of an url never changes so it can be cached forever
+// @ID				GetPfp
+// @Tags			users
+// @Produce		png
+// @Produce		jpeg
+// @Param			path	path		string	true	"<user id>/<version>/<size>.<ext>"
+// @Success		200
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/pfp/{path} [GET]
+func (h *pfpHttpHandler) GetPfp(c echo.Context) error {
+	key := "pfp/" + c.Param("*")
+	r, info, err := h.controller.GetPfp(c.Request().Context(), key)
+	if err != nil {
+		if err == controller.ErrPfpNotFound {
+			return respError(c, problemPfpNotFound, detailPfpNotFound, i18n.Params{"path": c.Request().URL.Path})
+		}
+		return fmt.Errorf("unexpected error trying to retrive the profile picture: %w", err)
+	}
+	defer r.Close()
+
+	header := c.Response().Header()
+	header.Set("Cache-Control", "public, max-age=31536000, immutable")
+	if info.ETag != "" {
+		header.Set("ETag", info.ETag)
+		if c.Request().Header.Get("If-None-Match") == info.ETag {
+			return c.NoContent(http.StatusNotModified)
+		}
+	}
+	if !info.LastModified.IsZero() {
+		header.Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+	if info.Size > 0 {
+		header.Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	}
+	return c.Stream(http.StatusOK, info.ContentType, io.Reader(r))
+}
@@ -0,0 +1,170 @@
+package httpserver
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+)
+
+// ProblemType is an entry of the catalog of the errors returned by the api,
+// the Type is the stable identifier clients can rely on
+type ProblemType struct {
+	Type   string `json:"type"`
+	Title  string `json:"title"`
+	Status int    `json:"status"`
+	//when the error is returned and how the client can fix it
+	Description string `json:"description"`
+}
+
+// problemTypes is the catalog indexed by type, filled by newProblemType
+var problemTypes = map[string]ProblemType{}
+
+// newProblemType adds a type to the catalog, the types must be unique
+func newProblemType(typ string, status int, title, description string) ProblemType {
+	if _, ok := problemTypes[typ]; ok {
+		panic(fmt.Sprintf("problem type %q registered twice", typ))
+	}
+	p := ProblemType{
+		Type:        typ,
+		Title:       title,
+		Status:      status,
+		Description: description,
+	}
+	problemTypes[typ] = p
+	return p
+}
+
+// ProblemTypes returns the catalog of the errors sorted by status and type
+func ProblemTypes() []ProblemType {
+	types := make([]ProblemType, 0, len(problemTypes))
+	for _, p := range problemTypes {
+		types = append(types, p)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		if types[i].Status != types[j].Status {
+			return types[i].Status < types[j].Status
+		}
+		return types[i].Type < types[j].Type
+	})
+	return types
+}
+
+// ErrorDocsPath is where the error reference is served, the doc url of a type
+// is the path followed by the type as fragment
+const ErrorDocsPath = "/docs/errors"
+
+// DocUrl returns the url of the documentation of the type, relative to the
+// service if the public url is empty. The problems without a type of their own
+// are about:blank as defined by RFC 7807
+func (p ProblemType) DocUrl(publicUrl string) string {
+	if p.Type == "" {
+		return "about:blank"
+	}
+	return publicUrl + ErrorDocsPath + "#" + p.Type
+}
+
+var (
+	//400
+	problemInvalidBody = newProblemType("invalid_body", http.StatusBadRequest, "invalid body",
+		"The body or the parameters of the request could not be parsed, check that the body is valid json and matches the documented schema.")
+	problemInvalidID = newProblemType("invalid_id", http.StatusBadRequest, "invalid id",
+		"The id in the path is not a number.")
+	problemInvalidUserID = newProblemType("invalid_userid", http.StatusBadRequest, "invalid userid",
+		"The userid query parameter is not a number.")
+	problemInvalidLocale = newProblemType("invalid_locale", http.StatusBadRequest, "invalid locale",
+		"The locale is not a BCP 47 language tag like en or it-IT.")
+	problemUserAlreadyExists = newProblemType("user_already_exists", http.StatusBadRequest, "user already exists",
+		"An user with the same email is already registered, login instead.")
+	problemInvalidRole = newProblemType("invalid_role", http.StatusBadRequest, "invalid role",
+		"The role must be user, admin or unupdatable.")
+	problemUnupdatableUser = newProblemType("unupdatable_user", http.StatusBadRequest, "unupdatable user",
+		"The user is protected and can't be modified.")
+	problemInvalidLogLevel = newProblemType("invalid_log_level", http.StatusBadRequest, "invalid log level",
+		"The level must be one of trace, debug, info, warn, error, fatal or panic and the ttl can't be negative.")
+	problemInvalidTTL = newProblemType("invalid_ttl", http.StatusBadRequest, "invalid ttl",
+		"The ttl is not a valid duration, use the go syntax like 10m or 1h.")
+	problemMissingPfp = newProblemType("missing_pfp", http.StatusBadRequest, "missing image",
+		"The profile picture must be sent as multipart form in the \"pfp\" field.")
+	problemInvalidPfp = newProblemType("invalid_pfp", http.StatusBadRequest, "invalid image",
+		"The uploaded profile picture could not be read.")
+	problemInvalidAvatarStyle = newProblemType("invalid_avatar_style", http.StatusBadRequest, "invalid style",
+		"The style of the avatar must be identicon or initials.")
+	problemInvalidAvatarFile = newProblemType("invalid_avatar_file", http.StatusBadRequest, "invalid file",
+		"The file of the avatar must be <seed>.png or <seed>.svg.")
+	problemInvalidAvatarFormat = newProblemType("invalid_avatar_format", http.StatusBadRequest, "invalid format",
+		"The format of the avatar must be png or svg.")
+	problemInvalidAvatarSeed = newProblemType("invalid_avatar_seed", http.StatusBadRequest, "invalid seed",
+		"The seed of the initials style must be <initials>-<hash>.")
+	problemInvalidAvatarSize = newProblemType("invalid_avatar_size", http.StatusBadRequest, "invalid size",
+		"The size of the avatar is not a number or it is out of the allowed range.")
+	problemInvalidWebhook = newProblemType("invalid_webhook", http.StatusBadRequest, "invalid webhook",
+		"The url or the events of the webhook are not valid, the detail explains which one.")
+	problemInvalidStatus = newProblemType("invalid_status", http.StatusBadRequest, "invalid status",
+		"The status filter must be one of pending, succeeded or dead.")
+	problemDeliveryNotResendable = newProblemType("delivery_not_resendable", http.StatusBadRequest, "delivery not resendable",
+		"Only the dead deliveries can be sent again.")
+
+	//401
+	problemMissingAuthorizationHeader = newProblemType("missing_authorization_header", http.StatusUnauthorized, "missing authorization header",
+		"The endpoint requires the Authorization header with a token obtained by the login.")
+	problemBrokenBearer = newProblemType("broken_bearer", http.StatusUnauthorized, "broken bearer",
+		"The Authorization header must be \"Bearer <token>\".")
+	problemInvalidToken = newProblemType("invalid_token", http.StatusUnauthorized, "invalid token",
+		"The token is malformed or it was not signed by the service, login again.")
+	problemTokenExpired = newProblemType("token_expired", http.StatusUnauthorized, "token expired",
+		"The token has expired, login again.")
+	problemWrongPassword = newProblemType("wrong_password", http.StatusUnauthorized, "wrong password",
+		"The password does not match the one of the user.")
+
+	//403
+	problemNotAdmin = newProblemType("not_admin", http.StatusForbidden, "forbidden",
+		"Only the admins can call the endpoint.")
+	problemUnauthorizedUpdate = newProblemType("unauthorized_update", http.StatusForbidden, "forbidden",
+		"Only the admins can update other users.")
+
+	//404
+	problemInvalidEndpoint = newProblemType("invalid_endpoint", http.StatusNotFound, "invalid endpoint",
+		"There is no endpoint at the requested path.")
+	problemUserNotFound = newProblemType("user_not_found", http.StatusNotFound, "user not found",
+		"There is no user with the given id or email, if it is the user of the token the account was deleted.")
+	problemNoUsersFound = newProblemType("no_users_found", http.StatusNotFound, "no users found",
+		"There are no users to list.")
+	problemComponentNotFound = newProblemType("component_not_found", http.StatusNotFound, "component not found",
+		"There is no log component with the given name, the components are listed by GET /admin/log-levels.")
+	problemPfpNotFound = newProblemType("pfp_not_found", http.StatusNotFound, "profile picture not found",
+		"There is no profile picture at the requested path.")
+	problemWebhookNotFound = newProblemType("webhook_not_found", http.StatusNotFound, "webhook not found",
+		"There is no webhook with the given id.")
+	problemDeliveryNotFound = newProblemType("delivery_not_found", http.StatusNotFound, "delivery not found",
+		"There is no delivery with the given id.")
+
+	//405
+	problemMethodNotAllowed = newProblemType("method_not_allowed", http.StatusMethodNotAllowed, "method not allowed",
+		"The endpoint exists but it doesn't handle the method of the request.")
+
+	//406
+	problemNotAcceptable = newProblemType("not_acceptable", http.StatusNotAcceptable, "not acceptable",
+		"The response can't be encoded in any of the media types of the Accept header, the detail lists the supported ones. The collections can be exported as text/csv.")
+
+	//413, 415
+	problemBodyTooLarge = newProblemType("body_too_large", http.StatusRequestEntityTooLarge, "body too large",
+		"The body of the request is bigger than the endpoint accepts.")
+	problemUnsupportedMediaType = newProblemType("unsupported_media_type", http.StatusUnsupportedMediaType, "unsupported media type",
+		"The Content-Type of the request is not supported by the endpoint, send application/json.")
+	problemPfpTooLarge = newProblemType("pfp_too_large", http.StatusRequestEntityTooLarge, "image too large",
+		"The profile picture is bigger than the allowed size, the detail reports the limit.")
+	problemUnsupportedPfpFormat = newProblemType("unsupported_pfp_format", http.StatusUnsupportedMediaType, "unsupported image format",
+		"The profile picture is not an image of a supported format.")
+
+	//429
+	problemRateLimited = newProblemType("rate_limited", http.StatusTooManyRequests, "too many requests",
+		"The rate limit of the endpoint was exceeded, retry after the seconds in the Retry-After header.")
+
+	//500
+	problemUnexpected = newProblemType("unexpected_error", http.StatusInternalServerError, "unexpected error",
+		"Something went wrong in the service, retry later and report the request_id if it persists.")
+)
+
+// problemHttpError is used for the statuses returned by echo that have no type in the catalog,
+// the status and the title are the ones of the response
+var problemHttpError = ProblemType{}
@@ -0,0 +1,142 @@
+package httpserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+)
+
+const (
+	headerAPIKey = "X-API-Key"
+	//headers of the IETF draft "RateLimit header fields for HTTP"
+	headerRateLimitLimit     = "RateLimit-Limit"
+	headerRateLimitRemaining = "RateLimit-Remaining"
+	headerRateLimitReset     = "RateLimit-Reset"
+	headerRateLimitPolicy    = "RateLimit-Policy"
+)
+
+// RateLimiters limit the requests of the route groups, a nil limiter doesn't limit
+type RateLimiters struct {
+	//register and login
+	Auth *ratelimit.Limiter
+	//every route of the api
+	API *ratelimit.Limiter
+	//the keys of the clients grouped by api key, the requests with another X-API-Key are grouped by ip
+	APIKeys []string
+}
+
+// rateLimitMiddleware limits the requests with the limiter, the requests are grouped by the key of its limit.
+// If the store fails the request is allowed, an unavailable store must not take down the service
+func rateLimitMiddleware(limiter *ratelimit.Limiter, keyer *rateLimitKeyer, l *logrus.Logger) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		if limiter == nil {
+			return next
+		}
+		return func(c echo.Context) error {
+			limit := limiter.Limit()
+			if !limit.Enabled() {
+				return next(c)
+			}
+			r, err := limiter.Allow(c.Request().Context(), keyer.key(c, limit.Key))
+			if err != nil {
+				logger.FromContext(c.Request().Context(), l).Errorf("rate limit %s not applied: %v", limiter.Name(), err)
+				return next(c)
+			}
+
+			h := c.Response().Header()
+			h.Set(headerRateLimitLimit, strconv.Itoa(r.Limit))
+			h.Set(headerRateLimitRemaining, strconv.Itoa(r.Remaining))
+			h.Set(headerRateLimitReset, seconds(r.Reset))
+			policy := fmt.Sprintf("%d;w=%s", limit.Requests, seconds(limit.Period))
+			if limit.Algorithm == ratelimit.TokenBucket {
+				policy += ";burst=" + strconv.Itoa(r.Limit)
+			}
+			h.Set(headerRateLimitPolicy, policy)
+			if r.Allowed {
+				return next(c)
+			}
+
+			h.Set(echo.HeaderRetryAfter, seconds(r.RetryAfter))
+			return respError(c, problemRateLimited, detailRateLimited, i18n.Params{"seconds": seconds(r.RetryAfter)})
+		}
+	}
+}
+
+// ipExtractor returns how the ip of the client is read. Without a trusted proxy it's the address of the
+// connection: X-Forwarded-For and X-Real-IP are set by the clients and a new value would be a new rate
+// limit bucket. Behind a proxy X-Forwarded-For is read, skipping only the addresses of the trusted proxies
+func ipExtractor(conf config.HTTP) echo.IPExtractor {
+	if !conf.TrustProxy {
+		return echo.ExtractIPDirect()
+	}
+	if len(conf.TrustedProxies) == 0 {
+		return echo.ExtractIPFromXFFHeader()
+	}
+	opts := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, cidr := range conf.TrustedProxies {
+		//the cidrs are checked by the validation of the config
+		if _, ipNet, err := net.ParseCIDR(cidr); err == nil {
+			opts = append(opts, echo.TrustIPRange(ipNet))
+		}
+	}
+	return echo.ExtractIPFromXFFHeader(opts...)
+}
+
+// rateLimitKeyer returns the keys the requests are grouped by, only the tokens and the api keys
+// that are valid get their own key: a random value per request must not skip the limit
+type rateLimitKeyer struct {
+	j *jwt.JWThandler
+	//the known api keys by their key, see apiKeyHash
+	apiKeys map[string]bool
+}
+
+func newRateLimitKeyer(j *jwt.JWThandler, apiKeys []string) *rateLimitKeyer {
+	k := &rateLimitKeyer{j: j, apiKeys: make(map[string]bool, len(apiKeys))}
+	for _, apiKey := range apiKeys {
+		k.apiKeys[apiKeyHash(apiKey)] = true
+	}
+	return k
+}
+
+// apiKeyHash is the key of the api key, the keys are secrets so they are not stored as they are
+func apiKeyHash(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return "api_key:" + hex.EncodeToString(sum[:16])
+}
+
+// key returns the key of the request, the requests without a valid token (or a known
+// api key) are grouped by ip so they are limited anyway
+func (k *rateLimitKeyer) key(c echo.Context, key string) string {
+	switch key {
+	case ratelimit.KeyUser:
+		token := strings.TrimPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+		if claims, err := k.j.ValidateToken(token); err == nil {
+			return "user:" + strconv.Itoa(claims.UserId)
+		}
+	case ratelimit.KeyAPIKey:
+		if apiKey := c.Request().Header.Get(headerAPIKey); apiKey != "" {
+			if hash := apiKeyHash(apiKey); k.apiKeys[hash] {
+				return hash
+			}
+		}
+	}
+	return "ip:" + c.RealIP()
+}
+
+// seconds rounds up, a client retrying after the rounded down time would be refused again
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
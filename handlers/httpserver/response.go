@@ -1,30 +1,50 @@
 package httpserver
 
-import "github.com/labstack/echo/v4"
-
-// ConnectionID string `json:"connection_id,omitemtpy"`
-type HttpError struct {
-	Code        int    `json:"code" example:"400"`
-	IsError     bool   `json:"is_error" example:"true"`
-	Message     string `json:"message" example:"Bad Request"`
-	Details     string `json:"details,omitempty" example:"Bad Request With More Info"`
-	Instance    string `json:"instance,omitempty" example:"/api/v1/users/1"`
-	ErrorType   string `json:"error_type,omitempty" example:"invalid_id"`
-	ErrorDocUrl string `json:"error_doc_url,omitempty" example:"https://example.com/docs/errors/invalid_id"`
+import (
+	"fmt"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/pkg/i18n"
+)
+
+// MIMEApplicationProblemJSON is the content type of the errors, see RFC 7807
+const MIMEApplicationProblemJSON = "application/problem+json"
+
+// errorDocsBaseUrl prefixes the doc urls of the problem types, set by InitRouter
+// with the public url of the service
+var errorDocsBaseUrl string
+
+// Problem is the body of every error response, as described by RFC 7807
+type Problem struct {
+	Type     string `json:"type" example:"http://localhost:8080/docs/errors#invalid_id"`
+	Title    string `json:"title" example:"invalid id"`
+	Status   int    `json:"status" example:"400"`
+	Detail   string `json:"detail,omitempty" example:"id \"abc\" is not a valid id as it is not a number"`
+	Instance string `json:"instance,omitempty" example:"/api/v1/user/abc"`
+	//extension members, the type without the url so clients can switch on it
+	ErrorType string `json:"error_type,omitempty" example:"invalid_id"`
+	RequestID string `json:"request_id,omitempty" example:"9b2f6d0c4e8a1f3b5d7c9e0a2b4c6d8e"`
 }
 
-func respError(c echo.Context, code int, message, details, errType string) error {
-	h := HttpError{
-		Instance:    c.Request().RequestURI,
-		IsError:     true,
-		Code:        code,
-		Message:     message,
-		Details:     details,
-		ErrorType:   errType,
-		ErrorDocUrl: "https://example.com/docs/errors/" + errType, //could be a map somwhere, just an example for now
+// respError writes the problem with the detail translated in the locale of the request,
+// the optional params are the values of the placeholders of the detail
+func respError(c echo.Context, p ProblemType, detail problemDetail, params ...i18n.Params) error {
+	var values i18n.Params
+	if len(params) > 0 {
+		values = params[0]
+	}
+	h := Problem{
+		Type:      p.DocUrl(errorDocsBaseUrl),
+		Title:     localize(c, problemKey(p), p.Title),
+		Status:    p.Status,
+		Detail:    i18n.Format(localize(c, detail.key(), string(detail)), values),
+		Instance:  c.Request().URL.Path,
+		ErrorType: p.Type,
+		RequestID: requestID(c),
 	}
 
-	return c.JSON(code, h)
+	c.Response().Header().Set(echo.HeaderContentType, MIMEApplicationProblemJSON)
+	return c.JSON(p.Status, h)
 }
 
 type HttpSuccess struct {
@@ -34,16 +54,31 @@ type HttpSuccess struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-func respSuccess(c echo.Context, code int, message string, data ...interface{}) error {
+// respSuccess encodes the response in the media type preferred by the client (see encoders),
+// the collections can also be exported as csv: only the data is written, a row per element
+func respSuccess(c echo.Context, code int, message successMessage, data ...interface{}) error {
 	h := HttpSuccess{
 		Code:    code,
 		IsError: false,
-		Message: message,
+		Message: localize(c, message.key(), string(message)),
 	}
 
 	if len(data) > 0 {
 		h.Data = data[0]
 	}
 
-	return c.JSON(code, h)
+	c.Response().Header().Add(echo.HeaderVary, echo.HeaderAccept)
+	enc, ok := negotiate(c.Request().Header.Get(echo.HeaderAccept), h.Data)
+	if !ok {
+		return respError(c, problemNotAcceptable, detailNotAcceptable, i18n.Params{"types": produced(h.Data)})
+	}
+	var body interface{} = h
+	if enc.rows {
+		body = h.Data
+	}
+	b, err := enc.encode(body)
+	if err != nil {
+		return fmt.Errorf("unable to encode the response as %s: %w", enc.mime, err)
+	}
+	return c.Blob(code, enc.contentType, b)
 }
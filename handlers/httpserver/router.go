@@ -1,21 +1,29 @@
 package httpserver
 
 import (
-	"fmt"
-	"net/http"
-
 	"github.com/labstack/echo/v4"
-	"github.com/labstack/echo/v4/middleware"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sirupsen/logrus"
 	echoSwagger "github.com/swaggo/echo-swagger"
 	"github.com/vano2903/service-template/config"
 	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/handlers/graphqlserver"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/pkg/i18n"
 	"github.com/vano2903/service-template/pkg/jwt"
 
 	_ "github.com/vano2903/service-template/docs"
 )
 
+// Controllers are all the controllers used by the handlers
+type Controllers struct {
+	User    *controller.User
+	Webhook *controller.Webhook
+	Pfp     *controller.Pfp
+	Admin   *controller.Admin
+}
+
 //The package is called httpserver and not http because it's a bad practice
 //to name a package after a standard library package (net/http in this case)
 
@@ -24,30 +32,84 @@ import (
 //	@title			Go Service Template
 //	@version		1.0
 //	@description	User Management Service
+//	@description	The messages and the titles of the errors are translated in the locale preferred by the user (see /user/update) or in the best one of the Accept-Language header
+//	@description	The responses are encoded as json, msgpack or cbor following the Accept header and the collections can be exported as text/csv, the other types are refused with 406. The errors are always application/problem+json
 //	@contact.name	Vano2903
 //	@contact.url	https://github.com/vano2903
 //	@contact.email	davidevanoncini2003@gmail.com
 //	@host			localhost:8080
 //	@BasePath		/api/v1
-func InitRouter(e *echo.Echo, l *logrus.Logger, userController *controller.User, conf *config.Config) {
-	e.Use(middleware.Logger())
-	e.Use(middleware.Recover())
+func InitRouter(e *echo.Echo, l *logrus.Logger, controllers Controllers, checks *health.Registry, conf *config.Config, limiters RateLimiters) {
+	//the ip of the client is used by the rate limits, the headers can be trusted only if set by a proxy
+	e.IPExtractor = ipExtractor(conf.HTTP)
+	//every error becomes a problem, the internals are hidden from the clients in production
+	e.HTTPErrorHandler = errorHandler(l, conf.App.Profile == config.ProfileProd)
+	e.Use(requestIDMiddleware())
+	//before recover so the panics are recorded as errors in the span
+	e.Use(tracingMiddleware())
+	//after the tracing so the logs have the trace id
+	e.Use(accessLogMiddleware(l))
+	e.Use(recoverMiddleware())
+	//the messages are in the locale preferred by the user or in the one of the Accept-Language
+	var users userGetter
+	if controllers.User != nil {
+		users = controllers.User
+	}
+	e.Use(localeMiddleware(users))
+	if metrics, err := metricsMiddleware(prometheus.DefaultRegisterer); err != nil {
+		l.Errorf("unable to register the http metrics: %v", err)
+	} else {
+		e.Use(metrics)
+	}
 
+	//the type of the errors links to the error reference served below
+	errorDocsBaseUrl = conf.HTTP.PublicUrl
 	echo.NotFoundHandler = func(c echo.Context) error {
 		// render your 404 page
-		return respError(c, http.StatusNotFound, "invalid endpoint", fmt.Sprintf("endpoint %s is not handled", c.Request().URL.Path), "invalid_endpoint")
+		return respError(c, problemInvalidEndpoint, detailInvalidEndpoint, i18n.Params{"path": c.Request().URL.Path})
 	}
 
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
+	errorDocsHttpHandler := NewErrorDocsHttpHandler(e, conf.HTTP.PublicUrl)
+	errorDocsHttpHandler.RegisterRoutes()
 	e.GET("/metrics", echo.WrapHandler(promhttp.Handler()))
 
-	api := e.Group("/api/v1")
-	user := api.Group("/user")
+	//liveness and readiness probes
+	healthHttpHandler := NewHealthHttpHandler(e, checks)
+	healthHttpHandler.RegisterRoutes()
 
-	//user routes
+	//avatars of the local logo provider, outside of the api as they are just images
+	avatarHttpHandler := NewAvatarHttpHandler(e.Group("/avatars"), l)
+	avatarHttpHandler.RegisterRoutes()
 
 	jwtHandler := jwt.NewJWThandler(conf.HTTP.JWTSecret, conf.App.Name+":"+conf.App.Version)
 
-	userHttpHandler := NewUserHttpHandler(user, userController, l, jwtHandler)
+	keyer := newRateLimitKeyer(jwtHandler, limiters.APIKeys)
+	api := e.Group("/api/v1", rateLimitMiddleware(limiters.API, keyer, l))
+	user := api.Group("/user")
+
+	//user routes
+
+	userHttpHandler := NewUserHttpHandler(user, controllers.User, l, jwtHandler, rateLimitMiddleware(limiters.Auth, keyer, l))
 	userHttpHandler.RegisterRoutes()
+
+	//uploaded profile pictures, the images are served outside of the api
+	pfpHttpHandler := NewPfpHttpHandler(user, e.Group("/pfp"), controllers.Pfp, l, jwtHandler, conf.Uploads.PfpMaxSize)
+	pfpHttpHandler.RegisterRoutes()
+
+	//the same user operations as a graphql schema
+	schema := graphqlserver.NewSchema(controllers.User, jwtHandler, l, graphqlserver.Options{
+		Introspection: conf.App.Profile != config.ProfileProd,
+	})
+	graphqlHttpHandler := NewGraphqlHttpHandler(api, schema, l, jwtHandler, limiters.Auth, limiters.APIKeys)
+	graphqlHttpHandler.RegisterRoutes()
+
+	//webhook routes
+	webhooks := api.Group("/webhooks")
+	webhookHttpHandler := NewWebhookHttpHandler(webhooks, controllers.Webhook, l, jwtHandler)
+	webhookHttpHandler.RegisterRoutes()
+
+	//admin routes
+	adminHttpHandler := NewAdminHttpHandler(api.Group("/admin"), controllers.Admin, l, jwtHandler)
+	adminHttpHandler.RegisterRoutes()
 }
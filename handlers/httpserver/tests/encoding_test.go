@@ -0,0 +1,93 @@
+package httpserver
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vmihailenco/msgpack/v5"
+	"gotest.tools/v3/assert"
+)
+
+// negotiated sends the request with the Accept header and returns the response
+func negotiated(t *testing.T, e *echo.Echo, path, token, accept string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec
+}
+
+// content negotiation, cases:
+// [x] json without the Accept header, for wildcards and for unknown parameters
+// [x] msgpack and cbor have the same fields of json
+// [x] the collections are exported as csv, a row per element
+// [x] the quality of the media ranges is respected
+// [x] the types that can't be produced are refused with 406, csv for a single resource too
+func TestContentNegotiation(t *testing.T) {
+	e := newServer(t)
+	token := login(t, e, "user@fixtures.test")
+
+	for _, accept := range []string{"", "*/*", "application/*", "application/json; charset=utf-8"} {
+		rec := negotiated(t, e, "/api/v1/user/me", token, accept)
+		assert.Equal(t, rec.Code, http.StatusOK, accept)
+		assert.Equal(t, rec.Header().Get("Content-Type"), echo.MIMEApplicationJSONCharsetUTF8, accept)
+		assert.Equal(t, rec.Header().Get("Vary"), "Accept")
+	}
+
+	decoders := map[string]func([]byte, interface{}) error{
+		"application/msgpack":   msgpack.Unmarshal,
+		"application/x-msgpack": msgpack.Unmarshal,
+		"application/cbor":      cbor.Unmarshal,
+	}
+	for accept, decode := range decoders {
+		rec := negotiated(t, e, "/api/v1/user/me", token, accept)
+		assert.Equal(t, rec.Code, http.StatusOK, accept)
+		body := struct {
+			Code    int    `json:"code" msgpack:"code" cbor:"code"`
+			Message string `json:"message" msgpack:"message" cbor:"message"`
+			Data    struct {
+				Email string
+			} `json:"data" msgpack:"data" cbor:"data"`
+		}{}
+		assert.NilError(t, decode(rec.Body.Bytes(), &body), accept)
+		assert.Equal(t, body.Code, http.StatusOK)
+		assert.Equal(t, body.Message, "user successfully retrieved")
+		assert.Equal(t, body.Data.Email, "user@fixtures.test")
+	}
+
+	rec := negotiated(t, e, "/api/v1/user/all", "", "text/csv")
+	assert.Equal(t, rec.Code, http.StatusOK)
+	assert.Equal(t, rec.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+	records, err := csv.NewReader(strings.NewReader(rec.Body.String())).ReadAll()
+	assert.NilError(t, err)
+	assert.DeepEqual(t, records[0], []string{"id", "first_name", "last_name", "pfp", "pfp_status", "email"})
+	assert.Equal(t, len(records), 5)
+	assert.Assert(t, strings.Contains(rec.Body.String(), ",user@fixtures.test\n"))
+
+	rec = negotiated(t, e, "/api/v1/user/all", "", "text/csv;q=0.5, application/cbor")
+	assert.Equal(t, rec.Header().Get("Content-Type"), "application/cbor")
+	rec = negotiated(t, e, "/api/v1/user/all", "", "application/xml, text/csv;q=0.1")
+	assert.Equal(t, rec.Header().Get("Content-Type"), "text/csv; charset=utf-8")
+
+	for _, accept := range []string{"application/xml", "text/csv", "application/json;q=0"} {
+		rec := negotiated(t, e, "/api/v1/user/me", token, accept)
+		assert.Equal(t, rec.Code, http.StatusNotAcceptable, accept)
+		assert.Equal(t, rec.Header().Get("Content-Type"), httpserver.MIMEApplicationProblemJSON)
+		problem := httpserver.Problem{}
+		assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+		assert.Equal(t, problem.ErrorType, "not_acceptable")
+	}
+}
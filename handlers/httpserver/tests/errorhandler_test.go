@@ -0,0 +1,116 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/labstack/echo/v4/middleware"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"gotest.tools/v3/assert"
+)
+
+// newFailingServer returns the router with routes that fail in all the ways the error handler maps
+func newFailingServer(t *testing.T, profile string) (*echo.Echo, *test.Hook) {
+	t.Helper()
+	l, hook := test.NewNullLogger()
+	e := newProfileServer(t, l, profile, httpserver.RateLimiters{})
+	e.GET("/fail/panic", func(c echo.Context) error {
+		panic("secret internals")
+	})
+	e.GET("/fail/error", func(c echo.Context) error {
+		return fmt.Errorf("query failed: %w", fmt.Errorf("secret internals"))
+	})
+	e.GET("/fail/sentinel", func(c echo.Context) error {
+		return fmt.Errorf("trying to get the webhook: %w", controller.ErrWebhookNotFound)
+	})
+	e.GET("/fail/unavailable", func(c echo.Context) error {
+		return echo.ErrServiceUnavailable
+	})
+	e.POST("/fail/limited", func(c echo.Context) error {
+		return c.NoContent(http.StatusNoContent)
+	}, middleware.BodyLimit("1K"))
+	return e, hook
+}
+
+func problem(t *testing.T, e *echo.Echo, method, path, body string) (int, httpserver.Problem) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Request-ID", "error-handler-test")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, rec.Header().Get(echo.HeaderContentType), httpserver.MIMEApplicationProblemJSON, rec.Body.String())
+	p := httpserver.Problem{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &p))
+	assert.Equal(t, p.Status, rec.Code)
+	assert.Equal(t, p.RequestID, "error-handler-test")
+	return rec.Code, p
+}
+
+// error handler, cases:
+// [x] the binding errors are invalid_body problems
+// [x] echo's 405 and 413 are problems
+// [x] the statuses without a type are about:blank
+// [x] the wrapped sentinel errors of the controllers are mapped
+// [x] the panics are 500 problems logged with stack and request id
+// [x] in production the detail of the unexpected errors is hidden
+func TestErrorHandler(t *testing.T) {
+	e, hook := newFailingServer(t, config.ProfileDev)
+
+	code, p := problem(t, e, http.MethodPost, "/api/v1/user/register", `{"email": 42}`)
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.Equal(t, p.ErrorType, "invalid_body")
+
+	code, p = problem(t, e, http.MethodPut, "/healthz", "")
+	assert.Equal(t, code, http.StatusMethodNotAllowed)
+	assert.Equal(t, p.ErrorType, "method_not_allowed")
+
+	code, p = problem(t, e, http.MethodPost, "/fail/limited", strings.Repeat("a", 2048))
+	assert.Equal(t, code, http.StatusRequestEntityTooLarge)
+	assert.Equal(t, p.ErrorType, "body_too_large")
+
+	code, p = problem(t, e, http.MethodGet, "/fail/unavailable", "")
+	assert.Equal(t, code, http.StatusServiceUnavailable)
+	assert.Equal(t, p.Type, "about:blank")
+	assert.Equal(t, p.Title, "service unavailable")
+	assert.Equal(t, p.ErrorType, "")
+
+	code, p = problem(t, e, http.MethodGet, "/fail/sentinel", "")
+	assert.Equal(t, code, http.StatusNotFound)
+	assert.Equal(t, p.ErrorType, "webhook_not_found")
+
+	hook.Reset()
+	code, p = problem(t, e, http.MethodGet, "/fail/panic", "")
+	assert.Equal(t, code, http.StatusInternalServerError)
+	assert.Equal(t, p.ErrorType, "unexpected_error")
+	assert.Assert(t, strings.Contains(p.Detail, "secret internals"))
+	var logged *logrus.Entry
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "unexpected error handling the request" {
+			logged = entry
+		}
+	}
+	assert.Assert(t, logged != nil)
+	assert.Equal(t, logged.Data["request_id"], "error-handler-test")
+	assert.Assert(t, strings.Contains(logged.Data["stack"].(string), "errorhandler_test.go"))
+
+	e, _ = newFailingServer(t, config.ProfileProd)
+	for _, path := range []string{"/fail/panic", "/fail/error"} {
+		code, p = problem(t, e, http.MethodGet, path, "")
+		assert.Equal(t, code, http.StatusInternalServerError)
+		assert.Assert(t, !strings.Contains(p.Detail, "secret"), p.Detail)
+		assert.Assert(t, strings.Contains(p.Detail, "error-handler-test"), p.Detail)
+	}
+	code, p = problem(t, e, http.MethodPost, "/api/v1/user/register", `{"email": 42}`)
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.Assert(t, !strings.Contains(p.Detail, "json:"), p.Detail)
+}
@@ -0,0 +1,78 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"gotest.tools/v3/assert"
+)
+
+// graphqlResponse is the body of the graphql responses, ErrorType is set by the problems
+type graphqlResponse struct {
+	Data   json.RawMessage `json:"data"`
+	Errors []struct {
+		Message    string            `json:"message"`
+		Extensions map[string]string `json:"extensions"`
+	} `json:"errors"`
+	ErrorType string `json:"error_type"`
+}
+
+func callGraphql(t *testing.T, e *echo.Echo, query, authorization string) (int, graphqlResponse) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/graphql", strings.NewReader(string(body)))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	resp := graphqlResponse{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	return rec.Code, resp
+}
+
+// graphql endpoint, cases:
+// [x] the token is optional, the restricted fields are null without it
+// [x] a malformed or invalid token is refused with the problem of the other endpoints
+// [x] the errors of the resolvers are in the body with status 200
+// [x] login shares the rate limit of the user login
+func TestGraphql(t *testing.T) {
+	store := ratelimit.NewMemory()
+	e := newLimitedServer(t, httpserver.RateLimiters{
+		Auth: limiter(t, "auth", store, ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 1, Period: time.Hour, Key: ratelimit.KeyIP}),
+	})
+	token := login(t, e, "user@fixtures.test")
+
+	status, resp := callGraphql(t, e, `{ me { email } user(id: "1") { firstName email } }`, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, string(resp.Data), `{"me":null,"user":{"firstName":"Ada","email":null}}`)
+	status, resp = callGraphql(t, e, `{ me { email } }`, "Bearer "+token)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, string(resp.Data), `{"me":{"email":"user@fixtures.test"}}`)
+
+	status, resp = callGraphql(t, e, `{ me { email } }`, "Token "+token)
+	assert.Equal(t, status, http.StatusUnauthorized)
+	assert.Equal(t, resp.ErrorType, "broken_bearer")
+	status, resp = callGraphql(t, e, `{ me { email } }`, "Bearer not.a.token")
+	assert.Equal(t, status, http.StatusUnauthorized)
+	assert.Equal(t, resp.ErrorType, "invalid_token")
+
+	status, resp = callGraphql(t, e, `mutation { deleteUser }`, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, len(resp.Errors), 1)
+	assert.Equal(t, resp.Errors[0].Extensions["code"], "missing_authorization_header")
+
+	//the limit was used by the login above
+	status, resp = callGraphql(t, e, `mutation { login(email: "user@fixtures.test", password: "user-password") { token } }`, "")
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, len(resp.Errors), 1)
+	assert.Equal(t, resp.Errors[0].Extensions["code"], "rate_limited")
+}
@@ -0,0 +1,124 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/locales"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"gotest.tools/v3/assert"
+)
+
+// translations, cases:
+// [x] the fallback catalog has every message, the titles of the problems are the ones of the catalog
+// [x] every locale translates every message of the fallback one
+// [x] the translations have the placeholders of the fallback message
+func TestTranslations(t *testing.T) {
+	b := locales.Bundle()
+	for _, key := range httpserver.MessageKeys() {
+		_, found, ok := b.Lookup(locales.Fallback, key)
+		assert.Assert(t, ok && found == locales.Fallback, "%s is missing in %s", key, locales.Fallback)
+	}
+	for _, p := range httpserver.ProblemTypes() {
+		assert.Equal(t, b.Message(locales.Fallback, "problem."+p.Type), p.Title)
+	}
+
+	assert.Assert(t, len(b.Locales()) > 1)
+	for _, tag := range b.Locales() {
+		assert.DeepEqual(t, b.Missing(tag), []string{})
+		for _, key := range httpserver.MessageKeys() {
+			want := i18n.Placeholders(b.Message(locales.Fallback, key))
+			assert.DeepEqual(t, i18n.Placeholders(b.Message(tag, key)), want)
+		}
+	}
+}
+
+// localized sends the request with the Accept-Language and returns the message or the title of the response
+func localized(t *testing.T, e *echo.Echo, method, path, body, token, acceptLanguage string) (int, string, string) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Accept-Language", acceptLanguage)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	resp := struct {
+		Message string `json:"message"`
+		Title   string `json:"title"`
+	}{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	return rec.Code, resp.Message + resp.Title, rec.Header().Get("Content-Language")
+}
+
+// localized responses, cases:
+// [x] the messages and the titles are in the locale of the Accept-Language
+// [x] an unsupported Accept-Language gets the fallback locale
+// [x] the locale preferred by the user wins over the Accept-Language
+// [x] an invalid locale is refused
+func TestLocalizedResponses(t *testing.T) {
+	e := newServer(t)
+	token := login(t, e, "user@fixtures.test")
+
+	code, message, language := localized(t, e, http.MethodGet, "/api/v1/user/me", "", token, "it-IT,it;q=0.9,en;q=0.8")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, message, "utente ottenuto")
+	assert.Equal(t, language, "it")
+	code, message, _ = localized(t, e, http.MethodGet, "/api/v1/user/abc", "", "", "it")
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.Equal(t, message, "id non valido")
+
+	code, message, language = localized(t, e, http.MethodGet, "/api/v1/user/me", "", token, "ja")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, message, "user successfully retrieved")
+	assert.Equal(t, language, "en")
+
+	code, message, _ = localized(t, e, http.MethodGet, "/api/v1/user/update", `{"locale": "es-MX"}`, token, "it")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, message, "usuario actualizado")
+	code, message, language = localized(t, e, http.MethodGet, "/api/v1/user/me", "", token, "it")
+	assert.Equal(t, code, http.StatusOK)
+	assert.Equal(t, message, "usuario obtenido")
+	assert.Equal(t, language, "es")
+	//without the token the user is unknown
+	_, message, _ = localized(t, e, http.MethodGet, "/api/v1/user/2", "", "", "it")
+	assert.Equal(t, message, "utente ottenuto")
+
+	code, message, _ = localized(t, e, http.MethodGet, "/api/v1/user/update", `{"locale": "not a locale!"}`, token, "")
+	assert.Equal(t, code, http.StatusBadRequest)
+	assert.Equal(t, message, "idioma no válido")
+}
+
+// localizedDetail sends the request with the Accept-Language and returns the detail of the problem
+func localizedDetail(t *testing.T, e *echo.Echo, method, path, body, acceptLanguage string) string {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("Accept-Language", acceptLanguage)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	p := httpserver.Problem{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &p), rec.Body.String())
+	return p.Detail
+}
+
+// localized details, cases:
+// [x] the details of the handlers are translated with their params
+// [x] the details of the error handler are translated, the reason stays in english
+func TestLocalizedDetails(t *testing.T) {
+	e := newServer(t)
+
+	assert.Equal(t, localizedDetail(t, e, http.MethodGet, "/api/v1/user/abc", "", "it"), `l'id "abc" non è valido perché non è un numero`)
+	assert.Equal(t, localizedDetail(t, e, http.MethodGet, "/api/v1/user/99", "", "es"), "no existe ningún usuario con id 99")
+	assert.Equal(t, localizedDetail(t, e, http.MethodGet, "/api/v1/user/99", "", "ja"), "there is no user with 99 as id")
+
+	assert.Equal(t, localizedDetail(t, e, http.MethodPut, "/healthz", "", "it"), "il metodo PUT non è consentito su /healthz")
+	detail := localizedDetail(t, e, http.MethodPost, "/api/v1/user/register", `{"email": 42}`, "es")
+	assert.Assert(t, strings.HasPrefix(detail, "el cuerpo no es válido: "), detail)
+}
@@ -0,0 +1,86 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"gotest.tools/v3/assert"
+)
+
+// problem responses, cases:
+// [x] the errors are application/problem+json with the doc url as type and the path as instance
+// [x] the request id of the response is in the problem
+// [x] the unknown endpoints are problems too
+func TestProblem(t *testing.T) {
+	e := newServer(t)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/abc?fields=all", nil)
+	req.Header.Set("X-Request-ID", "problem-test")
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, rec.Code, http.StatusBadRequest)
+	assert.Equal(t, rec.Header().Get("Content-Type"), httpserver.MIMEApplicationProblemJSON)
+
+	problem := httpserver.Problem{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &problem))
+	assert.DeepEqual(t, problem, httpserver.Problem{
+		Type:      "http://users.test/docs/errors#invalid_id",
+		Title:     "invalid id",
+		Status:    http.StatusBadRequest,
+		Detail:    `id "abc" is not a valid id as it is not a number`,
+		Instance:  "/api/v1/user/abc",
+		ErrorType: "invalid_id",
+		RequestID: "problem-test",
+	})
+
+	resp := call(t, e, http.MethodGet, "/api/v1/nothing", "", "")
+	assert.Equal(t, resp.Code, http.StatusNotFound)
+	assert.Equal(t, resp.ErrorType, "invalid_endpoint")
+	assert.Equal(t, resp.Instance, "/api/v1/nothing")
+}
+
+// error catalog, cases:
+// [x] every type has an error status and a title
+// [x] the reference is served as html and json with an entry per type
+// [x] docs/errors.md is up to date with the catalog
+func TestErrorCatalog(t *testing.T) {
+	types := httpserver.ProblemTypes()
+	assert.Assert(t, len(types) > 0)
+	for _, p := range types {
+		assert.Assert(t, p.Status >= 400 && p.Status < 600, p.Type)
+		assert.Assert(t, p.Title != "" && p.Description != "", p.Type)
+	}
+
+	e := newServer(t)
+	req := httptest.NewRequest(http.MethodGet, "/docs/errors", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	for _, p := range types {
+		assert.Assert(t, strings.Contains(rec.Body.String(), `id="`+p.Type+`"`), p.Type)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/docs/errors.json", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	assert.Equal(t, rec.Code, http.StatusOK)
+	entries := []struct {
+		Type   string `json:"type"`
+		DocUrl string `json:"doc_url"`
+	}{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &entries))
+	assert.Equal(t, len(entries), len(types))
+	assert.Equal(t, entries[0].DocUrl, "http://users.test/docs/errors#"+entries[0].Type)
+
+	reference := &bytes.Buffer{}
+	assert.NilError(t, httpserver.WriteErrorReference(reference))
+	committed, err := os.ReadFile("../../../docs/errors.md")
+	assert.NilError(t, err)
+	assert.Equal(t, string(committed), reference.String(), "docs/errors.md is outdated, run make swag")
+}
@@ -0,0 +1,154 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"gotest.tools/v3/assert"
+)
+
+func limiter(t *testing.T, name string, store ratelimit.Storer, limit ratelimit.Limit) *ratelimit.Limiter {
+	t.Helper()
+	l, err := ratelimit.NewLimiter(name, store, limit)
+	assert.NilError(t, err)
+	return l
+}
+
+// rate limits, cases:
+// [x] the login is refused after the limit with 429, Retry-After and the RateLimit headers
+// [x] the api is limited per user, the requests without a token per ip
+// [x] the limits can be disabled at runtime
+func TestRateLimit(t *testing.T) {
+	store := ratelimit.NewMemory()
+	limiters := httpserver.RateLimiters{
+		Auth: limiter(t, "auth", store, ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 2, Period: time.Hour, Key: ratelimit.KeyIP}),
+		API:  limiter(t, "api", store, ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Requests: 60, Period: time.Hour, Burst: 3, Key: ratelimit.KeyUser}),
+	}
+	e := newLimitedServer(t, limiters)
+
+	//the login also counts for the api
+	admin := login(t, e, "admin@fixtures.test")
+	_, headers := callWithHeaders(t, e, http.MethodPost, "/api/v1/user/login", `{"email": "user@fixtures.test", "password": "user-password"}`, "")
+	assert.Equal(t, headers.Get("RateLimit-Limit"), "2")
+	assert.Equal(t, headers.Get("RateLimit-Remaining"), "0")
+	assert.Equal(t, headers.Get("RateLimit-Policy"), "2;w=3600")
+	resp, headers := callWithHeaders(t, e, http.MethodPost, "/api/v1/user/login", `{"email": "user@fixtures.test", "password": "user-password"}`, "")
+	assert.Equal(t, resp.Code, http.StatusTooManyRequests)
+	assert.Equal(t, resp.ErrorType, "rate_limited")
+	assert.Assert(t, headers.Get("Retry-After") != "")
+
+	//the anonymous bucket was emptied by the logins, the admin has its own
+	resp, headers = callWithHeaders(t, e, http.MethodGet, "/api/v1/user/1", "", "")
+	assert.Equal(t, resp.Code, http.StatusTooManyRequests)
+	assert.Equal(t, headers.Get("RateLimit-Policy"), "60;w=3600;burst=3")
+	assert.Equal(t, headers.Get("Retry-After"), "60")
+	for i := 0; i < 3; i++ {
+		resp = call(t, e, http.MethodGet, "/api/v1/user/me", "", admin)
+		assert.Equal(t, resp.Code, http.StatusOK)
+	}
+	resp = call(t, e, http.MethodGet, "/api/v1/user/me", "", admin)
+	assert.Equal(t, resp.Code, http.StatusTooManyRequests)
+
+	assert.NilError(t, limiters.API.SetLimit(ratelimit.Limit{}))
+	resp, headers = callWithHeaders(t, e, http.MethodGet, "/api/v1/user/me", "", admin)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	assert.Equal(t, headers.Get("RateLimit-Limit"), "")
+}
+
+// loginFrom logs in from the address with the forwarding headers and returns the status
+func loginFrom(e *echo.Echo, remoteAddr, forwardedFor string) int {
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/user/login", strings.NewReader(`{"email": "user@fixtures.test", "password": "user-password"}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.RemoteAddr = remoteAddr
+	if forwardedFor != "" {
+		req.Header.Set(echo.HeaderXForwardedFor, forwardedFor)
+		req.Header.Set(echo.HeaderXRealIP, forwardedFor)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// ip of the clients, cases:
+// [x] without a trusted proxy the forwarding headers are ignored, a spoofed one doesn't get a new bucket
+// [x] behind a proxy the X-Forwarded-For of the trusted proxies is read
+// [x] the X-Forwarded-For of the other addresses is ignored
+func TestRateLimitClientIP(t *testing.T) {
+	l := logrus.New()
+	l.SetLevel(logrus.PanicLevel)
+	authLimit := func() httpserver.RateLimiters {
+		return httpserver.RateLimiters{
+			Auth: limiter(t, "auth", ratelimit.NewMemory(), ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 1, Period: time.Hour, Key: ratelimit.KeyIP}),
+		}
+	}
+
+	e := newConfiguredServer(t, l, authLimit(), func(*config.Config) {})
+	assert.Equal(t, loginFrom(e, "203.0.113.1:1234", ""), http.StatusOK)
+	assert.Equal(t, loginFrom(e, "203.0.113.1:1234", "198.51.100.7"), http.StatusTooManyRequests)
+	assert.Equal(t, loginFrom(e, "203.0.113.1:1234", "198.51.100.8"), http.StatusTooManyRequests)
+
+	e = newConfiguredServer(t, l, authLimit(), func(conf *config.Config) {
+		conf.HTTP.TrustProxy = true
+		conf.HTTP.TrustedProxies = []string{"10.1.0.0/16"}
+	})
+	assert.Equal(t, loginFrom(e, "10.1.0.5:1234", "198.51.100.7"), http.StatusOK)
+	assert.Equal(t, loginFrom(e, "10.1.0.5:1234", "198.51.100.7"), http.StatusTooManyRequests)
+	assert.Equal(t, loginFrom(e, "10.1.0.5:1234", "198.51.100.8"), http.StatusOK)
+	//not the proxy, like a client calling the service directly
+	assert.Equal(t, loginFrom(e, "10.2.0.5:1234", "198.51.100.9"), http.StatusOK)
+	assert.Equal(t, loginFrom(e, "10.2.0.5:1234", "198.51.100.10"), http.StatusTooManyRequests)
+}
+
+// getWithAPIKey reads a user with the X-API-Key and returns the status
+func getWithAPIKey(e *echo.Echo, apiKey string) int {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/user/1", nil)
+	req.Header.Set("X-API-Key", apiKey)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+// api keys, cases:
+// [x] a known api key has its own bucket
+// [x] the unknown api keys are grouped by ip, a random key per request doesn't get a fresh bucket
+func TestRateLimitAPIKey(t *testing.T) {
+	e := newLimitedServer(t, httpserver.RateLimiters{
+		API:     limiter(t, "api", ratelimit.NewMemory(), ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 1, Period: time.Hour, Key: ratelimit.KeyAPIKey}),
+		APIKeys: []string{"client-key"},
+	})
+
+	assert.Equal(t, getWithAPIKey(e, "client-key"), http.StatusOK)
+	assert.Equal(t, getWithAPIKey(e, "client-key"), http.StatusTooManyRequests)
+	assert.Equal(t, getWithAPIKey(e, "random-1"), http.StatusOK)
+	assert.Equal(t, getWithAPIKey(e, "random-2"), http.StatusTooManyRequests)
+	assert.Equal(t, getWithAPIKey(e, ""), http.StatusTooManyRequests)
+}
+
+// the requests are allowed when the store is down
+func TestRateLimitStoreDown(t *testing.T) {
+	srv := miniredis.RunT(t)
+	store := ratelimit.NewRedis(ratelimit.RedisOptions{Address: srv.Addr(), Timeout: 100 * time.Millisecond})
+	defer store.Close(context.Background())
+	e := newLimitedServer(t, httpserver.RateLimiters{
+		API: limiter(t, "api", store, ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Requests: 1, Period: time.Hour, Key: ratelimit.KeyIP}),
+	})
+
+	resp := call(t, e, http.MethodGet, "/api/v1/user/1", "", "")
+	assert.Equal(t, resp.Code, http.StatusOK)
+	resp = call(t, e, http.MethodGet, "/api/v1/user/1", "", "")
+	assert.Equal(t, resp.Code, http.StatusTooManyRequests)
+
+	srv.Close()
+	resp = call(t, e, http.MethodGet, "/api/v1/user/1", "", "")
+	assert.Equal(t, resp.Code, http.StatusOK)
+}
@@ -0,0 +1,175 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// response holds both the success envelope and the problem of the errors,
+// Code is the status of either
+type response struct {
+	Code      int             `json:"code"`
+	IsError   bool            `json:"is_error"`
+	Data      json.RawMessage `json:"data"`
+	Status    int             `json:"status"`
+	Type      string          `json:"type"`
+	ErrorType string          `json:"error_type"`
+	Instance  string          `json:"instance"`
+}
+
+// newServer returns the router with the users of the test fixtures
+func newServer(t *testing.T) *echo.Echo {
+	return newLimitedServer(t, httpserver.RateLimiters{})
+}
+
+func newLimitedServer(t *testing.T, limiters httpserver.RateLimiters) *echo.Echo {
+	t.Helper()
+	l := logrus.New()
+	l.SetLevel(logrus.PanicLevel)
+	return newProfileServer(t, l, config.ProfileDev, limiters)
+}
+
+func newProfileServer(t *testing.T, l *logrus.Logger, profile string, limiters httpserver.RateLimiters) *echo.Echo {
+	t.Helper()
+	return newConfiguredServer(t, l, limiters, func(conf *config.Config) {
+		conf.App.Profile = profile
+	})
+}
+
+// newConfiguredServer returns the router with the config of the tests changed by configure
+func newConfiguredServer(t *testing.T, l *logrus.Logger, limiters httpserver.RateLimiters, configure func(conf *config.Config)) *echo.Echo {
+	t.Helper()
+	repo := mock.NewRepo()
+	users := controller.NewUserController(repo, logo.NewServiceLogo("", ""), l)
+	_, err := fixtures.Test().Apply(context.Background(), users)
+	assert.NilError(t, err)
+
+	conf := &config.Config{}
+	conf.App.Name, conf.App.Version, conf.App.Profile = "users", "test", config.ProfileDev
+	conf.HTTP.JWTSecret = "a-secret-long-enough-for-the-tests"
+	conf.HTTP.PublicUrl = "http://users.test"
+	configure(conf)
+	e := echo.New()
+	httpserver.InitRouter(e, l, httpserver.Controllers{
+		User:    users,
+		Webhook: controller.NewWebhookController(repo, repo, l),
+		Admin:   controller.NewAdminController(repo, nil, l),
+	}, health.NewRegistry(), conf, limiters)
+	return e
+}
+
+func call(t *testing.T, e *echo.Echo, method, path, body, token string) response {
+	t.Helper()
+	resp, _ := callWithHeaders(t, e, method, path, body, token)
+	return resp
+}
+
+func callWithHeaders(t *testing.T, e *echo.Echo, method, path, body, token string) (response, http.Header) {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	resp := response{}
+	assert.NilError(t, json.Unmarshal(rec.Body.Bytes(), &resp), rec.Body.String())
+	if rec.Header().Get(echo.HeaderContentType) == httpserver.MIMEApplicationProblemJSON {
+		resp.Code, resp.IsError = resp.Status, true
+	}
+	assert.Equal(t, resp.Code, rec.Code)
+	return resp, rec.Header()
+}
+
+// login logs in as the user of the fixtures and returns the token
+func login(t *testing.T, e *echo.Echo, email string) string {
+	t.Helper()
+	fixture, ok := fixtures.Test().Find(email)
+	assert.Assert(t, ok, email)
+	body, _ := json.Marshal(map[string]string{"email": fixture.Email, "password": fixture.Password})
+	resp := call(t, e, http.MethodPost, "/api/v1/user/login", string(body), "")
+	assert.Equal(t, resp.Code, http.StatusOK)
+	data := struct {
+		Token string `json:"token"`
+	}{}
+	assert.NilError(t, json.Unmarshal(resp.Data, &data))
+	return data.Token
+}
+
+// users api, cases:
+// [x] the users of the fixtures log in and read their info
+// [x] a wrong password is refused
+// [x] the email of a fixture user can't be registered again
+// [x] the users are readable without a token
+func TestUsersApi(t *testing.T) {
+	e := newServer(t)
+
+	token := login(t, e, "admin@fixtures.test")
+	resp := call(t, e, http.MethodGet, "/api/v1/user/me", "", token)
+	assert.Equal(t, resp.Code, http.StatusOK)
+	me := model.User{}
+	assert.NilError(t, json.Unmarshal(resp.Data, &me))
+	assert.Equal(t, me.Email, "admin@fixtures.test")
+	assert.Equal(t, me.Role, model.RoleAdmin)
+
+	resp = call(t, e, http.MethodPost, "/api/v1/user/login", `{"email": "user@fixtures.test", "password": "wrong"}`, "")
+	assert.Equal(t, resp.Code, http.StatusUnauthorized)
+	assert.Equal(t, resp.ErrorType, "wrong_password")
+
+	resp = call(t, e, http.MethodPost, "/api/v1/user/register", `{"first_name": "again", "email": "user@fixtures.test", "password": "password"}`, "")
+	assert.Equal(t, resp.Code, http.StatusBadRequest)
+	assert.Equal(t, resp.ErrorType, "user_already_exists")
+
+	resp = call(t, e, http.MethodGet, "/api/v1/user/2", "", "")
+	assert.Equal(t, resp.Code, http.StatusOK)
+	assert.Assert(t, strings.Contains(string(resp.Data), "user@fixtures.test"))
+	assert.Assert(t, !strings.Contains(string(resp.Data), "user-password"))
+}
+
+// update of the user, the route is served as GET
+func TestUpdateUser(t *testing.T) {
+	e := newServer(t)
+	token := login(t, e, "user@fixtures.test")
+
+	resp := call(t, e, http.MethodGet, "/api/v1/user/update", `{"first_name": "Grace"}`, token)
+	assert.Equal(t, resp.Code, http.StatusOK)
+
+	resp = call(t, e, http.MethodGet, "/api/v1/user/me", "", token)
+	me := model.User{}
+	assert.NilError(t, json.Unmarshal(resp.Data, &me))
+	assert.Equal(t, me.FirstName, "Grace")
+}
+
+// deleted user, cases:
+// [x] the token of a deleted user gets user_not_found, not an unexpected error
+func TestDeletedUser(t *testing.T) {
+	e := newServer(t)
+	token := login(t, e, "user@fixtures.test")
+	status, resp := callGraphql(t, e, `mutation { deleteUser }`, "Bearer "+token)
+	assert.Equal(t, status, http.StatusOK)
+	assert.Equal(t, string(resp.Data), `{"deleteUser":true}`)
+
+	me := call(t, e, http.MethodGet, "/api/v1/user/me", "", token)
+	assert.Equal(t, me.Code, http.StatusNotFound)
+	assert.Equal(t, me.ErrorType, "user_not_found")
+	update := call(t, e, http.MethodGet, "/api/v1/user/update", `{"first_name": "Grace"}`, token)
+	assert.Equal(t, update.Code, http.StatusNotFound)
+	assert.Equal(t, update.ErrorType, "user_not_found")
+}
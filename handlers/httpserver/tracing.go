@@ -0,0 +1,70 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// the probes and the scraping of the metrics are called every few seconds, tracing them
+// would only bury the traces of the real requests
+var untracedRoutes = map[string]bool{
+	"/metrics": true,
+	"/healthz": true,
+	"/readyz":  true,
+}
+
+// tracingMiddleware starts the span of every request, the trace context sent by the client
+// (traceparent header) is used as parent so the trace continues the one of the caller.
+// The span is in the context of the request, the handlers must pass c.Request().Context() down
+func tracingMiddleware() echo.MiddlewareFunc {
+	tracer := otel.Tracer("github.com/vano2903/service-template/handlers/httpserver")
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			route := c.Path()
+			if untracedRoutes[route] {
+				return next(c)
+			}
+			if route == "" || route == "/*" {
+				route = unmatchedRoute
+			}
+
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+			ctx, span := tracer.Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPMethod(req.Method),
+					semconv.HTTPRoute(route),
+					semconv.HTTPTarget(req.URL.Path),
+					semconv.HTTPUserAgent(req.UserAgent()),
+					attribute.String("http.client_ip", c.RealIP()),
+					attribute.String("http.request_id", requestID(c)),
+				),
+			)
+			defer span.End()
+			c.SetRequest(req.WithContext(ctx))
+
+			if err := next(c); err != nil {
+				//same as the metrics, the response is written by the error handler
+				span.RecordError(err)
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPStatusCode(status))
+			//4xx are the fault of the client, the request was handled correctly
+			if status >= 500 {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return nil
+		}
+	}
+}
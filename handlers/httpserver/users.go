@@ -1,6 +1,7 @@
 package httpserver
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 
@@ -8,8 +9,9 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/vano2903/service-template/controller"
 	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/i18n"
 	"github.com/vano2903/service-template/pkg/jwt"
-	"github.com/vano2903/service-template/repo/mock"
+	"github.com/vano2903/service-template/pkg/logger"
 )
 
 const (
@@ -24,6 +26,7 @@ type (
 		FirstName string `json:"first_name"`
 		LastName  string `json:"last_name"`
 		Pfp       string `json:"pfp"`
+		PfpStatus string `json:"pfp_status"`
 		Email     string `json:"email"`
 	}
 	// We are not going to declare a model for the authorized request as we will just return the model
@@ -41,6 +44,8 @@ type (
 		LastName  string `json:"last_name,omitempty" validate:"optional"`
 		Email     string `json:"email,omitempty" validate:"optional"`
 		Password  string `json:"password,omitempty" validate:"optional"`
+		//BCP 47 tag (en, it-IT...) of the language of the messages sent to the user
+		Locale string `json:"locale,omitempty" validate:"optional" example:"it"`
 	}
 
 	HttpLoginUserPost struct {
@@ -53,15 +58,18 @@ type (
 		controller *controller.User
 		l          *logrus.Logger
 		j          *jwt.JWThandler
+		//limit of the register and of the login
+		authLimit echo.MiddlewareFunc
 	}
 )
 
-func NewUserHttpHandler(e *echo.Group, c *controller.User, l *logrus.Logger, jwtHandler *jwt.JWThandler) *userHttpHandler {
+func NewUserHttpHandler(e *echo.Group, c *controller.User, l *logrus.Logger, jwtHandler *jwt.JWThandler, authLimit echo.MiddlewareFunc) *userHttpHandler {
 	return &userHttpHandler{
 		e:          e,
 		controller: c,
 		l:          l,
 		j:          jwtHandler,
+		authLimit:  authLimit,
 	}
 }
 
@@ -70,23 +78,25 @@ func (h *userHttpHandler) RegisterRoutes() {
 	//user routes
 	h.e.GET("/:id", h.GetUnauthorizedUser)
 	h.e.GET("/all", h.GetAllUnauthorizedUsers)
-	h.e.POST("/register", h.CreateNewUser)
-	h.e.POST("/login", h.LoginUser)
+	h.e.POST("/register", h.CreateNewUser, h.authLimit)
+	h.e.POST("/login", h.LoginUser, h.authLimit)
 
 	h.e.GET("/me", h.GetUserInfo, h.jwtHeaderCheckerMiddleware)
 	h.e.GET("/update", h.UpdateUser, h.jwtHeaderCheckerMiddleware)
+	h.e.POST("/pfp/regenerate", h.RegeneratePfpUrl, h.jwtHeaderCheckerMiddleware)
+	h.e.POST("/pfp/regenerate/:userid", h.RegeneratePfpUrl, h.jwtHeaderCheckerMiddleware)
 }
 
 // @Summary		Get user from ID
 // @Description	Get user from ID for unauthorized users
 // @ID				getUnauthorizedUser
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param			id	path		int	true	"User ID"
 // @Success		200	{object}	HttpSuccess{data=HttpUnauthenticatedUser,code=int,message=string}
-// @Failure		400	{object}	HttpError
-// @Failure		404	{object}	HttpError
-// @Failure		500	{object}	HttpError
+// @Failure		400	{object}	Problem
+// @Failure		404	{object}	Problem
+// @Failure		500	{object}	Problem
 // @Router			/user/{id} [get]
 func (h *userHttpHandler) GetUnauthorizedUser(c echo.Context) error {
 	idParam := c.Param("id")
@@ -95,16 +105,15 @@ func (h *userHttpHandler) GetUnauthorizedUser(c echo.Context) error {
 		//we are not going to log this error as it is not an exception but a user error, we are just
 		//telling the user what he did wrong but not saving the problem.
 		//You could implement a request tracer that generates a request id and logs it, in that cause it would be more useful
-		return respError(c, 400, "invalid id", fmt.Sprintf("id %q is not a valid id as it is not a number", idParam), "invalid_id")
+		return respError(c, problemInvalidID, detailInvalidID, i18n.Params{"id": idParam})
 	}
 
-	user, err := h.controller.GetUser(id)
+	user, err := h.controller.GetUser(c.Request().Context(), id)
 	if err != nil {
-		_, ok := err.(*mock.ErrUserNotFound)
-		if ok {
-			return respError(c, 404, "user not found", fmt.Sprintf("user with id %d not found", id), "user_not_found")
+		if err == controller.ErrUserNotFound {
+			return respError(c, problemUserNotFound, detailUserIDNotFound, i18n.Params{"id": id})
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to retrive user %d", id), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to retrive user %d: %w", id, err)
 		}
 	}
 
@@ -114,62 +123,65 @@ func (h *userHttpHandler) GetUnauthorizedUser(c echo.Context) error {
 		FirstName: user.FirstName,
 		LastName:  user.LastName,
 		Pfp:       user.Pfp,
+		PfpStatus: user.PfpStatus,
 		Email:     user.Email,
 	}
 
-	return respSuccess(c, 200, "user succesfully retrived", httpUser)
+	return respSuccess(c, 200, msgUserRetrieved, httpUser)
 }
 
 // @Summary		Get all user
 // @Description	Get all user for an unauthorized user
 // @ID				getAllUnauthorizedUser
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor,text/csv
 // @Success		200	{object}	HttpSuccess{data=[]HttpUnauthenticatedUser,code=int,message=string}
-// @Failure		404	{object}	HttpError
-// @Failure		500	{object}	HttpError
+// @Failure		404	{object}	Problem
+// @Failure		500	{object}	Problem
 // @Router			/user/all [get]
 func (h *userHttpHandler) GetAllUnauthorizedUsers(c echo.Context) error {
-	users := h.controller.GetAllUsers()
+	users := h.controller.GetAllUsers(c.Request().Context())
 	if len(users) == 0 {
-		return respError(c, 404, "no users found", "no users were found for this unauthorized access", "no_users_found")
+		return respError(c, problemNoUsersFound, detailNoUsersFound)
 	}
-	unauthUser := make([]HttpUnauthenticatedUser, len(users))
+	unauthUser := make([]HttpUnauthenticatedUser, 0, len(users))
 	for _, u := range users {
 		unauthUser = append(unauthUser, HttpUnauthenticatedUser{
 			ID:        u.ID,
 			FirstName: u.FirstName,
 			LastName:  u.LastName,
 			Pfp:       u.Pfp,
+			PfpStatus: u.PfpStatus,
 			Email:     u.Email,
 		})
 	}
 
-	return respSuccess(c, 200, "all users succesfully retrived", unauthUser)
+	return respSuccess(c, 200, msgUsersRetrieved, unauthUser)
 }
 
 // @Summary		Register a new user
 // @Description	Register a new user
 // @ID				CreateNewUser
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param			account	body		HttpNewUserPost	true	"User Informations"
 // @Success		200		{object}	HttpSuccess{data=httpserver.CreateNewUser.HttpNewUserPostResponse,code=int,message=string}
-// @Failure		400		{object}	HttpError
-// @Failure		500		{object}	HttpError
+// @Failure		400		{object}	Problem
+// @Failure		429		{object}	Problem
+// @Failure		500		{object}	Problem
 // @Router			/user/register [POST]
 func (h *userHttpHandler) CreateNewUser(c echo.Context) error {
 	body := HttpNewUserPost{}
 	if err := c.Bind(&body); err != nil {
-		return respError(c, 400, "invalid body", fmt.Sprintf("invalid body: %v", err), "invalid_body")
+		return err
 	}
 
-	newUserID, err := h.controller.CreateUser(body.FirstName, body.LastName, body.Email, body.Password, model.RoleUser)
+	newUserID, err := h.controller.CreateUser(c.Request().Context(), body.FirstName, body.LastName, body.Email, body.Password, model.RoleUser)
 	if err != nil {
 		if err == controller.ErrUserAlreadyExists {
-			return respError(c, 400, "user already exists", fmt.Sprintf("user with email %s already exists", body.Email), "user_already_exists")
+			return respError(c, problemUserAlreadyExists, detailUserAlreadyExists, i18n.Params{"email": body.Email})
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to create user %s", body.Email), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to create user %s: %w", body.Email, err)
 		}
 	}
 
@@ -177,64 +189,64 @@ func (h *userHttpHandler) CreateNewUser(c echo.Context) error {
 		ID int `json:"id"`
 	}
 
-	return respSuccess(c, 200, "user succesfully created", HttpNewUserPostResponse{ID: newUserID})
+	return respSuccess(c, 200, msgUserCreated, HttpNewUserPostResponse{ID: newUserID})
 }
 
 // @Summary		Login
 // @Description	Login user given email and password
 // @ID				LoginUser
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param			credentials	body		HttpLoginUserPost	true	"email and password"
 // @Success		200			{object}	HttpSuccess{data=httpserver.LoginUser.HttpLoginUserPostResponse,code=int,message=string}
-// @Failure		400			{object}	HttpError
-// @Failure		401			{object}	HttpError
-// @Failure		404			{object}	HttpError
-// @Failure		500			{object}	HttpError
+// @Failure		400			{object}	Problem
+// @Failure		401			{object}	Problem
+// @Failure		404			{object}	Problem
+// @Failure		429			{object}	Problem
+// @Failure		500			{object}	Problem
 // @Router			/user/login [POST]
 func (h *userHttpHandler) LoginUser(c echo.Context) error {
 	body := HttpLoginUserPost{}
 	if err := c.Bind(&body); err != nil {
-		return respError(c, 400, "invalid body", fmt.Sprintf("invalid body: %v", err), "invalid_body")
+		return err
 	}
 
-	id, err := h.controller.CheckCredentials(body.Email, body.Password)
+	id, err := h.controller.CheckCredentials(c.Request().Context(), body.Email, body.Password)
 	if err != nil {
 		if err == controller.ErrUserNotFound {
-			return respError(c, 404, "user not found", fmt.Sprintf("there is no user with %s as email", body.Email), "user_not_found")
+			return respError(c, problemUserNotFound, detailUserEmailNotFound, i18n.Params{"email": body.Email})
 		} else if err == controller.ErrWrongPassword {
-			return respError(c, 401, "wrong password", "the password is not valid, check if spelled right", "wrong_password")
+			return respError(c, problemWrongPassword, detailWrongPassword)
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to login user %s", body.Email), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to login user %s: %w", body.Email, err)
 		}
 	}
 
-	user, _ := h.controller.GetUser(id)
+	user, _ := h.controller.GetUser(c.Request().Context(), id)
 
 	jwtString, err := h.j.GenerateToken(user.ID, user.Email, user.Role)
 	if err != nil {
-		h.l.Errorf("unexpected error trying to sign jwt token for user %s: %v", body.Email, err)
-		return respError(c, 500, "unexpected error", "unexpected error trying to generate your login token", "unexpected_error")
+		return fmt.Errorf("unexpected error trying to generate your login token: %w", err)
 	}
-	h.l.Debugf("token generated for user %s: %s", body.Email, jwtString)
+	logger.FromContext(c.Request().Context(), h.l).Debugf("token generated for user %d", user.ID)
 
 	type HttpLoginUserPostResponse struct {
 		Token string `json:"token"`
 	}
 
-	return respSuccess(c, 200, "user succesfully logged in", HttpLoginUserPostResponse{Token: jwtString})
+	return respSuccess(c, 200, msgUserLoggedIn, HttpLoginUserPostResponse{Token: jwtString})
 }
 
 // @Summary		Get user info
 // @Description	Get authenticated user info from jwt
 // @ID				GetUserInfo
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
 // @Success		200			{object}	HttpSuccess{data=model.User,code=int,message=string}
-// @Failure		401			{object}	HttpError
-// @Failure		404			{object}	HttpError
-// @Failure		500			{object}	HttpError
+// @Failure		401			{object}	Problem
+// @Failure		404			{object}	Problem
+// @Failure		500			{object}	Problem
 // @Router			/user/me [GET]
 func (h *userHttpHandler) GetUserInfo(c echo.Context) error {
 	//it wont panic because the middleware already checked it
@@ -244,19 +256,19 @@ func (h *userHttpHandler) GetUserInfo(c echo.Context) error {
 	//we just get the claims and handle the error
 	claims, err := h.j.ValidateToken(authHeader)
 	if err != nil {
-		return respError(c, 401, "invalid token", "invalid token", "invalid_token")
+		return respError(c, problemInvalidToken, detailInvalidToken)
 	}
 
-	user, err := h.controller.GetUser(claims.UserId)
+	user, err := h.controller.GetUser(c.Request().Context(), claims.UserId)
 	if err != nil {
 		if err == controller.ErrUserNotFound {
-			return respError(c, 404, "user not found", fmt.Sprintf("there is no user with %d as id", claims.UserId), "user_not_found")
+			return respError(c, problemUserNotFound, detailUserIDNotFound, i18n.Params{"id": claims.UserId})
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to retrive user %d", claims.UserId), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to retrive user %d: %w", claims.UserId, err)
 		}
 	}
 
-	return respSuccess(c, 200, "user succesfully retrived", user)
+	return respSuccess(c, 200, msgUserRetrieved, user)
 }
 
 // @Summary		Update user
@@ -266,25 +278,25 @@ func (h *userHttpHandler) GetUserInfo(c echo.Context) error {
 // @Description If the ID is not specified the user the update will be applied to the requesting user (only for admins, normal users can't update other users)
 // @ID				UpdateUser
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
 // @Param		user_info	body		HttpUpdateUserPost	true	"users information to update"
 // @Success		200			{object}	HttpSuccess{code=int,message=string}
-// @Failure		400			{object}	HttpError
-// @Failure		401			{object}	HttpError
-// @Failure		404			{object}	HttpError
-// @Failure		500			{object}	HttpError
+// @Failure		400			{object}	Problem
+// @Failure		401			{object}	Problem
+// @Failure		404			{object}	Problem
+// @Failure		500			{object}	Problem
 // @Router			/user/update [POST]
 func (h *userHttpHandler) UpdateUser(c echo.Context) error {
 	authHeader := c.Request().Header.Get("Authorization")[bearerHeaderLength:]
 	claims, err := h.j.ValidateToken(authHeader)
 	if err != nil {
-		return respError(c, 401, "invalid token", "invalid token", "invalid_token")
+		return respError(c, problemInvalidToken, detailInvalidToken)
 	}
 
 	body := HttpUpdateUserPost{}
 	if err := c.Bind(&body); err != nil {
-		return respError(c, 400, "invalid body", fmt.Sprintf("invalid body: %v", err), "invalid_body")
+		return err
 	}
 
 	toUpdateID := claims.UserId
@@ -292,18 +304,18 @@ func (h *userHttpHandler) UpdateUser(c echo.Context) error {
 		toUpdateID = body.ID
 	}
 
-	toUpdate, err := h.controller.GetUser(toUpdateID)
+	toUpdate, err := h.controller.GetUser(c.Request().Context(), toUpdateID)
 	if err != nil {
 		if err == controller.ErrUserNotFound {
-			_, err := h.controller.GetUser(claims.UserId)
+			_, err := h.controller.GetUser(c.Request().Context(), claims.UserId)
 			if err != nil {
 				//this is an extreme case, if the user deleted the account the related jwt should be deleted aswell by putting it in a blacklist
-				return respError(c, 404, "user not found", "the jwt references an user that does not exist, maybe the user deleted the account", "user_not_found")
+				return respError(c, problemUserNotFound, detailTokenUserNotFound)
 			} else {
-				return respError(c, 404, "user not found", fmt.Sprintf("there is no user with %d as id", toUpdateID), "user_not_found")
+				return respError(c, problemUserNotFound, detailUserIDNotFound, i18n.Params{"id": toUpdateID})
 			}
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to retrive user %d", toUpdateID), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to retrive user %d: %w", toUpdateID, err)
 		}
 	}
 
@@ -316,67 +328,84 @@ func (h *userHttpHandler) UpdateUser(c echo.Context) error {
 	} else if body.Password != "" {
 		toUpdate.Password = body.Password
 	}
+	if body.Locale != "" {
+		toUpdate.Locale = body.Locale
+	}
 
-	if err := h.controller.UpdateUser(claims.UserId, toUpdate); err != nil {
+	if err := h.controller.UpdateUser(c.Request().Context(), claims.UserId, toUpdate); err != nil {
 		if err == controller.ErrUnupdatableUser {
-			return respError(c, 400, "unupdatable user", "the user you are trying to update is not updatable", "unupdatable_user")
+			return respError(c, problemUnupdatableUser, detailUnupdatableUser)
+		} else if errors.Is(err, controller.ErrInvalidLocale) {
+			return respError(c, problemInvalidLocale, detailInvalidLocale, i18n.Params{"locale": body.Locale})
 		} else {
 			//we are excluding user not found because we already checked it
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to update user %d", toUpdateID), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to update user %d: %w", toUpdateID, err)
 		}
 	}
-	return respSuccess(c, 200, "user succesfully updated")
+	return respSuccess(c, 200, msgUserUpdated)
 }
 
 // @Summary Regenerate user's pfp
 // @Description Automatically regnerate user's profile picture
 // @Description Normal user do not need to specify anything, admins can specify a userid to update
+// @Description The picture is generated in background: the response is 202 with pfp_status "pending" and the
+// @Description user's pfp_status becomes "ready" (with the new pfp) or "failed" when the generation is over
 // @ID				RegeneratePfpUrl
 // @Tags			users
-// @Produce		json
+// @Produce		json,application/msgpack,application/cbor
 // @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
 // @Param		userid	path		int	false	"id of the user to update"
 // @Success		200			{object}	HttpSuccess{data=httpserver.RegeneratePfpUrl.HttpNewPfp,code=int,message=string}
-// @Failure		400			{object}	HttpError
-// @Failure		401			{object}	HttpError
-// @Failure		404			{object}	HttpError
-// @Failure		500			{object}	HttpError
-// @Router			/user/update [POST]
+// @Success		202			{object}	HttpSuccess{data=httpserver.RegeneratePfpUrl.HttpNewPfp,code=int,message=string}
+// @Failure		400			{object}	Problem
+// @Failure		401			{object}	Problem
+// @Failure		403			{object}	Problem
+// @Failure		404			{object}	Problem
+// @Failure		500			{object}	Problem
+// @Router			/user/pfp/regenerate/{userid} [POST]
 func (h *userHttpHandler) RegeneratePfpUrl(c echo.Context) error {
 	authHeader := c.Request().Header.Get("Authorization")[bearerHeaderLength:]
 	claims, err := h.j.ValidateToken(authHeader)
 	if err != nil {
-		return respError(c, 401, "invalid token", "invalid token", "invalid_token")
+		return respError(c, problemInvalidToken, detailInvalidToken)
 	}
-	u, err := h.controller.GetUser(claims.UserId)
+	u, err := h.controller.GetUser(c.Request().Context(), claims.UserId)
 	if err != nil {
-		return respError(c, 404, "user not found", "the jwt references an user that does not exist, maybe the user deleted the account", "user_not_found")
+		return respError(c, problemUserNotFound, detailTokenUserNotFound)
 	}
 	userIdToUpdate := claims.UserId
 
 	if c.Param("userid") != "" {
 		if u.Role != model.RoleAdmin {
-			return respError(c, 401, "unauthorized", "only admins can update other users", "unauthorized_update")
+			return respError(c, problemUnauthorizedUpdate, detailUnauthorizedUpdate)
 		} else {
 			userIdToUpdate, err = strconv.Atoi(c.Param("userid"))
 			if err != nil {
-				return respError(c, 400, "invalid userid", "the userid must be an integer", "invalid_userid")
+				return respError(c, problemInvalidUserID, detailInvalidUserID)
 			}
 		}
 	}
 
-	if err := h.controller.RegeneratePfp(userIdToUpdate); err != nil {
+	if err := h.controller.RegeneratePfp(c.Request().Context(), userIdToUpdate); err != nil {
 		if err == controller.ErrUserNotFound {
-			return respError(c, 404, "user not found", fmt.Sprintf("there is no user with %d as id", userIdToUpdate), "user_not_found")
+			return respError(c, problemUserNotFound, detailUserIDNotFound, i18n.Params{"id": userIdToUpdate})
 		} else if err == controller.ErrUnupdatableUser {
-			return respError(c, 400, "unupdatable user", "the user you are trying to update is not updatable", "unupdatable_user")
+			return respError(c, problemUnupdatableUser, detailUnupdatableUser)
 		} else {
-			return respError(c, 500, "unexpected error", fmt.Sprintf("unexpected error trying to update user %d", userIdToUpdate), "unexpected_error")
+			return fmt.Errorf("unexpected error trying to update user %d: %w", userIdToUpdate, err)
 		}
 	}
 	type HttpNewPfp struct {
-		NewPfp string `json:"new_pfp"`
+		NewPfp    string `json:"new_pfp"`
+		PfpStatus string `json:"pfp_status"`
+	}
+	u, err = h.controller.GetUser(c.Request().Context(), userIdToUpdate)
+	if err != nil {
+		return fmt.Errorf("unexpected error trying to retrive user %d: %w", userIdToUpdate, err)
+	}
+	if u.PfpStatus == model.PfpStatusPending {
+		//new_pfp is still the old picture
+		return respSuccess(c, 202, msgPfpGenerating, HttpNewPfp{NewPfp: u.Pfp, PfpStatus: u.PfpStatus})
 	}
-	u, _ = h.controller.GetUser(userIdToUpdate)
-	return respSuccess(c, 200, "user succesfully updated with a new profile picture", HttpNewPfp{NewPfp: u.Pfp})
+	return respSuccess(c, 200, msgPfpRegenerated, HttpNewPfp{NewPfp: u.Pfp, PfpStatus: u.PfpStatus})
 }
@@ -0,0 +1,328 @@
+package httpserver
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/i18n"
+	"github.com/vano2903/service-template/pkg/jwt"
+)
+
+type (
+	HttpNewWebhookPost struct {
+		URL string `json:"url"`
+		//event types to subscribe to, empty means every event
+		Events []string `json:"events,omitempty" validate:"optional"`
+		//if empty a random secret is generated
+		Secret string `json:"secret,omitempty" validate:"optional"`
+	}
+
+	// The secret is never returned, except when the webhook is created
+	HttpWebhook struct {
+		ID        int       `json:"id"`
+		URL       string    `json:"url"`
+		Events    []string  `json:"events"`
+		Secret    string    `json:"secret,omitempty"`
+		CreatedBy int       `json:"created_by"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+
+	HttpWebhookAttempt struct {
+		At         time.Time `json:"at"`
+		StatusCode int       `json:"status_code,omitempty"`
+		Error      string    `json:"error,omitempty"`
+		DurationMs int64     `json:"duration_ms"`
+	}
+
+	HttpWebhookDelivery struct {
+		ID             int                  `json:"id"`
+		WebhookID      int                  `json:"webhook_id"`
+		EventID        int                  `json:"event_id"`
+		EventType      string               `json:"event_type"`
+		Status         string               `json:"status"`
+		FailedAttempts int                  `json:"failed_attempts"`
+		NextAttemptAt  *time.Time           `json:"next_attempt_at,omitempty"`
+		CreatedAt      time.Time            `json:"created_at"`
+		Attempts       []HttpWebhookAttempt `json:"attempts"`
+	}
+
+	webhookHttpHandler struct {
+		e          *echo.Group
+		controller *controller.Webhook
+		l          *logrus.Logger
+		j          *jwt.JWThandler
+	}
+)
+
+func NewWebhookHttpHandler(e *echo.Group, c *controller.Webhook, l *logrus.Logger, jwtHandler *jwt.JWThandler) *webhookHttpHandler {
+	return &webhookHttpHandler{
+		e:          e,
+		controller: c,
+		l:          l,
+		j:          jwtHandler,
+	}
+}
+
+// Registers only the routes and links functions,
+// every route requires an admin token
+func (h *webhookHttpHandler) RegisterRoutes() {
+	h.e.Use(jwtHeaderChecker(h.j, h.l))
+
+	h.e.POST("", h.CreateWebhook)
+	h.e.GET("", h.GetAllWebhooks)
+	h.e.DELETE("/:id", h.DeleteWebhook)
+	h.e.GET("/:id/deliveries", h.GetDeliveries)
+	h.e.POST("/deliveries/:id/redeliver", h.Redeliver)
+}
+
+func newHttpWebhook(w *model.Webhook) HttpWebhook {
+	events := w.Events
+	if events == nil {
+		events = []string{}
+	}
+	return HttpWebhook{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    events,
+		CreatedBy: w.CreatedBy,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+func newHttpWebhookDelivery(d *model.WebhookDelivery) HttpWebhookDelivery {
+	h := HttpWebhookDelivery{
+		ID:             d.ID,
+		WebhookID:      d.WebhookID,
+		EventID:        d.EventID,
+		EventType:      d.EventType,
+		Status:         d.Status,
+		FailedAttempts: d.FailedAttempts,
+		CreatedAt:      d.CreatedAt,
+		Attempts:       make([]HttpWebhookAttempt, 0, len(d.Attempts)),
+	}
+	if d.Status == model.DeliveryPending {
+		next := d.NextAttemptAt
+		h.NextAttemptAt = &next
+	}
+	for _, a := range d.Attempts {
+		h.Attempts = append(h.Attempts, HttpWebhookAttempt{
+			At:         a.At,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMs: a.Duration.Milliseconds(),
+		})
+	}
+	return h
+}
+
+// respControllerError maps the errors shared by all the webhook endpoints
+func (h *webhookHttpHandler) respControllerError(c echo.Context, err error, action string) error {
+	switch {
+	case err == controller.ErrNotAdmin:
+		return respError(c, problemNotAdmin, detailNotAdminWebhooks)
+	case err == controller.ErrUserNotFound:
+		return respError(c, problemUserNotFound, detailTokenUserNotFound)
+	case err == controller.ErrWebhookNotFound:
+		return respError(c, problemWebhookNotFound, detailWebhookNotFound)
+	case err == controller.ErrDeliveryNotFound:
+		return respError(c, problemDeliveryNotFound, detailDeliveryNotFound)
+	case errors.Is(err, controller.ErrInvalidWebhook):
+		return respError(c, problemInvalidWebhook, detailInvalidWebhook, i18n.Params{"reason": reason(err, controller.ErrInvalidWebhook)})
+	case errors.Is(err, controller.ErrDeliveryNotResendable):
+		return respError(c, problemDeliveryNotResendable, detailDeliveryNotResendable, i18n.Params{"reason": reason(err, controller.ErrDeliveryNotResendable)})
+	default:
+		return fmt.Errorf("unexpected error trying to %s: %w", action, err)
+	}
+}
+
+func (h *webhookHttpHandler) requesterID(c echo.Context) (int, error) {
+	//it wont panic because the middleware already checked it
+	authHeader := c.Request().Header.Get("Authorization")[bearerHeaderLength:]
+	claims, err := h.j.ValidateToken(authHeader)
+	if err != nil {
+		return 0, err
+	}
+	return claims.UserId, nil
+}
+
+// @Summary		Create webhook
+// @Description	Subscribe an url to the domain events, only for admins
+// @Description	The deliveries are signed with HMAC-SHA256 of "<timestamp>.<body>" using the secret (X-Webhook-Signature and X-Webhook-Timestamp headers)
+// @Description	The secret is returned only in this response
+// @ID				CreateWebhook
+// @Tags			webhooks
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			webhook	body		HttpNewWebhookPost	true	"webhook informations"
+// @Success		200		{object}	HttpSuccess{data=HttpWebhook,code=int,message=string}
+// @Failure		400		{object}	Problem
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/webhooks [POST]
+func (h *webhookHttpHandler) CreateWebhook(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	body := HttpNewWebhookPost{}
+	if err := c.Bind(&body); err != nil {
+		return err
+	}
+
+	w, err := h.controller.CreateWebhook(c.Request().Context(), requesterID, body.URL, body.Events, body.Secret)
+	if err != nil {
+		return h.respControllerError(c, err, "create the webhook")
+	}
+
+	resp := newHttpWebhook(w)
+	resp.Secret = w.Secret
+	return respSuccess(c, 200, msgWebhookCreated, resp)
+}
+
+// @Summary		Get all webhooks
+// @Description	Get all the webhooks, only for admins
+// @ID				GetAllWebhooks
+// @Tags			webhooks
+// @Produce		json,application/msgpack,application/cbor,text/csv
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Success		200		{object}	HttpSuccess{data=[]HttpWebhook,code=int,message=string}
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/webhooks [GET]
+func (h *webhookHttpHandler) GetAllWebhooks(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	webhooks, err := h.controller.GetAllWebhooks(c.Request().Context(), requesterID)
+	if err != nil {
+		return h.respControllerError(c, err, "retrive the webhooks")
+	}
+
+	resp := make([]HttpWebhook, 0, len(webhooks))
+	for _, w := range webhooks {
+		resp = append(resp, newHttpWebhook(w))
+	}
+	return respSuccess(c, 200, msgWebhooksRetrieved, resp)
+}
+
+// @Summary		Delete webhook
+// @Description	Delete a webhook, the pending deliveries won't be sent, only for admins
+// @ID				DeleteWebhook
+// @Tags			webhooks
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			id	path		int	true	"Webhook ID"
+// @Success		200		{object}	HttpSuccess{code=int,message=string}
+// @Failure		400		{object}	Problem
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/webhooks/{id} [DELETE]
+func (h *webhookHttpHandler) DeleteWebhook(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return respError(c, problemInvalidID, detailInvalidID, i18n.Params{"id": idParam})
+	}
+
+	if err := h.controller.DeleteWebhook(c.Request().Context(), requesterID, id); err != nil {
+		return h.respControllerError(c, err, fmt.Sprintf("delete webhook %d", id))
+	}
+	return respSuccess(c, 200, msgWebhookDeleted)
+}
+
+// @Summary		Get webhook deliveries
+// @Description	Delivery log of a webhook with every attempt, only for admins
+// @Description	Use status=dead to get the dead-letter list (deliveries that exhausted the attempts)
+// @ID				GetWebhookDeliveries
+// @Tags			webhooks
+// @Produce		json,application/msgpack,application/cbor,text/csv
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			id		path		int		true	"Webhook ID"
+// @Param			status	query		string	false	"filter by status"	Enums(pending, succeeded, dead)
+// @Success		200		{object}	HttpSuccess{data=[]HttpWebhookDelivery,code=int,message=string}
+// @Failure		400		{object}	Problem
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/webhooks/{id}/deliveries [GET]
+func (h *webhookHttpHandler) GetDeliveries(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return respError(c, problemInvalidID, detailInvalidID, i18n.Params{"id": idParam})
+	}
+
+	status := c.QueryParam("status")
+	switch status {
+	case "", model.DeliveryPending, model.DeliverySucceeded, model.DeliveryDead:
+	default:
+		return respError(c, problemInvalidStatus, detailInvalidStatus, i18n.Params{"status": status})
+	}
+
+	deliveries, err := h.controller.GetDeliveries(c.Request().Context(), requesterID, id, status)
+	if err != nil {
+		return h.respControllerError(c, err, fmt.Sprintf("retrive the deliveries of webhook %d", id))
+	}
+
+	resp := make([]HttpWebhookDelivery, 0, len(deliveries))
+	for _, d := range deliveries {
+		resp = append(resp, newHttpWebhookDelivery(d))
+	}
+	return respSuccess(c, 200, msgDeliveriesRetrieved, resp)
+}
+
+// @Summary		Redeliver
+// @Description	Send a dead delivery again as soon as possible with all the attempts again, the pending and the succeeded ones are not resendable, only for admins
+// @ID				RedeliverWebhookDelivery
+// @Tags			webhooks
+// @Produce		json,application/msgpack,application/cbor
+// @Param Authorization header string  true "jwt token"     default(Bearer xxx.xxx.xxx)
+// @Param			id	path		int	true	"Delivery ID"
+// @Success		200		{object}	HttpSuccess{code=int,message=string}
+// @Failure		400		{object}	Problem
+// @Failure		401		{object}	Problem
+// @Failure		403		{object}	Problem
+// @Failure		404		{object}	Problem
+// @Failure		500		{object}	Problem
+// @Router			/webhooks/deliveries/{id}/redeliver [POST]
+func (h *webhookHttpHandler) Redeliver(c echo.Context) error {
+	requesterID, err := h.requesterID(c)
+	if err != nil {
+		return respError(c, problemInvalidToken, detailInvalidToken)
+	}
+
+	idParam := c.Param("id")
+	id, err := strconv.Atoi(idParam)
+	if err != nil {
+		return respError(c, problemInvalidID, detailInvalidID, i18n.Params{"id": idParam})
+	}
+
+	if err := h.controller.Redeliver(c.Request().Context(), requesterID, id); err != nil {
+		return h.respControllerError(c, err, fmt.Sprintf("redeliver delivery %d", id))
+	}
+	return respSuccess(c, 200, msgDeliveryScheduled)
+}
@@ -0,0 +1,34 @@
+package locales
+
+import (
+	"embed"
+	"sync"
+
+	"github.com/vano2903/service-template/pkg/i18n"
+	"golang.org/x/text/language"
+)
+
+// Fallback is the locale of the messages when the client has no supported preference,
+// its catalog must have every message
+var Fallback = language.English
+
+//go:embed *.yaml
+var catalogs embed.FS
+
+var (
+	bundle     *i18n.Bundle
+	bundleOnce sync.Once
+)
+
+// Bundle returns the catalogs of the messages sent to the users, they are embedded so
+// an invalid catalog is a bug: it panics like fixtures.Test
+func Bundle() *i18n.Bundle {
+	bundleOnce.Do(func() {
+		b := i18n.NewBundle(Fallback)
+		if err := b.LoadFS(catalogs, "."); err != nil {
+			panic(err)
+		}
+		bundle = b
+	})
+	return bundle
+}
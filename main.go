@@ -1,57 +1,146 @@
 package main
 
 import (
-	"log"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
 
-	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/vano2903/service-template/config"
 	"github.com/vano2903/service-template/controller"
-	"github.com/vano2903/service-template/handlers/httpserver"
-	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/handlers/cli"
+	"github.com/vano2903/service-template/pkg/jwt"
 	"github.com/vano2903/service-template/pkg/logger"
 	"github.com/vano2903/service-template/providers/logo"
-	"github.com/vano2903/service-template/repo/mock"
+	"github.com/vano2903/service-template/repo/instrumented"
 )
 
-func main() {
-	conf, err := config.NewConfig()
-	if err != nil {
-		panic(err)
-	}
-
-	l := logger.NewLogger(conf.Log.Level, conf.Log.Type)
-	l.Debug("initizalized logger")
+const usage = `usage: %s [--config <file>] [--profile <profile>] <command>
 
-	if conf.Database.Driver != "mock" {
-		log.Fatal("only mock database is supported in this example")
-	}
+commands:
+  serve                         start the service (the default)
+  config check                  validate the config and list every problem
+  config print                  print the effective config with the secrets redacted
+%s
 
-	//creating the instances for the application
-	repo := mock.NewRepo()
-	logoService := logo.NewServiceLogo(conf.Services.Logo.ApiKey, conf.Services.Logo.BaseUrl)
+flags:
+`
 
-	//creating the controller
-	c := controller.NewUserController(repo, logoService, l)
+func main() {
+	configPath := flag.String("config", "", "path of the config file (default $USERSVC_CONFIG or "+config.DefaultPath+")")
+	profile := flag.String("profile", "", "overlay loaded on top of the config file: dev, staging or prod (default $USERSVC_PROFILE or dev)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), usage, os.Args[0], cli.Usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+	if len(args) == 0 {
+		args = []string{"serve"}
+	}
 
-	GenerateExampleEntries(l, c)
+	conf, err := config.Load(config.Options{Path: *configPath, Profile: *profile})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	//creating the http server
-	e := echo.New()
-	httpserver.InitRouter(e, l, c, conf)
+	switch args[0] {
+	case "config":
+		os.Exit(configCommand(conf, args[1:]))
+	case "serve":
+		if len(args) > 1 {
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+	//every problem is listed so they can be fixed all at once
+	if err := conf.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if args[0] == "serve" {
+		serve(conf)
+		return
+	}
+	os.Exit(runCommand(conf, args))
+}
 
-	//starting the server
-	e.Logger.Fatal(e.Start(":" + conf.HTTP.Port))
+// configCommand runs config check and config print, they don't need the rest of the wiring
+func configCommand(conf *config.Config, args []string) int {
+	if len(args) != 1 || (args[0] != "check" && args[0] != "print") {
+		flag.Usage()
+		return 2
+	}
+	if args[0] == "print" {
+		if err := conf.WriteRedacted(os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+	if err := conf.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if args[0] == "check" {
+		fmt.Printf("config is valid (profile %s, files %v)\n", conf.App.Profile, conf.Sources)
+	}
+	return 0
 }
 
-func GenerateExampleEntries(l *logrus.Logger, c *controller.User) {
-	if _, err := c.CreateUser("Davide", "Vanoncini", "davidevanoncini2003@gmail.com", "password", model.RoleAdmin); err != nil {
-		l.Fatal("unable to create test user")
+// runCommand runs a cli command with the same repo and controllers of the service,
+// the data is saved when the repo is closed
+func runCommand(conf *config.Config, args []string) int {
+	//the output of the commands is on stdout, the logs must not mix with it
+	conf.Log.Outputs = []string{"stderr"}
+	l, output, err := newLogger(conf.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer output.Close()
+	logs := logger.NewLevels(l)
+
+	repo, migrator, err := newRepo(conf.Database)
+	if err != nil {
+		l.Errorf("unable to create the repo: %v", err)
+		return 1
 	}
-	if _, err := c.CreateUser("John", "Doe", "johndoe@bingchilling.cn", "123secure", model.RoleUser); err != nil {
-		l.Fatal("unable to create test user")
+	users, err := instrumented.NewUserRepo(repo, prometheus.NewRegistry(), logs.Component("repo"))
+	if err != nil {
+		l.Errorf("unable to instrument the repo: %v", err)
+		return 1
+	}
+	logoMetrics, err := logo.NewServiceMetrics(prometheus.NewRegistry())
+	if err != nil {
+		l.Errorf("unable to register the logo metrics: %v", err)
+		return 1
+	}
+	logoService, _, err := newLogoService(conf.Services.Logo, logs.Component("logo"), logoMetrics)
+	if err != nil {
+		l.Errorf("unable to create the logo provider: %v", err)
+		return 1
+	}
+	//without the pfp queue the profile pictures are generated by the commands
+	c := controller.NewUserController(users, logoService, logs.Component("controller"))
+	jwtHandler := jwt.NewJWThandler(conf.HTTP.JWTSecret, conf.App.Name+":"+conf.App.Version)
+
+	ctx := context.Background()
+	err = cli.NewCLI(c, jwtHandler, migrator, conf, os.Stdin, os.Stdout, os.Stderr).Run(ctx, args)
+	if closeErr := repo.Close(ctx); closeErr != nil {
+		l.Errorf("unable to close the repo: %v", closeErr)
+		if err == nil {
+			return 1
+		}
 	}
-	if _, err := c.CreateUser("Foo", "Bar", "foo@bar.com", "psw1", model.RoleUnupdatable); err != nil {
-		l.Fatal("unable to create test user")
+	switch {
+	case errors.Is(err, cli.ErrUsage):
+		return 2
+	case err != nil:
+		fmt.Fprintln(os.Stderr, err)
+		return 1
 	}
+	return 0
 }
@@ -0,0 +1,49 @@
+package model
+
+import "time"
+
+// An event is a fact about something that happened to a user (it was created, updated, deleted...).
+// Events are written by the controller in the repo outbox in the same transaction of the change
+// they describe, so that an event is stored if and only if the change is stored.
+// The dispatcher then reads the outbox and delivers the events to the sinks.
+type (
+	Event struct {
+		ID     int
+		Type   string
+		UserID int
+		//json encoded snapshot of the user at the time of the event (see controller.EventUser),
+		//it never contains the password
+		Payload    []byte
+		OccurredAt time.Time
+
+		//delivery state, handled by the dispatcher
+		Attempts      int
+		LastError     string
+		NextAttemptAt time.Time
+		DeliveredAt   time.Time
+	}
+)
+
+const (
+	EventUserCreated    = "user.created"
+	EventUserUpdated    = "user.updated"
+	EventUserBanned     = "user.banned"
+	EventUserDeleted    = "user.deleted"
+	EventPfpRegenerated = "user.pfp_regenerated"
+	EventPfpUploaded    = "user.pfp_uploaded"
+)
+
+// EventTypes are all the types of event that can be published
+var EventTypes = []string{
+	EventUserCreated,
+	EventUserUpdated,
+	EventUserBanned,
+	EventUserDeleted,
+	EventPfpRegenerated,
+	EventPfpUploaded,
+}
+
+// IsDelivered returns true if the event has been delivered to every sink
+func (e *Event) IsDelivered() bool {
+	return !e.DeliveredAt.IsZero()
+}
@@ -12,10 +12,12 @@ type (
 		FirstName                 string //`json:"first_name"`
 		LastName                  string //`json:"last_name"`
 		Pfp                       string //`json:"pfp"`
+		PfpStatus                 string //`json:"pfp_status"`
 		Email                     string //`json:"email"`
 		Password                  string //`json:"password"`
 		Role                      string //`json:"role"`
 		IsBanned                  bool   //`json:"is_banned"`
+		Locale                    string //`json:"locale"`
 		SpecialMagicalSecretField string //`json:"special_magical_secret_field"`
 	}
 )
@@ -25,3 +27,15 @@ const (
 	RoleUser        = "user"
 	RoleUnupdatable = "unupdatable"
 )
+
+func IsRole(role string) bool {
+	return role == RoleAdmin || role == RoleUser || role == RoleUnupdatable
+}
+
+// The profile picture is generated in background after the user is created,
+// until it's ready the pfp is empty (or the previous one when it's regenerated)
+const (
+	PfpStatusPending = "pending"
+	PfpStatusReady   = "ready"
+	PfpStatusFailed  = "failed"
+)
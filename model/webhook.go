@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// A webhook is a subscription of an integrator to the domain events,
+// every event matching the filter is sent to the URL signed with the secret
+type (
+	Webhook struct {
+		ID  int
+		URL string
+		//event types the webhook is subscribed to, empty means every event
+		Events    []string
+		Secret    string
+		CreatedBy int
+		CreatedAt time.Time
+	}
+
+	//A delivery is a single event to send to a single webhook,
+	//it keeps track of every attempt so it can be used as delivery log
+	WebhookDelivery struct {
+		ID        int
+		WebhookID int
+		EventID   int
+		EventType string
+		//body of the request, it's fixed when the delivery is created so every attempt sends the same body
+		Payload  []byte
+		Status   string
+		Attempts []WebhookAttempt
+		//failed attempts since the delivery was created or manually redelivered, when it reaches
+		//the limit the delivery is dead
+		FailedAttempts int
+		NextAttemptAt  time.Time
+		CreatedAt      time.Time
+	}
+
+	WebhookAttempt struct {
+		At         time.Time
+		StatusCode int
+		Error      string
+		Duration   time.Duration
+	}
+)
+
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	//a dead delivery exhausted the attempts, it stays in the dead-letter list until it's manually redelivered
+	DeliveryDead = "dead"
+)
+
+// Accepts returns true if the webhook is subscribed to the event type
+func (w *Webhook) Accepts(eventType string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, t := range w.Events {
+		if t == eventType || t == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,198 @@
+// Package avatar renders deterministic avatars: the same seed always gives the same image.
+// Two styles are supported, identicons (a symmetric 5x5 pattern like github's) and
+// initials on a colored background, both as PNG and SVG.
+package avatar
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"unicode"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	StyleIdenticon = "identicon"
+	StyleInitials  = "initials"
+
+	gridSize = 5
+)
+
+var background = color.RGBA{R: 0xf0, G: 0xf0, B: 0xf0, A: 0xff}
+
+// Hash is the seed used by the avatars of a user, emails are case insensitive
+func Hash(email string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return fmt.Sprintf("%x", sum[:16])
+}
+
+// Initials returns the uppercase first letters of the first and last name
+func Initials(firstName, lastName string) string {
+	var initials []rune
+	for _, name := range []string{firstName, lastName} {
+		for _, r := range strings.TrimSpace(name) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				initials = append(initials, unicode.ToUpper(r))
+				break
+			}
+		}
+	}
+	if len(initials) == 0 {
+		return "?"
+	}
+	return string(initials)
+}
+
+// foreground derives the color from the seed, the saturation and lightness are fixed
+// so that every color is readable on the light background and with white text
+func foreground(seed string) color.RGBA {
+	sum := sha256.Sum256([]byte(seed))
+	hue := float64(int(sum[0])<<8|int(sum[1])) / 65536 * 360
+	return hslToRGB(hue, 0.55, 0.5)
+}
+
+func hslToRGB(h, s, l float64) color.RGBA {
+	c := (1 - abs(2*l-1)) * s
+	hp := h / 60
+	x := c * (1 - abs(mod2(hp)-1))
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g, b = c, x, 0
+	case hp < 2:
+		r, g, b = x, c, 0
+	case hp < 3:
+		r, g, b = 0, c, x
+	case hp < 4:
+		r, g, b = 0, x, c
+	case hp < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	m := l - c/2
+	return color.RGBA{R: uint8((r + m) * 255), G: uint8((g + m) * 255), B: uint8((b + m) * 255), A: 0xff}
+}
+
+func abs(f float64) float64 {
+	if f < 0 {
+		return -f
+	}
+	return f
+}
+
+func mod2(f float64) float64 {
+	for f >= 2 {
+		f -= 2
+	}
+	return f
+}
+
+// grid returns which cells of the identicon are filled, the right half mirrors the left one
+func grid(seed string) [gridSize][gridSize]bool {
+	sum := sha256.Sum256([]byte(seed))
+	var g [gridSize][gridSize]bool
+	i := 0
+	for x := 0; x < (gridSize+1)/2; x++ {
+		for y := 0; y < gridSize; y++ {
+			filled := sum[i]%2 == 0
+			g[y][x] = filled
+			g[y][gridSize-1-x] = filled
+			i++
+		}
+	}
+	return g
+}
+
+// Identicon renders the identicon of the seed as a size x size image
+func Identicon(seed string, size int) image.Image {
+	fg := foreground(seed)
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: background}, image.Point{}, draw.Src)
+
+	//half a cell of margin on every side
+	cell := float64(size) / (gridSize + 1)
+	margin := cell / 2
+	for y, row := range grid(seed) {
+		for x, filled := range row {
+			if !filled {
+				continue
+			}
+			r := image.Rect(
+				int(margin+float64(x)*cell), int(margin+float64(y)*cell),
+				int(margin+float64(x+1)*cell), int(margin+float64(y+1)*cell),
+			)
+			draw.Draw(img, r, &image.Uniform{C: fg}, image.Point{}, draw.Src)
+		}
+	}
+	return img
+}
+
+// IdenticonSVG renders the identicon of the seed as svg
+func IdenticonSVG(seed string, size int) string {
+	fg := foreground(seed)
+	b := &strings.Builder{}
+	fmt.Fprintf(b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 12 12" shape-rendering="crispEdges">`, size, size)
+	fmt.Fprintf(b, `<rect width="12" height="12" fill="%s"/>`, hex(background))
+	for y, row := range grid(seed) {
+		for x, filled := range row {
+			if filled {
+				fmt.Fprintf(b, `<rect x="%d" y="%d" width="2" height="2" fill="%s"/>`, 1+x*2, 1+y*2, hex(fg))
+			}
+		}
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// InitialsImage renders the initials in white on a background derived from the seed.
+// The text is drawn with a small bitmap font and then scaled, it's not pretty but
+// doesn't need any font file, use the svg version where possible.
+func InitialsImage(initials, seed string, size int) image.Image {
+	face := basicfont.Face7x13
+	textWidth := font.MeasureString(face, initials).Ceil()
+	//the text is drawn on a small canvas with some padding and then scaled up
+	small := image.NewRGBA(image.Rect(0, 0, textWidth+8, textWidth+8))
+	draw.Draw(small, small.Bounds(), &image.Uniform{C: foreground(seed)}, image.Point{}, draw.Src)
+	d := &font.Drawer{
+		Dst:  small,
+		Src:  image.White,
+		Face: face,
+		Dot: fixed.P(
+			(small.Bounds().Dx()-textWidth)/2,
+			(small.Bounds().Dy()+face.Ascent-face.Descent)/2,
+		),
+	}
+	d.DrawString(initials)
+
+	img := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.NearestNeighbor.Scale(img, img.Bounds(), small, small.Bounds(), draw.Src, nil)
+	return img
+}
+
+// InitialsSVG renders the initials in white on a background derived from the seed
+func InitialsSVG(initials, seed string, size int) string {
+	return fmt.Sprintf(
+		`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 100 100">`+
+			`<rect width="100" height="100" fill="%s"/>`+
+			`<text x="50" y="50" dy=".35em" text-anchor="middle" font-family="Helvetica, Arial, sans-serif" font-size="42" fill="#ffffff">%s</text>`+
+			`</svg>`,
+		size, size, hex(foreground(seed)), escapeXML(initials),
+	)
+}
+
+func hex(c color.RGBA) string {
+	return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B)
+}
+
+func escapeXML(s string) string {
+	r := strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "'", "&apos;")
+	return r.Replace(s)
+}
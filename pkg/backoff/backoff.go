@@ -0,0 +1,30 @@
+package backoff
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Exponential returns how long to wait before the next attempt given how many attempts already failed,
+// the wait starts from min and doubles every failed attempt without going over max
+func Exponential(failedAttempts int, min, max time.Duration) time.Duration {
+	wait := min
+	for i := 0; i < failedAttempts; i++ {
+		wait *= 2
+		if wait >= max {
+			return max
+		}
+	}
+	return wait
+}
+
+// Jitter returns a random duration between d/2 and d, spreading the retries of
+// different callers so they don't all hit the dependency at the same time.
+// The random source is given by the caller as it's not safe for concurrent use.
+func Jitter(d time.Duration, r *rand.Rand) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + time.Duration(r.Int63n(int64(d-half)+1))
+}
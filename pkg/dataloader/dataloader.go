@@ -0,0 +1,167 @@
+// Package dataloader batches the loads made concurrently (like the fields of a graphql query,
+// resolved in parallel) into a single call of a batch function, and caches the results.
+// The cache is never invalidated by time, a loader is meant to live as long as a request.
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+const (
+	_DEFAULT_WAIT      = time.Millisecond
+	_DEFAULT_MAX_BATCH = 100
+)
+
+// ErrNotFound is returned by Load when the batch function didn't return the key
+var ErrNotFound = errors.New("not found")
+
+type (
+	//BatchFunc returns the values of the keys, the keys without a value must be left out of the map.
+	//The error makes every load of the batch fail
+	BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+	Options struct {
+		//how long the first load of a batch waits for the others, 1ms if 0
+		Wait time.Duration
+		//the batch is fetched as soon as it has this many keys, 100 if 0
+		MaxBatch int
+	}
+
+	result[V any] struct {
+		value V
+		err   error
+		done  chan struct{}
+	}
+
+	batch[K comparable, V any] struct {
+		//the context of the first load, the batch fails if it's cancelled
+		ctx     context.Context
+		keys    []K
+		results []*result[V]
+		timer   *time.Timer
+	}
+)
+
+// Loader is safe for concurrent use, the same key is fetched once: the loads of a key
+// already requested wait for its result. The failed loads are not cached
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+	opts  Options
+
+	mu      sync.Mutex
+	cache   map[K]*result[V]
+	pending *batch[K, V]
+}
+
+func New[K comparable, V any](fetch BatchFunc[K, V], opts Options) *Loader[K, V] {
+	if opts.Wait <= 0 {
+		opts.Wait = _DEFAULT_WAIT
+	}
+	if opts.MaxBatch <= 0 {
+		opts.MaxBatch = _DEFAULT_MAX_BATCH
+	}
+	return &Loader[K, V]{
+		fetch: fetch,
+		opts:  opts,
+		cache: make(map[K]*result[V]),
+	}
+}
+
+// Load returns the value of the key, fetching it with the other keys loaded in the same window
+func (l *Loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	r, ok := l.cache[key]
+	if !ok {
+		r = &result[V]{done: make(chan struct{})}
+		l.cache[key] = r
+		l.enqueue(ctx, key, r)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-r.done:
+		return r.value, r.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+// enqueue adds the key to the pending batch, it must be called with the lock held
+func (l *Loader[K, V]) enqueue(ctx context.Context, key K, r *result[V]) {
+	b := l.pending
+	if b == nil {
+		b = &batch[K, V]{ctx: ctx}
+		b.timer = time.AfterFunc(l.opts.Wait, func() { l.dispatch(b) })
+		l.pending = b
+	}
+	b.keys = append(b.keys, key)
+	b.results = append(b.results, r)
+	if len(b.keys) >= l.opts.MaxBatch {
+		b.timer.Stop()
+		l.pending = nil
+		go l.run(b)
+	}
+}
+
+// dispatch runs the batch when its wait is over, unless it was already run because full
+func (l *Loader[K, V]) dispatch(b *batch[K, V]) {
+	l.mu.Lock()
+	if l.pending != b {
+		l.mu.Unlock()
+		return
+	}
+	l.pending = nil
+	l.mu.Unlock()
+	l.run(b)
+}
+
+func (l *Loader[K, V]) run(b *batch[K, V]) {
+	values, err := l.fetch(b.ctx, b.keys)
+	for i, key := range b.keys {
+		r := b.results[i]
+		switch value, ok := values[key]; {
+		case err != nil:
+			r.err = err
+		case !ok:
+			r.err = ErrNotFound
+		default:
+			r.value = value
+		}
+		if r.err != nil {
+			l.forget(key, r)
+		}
+		close(r.done)
+	}
+}
+
+func (l *Loader[K, V]) forget(key K, r *result[V]) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.cache[key] == r {
+		delete(l.cache, key)
+	}
+}
+
+// Prime caches the value of the key if it's not loaded yet, the values read in other
+// ways (a list, a mutation) don't need to be fetched again
+func (l *Loader[K, V]) Prime(key K, value V) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.cache[key]; ok {
+		return
+	}
+	r := &result[V]{value: value, done: make(chan struct{})}
+	close(r.done)
+	l.cache[key] = r
+}
+
+// Clear removes the key from the cache, the next load fetches it again
+func (l *Loader[K, V]) Clear(key K) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.cache, key)
+}
@@ -0,0 +1,106 @@
+package dataloader
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/pkg/dataloader"
+	"gotest.tools/v3/assert"
+)
+
+// fetcher records the batches, the odd keys have no value
+type fetcher struct {
+	mu      sync.Mutex
+	batches [][]int
+	err     error
+}
+
+func (f *fetcher) fetch(_ context.Context, keys []int) (map[int]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	batch := append([]int(nil), keys...)
+	sort.Ints(batch)
+	f.batches = append(f.batches, batch)
+	if f.err != nil {
+		return nil, f.err
+	}
+	values := map[int]string{}
+	for _, k := range keys {
+		if k%2 == 0 {
+			values[k] = "value"
+		}
+	}
+	return values, nil
+}
+
+// loadAll loads the keys concurrently
+func loadAll(l *dataloader.Loader[int, string], keys ...int) []error {
+	errs := make([]error, len(keys))
+	wg := sync.WaitGroup{}
+	for i, k := range keys {
+		wg.Add(1)
+		go func(i, k int) {
+			defer wg.Done()
+			_, errs[i] = l.Load(context.Background(), k)
+		}(i, k)
+	}
+	wg.Wait()
+	return errs
+}
+
+// batching, cases:
+// [x] the concurrent loads are fetched with a single call, the duplicated keys once
+// [x] the keys left out by the batch function are not found
+// [x] the loaded keys are cached, the cleared ones fetched again
+// [x] the batches are split at the max size
+func TestLoader(t *testing.T) {
+	f := &fetcher{}
+	l := dataloader.New(f.fetch, dataloader.Options{Wait: 20 * time.Millisecond})
+
+	errs := loadAll(l, 2, 4, 4, 3)
+	assert.DeepEqual(t, f.batches, [][]int{{2, 3, 4}})
+	assert.NilError(t, errs[0])
+	assert.NilError(t, errs[2])
+	assert.Assert(t, errors.Is(errs[3], dataloader.ErrNotFound))
+
+	value, err := l.Load(context.Background(), 2)
+	assert.NilError(t, err)
+	assert.Equal(t, value, "value")
+	assert.Equal(t, len(f.batches), 1)
+
+	l.Clear(2)
+	l.Prime(6, "primed")
+	value, err = l.Load(context.Background(), 6)
+	assert.NilError(t, err)
+	assert.Equal(t, value, "primed")
+	loadAll(l, 2)
+	assert.DeepEqual(t, f.batches[1:], [][]int{{2}})
+
+	f.batches = nil
+	l = dataloader.New(f.fetch, dataloader.Options{Wait: time.Second, MaxBatch: 2})
+	begin := time.Now()
+	loadAll(l, 10, 12)
+	assert.Assert(t, time.Since(begin) < time.Second, "the full batch waited")
+	assert.DeepEqual(t, f.batches, [][]int{{10, 12}})
+}
+
+// errors, cases:
+// [x] the error of the batch function fails every load of the batch
+// [x] the failed loads are fetched again
+func TestLoaderErrors(t *testing.T) {
+	f := &fetcher{err: errors.New("repo down")}
+	l := dataloader.New(f.fetch, dataloader.Options{Wait: 20 * time.Millisecond})
+
+	for _, err := range loadAll(l, 2, 4) {
+		assert.ErrorContains(t, err, "repo down")
+	}
+	f.err = nil
+	for _, err := range loadAll(l, 2, 4) {
+		assert.NilError(t, err)
+	}
+	assert.Equal(t, len(f.batches), 2)
+}
@@ -0,0 +1,166 @@
+// Package health keeps the health checks of the dependencies of the service (repo, providers...)
+// and runs them for the readiness probe.
+package health
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const _DEFAULT_TIMEOUT = 2 * time.Second
+
+const (
+	//status of the service
+	StatusOK           = "ok"
+	StatusDegraded     = "degraded"
+	StatusUnavailable  = "unavailable"
+	StatusShuttingDown = "shutting_down"
+
+	//status of a component
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+type (
+	//Checkers are the dependencies that know how to check themselves
+	Checker interface {
+		Check(ctx context.Context) error
+	}
+
+	CheckFunc func(ctx context.Context) error
+
+	check struct {
+		name     string
+		critical bool
+		timeout  time.Duration
+		fn       CheckFunc
+	}
+
+	Report struct {
+		Status     string                     `json:"status"`
+		Components map[string]ComponentReport `json:"components,omitempty"`
+	}
+
+	ComponentReport struct {
+		Status string `json:"status"`
+		//a non critical component being down makes the service degraded but still ready
+		Critical bool   `json:"critical"`
+		Duration string `json:"duration"`
+		Error    string `json:"error,omitempty"`
+	}
+)
+
+// The registry is safe for concurrent use, the checks can be registered while the probes run
+type Registry struct {
+	mu     sync.RWMutex
+	checks []check
+
+	shuttingDown atomic.Bool
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a check, if timeout is not positive the default (2s) is used.
+// A check that doesn't return before the timeout is considered failed.
+func (r *Registry) Register(name string, critical bool, timeout time.Duration, fn CheckFunc) {
+	if timeout <= 0 {
+		timeout = _DEFAULT_TIMEOUT
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, check{name: name, critical: critical, timeout: timeout, fn: fn})
+}
+
+// RegisterChecker is Register for the dependencies implementing Checker
+func (r *Registry) RegisterChecker(name string, critical bool, timeout time.Duration, c Checker) {
+	r.Register(name, critical, timeout, c.Check)
+}
+
+// SetShuttingDown makes the service not ready, the checks are not run anymore
+func (r *Registry) SetShuttingDown() {
+	r.shuttingDown.Store(true)
+}
+
+// Live reports if the process is alive, it doesn't run the checks: a dependency
+// being down must not make the orchestrator restart the service
+func (r *Registry) Live() Report {
+	return Report{Status: StatusOK}
+}
+
+// Ready runs every check concurrently and reports if the service can handle requests:
+// it's unavailable if a critical component is down or the service is shutting down
+func (r *Registry) Ready(ctx context.Context) Report {
+	if r.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+
+	r.mu.RLock()
+	checks := append([]check(nil), r.checks...)
+	r.mu.RUnlock()
+	sort.Slice(checks, func(i, j int) bool { return checks[i].name < checks[j].name })
+
+	reports := make([]ComponentReport, len(checks))
+	wg := sync.WaitGroup{}
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c check) {
+			defer wg.Done()
+			reports[i] = run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Components: make(map[string]ComponentReport, len(checks))}
+	for i, c := range checks {
+		report.Components[c.name] = reports[i]
+		if reports[i].Status == StatusUp {
+			continue
+		}
+		if c.critical {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+// IsReady tells if the status of the report allows to send requests to the service
+func (r Report) IsReady() bool {
+	return r.Status == StatusOK || r.Status == StatusDegraded
+}
+
+func run(ctx context.Context, c check) ComponentReport {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	begin := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- c.fn(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		//the check ignored the context, we don't wait for it
+		err = ctx.Err()
+	}
+
+	report := ComponentReport{
+		Status:   StatusUp,
+		Critical: c.critical,
+		Duration: time.Since(begin).Round(time.Microsecond).String(),
+	}
+	if err != nil {
+		report.Status = StatusDown
+		report.Error = err.Error()
+	}
+	return report
+}
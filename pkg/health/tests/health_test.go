@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/pkg/health"
+	"gotest.tools/v3/assert"
+)
+
+func up(context.Context) error {
+	return nil
+}
+
+func down(context.Context) error {
+	return errors.New("connection refused")
+}
+
+// health checks, cases:
+// [x] every component up is ok
+// [x] a non critical component down is degraded but ready
+// [x] a critical component down is unavailable
+// [x] a check that doesn't answer in time is down
+// [x] the service is not ready while shutting down
+func TestHealth(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		r := health.NewRegistry()
+		r.Register("repo", true, 0, up)
+		r.Register("logo provider", false, 0, up)
+
+		report := r.Ready(context.Background())
+		assert.Equal(t, report.Status, health.StatusOK)
+		assert.Assert(t, report.IsReady())
+		assert.Equal(t, len(report.Components), 2)
+		assert.Equal(t, report.Components["repo"].Status, health.StatusUp)
+	})
+
+	t.Run("degraded", func(t *testing.T) {
+		r := health.NewRegistry()
+		r.Register("repo", true, 0, up)
+		r.Register("logo provider", false, 0, down)
+
+		report := r.Ready(context.Background())
+		assert.Equal(t, report.Status, health.StatusDegraded)
+		assert.Assert(t, report.IsReady())
+		assert.Equal(t, report.Components["logo provider"].Status, health.StatusDown)
+		assert.Equal(t, report.Components["logo provider"].Error, "connection refused")
+	})
+
+	t.Run("unavailable", func(t *testing.T) {
+		r := health.NewRegistry()
+		r.Register("repo", true, 0, down)
+		r.Register("logo provider", false, 0, down)
+
+		report := r.Ready(context.Background())
+		assert.Equal(t, report.Status, health.StatusUnavailable)
+		assert.Assert(t, !report.IsReady())
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		r := health.NewRegistry()
+		block := make(chan struct{})
+		defer close(block)
+		//the check ignores the context, the registry must not wait for it
+		r.Register("repo", true, 20*time.Millisecond, func(context.Context) error {
+			<-block
+			return nil
+		})
+
+		begin := time.Now()
+		report := r.Ready(context.Background())
+		assert.Assert(t, time.Since(begin) < time.Second)
+		assert.Equal(t, report.Status, health.StatusUnavailable)
+		assert.Equal(t, report.Components["repo"].Error, context.DeadlineExceeded.Error())
+	})
+
+	t.Run("shutting down", func(t *testing.T) {
+		r := health.NewRegistry()
+		r.Register("repo", true, 0, up)
+		assert.Assert(t, r.Ready(context.Background()).IsReady())
+
+		r.SetShuttingDown()
+		report := r.Ready(context.Background())
+		assert.Equal(t, report.Status, health.StatusShuttingDown)
+		assert.Assert(t, !report.IsReady())
+		assert.Equal(t, r.Live().Status, health.StatusOK)
+	})
+}
@@ -0,0 +1,147 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+	"gopkg.in/yaml.v3"
+)
+
+var ErrInvalidCatalog = errors.New("invalid catalog")
+
+// Bundle holds the catalogs of the supported locales, a catalog maps the keys of the
+// messages to their translation. The fallback catalog must contain every key, the
+// others can be partial: a missing message is looked up in the parent locale
+// (it-CH -> it) and then in the fallback one
+type Bundle struct {
+	fallback language.Tag
+	catalogs map[language.Tag]map[string]string
+	//the fallback is the first, the matcher returns it when nothing matches
+	tags    []language.Tag
+	matcher language.Matcher
+}
+
+func NewBundle(fallback language.Tag) *Bundle {
+	b := &Bundle{
+		fallback: fallback,
+		catalogs: make(map[language.Tag]map[string]string),
+	}
+	b.Add(fallback, map[string]string{})
+	return b
+}
+
+// Add merges the messages into the catalog of the locale
+func (b *Bundle) Add(tag language.Tag, messages map[string]string) {
+	catalog, ok := b.catalogs[tag]
+	if !ok {
+		catalog = make(map[string]string, len(messages))
+		b.catalogs[tag] = catalog
+		b.tags = append(b.tags, tag)
+		b.matcher = language.NewMatcher(b.tags)
+	}
+	for key, message := range messages {
+		catalog[key] = message
+	}
+}
+
+// LoadFS adds the catalogs in the directory, every file is named <locale>.yaml and
+// contains the messages as a flat map of keys
+func (b *Bundle) LoadFS(fsys fs.FS, dir string) error {
+	files, err := fs.Glob(fsys, path.Join(dir, "*.yaml"))
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".yaml")
+		tag, err := language.Parse(name)
+		if err != nil {
+			return fmt.Errorf("%w: %s is not named after a locale: %v", ErrInvalidCatalog, file, err)
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return err
+		}
+		messages := map[string]string{}
+		if err := yaml.Unmarshal(content, &messages); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidCatalog, file, err)
+		}
+		b.Add(tag, messages)
+	}
+	return nil
+}
+
+// Fallback returns the locale used when no preference matches
+func (b *Bundle) Fallback() language.Tag {
+	return b.fallback
+}
+
+// Locales returns the locales with a catalog, the fallback first
+func (b *Bundle) Locales() []language.Tag {
+	return append([]language.Tag(nil), b.tags...)
+}
+
+// Match returns the supported locale closest to the preferences, they are tried in
+// order and each one can be a locale (it-IT) or an Accept-Language header
+// (it-IT,it;q=0.9,en;q=0.8). The empty and the invalid ones are skipped, if none
+// of them matches the fallback locale is returned
+func (b *Bundle) Match(preferences ...string) language.Tag {
+	for _, preference := range preferences {
+		if preference == "" {
+			continue
+		}
+		desired, _, err := language.ParseAcceptLanguage(preference)
+		if err != nil || len(desired) == 0 {
+			continue
+		}
+		_, index, confidence := b.matcher.Match(desired...)
+		if confidence != language.No {
+			return b.tags[index]
+		}
+	}
+	return b.fallback
+}
+
+// Lookup returns the message of the key in the locale, following the fallback chain,
+// and the locale of the catalog that had it
+func (b *Bundle) Lookup(tag language.Tag, key string) (string, language.Tag, bool) {
+	for t := tag; ; t = t.Parent() {
+		if message, ok := b.catalogs[t][key]; ok {
+			return message, t, true
+		}
+		if t == language.Und {
+			break
+		}
+	}
+	if message, ok := b.catalogs[b.fallback][key]; ok {
+		return message, b.fallback, true
+	}
+	return "", language.Und, false
+}
+
+// Message returns the message of the key in the locale, or the key itself if no catalog
+// of the fallback chain has it
+func (b *Bundle) Message(tag language.Tag, key string) string {
+	if message, _, ok := b.Lookup(tag, key); ok {
+		return message
+	}
+	return key
+}
+
+// Missing returns, sorted, the keys of the fallback catalog that the catalog of the
+// locale doesn't translate
+func (b *Bundle) Missing(tag language.Tag) []string {
+	missing := []string{}
+	catalog := b.catalogs[tag]
+	for key := range b.catalogs[b.fallback] {
+		if _, ok := catalog[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
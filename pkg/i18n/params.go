@@ -0,0 +1,43 @@
+package i18n
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// placeholder is a parameter of a message, like {id}
+var placeholder = regexp.MustCompile(`\{([a-z_]+)\}`)
+
+// Params are the values of the placeholders of a message, by name
+type Params map[string]interface{}
+
+// Format replaces the placeholders of the message with the values of the params, the
+// placeholders without a value are left as they are so the missing ones are visible
+func Format(message string, params Params) string {
+	if len(params) == 0 {
+		return message
+	}
+	return placeholder.ReplaceAllStringFunc(message, func(p string) string {
+		value, ok := params[p[1:len(p)-1]]
+		if !ok {
+			return p
+		}
+		return fmt.Sprint(value)
+	})
+}
+
+// Placeholders returns, sorted and without duplicates, the names of the placeholders of the
+// message. The translations of a message must have the same ones
+func Placeholders(message string) []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, m := range placeholder.FindAllStringSubmatch(message, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
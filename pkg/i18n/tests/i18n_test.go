@@ -0,0 +1,95 @@
+package i18n
+
+import (
+	"fmt"
+	"testing"
+	"testing/fstest"
+
+	"github.com/vano2903/service-template/pkg/i18n"
+	"golang.org/x/text/language"
+	"gotest.tools/v3/assert"
+)
+
+func bundle(t *testing.T) *i18n.Bundle {
+	t.Helper()
+	b := i18n.NewBundle(language.English)
+	assert.NilError(t, b.LoadFS(fstest.MapFS{
+		"catalogs/en.yaml":    {Data: []byte("hello: hello\nbye: bye\ncolor: color\n")},
+		"catalogs/en-GB.yaml": {Data: []byte("color: colour\n")},
+		"catalogs/it.yaml":    {Data: []byte("hello: ciao\n")},
+		"catalogs/readme.md":  {Data: []byte("not a catalog")},
+	}, "catalogs"))
+	return b
+}
+
+// lookup, cases:
+// [x] the message is taken from the catalog of the locale
+// [x] a missing message falls back to the parent locale and then to the fallback one
+// [x] a message missing everywhere is the key itself
+func TestMessage(t *testing.T) {
+	b := bundle(t)
+
+	assert.Equal(t, b.Message(language.Italian, "hello"), "ciao")
+	assert.Equal(t, b.Message(language.MustParse("it-CH"), "hello"), "ciao")
+	message, found, ok := b.Lookup(language.MustParse("it-CH"), "bye")
+	assert.Assert(t, ok)
+	assert.Equal(t, message, "bye")
+	assert.Equal(t, found, language.English)
+	assert.Equal(t, b.Message(language.BritishEnglish, "color"), "colour")
+	assert.Equal(t, b.Message(language.BritishEnglish, "hello"), "hello")
+	assert.Equal(t, b.Message(language.Japanese, "bye"), "bye")
+
+	_, _, ok = b.Lookup(language.Italian, "unknown")
+	assert.Assert(t, !ok)
+	assert.Equal(t, b.Message(language.Italian, "unknown"), "unknown")
+}
+
+// negotiation, cases:
+// [x] the Accept-Language is matched by quality
+// [x] a regional variant matches its language
+// [x] the preferences are tried in order, the empty and invalid ones are skipped
+// [x] nothing supported is the fallback
+func TestMatch(t *testing.T) {
+	b := bundle(t)
+
+	assert.Equal(t, b.Match("fr;q=0.9, it;q=0.8, en;q=0.1"), language.Italian)
+	assert.Equal(t, b.Match("it-IT"), language.Italian)
+	assert.Equal(t, b.Match("en-GB"), language.BritishEnglish)
+	assert.Equal(t, b.Match("", "it"), language.Italian)
+	assert.Equal(t, b.Match("not a locale!", "it"), language.Italian)
+	assert.Equal(t, b.Match("ja", "it"), language.Italian)
+	assert.Equal(t, b.Match("ja"), language.English)
+	assert.Equal(t, b.Match(), language.English)
+}
+
+// catalogs, cases:
+// [x] the keys not translated by a locale are reported
+// [x] the catalogs must be named after a locale and be flat maps
+func TestCatalogs(t *testing.T) {
+	b := bundle(t)
+	assert.DeepEqual(t, b.Missing(language.Italian), []string{"bye", "color"})
+	assert.DeepEqual(t, b.Missing(language.English), []string{})
+	assert.Equal(t, fmt.Sprint(b.Locales()), "[en en-GB it]")
+
+	err := i18n.NewBundle(language.English).LoadFS(fstest.MapFS{
+		"messages.yaml": {Data: []byte("hello: hello\n")},
+	}, ".")
+	assert.ErrorIs(t, err, i18n.ErrInvalidCatalog)
+	err = i18n.NewBundle(language.English).LoadFS(fstest.MapFS{
+		"en.yaml": {Data: []byte("hello:\n  nested: hello\n")},
+	}, ".")
+	assert.ErrorIs(t, err, i18n.ErrInvalidCatalog)
+}
+
+// parameters, cases:
+// [x] the placeholders are replaced by the params
+// [x] the placeholders without a param are left as they are
+// [x] the placeholders of a message are listed once, sorted
+func TestFormat(t *testing.T) {
+	assert.Equal(t, i18n.Format("no user with {id} as id", i18n.Params{"id": 42}), "no user with 42 as id")
+	assert.Equal(t, i18n.Format("size between {min} and {max}", i18n.Params{"min": 16}), "size between 16 and {max}")
+	assert.Equal(t, i18n.Format("<seed>.png {not a placeholder}", nil), "<seed>.png {not a placeholder}")
+
+	assert.DeepEqual(t, i18n.Placeholders("{max} {min} {max}"), []string{"max", "min"})
+	assert.DeepEqual(t, i18n.Placeholders("no placeholders"), []string{})
+}
@@ -0,0 +1,111 @@
+// Package imageproc validates uploaded images and creates the square thumbnails.
+// Images are always decoded and encoded again: this strips every metadata
+// (EXIF with gps position, comments...) and anything hidden after the image data.
+package imageproc
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"net/http"
+
+	//registering the gif decoder
+	_ "image/gif"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+const (
+	//protects from decompression bombs: small files that decode to huge images
+	maxPixels   = 40000000
+	jpegQuality = 90
+)
+
+var (
+	ErrUnsupportedType = errors.New("unsupported image type")
+	ErrInvalidImage    = errors.New("invalid image")
+)
+
+// SupportedTypes are the content types accepted as input
+var SupportedTypes = []string{"image/jpeg", "image/png", "image/gif"}
+
+type Thumbnail struct {
+	Size        int
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+// DetectType sniffs the content type from the data, the type declared by the
+// client is never trusted. It returns ErrUnsupportedType if it's not a supported image.
+func DetectType(data []byte) (string, error) {
+	contentType := http.DetectContentType(data)
+	for _, t := range SupportedTypes {
+		if t == contentType {
+			return contentType, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s", ErrUnsupportedType, contentType)
+}
+
+// Thumbnails crops the image to a centered square and resizes it to every size.
+// Jpeg images stay jpeg, everything else becomes png (to keep transparency).
+func Thumbnails(data []byte, sizes []int) ([]Thumbnail, error) {
+	contentType, err := DetectType(data)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	if cfg.Width*cfg.Height > maxPixels {
+		return nil, fmt.Errorf("%w: the image is too big (%dx%d)", ErrInvalidImage, cfg.Width, cfg.Height)
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidImage, err)
+	}
+	square := cropSquare(img)
+
+	thumbnails := make([]Thumbnail, 0, len(sizes))
+	for _, size := range sizes {
+		dst := image.NewRGBA(image.Rect(0, 0, size, size))
+		xdraw.CatmullRom.Scale(dst, dst.Bounds(), square, square.Bounds(), draw.Src, nil)
+
+		buf := &bytes.Buffer{}
+		t := Thumbnail{Size: size}
+		if contentType == "image/jpeg" {
+			err = jpeg.Encode(buf, dst, &jpeg.Options{Quality: jpegQuality})
+			t.ContentType, t.Ext = "image/jpeg", "jpg"
+		} else {
+			err = png.Encode(buf, dst)
+			t.ContentType, t.Ext = "image/png", "png"
+		}
+		if err != nil {
+			return nil, err
+		}
+		t.Data = buf.Bytes()
+		thumbnails = append(thumbnails, t)
+	}
+	return thumbnails, nil
+}
+
+func cropSquare(img image.Image) image.Image {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+	x0 := b.Min.X + (b.Dx()-side)/2
+	y0 := b.Min.Y + (b.Dy()-side)/2
+	square := image.NewRGBA(image.Rect(0, 0, side, side))
+	draw.Draw(square, square.Bounds(), img, image.Point{X: x0, Y: y0}, draw.Src)
+	return square
+}
@@ -1,6 +1,7 @@
 package jwt
 
 import (
+	"errors"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -35,6 +36,13 @@ func NewJWThandler(secret, issuer string, expirationTime ...time.Duration) *JWTh
 	}
 }
 
+// WithExpiration returns a copy of the handler whose tokens last for expirationTime
+func (j *JWThandler) WithExpiration(expirationTime time.Duration) *JWThandler {
+	copy := *j
+	copy.expirationTime = expirationTime
+	return &copy
+}
+
 func (j *JWThandler) SigningKey() []byte {
 	return []byte(j.secret)
 }
@@ -78,3 +86,9 @@ func (j *JWThandler) IsTokenExpired(tokenString string) (bool, error) {
 	}
 	return claims.ExpiresAt < time.Now().Unix(), nil
 }
+
+// IsExpiredError tells if ValidateToken refused the token only because it expired
+func IsExpiredError(err error) bool {
+	var ve *jwt.ValidationError
+	return errors.As(err, &ve) && ve.Errors == jwt.ValidationErrorExpired
+}
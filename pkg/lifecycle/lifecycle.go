@@ -0,0 +1,182 @@
+// Package lifecycle starts the components of the service in order and stops them
+// in reverse order when the service receives SIGINT/SIGTERM, giving them a limited
+// time (the drain timeout) to finish what they are doing.
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const _DEFAULT_DRAIN_TIMEOUT = 15 * time.Second
+
+type (
+	// Component is a part of the service with a start and a stop, both are optional.
+	// Start must not block: long running work goes in a goroutine (see Background).
+	// Stop receives a context that expires at the end of the drain timeout.
+	Component struct {
+		Name  string
+		Start func(ctx context.Context) error
+		Stop  func(ctx context.Context) error
+	}
+
+	Manager struct {
+		l            *logrus.Logger
+		drainTimeout time.Duration
+		components   []Component
+		signals      []os.Signal
+
+		failed   chan error
+		stopping chan struct{}
+		once     sync.Once
+	}
+)
+
+func New(l *logrus.Logger, drainTimeout time.Duration) *Manager {
+	if drainTimeout <= 0 {
+		drainTimeout = _DEFAULT_DRAIN_TIMEOUT
+	}
+	return &Manager{
+		l:            l,
+		drainTimeout: drainTimeout,
+		signals:      []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+		failed:       make(chan error, 1),
+		stopping:     make(chan struct{}),
+	}
+}
+
+// Add appends a component, components are started in the order they are added
+func (m *Manager) Add(c Component) {
+	m.components = append(m.components, c)
+}
+
+// Background adds a component that runs fn in a goroutine until it's stopped,
+// fn must return when its context is canceled. Stop waits for fn to return.
+func (m *Manager) Background(name string, fn func(ctx context.Context)) {
+	var cancel context.CancelFunc
+	done := make(chan struct{})
+	m.Add(Component{
+		Name: name,
+		Start: func(context.Context) error {
+			var ctx context.Context
+			ctx, cancel = context.WithCancel(context.Background())
+			go func() {
+				defer close(done)
+				fn(ctx)
+			}()
+			return nil
+		},
+		Stop: func(ctx context.Context) error {
+			cancel()
+			select {
+			case <-done:
+				return nil
+			case <-ctx.Done():
+				return fmt.Errorf("%s didn't stop in time", name)
+			}
+		},
+	})
+}
+
+// Fail reports that a running component failed, the service is shut down
+func (m *Manager) Fail(name string, err error) {
+	select {
+	case m.failed <- fmt.Errorf("%s: %w", name, err):
+	default:
+		//the service is already shutting down
+		m.l.Errorf("lifecycle: %s failed: %v", name, err)
+	}
+}
+
+// Stopping is closed when the shutdown begins, before any component is stopped
+func (m *Manager) Stopping() <-chan struct{} {
+	return m.stopping
+}
+
+// Run starts every component and blocks until the service receives a signal, ctx is canceled
+// or a component fails, then stops the started components in reverse order.
+// It returns the error that caused the shutdown or the first error of a stop.
+func (m *Manager) Run(ctx context.Context) error {
+	sig := make(chan os.Signal, 2)
+	signal.Notify(sig, m.signals...)
+	defer signal.Stop(sig)
+
+	started, err := m.start(ctx)
+	if err == nil {
+		err = m.wait(ctx, sig)
+	}
+
+	m.once.Do(func() { close(m.stopping) })
+	if stopErr := m.stop(started); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+func (m *Manager) start(ctx context.Context) (int, error) {
+	for i, c := range m.components {
+		if c.Start == nil {
+			continue
+		}
+		m.l.Debugf("lifecycle: starting %s", c.Name)
+		if err := c.Start(ctx); err != nil {
+			return i, fmt.Errorf("unable to start %s: %w", c.Name, err)
+		}
+	}
+	m.l.Infof("lifecycle: started %d components", len(m.components))
+	return len(m.components), nil
+}
+
+func (m *Manager) wait(ctx context.Context, sig chan os.Signal) error {
+	var err error
+	select {
+	case s := <-sig:
+		m.l.Infof("lifecycle: received %s, shutting down (drain timeout %s)", s, m.drainTimeout)
+	case <-ctx.Done():
+		m.l.Infof("lifecycle: context canceled, shutting down (drain timeout %s)", m.drainTimeout)
+	case err = <-m.failed:
+		m.l.Errorf("lifecycle: %v, shutting down (drain timeout %s)", err, m.drainTimeout)
+	}
+
+	//a second signal means that whoever is stopping the service doesn't want to wait
+	go func() {
+		s := <-sig
+		m.l.Errorf("lifecycle: received %s again, exiting without waiting", s)
+		os.Exit(1)
+	}()
+	return err
+}
+
+// stop stops the first n components in reverse order, every component gets what's left of the drain timeout
+func (m *Manager) stop(n int) error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+
+	begin := time.Now()
+	var firstErr error
+	for i := n - 1; i >= 0; i-- {
+		c := m.components[i]
+		if c.Stop == nil {
+			continue
+		}
+		m.l.Infof("lifecycle: stopping %s (%d/%d)", c.Name, n-i, n)
+		start := time.Now()
+		if err := c.Stop(ctx); err != nil {
+			m.l.Errorf("lifecycle: error stopping %s: %v", c.Name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("unable to stop %s: %w", c.Name, err)
+			}
+			continue
+		}
+		m.l.Debugf("lifecycle: stopped %s in %s", c.Name, time.Since(start))
+	}
+	m.l.Infof("lifecycle: shutdown completed in %s", time.Since(begin))
+	return firstErr
+}
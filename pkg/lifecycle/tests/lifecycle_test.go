@@ -0,0 +1,119 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/lifecycle"
+	"gotest.tools/v3/assert"
+)
+
+type recorder struct {
+	mu    sync.Mutex
+	steps []string
+}
+
+func (r *recorder) component(name string, startErr error) lifecycle.Component {
+	return lifecycle.Component{
+		Name: name,
+		Start: func(context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		Stop: func(context.Context) error {
+			r.add("stop " + name)
+			return nil
+		},
+	}
+}
+
+func (r *recorder) add(step string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps = append(r.steps, step)
+}
+
+func newLogger() *logrus.Logger {
+	l := logrus.New()
+	l.SetOutput(io.Discard)
+	return l
+}
+
+// lifecycle, cases:
+// [x] components are started in order and stopped in reverse order
+// [x] if a component doesn't start only the started ones are stopped
+// [x] a failed component shuts the service down
+// [x] a component that doesn't stop in the drain timeout doesn't block the shutdown
+func TestLifecycle(t *testing.T) {
+	t.Run("order", func(t *testing.T) {
+		r := &recorder{}
+		m := lifecycle.New(newLogger(), time.Second)
+		m.Add(r.component("repo", nil))
+		m.Add(r.component("worker", nil))
+		m.Add(r.component("http", nil))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		assert.NilError(t, m.Run(ctx))
+		assert.DeepEqual(t, r.steps, []string{"start repo", "start worker", "start http", "stop http", "stop worker", "stop repo"})
+
+		select {
+		case <-m.Stopping():
+		default:
+			t.Fatal("stopping channel not closed")
+		}
+	})
+
+	t.Run("failed start", func(t *testing.T) {
+		r := &recorder{}
+		m := lifecycle.New(newLogger(), time.Second)
+		m.Add(r.component("repo", nil))
+		m.Add(r.component("http", errors.New("address already in use")))
+		m.Add(r.component("never", nil))
+
+		err := m.Run(context.Background())
+		assert.ErrorContains(t, err, "unable to start http: address already in use")
+		assert.DeepEqual(t, r.steps, []string{"start repo", "start http", "stop repo"})
+	})
+
+	t.Run("failed component", func(t *testing.T) {
+		m := lifecycle.New(newLogger(), time.Second)
+		stopped := false
+		m.Background("worker", func(ctx context.Context) {
+			<-ctx.Done()
+			stopped = true
+		})
+		m.Add(lifecycle.Component{
+			Name: "http",
+			Start: func(context.Context) error {
+				go m.Fail("http", errors.New("listener closed"))
+				return nil
+			},
+		})
+
+		err := m.Run(context.Background())
+		assert.ErrorContains(t, err, "http: listener closed")
+		assert.Assert(t, stopped)
+	})
+
+	t.Run("drain timeout", func(t *testing.T) {
+		m := lifecycle.New(newLogger(), 50*time.Millisecond)
+		block := make(chan struct{})
+		defer close(block)
+		m.Background("stuck", func(ctx context.Context) {
+			<-block
+		})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		begin := time.Now()
+		err := m.Run(ctx)
+		assert.ErrorContains(t, err, "stuck didn't stop in time")
+		assert.Assert(t, time.Since(begin) < time.Second)
+	})
+}
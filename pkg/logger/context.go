@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+)
+
+type entryKey struct{}
+
+// WithEntry returns a copy of the context carrying the entry, the entry has the fields
+// of the request (request id, route, user id...) so every log of the request can be correlated
+func WithEntry(ctx context.Context, entry *logrus.Entry) context.Context {
+	return context.WithValue(ctx, entryKey{}, entry)
+}
+
+// FromContext returns an entry of the logger with the fields of the request carried by the context,
+// outside of a request (startup, background jobs...) it has no fields. The entry is built on the
+// logger of the caller, so its level and its component are used and not the ones of the http logger
+// that created the entry of the request
+func FromContext(ctx context.Context, fallback *logrus.Logger) *logrus.Entry {
+	if entry, ok := ctx.Value(entryKey{}).(*logrus.Entry); ok {
+		return fallback.WithFields(entry.Data).WithContext(ctx)
+	}
+	return logrus.NewEntry(fallback).WithContext(ctx)
+}
+
+// AddFields adds the fields to the entry carried by the context, the fields known only
+// later in the request (the user id after the authentication...) are added this way
+func AddFields(ctx context.Context, fallback *logrus.Logger, fields logrus.Fields) context.Context {
+	return WithEntry(ctx, FromContext(ctx, fallback).WithFields(fields))
+}
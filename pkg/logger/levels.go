@@ -0,0 +1,234 @@
+package logger
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RootComponent is the name of the root logger, the components without
+// a level of their own follow its level
+const RootComponent = "root"
+
+var ErrUnknownComponent = errors.New("unknown log component")
+
+type (
+	// Levels keeps a logger for every component of the service (http, controller, repo...).
+	// The loggers share the output, the formatter and the hooks of the root logger but each
+	// one has its own level, the levels can be changed at runtime for a limited time
+	Levels struct {
+		mu         sync.Mutex
+		root       *logrus.Logger
+		configured logrus.Level
+		rootLevel  *override
+		components map[string]*component
+	}
+
+	component struct {
+		l     *logrus.Logger
+		level *override
+	}
+
+	// level set at runtime, when the timer fires the level goes back to the default one
+	override struct {
+		level     logrus.Level
+		expiresAt time.Time
+		timer     *time.Timer
+	}
+
+	ComponentLevel struct {
+		Component string `json:"component"`
+		Level     string `json:"level"`
+		//the level was set at runtime, it's not the configured one
+		Overridden bool `json:"overridden"`
+		//when the level goes back to the default one, nil if it doesn't expire
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}
+)
+
+// NewLevels must be called after the hooks are added to the root logger,
+// the loggers of the components copy them when they are created
+func NewLevels(root *logrus.Logger) *Levels {
+	return &Levels{
+		root:       root,
+		configured: root.GetLevel(),
+		components: make(map[string]*component),
+	}
+}
+
+// componentHook adds the name of the component to the entries of its logger
+type componentHook string
+
+func (h componentHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (h componentHook) Fire(e *logrus.Entry) error {
+	e.Data["component"] = string(h)
+	return nil
+}
+
+// Component returns the logger of the component, it's created the first time
+func (lv *Levels) Component(name string) *logrus.Logger {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	if c, ok := lv.components[name]; ok {
+		return c.l
+	}
+
+	hooks := make(logrus.LevelHooks, len(lv.root.Hooks))
+	for level, hs := range lv.root.Hooks {
+		hooks[level] = append([]logrus.Hook(nil), hs...)
+	}
+	hooks.Add(componentHook(name))
+	l := &logrus.Logger{
+		Out:          lv.root.Out,
+		Hooks:        hooks,
+		Formatter:    lv.root.Formatter,
+		ReportCaller: lv.root.ReportCaller,
+		Level:        lv.root.GetLevel(),
+		ExitFunc:     lv.root.ExitFunc,
+	}
+	lv.components[name] = &component{l: l}
+	return l
+}
+
+// SetLevel changes the level of the component (RootComponent for the root logger and every component
+// without a level of its own). If ttl is positive the level goes back to the default one after ttl:
+// the configured level for the root, the level of the root for the components
+func (lv *Levels) SetLevel(name string, level logrus.Level, ttl time.Duration) error {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	o := &override{level: level}
+	if name == RootComponent {
+		lv.rootLevel.stop()
+		lv.rootLevel = o
+	} else {
+		c, ok := lv.components[name]
+		if !ok {
+			return ErrUnknownComponent
+		}
+		c.level.stop()
+		c.level = o
+	}
+	if ttl > 0 {
+		o.expiresAt = time.Now().Add(ttl)
+		o.timer = time.AfterFunc(ttl, func() { lv.expire(name, o) })
+	}
+	lv.apply()
+	return nil
+}
+
+// ResetLevel removes the level set at runtime
+func (lv *Levels) ResetLevel(name string) error {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	if name == RootComponent {
+		lv.rootLevel.stop()
+		lv.rootLevel = nil
+	} else {
+		c, ok := lv.components[name]
+		if !ok {
+			return ErrUnknownComponent
+		}
+		c.level.stop()
+		c.level = nil
+	}
+	lv.apply()
+	return nil
+}
+
+// SetConfigured changes the default level of the root (the one of the config), used when the
+// config is reloaded. The levels set at runtime are kept, they still expire to the new default
+func (lv *Levels) SetConfigured(level logrus.Level) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+	lv.configured = level
+	lv.apply()
+}
+
+func (lv *Levels) expire(name string, o *override) {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	//the level could have been changed again in the meantime
+	if name == RootComponent {
+		if lv.rootLevel != o {
+			return
+		}
+		lv.rootLevel = nil
+	} else {
+		c := lv.components[name]
+		if c.level != o {
+			return
+		}
+		c.level = nil
+	}
+	lv.apply()
+	lv.root.Infof("logger: the log level of %s went back to %s", name, lv.levelOf(name))
+}
+
+// apply sets the levels of the loggers, it must be called with the lock held
+func (lv *Levels) apply() {
+	root := lv.configured
+	if lv.rootLevel != nil {
+		root = lv.rootLevel.level
+	}
+	lv.root.SetLevel(root)
+	for _, c := range lv.components {
+		if c.level != nil {
+			c.l.SetLevel(c.level.level)
+		} else {
+			c.l.SetLevel(root)
+		}
+	}
+}
+
+func (lv *Levels) levelOf(name string) logrus.Level {
+	if name == RootComponent {
+		return lv.root.GetLevel()
+	}
+	return lv.components[name].l.GetLevel()
+}
+
+// Levels returns the level of the root and of every component sorted by name
+func (lv *Levels) Levels() []ComponentLevel {
+	lv.mu.Lock()
+	defer lv.mu.Unlock()
+
+	levels := []ComponentLevel{newComponentLevel(RootComponent, lv.root.GetLevel(), lv.rootLevel)}
+	names := make([]string, 0, len(lv.components))
+	for name := range lv.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c := lv.components[name]
+		levels = append(levels, newComponentLevel(name, c.l.GetLevel(), c.level))
+	}
+	return levels
+}
+
+func newComponentLevel(name string, level logrus.Level, o *override) ComponentLevel {
+	cl := ComponentLevel{
+		Component:  name,
+		Level:      level.String(),
+		Overridden: o != nil,
+	}
+	if o != nil && !o.expiresAt.IsZero() {
+		expiresAt := o.expiresAt
+		cl.ExpiresAt = &expiresAt
+	}
+	return cl
+}
+
+func (o *override) stop() {
+	if o != nil && o.timer != nil {
+		o.timer.Stop()
+	}
+}
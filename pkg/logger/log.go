@@ -1,6 +1,10 @@
 package logger
 
 import (
+	"errors"
+	"os"
+	"syscall"
+
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,18 +20,31 @@ func NewLogger(level, logType string) *logrus.Logger {
 		logger.SetFormatter(&logrus.JSONFormatter{})
 	}
 
-	switch level {
-	case "debug":
-		logger.SetLevel(logrus.DebugLevel)
-	case "info":
-		logger.SetLevel(logrus.InfoLevel)
-	case "warn":
-		logger.SetLevel(logrus.WarnLevel)
-	case "error":
-		logger.SetLevel(logrus.ErrorLevel)
-	default:
-		logger.SetLevel(logrus.InfoLevel)
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		lvl = logrus.InfoLevel
+	}
+	logger.SetLevel(lvl)
+	if err != nil {
+		//a typo in the config must not go unnoticed
+		logger.Warnf("unknown log level %q, using %s", level, lvl)
 	}
 
 	return logger
 }
+
+// Flush writes to the disk what the logger buffered, it must be called before exiting.
+// Logrus doesn't buffer but the output could be a file kept in the page cache.
+func Flush(logger *logrus.Logger) error {
+	if syncer, ok := logger.Out.(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil && !syncUnsupported(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// the terminals and the pipes can't be synced, it's not an error
+func syncUnsupported(err error) bool {
+	return errors.Is(err, os.ErrInvalid) || errors.Is(err, syscall.EINVAL) || errors.Is(err, syscall.ENOTTY)
+}
@@ -0,0 +1,129 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+const (
+	OutputStdout = "stdout"
+	OutputStderr = "stderr"
+	//file rotated when it's too big, the old files are deleted when they are too old or too many
+	OutputFile = "file"
+)
+
+type (
+	OutputOptions struct {
+		//where the logs are written, any of stdout, stderr and file
+		Outputs []string
+		File    FileOptions
+	}
+
+	FileOptions struct {
+		Path string
+		//size in megabytes after which the file is rotated
+		MaxSizeMB int
+		//days after which the rotated files are deleted, 0 keeps them
+		MaxAgeDays int
+		//number of rotated files kept, 0 keeps them all
+		MaxBackups int
+		//the rotated files are compressed with gzip
+		Compress bool
+	}
+
+	// Output writes the logs to every configured destination
+	Output struct {
+		mu      sync.Mutex
+		writers []io.Writer
+		files   []*lumberjack.Logger
+	}
+)
+
+func NewOutput(opts OutputOptions) (*Output, error) {
+	if len(opts.Outputs) == 0 {
+		return nil, fmt.Errorf("at least an output is needed")
+	}
+	o := &Output{}
+	seen := make(map[string]bool, len(opts.Outputs))
+	for _, name := range opts.Outputs {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		switch name {
+		case OutputStdout:
+			o.writers = append(o.writers, os.Stdout)
+		case OutputStderr:
+			o.writers = append(o.writers, os.Stderr)
+		case OutputFile:
+			if opts.File.Path == "" {
+				return nil, fmt.Errorf("the file output needs the path of the file")
+			}
+			//lumberjack creates the directory only when it rotates the file
+			if err := os.MkdirAll(filepath.Dir(opts.File.Path), 0o755); err != nil {
+				return nil, fmt.Errorf("unable to create the directory of the log file: %w", err)
+			}
+			f := &lumberjack.Logger{
+				Filename:   opts.File.Path,
+				MaxSize:    opts.File.MaxSizeMB,
+				MaxAge:     opts.File.MaxAgeDays,
+				MaxBackups: opts.File.MaxBackups,
+				Compress:   opts.File.Compress,
+				LocalTime:  true,
+			}
+			o.writers = append(o.writers, f)
+			o.files = append(o.files, f)
+		default:
+			return nil, fmt.Errorf("unknown log output %q, it must be one of stdout, stderr or file", name)
+		}
+	}
+	return o, nil
+}
+
+// Write writes the entry to every destination, a failing destination doesn't stop the others
+func (o *Output) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var firstErr error
+	for _, w := range o.writers {
+		if _, err := w.Write(p); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	if firstErr != nil {
+		return 0, firstErr
+	}
+	return len(p), nil
+}
+
+// Sync is called by Flush, lumberjack doesn't buffer so only the standard outputs are synced
+func (o *Output) Sync() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, w := range o.writers {
+		if f, ok := w.(*os.File); ok {
+			if err := f.Sync(); err != nil && !syncUnsupported(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes the log files, writing after closing reopens them
+func (o *Output) Close() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	var firstErr error
+	for _, f := range o.files {
+		if err := f.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
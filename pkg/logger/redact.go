@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+// patterns of the sensitive data that can end up in the messages (or in the fields) of the logs
+var redactPatterns = map[string]struct {
+	re      *regexp.Regexp
+	replace string
+}{
+	//header.payload.signature, the header of a JWT always starts with {" that is eyJ in base64
+	"jwt": {
+		re:      regexp.MustCompile(`eyJ[A-Za-z0-9_-]*\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+		replace: "[REDACTED_JWT]",
+	},
+	"bearer": {
+		re:      regexp.MustCompile(`(?i)(bearer|basic)\s+[A-Za-z0-9._~+/=-]+`),
+		replace: "$1 " + redacted,
+	},
+	//password=..., "password": "...", pwd: ... in messages, query strings and json
+	"password": {
+		re:      regexp.MustCompile(`(?i)((?:password|passwd|pwd|secret)["']?\s*[:=]\s*["']?)[^\s"'&,;}]+`),
+		replace: "$1" + redacted,
+	},
+	//the first letter and the domain are kept, they are enough to tell two users apart while debugging
+	"email": {
+		re:      regexp.MustCompile(`([A-Za-z0-9])[A-Za-z0-9._%+-]*@([A-Za-z0-9.-]+\.[A-Za-z]{2,})`),
+		replace: "$1***@$2",
+	},
+}
+
+// RedactPatterns are the names of the patterns known by the redaction
+func RedactPatterns() []string {
+	return []string{"jwt", "bearer", "password", "email"}
+}
+
+type RedactOptions struct {
+	//names of the fields whose value is always hidden (case insensitive)
+	Fields []string
+	//names of the patterns masked in the messages and in the values of the fields, see RedactPatterns
+	Patterns []string
+}
+
+// RedactHook masks the sensitive data of the entries before they are written. It must be the first
+// hook added to the logger or the hooks added before it would see the data in clear
+type RedactHook struct {
+	fields   map[string]bool
+	patterns []*regexp.Regexp
+	replaces []string
+}
+
+func NewRedactHook(opts RedactOptions) (*RedactHook, error) {
+	h := &RedactHook{fields: make(map[string]bool, len(opts.Fields))}
+	for _, f := range opts.Fields {
+		if f = strings.TrimSpace(f); f != "" {
+			h.fields[strings.ToLower(f)] = true
+		}
+	}
+	for _, name := range opts.Patterns {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		p, ok := redactPatterns[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown redact pattern %q, the known ones are %s", name, strings.Join(RedactPatterns(), ", "))
+		}
+		h.patterns = append(h.patterns, p.re)
+		h.replaces = append(h.replaces, p.replace)
+	}
+	return h, nil
+}
+
+func (h *RedactHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+// Fire changes the entry in place, logrus gives the hooks a copy of the entry
+// so the entry used by the caller (and its fields) is not modified
+func (h *RedactHook) Fire(e *logrus.Entry) error {
+	e.Message = h.Redact(e.Message)
+	for k, v := range e.Data {
+		if h.fields[strings.ToLower(k)] {
+			e.Data[k] = redacted
+			continue
+		}
+		e.Data[k] = h.redactValue(v)
+	}
+	return nil
+}
+
+// Redact masks the patterns in s
+func (h *RedactHook) Redact(s string) string {
+	for i, re := range h.patterns {
+		s = re.ReplaceAllString(s, h.replaces[i])
+	}
+	return s
+}
+
+func (h *RedactHook) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil, bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return v
+	case string:
+		return h.Redact(v)
+	case error:
+		if s := v.Error(); h.Redact(s) != s {
+			return h.Redact(s)
+		}
+		return v
+	default:
+		//structs, maps... are kept as they are unless they contain something to mask
+		s := fmt.Sprintf("%+v", v)
+		if r := h.Redact(s); r != s {
+			return r
+		}
+		return v
+	}
+}
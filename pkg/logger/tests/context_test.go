@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/vano2903/service-template/pkg/logger"
+	"gotest.tools/v3/assert"
+)
+
+// request scoped logger, cases:
+// [x] without an entry in the context the fallback logger is used
+// [x] the entry in the context is used with its fields
+// [x] the fields added later are kept by the context and not by the parent context
+func TestContextEntry(t *testing.T) {
+	l, hook := test.NewNullLogger()
+
+	logger.FromContext(context.Background(), l).Info("startup")
+	assert.Equal(t, len(hook.LastEntry().Data), 0)
+
+	ctx := logger.WithEntry(context.Background(), l.WithField("request_id", "abc"))
+	logger.FromContext(ctx, l).Info("request")
+	assert.Equal(t, hook.LastEntry().Data["request_id"], "abc")
+
+	withUser := logger.AddFields(ctx, l, logrus.Fields{"user_id": 7})
+	logger.FromContext(withUser, l).Info("authenticated")
+	assert.Equal(t, hook.LastEntry().Data["request_id"], "abc")
+	assert.Equal(t, hook.LastEntry().Data["user_id"], 7)
+
+	logger.FromContext(ctx, l).Info("parent")
+	_, ok := hook.LastEntry().Data["user_id"]
+	assert.Assert(t, !ok)
+}
+
+// components in a request, cases:
+// [x] the logs of a component in a request use the level of the component, not the one of http
+// [x] they are tagged with the component and keep the fields of the request
+func TestContextEntryComponent(t *testing.T) {
+	root, hook := test.NewNullLogger()
+	levels := logger.NewLevels(root)
+	http, controller := levels.Component("http"), levels.Component("controller")
+	assert.NilError(t, levels.SetLevel("http", logrus.InfoLevel, 0))
+	assert.NilError(t, levels.SetLevel("controller", logrus.DebugLevel, 0))
+
+	ctx := logger.WithEntry(context.Background(), http.WithField("request_id", "abc"))
+	logger.FromContext(ctx, controller).Debug("controller debug")
+	entry := hook.LastEntry()
+	assert.Assert(t, entry != nil)
+	assert.Equal(t, entry.Message, "controller debug")
+	assert.Equal(t, entry.Data["component"], "controller")
+	assert.Equal(t, entry.Data["request_id"], "abc")
+
+	hook.Reset()
+	logger.FromContext(ctx, http).Debug("http debug")
+	assert.Assert(t, hook.LastEntry() == nil)
+	logger.FromContext(ctx, http).Info("http info")
+	assert.Equal(t, hook.LastEntry().Data["component"], "http")
+}
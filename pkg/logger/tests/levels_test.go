@@ -0,0 +1,114 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/logger"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+func levelOf(levels []logger.ComponentLevel, component string) logger.ComponentLevel {
+	for _, l := range levels {
+		if l.Component == component {
+			return l
+		}
+	}
+	return logger.ComponentLevel{}
+}
+
+// runtime log levels, cases:
+// [x] the components start with the level of the root and write to its output with their name
+// [x] changing the root changes the components without a level of their own
+// [x] changing a component doesn't change the others
+// [x] the level goes back to the default one after the ttl
+// [x] a reset removes the level set at runtime
+// [x] an unknown component is an error
+func TestLevels(t *testing.T) {
+	root := logger.NewLogger("info", "json")
+	out := &bytes.Buffer{}
+	root.SetOutput(out)
+	levels := logger.NewLevels(root)
+
+	repo := levels.Component("repo")
+	http := levels.Component("http")
+	assert.Equal(t, repo, levels.Component("repo"))
+	assert.Equal(t, repo.GetLevel(), logrus.InfoLevel)
+
+	repo.Info("from the repo")
+	assert.Assert(t, strings.Contains(out.String(), `"component":"repo"`))
+	repo.Debug("hidden")
+	assert.Assert(t, !strings.Contains(out.String(), "hidden"))
+
+	assert.NilError(t, levels.SetLevel(logger.RootComponent, logrus.WarnLevel, 0))
+	assert.Equal(t, root.GetLevel(), logrus.WarnLevel)
+	assert.Equal(t, repo.GetLevel(), logrus.WarnLevel)
+
+	assert.NilError(t, levels.SetLevel("repo", logrus.DebugLevel, 0))
+	assert.Equal(t, repo.GetLevel(), logrus.DebugLevel)
+	assert.Equal(t, http.GetLevel(), logrus.WarnLevel)
+	assert.Assert(t, levelOf(levels.Levels(), "repo").Overridden)
+	assert.Assert(t, !levelOf(levels.Levels(), "http").Overridden)
+
+	assert.NilError(t, levels.SetLevel("http", logrus.TraceLevel, 50*time.Millisecond))
+	assert.Assert(t, levelOf(levels.Levels(), "http").ExpiresAt != nil)
+	assert.Equal(t, http.GetLevel(), logrus.TraceLevel)
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if http.GetLevel() == logrus.WarnLevel {
+			return poll.Success()
+		}
+		return poll.Continue("http is still at %s", http.GetLevel())
+	}, poll.WithTimeout(2*time.Second), poll.WithDelay(10*time.Millisecond))
+	assert.Assert(t, !levelOf(levels.Levels(), "http").Overridden)
+
+	assert.NilError(t, levels.ResetLevel(logger.RootComponent))
+	assert.Equal(t, root.GetLevel(), logrus.InfoLevel)
+	assert.Equal(t, http.GetLevel(), logrus.InfoLevel)
+	assert.Equal(t, repo.GetLevel(), logrus.DebugLevel)
+
+	assert.Equal(t, levels.SetLevel("graphql", logrus.DebugLevel, 0), logger.ErrUnknownComponent)
+	assert.Equal(t, levels.ResetLevel("graphql"), logger.ErrUnknownComponent)
+}
+
+// the ttl of an old change must not revert a newer one
+func TestLevelsTTLReplaced(t *testing.T) {
+	root := logger.NewLogger("info", "json")
+	root.SetOutput(&bytes.Buffer{})
+	levels := logger.NewLevels(root)
+	repo := levels.Component("repo")
+
+	assert.NilError(t, levels.SetLevel("repo", logrus.DebugLevel, 20*time.Millisecond))
+	assert.NilError(t, levels.SetLevel("repo", logrus.ErrorLevel, 0))
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, repo.GetLevel(), logrus.ErrorLevel)
+}
+
+// a reload of the config changes the default level, the levels set at runtime are kept
+func TestLevelsSetConfigured(t *testing.T) {
+	root := logger.NewLogger("info", "json")
+	root.SetOutput(&bytes.Buffer{})
+	levels := logger.NewLevels(root)
+	repo := levels.Component("repo")
+	http := levels.Component("http")
+	assert.NilError(t, levels.SetLevel("repo", logrus.DebugLevel, 0))
+
+	levels.SetConfigured(logrus.WarnLevel)
+	assert.Equal(t, root.GetLevel(), logrus.WarnLevel)
+	assert.Equal(t, http.GetLevel(), logrus.WarnLevel)
+	assert.Equal(t, repo.GetLevel(), logrus.DebugLevel)
+
+	//the runtime level of the root still goes back to the configured one
+	assert.NilError(t, levels.SetLevel(logger.RootComponent, logrus.TraceLevel, 0))
+	assert.NilError(t, levels.ResetLevel(logger.RootComponent))
+	assert.Equal(t, root.GetLevel(), logrus.WarnLevel)
+}
+
+// an unknown level falls back to info (with a warning)
+func TestUnknownLevel(t *testing.T) {
+	l := logger.NewLogger("verbose", "json")
+	assert.Equal(t, l.GetLevel(), logrus.InfoLevel)
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/pkg/logger"
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// log outputs, cases:
+// [x] the logs are written in the file, the directory is created
+// [x] the file is rotated when it's too big and the rotated files are compressed
+// [x] an unknown output is an error
+func TestOutput(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	path := filepath.Join(dir, "service.log")
+	output, err := logger.NewOutput(logger.OutputOptions{
+		Outputs: []string{logger.OutputFile},
+		File: logger.FileOptions{
+			Path:       path,
+			MaxSizeMB:  1,
+			MaxBackups: 2,
+			Compress:   true,
+		},
+	})
+	assert.NilError(t, err)
+
+	l := logger.NewLogger("info", "json")
+	l.SetOutput(output)
+	l.Info("first line")
+	assert.NilError(t, logger.Flush(l))
+	content, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(content), "first line"))
+
+	//a bit more than a megabyte
+	line := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		l.Info(line)
+	}
+	assert.NilError(t, output.Close())
+
+	//the compression runs in background
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		matches, err := filepath.Glob(filepath.Join(dir, "service-*.log.gz"))
+		if err != nil {
+			return poll.Error(err)
+		}
+		if len(matches) == 1 {
+			return poll.Success()
+		}
+		return poll.Continue("%d rotated files compressed", len(matches))
+	}, poll.WithTimeout(2*time.Second), poll.WithDelay(20*time.Millisecond))
+
+	_, err = logger.NewOutput(logger.OutputOptions{Outputs: []string{"syslog"}})
+	assert.ErrorContains(t, err, "unknown log output")
+}
@@ -0,0 +1,184 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	ErrIrreversible = errors.New("the migration can't be reverted")
+	//the store has a migration that the service doesn't know, it was applied by a newer version
+	ErrUnknownMigration = errors.New("unknown migration applied")
+)
+
+type (
+	// Migration changes the schema (or the data) of a store, Down reverts Up.
+	// A nil Down makes the migration irreversible
+	Migration struct {
+		Version int
+		Name    string
+		Up      func(ctx context.Context) error
+		Down    func(ctx context.Context) error
+	}
+
+	Applied struct {
+		Version   int       `json:"version"`
+		Name      string    `json:"name"`
+		AppliedAt time.Time `json:"applied_at"`
+	}
+
+	// Storer keeps track of the migrations applied to the store
+	Storer interface {
+		AppliedMigrations(ctx context.Context) ([]Applied, error)
+		MarkApplied(ctx context.Context, a Applied) error
+		MarkReverted(ctx context.Context, version int) error
+	}
+
+	Status struct {
+		Version int
+		Name    string
+		//nil if the migration is pending
+		AppliedAt *time.Time
+	}
+
+	// Migrator applies the migrations in order of version and reverts them in reverse order
+	Migrator struct {
+		store      Storer
+		migrations []Migration
+	}
+)
+
+// New sorts the migrations by version, the versions must be positive and unique
+func New(store Storer, migrations []Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("migration %q has version %d, the versions must be positive", m.Name, m.Version)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("migrations %q and %q have the same version %d", sorted[i-1].Name, m.Name, m.Version)
+		}
+		if m.Up == nil {
+			return nil, fmt.Errorf("migration %q has no up", m.Name)
+		}
+	}
+	return &Migrator{store: store, migrations: sorted}, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]Applied, error) {
+	list, err := m.store.AppliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+	applied := make(map[int]Applied, len(list))
+	for _, a := range list {
+		applied[a.Version] = a
+	}
+	for version, a := range applied {
+		if !m.known(version) {
+			return nil, fmt.Errorf("%w: %d (%s)", ErrUnknownMigration, version, a.Name)
+		}
+	}
+	return applied, nil
+}
+
+func (m *Migrator) known(version int) bool {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+// Up applies at most steps pending migrations (all of them if steps is not positive)
+// and returns the ones applied, it stops at the first failure
+func (m *Migrator) Up(ctx context.Context, steps int) ([]Migration, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, migration := range m.migrations {
+		if steps > 0 && len(done) == steps {
+			break
+		}
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+		if err := migration.Up(ctx); err != nil {
+			return done, fmt.Errorf("migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if err := m.store.MarkApplied(ctx, Applied{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().UTC()}); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations (at least one) and returns the ones reverted,
+// it stops at the first failure or irreversible migration
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(m.migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := m.migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %d (%s): %w", migration.Version, migration.Name, ErrIrreversible)
+		}
+		if err := migration.Down(ctx); err != nil {
+			return done, fmt.Errorf("revert of migration %d (%s) failed: %w", migration.Version, migration.Name, err)
+		}
+		if err := m.store.MarkReverted(ctx, migration.Version); err != nil {
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// Status returns every migration with the time it was applied
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := make([]Status, len(m.migrations))
+	for i, migration := range m.migrations {
+		status[i] = Status{Version: migration.Version, Name: migration.Name}
+		if a, ok := applied[migration.Version]; ok {
+			appliedAt := a.AppliedAt
+			status[i].AppliedAt = &appliedAt
+		}
+	}
+	return status, nil
+}
+
+// Pending returns the number of migrations not applied yet
+func (m *Migrator) Pending(ctx context.Context) (int, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	pending := 0
+	for _, s := range status {
+		if s.AppliedAt == nil {
+			pending++
+		}
+	}
+	return pending, nil
+}
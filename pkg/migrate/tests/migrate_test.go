@@ -0,0 +1,127 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/vano2903/service-template/pkg/migrate"
+	"gotest.tools/v3/assert"
+)
+
+// store keeps the applied migrations in memory
+type store struct {
+	applied []migrate.Applied
+}
+
+func (s *store) AppliedMigrations(context.Context) ([]migrate.Applied, error) {
+	return s.applied, nil
+}
+
+func (s *store) MarkApplied(_ context.Context, a migrate.Applied) error {
+	s.applied = append(s.applied, a)
+	return nil
+}
+
+func (s *store) MarkReverted(_ context.Context, version int) error {
+	for i, a := range s.applied {
+		if a.Version == version {
+			s.applied = append(s.applied[:i], s.applied[i+1:]...)
+		}
+	}
+	return nil
+}
+
+// migrations returns the migrations, in the wrong order, that record in log what they run
+func migrations(log *[]string) []migrate.Migration {
+	step := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			*log = append(*log, name)
+			return nil
+		}
+	}
+	return []migrate.Migration{
+		{Version: 2, Name: "second", Up: step("up 2"), Down: step("down 2")},
+		{Version: 1, Name: "first", Up: step("up 1"), Down: step("down 1")},
+		{Version: 3, Name: "third", Up: step("up 3"), Down: step("down 3")},
+	}
+}
+
+// migrator, cases:
+// [x] the migrations are applied in order of version, only once
+// [x] the steps limit the migrations applied and reverted
+// [x] down reverts the last migration by default
+// [x] the status reports the pending migrations
+func TestMigrator(t *testing.T) {
+	ctx := context.Background()
+	var log []string
+	s := &store{}
+	m, err := migrate.New(s, migrations(&log))
+	assert.NilError(t, err)
+
+	pending, err := m.Pending(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, pending, 3)
+
+	applied, err := m.Up(ctx, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(applied), 2)
+	status, err := m.Status(ctx)
+	assert.NilError(t, err)
+	assert.Assert(t, status[0].AppliedAt != nil && status[1].AppliedAt != nil)
+	assert.Assert(t, status[2].AppliedAt == nil)
+
+	_, err = m.Up(ctx, 0)
+	assert.NilError(t, err)
+	applied, err = m.Up(ctx, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(applied), 0)
+
+	reverted, err := m.Down(ctx, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(reverted), 1)
+	assert.Equal(t, reverted[0].Version, 3)
+	_, err = m.Down(ctx, 5)
+	assert.NilError(t, err)
+	pending, err = m.Pending(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, pending, 3)
+
+	assert.DeepEqual(t, log, []string{"up 1", "up 2", "up 3", "down 3", "down 2", "down 1"})
+}
+
+// errors, cases:
+// [x] the versions must be positive and unique
+// [x] a failed migration stops the others and is not marked as applied
+// [x] an irreversible migration stops down
+// [x] a migration applied by a newer version of the service is reported
+func TestMigratorErrors(t *testing.T) {
+	ctx := context.Background()
+	noop := func(context.Context) error { return nil }
+
+	_, err := migrate.New(&store{}, []migrate.Migration{{Version: 0, Name: "zero", Up: noop}})
+	assert.ErrorContains(t, err, "positive")
+	_, err = migrate.New(&store{}, []migrate.Migration{{Version: 1, Name: "a", Up: noop}, {Version: 1, Name: "b", Up: noop}})
+	assert.ErrorContains(t, err, "same version")
+
+	failure := errors.New("disk full")
+	s := &store{}
+	m, err := migrate.New(s, []migrate.Migration{
+		{Version: 1, Name: "irreversible", Up: noop},
+		{Version: 2, Name: "failing", Up: func(context.Context) error { return failure }},
+		{Version: 3, Name: "never run", Up: noop},
+	})
+	assert.NilError(t, err)
+	applied, err := m.Up(ctx, 0)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, len(applied), 1)
+	assert.Equal(t, len(s.applied), 1)
+
+	_, err = m.Down(ctx, 1)
+	assert.ErrorIs(t, err, migrate.ErrIrreversible)
+	assert.Equal(t, len(s.applied), 1)
+
+	s.applied = append(s.applied, migrate.Applied{Version: 9, Name: "from the future"})
+	_, err = m.Status(ctx)
+	assert.ErrorIs(t, err, migrate.ErrUnknownMigration)
+}
@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// how often the idle keys are removed
+const sweepInterval = time.Minute
+
+type (
+	bucket struct {
+		tokens float64
+		last   time.Time
+		//when the bucket is full again, then it's the same as a missing one
+		full time.Time
+	}
+
+	window struct {
+		//unix nanoseconds, the times with the monotonic clock would not be comparable
+		start int64
+		size  time.Duration
+		prev  int
+		curr  int
+	}
+
+	// Memory keeps the limits in memory, every instance of the service has its own limits
+	Memory struct {
+		mu        sync.Mutex
+		buckets   map[string]*bucket
+		windows   map[string]*window
+		lastSweep time.Time
+	}
+)
+
+var _ Storer = new(Memory)
+
+func NewMemory() *Memory {
+	return &Memory{
+		buckets: make(map[string]*bucket),
+		windows: make(map[string]*window),
+	}
+}
+
+func (m *Memory) TakeToken(_ context.Context, key string, capacity int, interval time.Duration, now time.Time) (bool, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(capacity), last: now}
+		m.buckets[key] = b
+	}
+	if now.After(b.last) {
+		b.tokens = math.Min(float64(capacity), b.tokens+float64(now.Sub(b.last))/float64(interval))
+		b.last = now
+	}
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+	b.full = b.last.Add(time.Duration((float64(capacity) - b.tokens) * float64(interval)))
+	return allowed, b.tokens, nil
+}
+
+func (m *Memory) Hit(_ context.Context, key string, limit int, size time.Duration, now time.Time) (bool, int, int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now)
+
+	elapsed := WindowElapsed(now, size)
+	start := now.UnixNano() - int64(elapsed)
+	w, ok := m.windows[key]
+	if !ok {
+		w = &window{start: start, size: size}
+		m.windows[key] = w
+	}
+	switch {
+	case start == w.start+int64(size):
+		w.start, w.prev, w.curr = start, w.curr, 0
+	case start > w.start:
+		w.start, w.prev, w.curr = start, 0, 0
+	}
+	w.size = size
+	if Estimate(w.prev, w.curr, elapsed, size)+1 > float64(limit) {
+		return false, w.prev, w.curr, nil
+	}
+	w.curr++
+	return true, w.prev, w.curr, nil
+}
+
+// sweep removes the full buckets and the windows that don't count anymore, they are
+// the same as the missing ones. It must be called with the lock held
+func (m *Memory) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		if !now.Before(b.full) {
+			delete(m.buckets, key)
+		}
+	}
+	for key, w := range m.windows {
+		//after two windows the counts are not used anymore
+		if now.UnixNano() >= w.start+2*int64(w.size) {
+			delete(m.windows, key)
+		}
+	}
+}
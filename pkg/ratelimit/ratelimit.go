@@ -0,0 +1,211 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// algorithms
+const (
+	// TokenBucket allows bursts of Burst requests, the tokens are refilled at Requests per Period
+	TokenBucket = "token_bucket"
+	// SlidingWindow allows Requests in any Period, it's estimated from the counts of the current
+	// and of the previous fixed windows so it needs only two counters per key
+	SlidingWindow = "sliding_window"
+)
+
+// what the requests are grouped by, the key is extracted by the callers (like the http middleware)
+const (
+	KeyIP     = "ip"
+	KeyUser   = "user"
+	KeyAPIKey = "api_key"
+)
+
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+type (
+	Limit struct {
+		Algorithm string
+		//requests allowed every period, 0 disables the limit
+		Requests int
+		Period   time.Duration
+		//requests allowed at once by the token bucket, Requests if 0
+		Burst int
+		//what the requests are grouped by: ip, user or api_key
+		Key string
+	}
+
+	Result struct {
+		Allowed bool
+		//requests allowed in the period (or the size of the bucket)
+		Limit     int
+		Remaining int
+		//time until the limit is completely restored
+		Reset time.Duration
+		//time to wait before the next request is allowed, 0 if this one is allowed
+		RetryAfter time.Duration
+	}
+
+	// Storer keeps the state of the limits, the operations must be atomic as the
+	// same key can be limited by more instances of the service at the same time
+	Storer interface {
+		// TakeToken refills the bucket of key and takes a token if there is one,
+		// it returns if the token was taken and the tokens left
+		TakeToken(ctx context.Context, key string, capacity int, interval time.Duration, now time.Time) (bool, float64, error)
+		// Hit counts the request in the current window of key if the estimated count is under the limit,
+		// it returns if the request was counted and the counts of the previous and of the current window
+		Hit(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, int, error)
+	}
+)
+
+// Validate checks the limit, a disabled limit is always valid
+func (l Limit) Validate() error {
+	if l.Requests == 0 {
+		return nil
+	}
+	switch {
+	case l.Algorithm != TokenBucket && l.Algorithm != SlidingWindow:
+		return fmt.Errorf("%w: unknown algorithm %q", ErrInvalidLimit, l.Algorithm)
+	case l.Requests < 0:
+		return fmt.Errorf("%w: the requests can't be negative", ErrInvalidLimit)
+	case l.Period <= 0:
+		return fmt.Errorf("%w: the period must be positive", ErrInvalidLimit)
+	case l.Period < time.Duration(l.Requests):
+		return fmt.Errorf("%w: the period is too short for %d requests", ErrInvalidLimit, l.Requests)
+	case l.Burst < 0:
+		return fmt.Errorf("%w: the burst can't be negative", ErrInvalidLimit)
+	case l.Key != KeyIP && l.Key != KeyUser && l.Key != KeyAPIKey:
+		return fmt.Errorf("%w: unknown key %q", ErrInvalidLimit, l.Key)
+	}
+	return nil
+}
+
+// Enabled returns false if the limit allows every request
+func (l Limit) Enabled() bool {
+	return l.Requests > 0
+}
+
+// Limiter limits the requests of a group (like the login or the whole api), the keys
+// of different limiters don't collide even if they use the same store
+type Limiter struct {
+	name  string
+	store Storer
+	limit atomic.Pointer[Limit]
+}
+
+func NewLimiter(name string, store Storer, limit Limit) (*Limiter, error) {
+	l := &Limiter{name: name, store: store}
+	if err := l.SetLimit(limit); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// SetLimit replaces the limit, it's used to apply the config without a restart.
+// The state kept by the store is reused so a changed limit doesn't reset the counters
+func (l *Limiter) SetLimit(limit Limit) error {
+	if err := limit.Validate(); err != nil {
+		return err
+	}
+	l.limit.Store(&limit)
+	return nil
+}
+
+func (l *Limiter) Name() string {
+	return l.name
+}
+
+func (l *Limiter) Limit() Limit {
+	return *l.limit.Load()
+}
+
+// Allow takes a request of key from the limit
+func (l *Limiter) Allow(ctx context.Context, key string) (Result, error) {
+	return l.AllowAt(ctx, key, time.Now())
+}
+
+// AllowAt is Allow at the given time
+func (l *Limiter) AllowAt(ctx context.Context, key string, now time.Time) (Result, error) {
+	limit := l.Limit()
+	if !limit.Enabled() {
+		return Result{Allowed: true}, nil
+	}
+	key = l.name + ":" + limit.Algorithm + ":" + key
+	if limit.Algorithm == TokenBucket {
+		return tokenBucket(ctx, l.store, key, limit, now)
+	}
+	return slidingWindow(ctx, l.store, key, limit, now)
+}
+
+func tokenBucket(ctx context.Context, store Storer, key string, limit Limit, now time.Time) (Result, error) {
+	capacity := limit.Burst
+	if capacity == 0 {
+		capacity = limit.Requests
+	}
+	interval := limit.Period / time.Duration(limit.Requests)
+	allowed, tokens, err := store.TakeToken(ctx, key, capacity, interval, now)
+	if err != nil {
+		return Result{}, err
+	}
+	r := Result{
+		Allowed:   allowed,
+		Limit:     capacity,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     time.Duration((float64(capacity) - tokens) * float64(interval)),
+	}
+	if !allowed {
+		r.RetryAfter = time.Duration((1 - tokens) * float64(interval))
+	}
+	return r, nil
+}
+
+func slidingWindow(ctx context.Context, store Storer, key string, limit Limit, now time.Time) (Result, error) {
+	window := limit.Period
+	allowed, prev, curr, err := store.Hit(ctx, key, limit.Requests, window, now)
+	if err != nil {
+		return Result{}, err
+	}
+	elapsed := WindowElapsed(now, window)
+	estimate := Estimate(prev, curr, elapsed, window)
+	r := Result{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Max(0, float64(limit.Requests)-math.Ceil(estimate))),
+		//the previous window stops counting at the end of the current one
+		Reset: window - elapsed,
+	}
+	if curr == 0 && prev == 0 {
+		r.Reset = 0
+	}
+	if allowed {
+		return r, nil
+	}
+
+	//the previous window counts less and less, the request is allowed when the estimate drops below the limit
+	free := float64(limit.Requests - 1 - curr)
+	if free >= 0 && prev > 0 {
+		r.RetryAfter = time.Duration(float64(window)*(1-free/float64(prev))) - elapsed
+	} else {
+		//the current window is full, in the next one it becomes the previous
+		r.RetryAfter = window - elapsed + time.Duration(float64(window)*math.Max(0, 1-float64(limit.Requests-1)/float64(curr)))
+	}
+	if r.RetryAfter <= 0 {
+		r.RetryAfter = time.Millisecond
+	}
+	return r, nil
+}
+
+// WindowElapsed returns the time elapsed since the start of the fixed window of now, the windows
+// start at the unix epoch so every instance of the service agrees on them
+func WindowElapsed(now time.Time, window time.Duration) time.Duration {
+	return time.Duration(now.UnixNano() % int64(window))
+}
+
+// Estimate returns the requests counted in the sliding window, the stores use it to decide if a request is allowed
+func Estimate(prev, curr int, elapsed, window time.Duration) float64 {
+	return float64(prev)*(1-float64(elapsed)/float64(window)) + float64(curr)
+}
@@ -0,0 +1,131 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// the scripts run atomically in redis, the time is sent by the service so they
+// behave like the memory store and every instance agrees on the windows
+
+// KEYS[1] bucket, ARGV: capacity, interval in ms (with decimals), now in ms
+var takeToken = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local interval = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "last")
+local tokens = tonumber(state[1]) or capacity
+local last = tonumber(state[2]) or now
+if now > last then
+	tokens = math.min(capacity, tokens + (now - last) / interval)
+	last = now
+end
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "last", last)
+redis.call("PEXPIRE", KEYS[1], math.ceil((capacity - tokens) * interval) + 1000)
+return {allowed, tostring(tokens)}
+`)
+
+// KEYS[1] previous window, KEYS[2] current window, ARGV: limit, window in ms, elapsed in the window in ms
+var hit = redis.NewScript(`
+local limit = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local elapsed = tonumber(ARGV[3])
+local prev = tonumber(redis.call("GET", KEYS[1]) or "0")
+local curr = tonumber(redis.call("GET", KEYS[2]) or "0")
+if prev * (1 - elapsed / window) + curr + 1 > limit then
+	return {0, prev, curr}
+end
+curr = redis.call("INCR", KEYS[2])
+redis.call("PEXPIRE", KEYS[2], window * 2)
+return {1, prev, curr}
+`)
+
+type (
+	RedisOptions struct {
+		//host:port of the server (or of anything speaking the redis protocol, like valkey or dragonfly)
+		Address  string
+		Password string
+		DB       int
+		//prepended to the keys, so the server can be shared with other services
+		Prefix string
+		//timeout of every command
+		Timeout time.Duration
+	}
+
+	// Redis keeps the limits in redis, the instances of the service share the limits
+	Redis struct {
+		client *redis.Client
+		prefix string
+	}
+)
+
+var _ Storer = new(Redis)
+
+func NewRedis(opts RedisOptions) *Redis {
+	return &Redis{
+		client: redis.NewClient(&redis.Options{
+			Addr:         opts.Address,
+			Password:     opts.Password,
+			DB:           opts.DB,
+			DialTimeout:  opts.Timeout,
+			ReadTimeout:  opts.Timeout,
+			WriteTimeout: opts.Timeout,
+		}),
+		prefix: opts.Prefix,
+	}
+}
+
+func (r *Redis) TakeToken(ctx context.Context, key string, capacity int, interval time.Duration, now time.Time) (bool, float64, error) {
+	res, err := takeToken.Run(ctx, r.client, []string{r.prefix + key}, capacity, ms(interval), now.UnixMilli()).Slice()
+	if err != nil {
+		return false, 0, fmt.Errorf("rate limit of %s: %w", key, err)
+	}
+	if len(res) != 2 {
+		return false, 0, fmt.Errorf("rate limit of %s: unexpected reply %v", key, res)
+	}
+	tokens, err := strconv.ParseFloat(fmt.Sprint(res[1]), 64)
+	if err != nil {
+		return false, 0, fmt.Errorf("rate limit of %s: unexpected tokens %v", key, res[1])
+	}
+	return res[0] == int64(1), tokens, nil
+}
+
+func (r *Redis) Hit(ctx context.Context, key string, limit int, window time.Duration, now time.Time) (bool, int, int, error) {
+	elapsed := WindowElapsed(now, window)
+	start := now.Add(-elapsed).UnixMilli()
+	keys := []string{
+		r.prefix + key + ":" + strconv.FormatInt(start-window.Milliseconds(), 10),
+		r.prefix + key + ":" + strconv.FormatInt(start, 10),
+	}
+	res, err := hit.Run(ctx, r.client, keys, limit, window.Milliseconds(), elapsed.Milliseconds()).Int64Slice()
+	if err != nil {
+		return false, 0, 0, fmt.Errorf("rate limit of %s: %w", key, err)
+	}
+	if len(res) != 3 {
+		return false, 0, 0, fmt.Errorf("rate limit of %s: unexpected reply %v", key, res)
+	}
+	return res[0] == 1, int(res[1]), int(res[2]), nil
+}
+
+// Check pings the server, it's used by the readiness probe
+func (r *Redis) Check(ctx context.Context) error {
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *Redis) Close(context.Context) error {
+	return r.client.Close()
+}
+
+// ms returns the milliseconds with decimals, the intervals of the buckets can be shorter than a millisecond
+func ms(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+}
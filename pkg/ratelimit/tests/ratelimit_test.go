@@ -0,0 +1,171 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"gotest.tools/v3/assert"
+)
+
+// stores returns the stores to test, redis is served by miniredis
+func stores(t *testing.T) map[string]ratelimit.Storer {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	r := ratelimit.NewRedis(ratelimit.RedisOptions{Address: srv.Addr(), Prefix: "test:", Timeout: time.Second})
+	t.Cleanup(func() { r.Close(context.Background()) })
+	return map[string]ratelimit.Storer{
+		"memory": ratelimit.NewMemory(),
+		"redis":  r,
+	}
+}
+
+// the start of a minute, so the windows are predictable
+var start = time.Date(2030, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// token bucket, cases:
+// [x] the burst is allowed at once, then the requests wait for the refill
+// [x] the headers values (remaining, reset, retry after) follow the tokens
+// [x] the keys have separate buckets
+func TestTokenBucket(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limiter, err := ratelimit.NewLimiter("login", store, ratelimit.Limit{
+				Algorithm: ratelimit.TokenBucket,
+				Requests:  60,
+				Period:    time.Minute,
+				Burst:     3,
+				Key:       ratelimit.KeyIP,
+			})
+			assert.NilError(t, err)
+
+			for i := 0; i < 3; i++ {
+				r, err := limiter.AllowAt(ctx, "10.0.0.1", start)
+				assert.NilError(t, err)
+				assert.Assert(t, r.Allowed)
+				assert.Equal(t, r.Limit, 3)
+				assert.Equal(t, r.Remaining, 2-i)
+			}
+			r, err := limiter.AllowAt(ctx, "10.0.0.1", start)
+			assert.NilError(t, err)
+			assert.Assert(t, !r.Allowed)
+			assert.Equal(t, r.RetryAfter, time.Second)
+			assert.Equal(t, r.Reset, 3*time.Second)
+
+			r, err = limiter.AllowAt(ctx, "10.0.0.2", start)
+			assert.NilError(t, err)
+			assert.Assert(t, r.Allowed)
+
+			//one token every second
+			r, err = limiter.AllowAt(ctx, "10.0.0.1", start.Add(1500*time.Millisecond))
+			assert.NilError(t, err)
+			assert.Assert(t, r.Allowed)
+			assert.Equal(t, r.Remaining, 0)
+			r, err = limiter.AllowAt(ctx, "10.0.0.1", start.Add(1600*time.Millisecond))
+			assert.NilError(t, err)
+			assert.Assert(t, !r.Allowed)
+			assert.Equal(t, r.RetryAfter, 400*time.Millisecond)
+
+			r, err = limiter.AllowAt(ctx, "10.0.0.1", start.Add(time.Hour))
+			assert.NilError(t, err)
+			assert.Equal(t, r.Remaining, 2)
+		})
+	}
+}
+
+// sliding window, cases:
+// [x] the requests of the period are allowed, the next one is refused
+// [x] the previous window counts less as time passes
+// [x] the retry after is when the estimate drops below the limit
+func TestSlidingWindow(t *testing.T) {
+	for name, store := range stores(t) {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			limiter, err := ratelimit.NewLimiter("api", store, ratelimit.Limit{
+				Algorithm: ratelimit.SlidingWindow,
+				Requests:  4,
+				Period:    time.Minute,
+				Key:       ratelimit.KeyUser,
+			})
+			assert.NilError(t, err)
+
+			for i := 0; i < 4; i++ {
+				r, err := limiter.AllowAt(ctx, "42", start.Add(30*time.Second))
+				assert.NilError(t, err)
+				assert.Assert(t, r.Allowed)
+				assert.Equal(t, r.Remaining, 3-i)
+				assert.Equal(t, r.Reset, 30*time.Second)
+			}
+			r, err := limiter.AllowAt(ctx, "42", start.Add(30*time.Second))
+			assert.NilError(t, err)
+			assert.Assert(t, !r.Allowed)
+			//the next window starts full, it's free enough after a quarter of it
+			assert.Equal(t, r.RetryAfter, 45*time.Second)
+
+			//a quarter in the next window the 4 requests count as 3
+			r, err = limiter.AllowAt(ctx, "42", start.Add(75*time.Second))
+			assert.NilError(t, err)
+			assert.Assert(t, r.Allowed)
+			assert.Equal(t, r.Remaining, 0)
+			r, err = limiter.AllowAt(ctx, "42", start.Add(75*time.Second))
+			assert.NilError(t, err)
+			assert.Assert(t, !r.Allowed)
+			//the previous window must count 2, at half of the window
+			assert.Equal(t, r.RetryAfter, 15*time.Second)
+
+			r, err = limiter.AllowAt(ctx, "42", start.Add(5*time.Minute))
+			assert.NilError(t, err)
+			assert.Assert(t, r.Allowed)
+			assert.Equal(t, r.Remaining, 3)
+		})
+	}
+}
+
+// limits, cases:
+// [x] a disabled limit allows everything
+// [x] the limit can be changed, the invalid ones are refused
+func TestLimit(t *testing.T) {
+	ctx := context.Background()
+	limiter, err := ratelimit.NewLimiter("api", ratelimit.NewMemory(), ratelimit.Limit{})
+	assert.NilError(t, err)
+	for i := 0; i < 100; i++ {
+		r, err := limiter.Allow(ctx, "10.0.0.1")
+		assert.NilError(t, err)
+		assert.Assert(t, r.Allowed)
+	}
+
+	limit := ratelimit.Limit{Algorithm: ratelimit.SlidingWindow, Requests: 1, Period: time.Hour, Key: ratelimit.KeyIP}
+	assert.NilError(t, limiter.SetLimit(limit))
+	r, err := limiter.Allow(ctx, "10.0.0.1")
+	assert.NilError(t, err)
+	assert.Assert(t, r.Allowed)
+	r, err = limiter.Allow(ctx, "10.0.0.1")
+	assert.NilError(t, err)
+	assert.Assert(t, !r.Allowed)
+
+	for _, invalid := range []ratelimit.Limit{
+		{Algorithm: "leaky_bucket", Requests: 1, Period: time.Second, Key: ratelimit.KeyIP},
+		{Algorithm: ratelimit.TokenBucket, Requests: 1, Key: ratelimit.KeyIP},
+		{Algorithm: ratelimit.TokenBucket, Requests: 1, Period: time.Second, Key: "country"},
+		{Algorithm: ratelimit.TokenBucket, Requests: -1, Period: time.Second, Key: ratelimit.KeyIP},
+		{Algorithm: ratelimit.TokenBucket, Requests: 10, Period: time.Nanosecond, Key: ratelimit.KeyIP},
+	} {
+		assert.ErrorIs(t, limiter.SetLimit(invalid), ratelimit.ErrInvalidLimit)
+	}
+	assert.Equal(t, limiter.Limit(), limit)
+}
+
+// the redis store fails when the server is down, the callers decide what to do
+func TestRedisDown(t *testing.T) {
+	srv := miniredis.RunT(t)
+	r := ratelimit.NewRedis(ratelimit.RedisOptions{Address: srv.Addr(), Timeout: 100 * time.Millisecond})
+	defer r.Close(context.Background())
+	assert.NilError(t, r.Check(context.Background()))
+	srv.Close()
+	assert.Assert(t, r.Check(context.Background()) != nil)
+	_, _, err := r.TakeToken(context.Background(), "key", 1, time.Second, time.Now())
+	assert.Assert(t, err != nil)
+}
@@ -0,0 +1,124 @@
+package secrets
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	_ Provider = new(File)
+	_ Provider = new(Env)
+)
+
+var (
+	ErrNotFound = errors.New("secret not found")
+	//the value looks like a reference but nothing is registered for its scheme
+	ErrUnknownScheme = errors.New("unknown secret scheme")
+)
+
+// Provider returns the secret referenced by ref, ref is the reference without the scheme:
+//
+//	file:///run/secrets/jwt         -> /run/secrets/jwt
+//	env:JWT_SECRET                  -> JWT_SECRET
+//	vault://secret/data/service#jwt -> secret/data/service#jwt
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// Resolver replaces the references (<scheme>:<ref>) with the secrets returned by the provider of the scheme,
+// the other values are returned as they are so a secret can still be written directly
+type Resolver struct {
+	mu        sync.RWMutex
+	providers map[string]Provider
+}
+
+// NewResolver returns a resolver with the file and env providers
+func NewResolver() *Resolver {
+	r := &Resolver{providers: make(map[string]Provider)}
+	r.Register("file", File{})
+	r.Register("env", Env{})
+	return r
+}
+
+// Register adds the provider of the scheme, replacing the previous one if any
+func (r *Resolver) Register(scheme string, p Provider) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers[scheme] = p
+}
+
+// IsReference says if the value references a secret of one of the known schemes
+// (registered or not, an unregistered scheme is an error when resolving)
+func IsReference(value string) bool {
+	scheme, _, ok := parse(value)
+	if !ok {
+		return false
+	}
+	switch scheme {
+	case "file", "env", "vault":
+		return true
+	}
+	return false
+}
+
+// Resolve returns the secret referenced by value or value itself if it's not a reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := parse(value)
+	if !ok {
+		return value, nil
+	}
+	r.mu.RLock()
+	p, registered := r.providers[scheme]
+	r.mu.RUnlock()
+	if !registered {
+		if IsReference(value) {
+			return "", fmt.Errorf("%w %q, the provider is not configured", ErrUnknownScheme, scheme)
+		}
+		//like http://..., not a reference
+		return value, nil
+	}
+	secret, err := p.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve the %s secret %q: %w", scheme, ref, err)
+	}
+	return secret, nil
+}
+
+// parse splits <scheme>:[//]<ref>
+func parse(value string) (string, string, bool) {
+	scheme, ref, found := strings.Cut(value, ":")
+	if !found || scheme == "" || ref == "" || strings.ContainsAny(scheme, " /") {
+		return "", "", false
+	}
+	return scheme, strings.TrimPrefix(ref, "//"), true
+}
+
+// File reads the secret from a file (like the docker and kubernetes secrets), the trailing newline is removed
+type File struct{}
+
+func (File) Resolve(_ context.Context, path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fmt.Errorf("%w: %v", ErrNotFound, err)
+		}
+		return "", err
+	}
+	return strings.TrimRight(string(content), "\r\n"), nil
+}
+
+// Env reads the secret from an environment variable, useful when the platform
+// injects the secrets with names we can't choose
+type Env struct{}
+
+func (Env) Resolve(_ context.Context, name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: the environment variable %s is not set", ErrNotFound, name)
+	}
+	return value, nil
+}
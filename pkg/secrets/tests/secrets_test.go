@@ -0,0 +1,143 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vano2903/service-template/pkg/secrets"
+	"gotest.tools/v3/assert"
+)
+
+const token = "s.test-token"
+
+// vaultStub answers like the kv engines of vault: secret/ is a v2 engine and kv/ a v1 one
+func vaultStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	kv := map[string]map[string]interface{}{
+		"/v1/secret/data/users": {"jwt_secret": "from-v2", "port": 8080},
+		"/v1/kv/users":          {"api_key": "from-v1"},
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != token {
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"errors":["permission denied"]}`))
+			return
+		}
+		if r.URL.Path == "/v1/secret/data/team" && r.Header.Get("X-Vault-Namespace") != "team" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.URL.Path == "/v1/secret/data/team" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": map[string]string{"key": "from-namespace"}}})
+			return
+		}
+		data, ok := kv[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"errors":[]}`))
+			return
+		}
+		if strings.HasPrefix(r.URL.Path, "/v1/secret/") {
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"data": data, "metadata": map[string]int{"version": 3}}})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// vault provider, cases:
+// [x] the secrets of the v2 and v1 kv engines are read
+// [x] the namespace is sent
+// [x] missing secrets and keys are not found errors
+// [x] a refused token is an unauthorized error
+// [x] non string values and invalid references are errors
+func TestVault(t *testing.T) {
+	srv := vaultStub(t)
+	ctx := context.Background()
+	vault, err := secrets.NewVault(secrets.VaultOptions{Address: srv.URL + "/", Token: token, Timeout: time.Second})
+	assert.NilError(t, err)
+
+	secret, err := vault.Resolve(ctx, "secret/data/users#jwt_secret")
+	assert.NilError(t, err)
+	assert.Equal(t, secret, "from-v2")
+	secret, err = vault.Resolve(ctx, "kv/users#api_key")
+	assert.NilError(t, err)
+	assert.Equal(t, secret, "from-v1")
+
+	_, err = vault.Resolve(ctx, "secret/data/team#key")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+	team, err := secrets.NewVault(secrets.VaultOptions{Address: srv.URL, Token: token, Namespace: "team", Timeout: time.Second})
+	assert.NilError(t, err)
+	secret, err = team.Resolve(ctx, "secret/data/team#key")
+	assert.NilError(t, err)
+	assert.Equal(t, secret, "from-namespace")
+
+	_, err = vault.Resolve(ctx, "secret/data/orders#jwt_secret")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+	_, err = vault.Resolve(ctx, "secret/data/users#api_key")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+	_, err = vault.Resolve(ctx, "secret/data/users#port")
+	assert.ErrorContains(t, err, "not a string")
+	_, err = vault.Resolve(ctx, "secret/data/users")
+	assert.ErrorContains(t, err, "<path>#<key>")
+
+	wrong, err := secrets.NewVault(secrets.VaultOptions{Address: srv.URL, Token: "s.wrong", Timeout: time.Second})
+	assert.NilError(t, err)
+	_, err = wrong.Resolve(ctx, "secret/data/users#jwt_secret")
+	assert.ErrorIs(t, err, secrets.ErrVaultUnauthorized)
+
+	_, err = secrets.NewVault(secrets.VaultOptions{Address: "vault:8200", Token: token})
+	assert.ErrorContains(t, err, "invalid vault address")
+	_, err = secrets.NewVault(secrets.VaultOptions{Address: srv.URL})
+	assert.ErrorContains(t, err, "token is required")
+}
+
+// resolver, cases:
+// [x] the values that are not references are returned as they are
+// [x] file and env references are resolved without configuration
+// [x] vault references need the vault provider
+// [x] the registered providers are used for their scheme
+func TestResolver(t *testing.T) {
+	ctx := context.Background()
+	r := secrets.NewResolver()
+
+	for _, plain := range []string{"", "a-plain-secret", "http://localhost:8080", "mongodb://user@localhost", "with:colon"} {
+		value, err := r.Resolve(ctx, plain)
+		assert.NilError(t, err)
+		assert.Equal(t, value, plain)
+	}
+
+	path := filepath.Join(t.TempDir(), "jwt")
+	assert.NilError(t, os.WriteFile(path, []byte("from-file\n"), 0o600))
+	value, err := r.Resolve(ctx, "file://"+path)
+	assert.NilError(t, err)
+	assert.Equal(t, value, "from-file")
+	_, err = r.Resolve(ctx, "file:///run/secrets/missing")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+
+	t.Setenv("SECRETS_TEST_VALUE", "from-env")
+	value, err = r.Resolve(ctx, "env:SECRETS_TEST_VALUE")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "from-env")
+	_, err = r.Resolve(ctx, "env:SECRETS_TEST_MISSING")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+
+	_, err = r.Resolve(ctx, "vault://secret/data/users#jwt_secret")
+	assert.ErrorIs(t, err, secrets.ErrUnknownScheme)
+	srv := vaultStub(t)
+	vault, err := secrets.NewVault(secrets.VaultOptions{Address: srv.URL, Token: token, Timeout: time.Second})
+	assert.NilError(t, err)
+	r.Register("vault", vault)
+	value, err = r.Resolve(ctx, "vault://secret/data/users#jwt_secret")
+	assert.NilError(t, err)
+	assert.Equal(t, value, "from-v2")
+}
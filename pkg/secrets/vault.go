@@ -0,0 +1,126 @@
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/vano2903/service-template/pkg/tracing"
+)
+
+var _ Provider = new(Vault)
+
+var ErrVaultUnauthorized = errors.New("vault refused the token")
+
+// Vault reads the secrets from the KV engine (v1 or v2) of a vault server (or anything with the same api).
+// The reference is the path of the secret and the key inside it:
+//
+//	vault://secret/data/service-template#jwt_secret
+//
+// that is read with
+//
+//	GET <address>/v1/secret/data/service-template
+//	X-Vault-Token: <token>
+//
+// the v2 engine answers with {"data": {"data": {"jwt_secret": "..."}}}, the v1 one with {"data": {"jwt_secret": "..."}}
+type Vault struct {
+	address   string
+	token     string
+	namespace string
+	http      *http.Client
+}
+
+type VaultOptions struct {
+	//like https://vault.example.com:8200
+	Address string
+	Token   string
+	//enterprise namespace, empty for the root one
+	Namespace string
+	//timeout of a single read
+	Timeout time.Duration
+}
+
+type vaultResponse struct {
+	Data map[string]json.RawMessage `json:"data"`
+}
+
+func NewVault(opts VaultOptions) (*Vault, error) {
+	u, err := url.Parse(opts.Address)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid vault address %q", opts.Address)
+	}
+	if opts.Token == "" {
+		return nil, fmt.Errorf("the vault token is required")
+	}
+	return &Vault{
+		address:   strings.TrimRight(opts.Address, "/"),
+		token:     opts.Token,
+		namespace: opts.Namespace,
+		http: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: tracing.Transport(http.DefaultTransport),
+		},
+	}, nil
+}
+
+func (v *Vault) Resolve(ctx context.Context, ref string) (string, error) {
+	path, key, found := strings.Cut(ref, "#")
+	path = strings.Trim(path, "/")
+	if !found || path == "" || key == "" {
+		return "", fmt.Errorf("the vault reference must be <path>#<key>")
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.address+"/v1/"+path, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("X-Vault-Token", v.token)
+	if v.namespace != "" {
+		req.Header.Set("X-Vault-Namespace", v.namespace)
+	}
+	resp, err := v.http.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("unable to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return "", fmt.Errorf("%w: no secret at %s", ErrNotFound, path)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", ErrVaultUnauthorized
+	case resp.StatusCode != http.StatusOK:
+		return "", fmt.Errorf("unexpected status code from vault: %d", resp.StatusCode)
+	}
+
+	body := vaultResponse{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&body); err != nil {
+		return "", fmt.Errorf("invalid response from vault: %w", err)
+	}
+	data := body.Data
+	//the v2 engine wraps the secret with its metadata
+	if nested, ok := data["data"]; ok {
+		if _, isKey := data[key]; !isKey {
+			data = nil
+			if err := json.Unmarshal(nested, &data); err != nil {
+				return "", fmt.Errorf("invalid response from vault: %w", err)
+			}
+		}
+	}
+
+	raw, ok := data[key]
+	if !ok {
+		return "", fmt.Errorf("%w: no key %s in %s", ErrNotFound, key, path)
+	}
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return "", fmt.Errorf("the key %s in %s is not a string", key, path)
+	}
+	return value, nil
+}
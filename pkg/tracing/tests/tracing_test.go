@@ -0,0 +1,137 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vano2903/service-template/pkg/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"gotest.tools/v3/assert"
+)
+
+var (
+	recorderOnce sync.Once
+	recorder     *tracetest.SpanRecorder
+)
+
+// the tracers obtained before the global provider is set are bound to the
+// first provider set, so all the tests share the same recorder
+func spanRecorder() *tracetest.SpanRecorder {
+	recorderOnce.Do(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+	return recorder
+}
+
+func spansOf(traceID trace.TraceID) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, s := range spanRecorder().Ended() {
+		if s.SpanContext().TraceID() == traceID {
+			spans = append(spans, s)
+		}
+	}
+	return spans
+}
+
+// transport, cases:
+// [x] the trace context is sent to the called service
+// [x] the client span is a child of the span in the context of the request
+// [x] a 5xx response marks the span as failed
+// [x] the request given to the transport is not modified
+func TestTransport(t *testing.T) {
+	spanRecorder()
+
+	var received string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header.Get("traceparent")
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: tracing.Transport(nil)}
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/ok?key=secret", nil)
+	assert.NilError(t, err)
+	resp, err := client.Do(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, req.Header.Get("traceparent"), "")
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/fail", nil)
+	assert.NilError(t, err)
+	resp, err = client.Do(req)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	parent.End()
+
+	traceID := parent.SpanContext().TraceID()
+	assert.Assert(t, strings.Contains(received, traceID.String()), "traceparent %q", received)
+
+	spans := spansOf(traceID)
+	assert.Equal(t, len(spans), 3)
+	ok, failed := spans[0], spans[1]
+	assert.Equal(t, ok.Name(), "HTTP GET")
+	assert.Equal(t, ok.SpanKind(), trace.SpanKindClient)
+	assert.Equal(t, ok.Parent().SpanID(), parent.SpanContext().SpanID())
+	assert.Equal(t, ok.Status().Code, codes.Unset)
+	for _, attr := range ok.Attributes() {
+		if attr.Key == "http.url" {
+			assert.Equal(t, attr.Value.AsString(), srv.URL+"/ok")
+		}
+	}
+	assert.Equal(t, failed.Status().Code, codes.Error)
+}
+
+// setup, cases:
+// [x] the file exporter writes the spans in the file when the tracing shuts down
+// [x] an unknown exporter is an error
+// [x] the none exporter doesn't need anything
+func TestSetup(t *testing.T) {
+	t.Run("file exporter", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "traces", "traces.json")
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+			ServiceName: "test",
+			Exporter:    tracing.ExporterFile,
+			File:        path,
+			SampleRatio: 1,
+		})
+		assert.NilError(t, err)
+
+		//the global provider was already set by the other tests, the provider
+		//set by Setup is used directly
+		_, span := otel.GetTracerProvider().Tracer("test").Start(context.Background(), "exported span")
+		span.End()
+		assert.NilError(t, shutdown(context.Background()))
+
+		content, err := os.ReadFile(path)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(content), "exported span"))
+	})
+
+	t.Run("unknown exporter", func(t *testing.T) {
+		_, err := tracing.Setup(context.Background(), tracing.Options{Exporter: "carrier pigeon"})
+		assert.ErrorContains(t, err, "unknown trace exporter")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		shutdown, err := tracing.Setup(context.Background(), tracing.Options{Exporter: tracing.ExporterNone})
+		assert.NilError(t, err)
+		assert.NilError(t, shutdown(context.Background()))
+	})
+}
@@ -0,0 +1,64 @@
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/workqueue"
+	"gotest.tools/v3/assert"
+)
+
+type failure struct {
+	id  int
+	err error
+}
+
+// retries, cases:
+// [x] a retry that finds the queue full is reported as failed with ErrQueueFull
+func TestRetryQueueFull(t *testing.T) {
+	l := logrus.New()
+	l.SetLevel(logrus.PanicLevel)
+	started, release := make(chan int, 3), make(chan struct{})
+	failed := make(chan failure, 3)
+	q := workqueue.New("test", l, func(ctx context.Context, id int) error {
+		started <- id
+		if id == 1 {
+			return errors.New("provider is down")
+		}
+		<-release
+		return nil
+	}, func(id int, err error) {
+		failed <- failure{id, err}
+	}, workqueue.Options{Workers: 1, Size: 1, MaxAttempts: 3, MinBackoff: 100 * time.Millisecond, MaxBackoff: 100 * time.Millisecond})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		q.Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		close(release)
+		cancel()
+		<-done
+	}()
+
+	assert.NilError(t, q.Enqueue(1))
+	assert.Equal(t, <-started, 1)
+	//while 1 waits for the retry the worker is busy with 2 and 3 fills the queue
+	assert.NilError(t, q.Enqueue(2))
+	assert.Equal(t, <-started, 2)
+	assert.NilError(t, q.Enqueue(3))
+
+	select {
+	case f := <-failed:
+		assert.Equal(t, f.id, 1)
+		assert.ErrorIs(t, f.err, workqueue.ErrQueueFull)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the dropped retry was not reported")
+	}
+	assert.Equal(t, q.Len(), 1)
+}
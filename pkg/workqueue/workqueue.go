@@ -0,0 +1,215 @@
+// Package workqueue runs background jobs identified by an int id (usually the id of a user)
+// with a pool of workers, retrying the failed ones with an exponential backoff.
+// The queue is in memory, whoever enqueues the jobs must be able to enqueue them again
+// after a restart (for example looking at a status stored in the repo).
+package workqueue
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/backoff"
+)
+
+const (
+	_DEFAULT_WORKERS      = 4
+	_DEFAULT_SIZE         = 1000
+	_DEFAULT_MAX_ATTEMPTS = 5
+	_DEFAULT_MIN_BACKOFF  = time.Second
+	_DEFAULT_MAX_BACKOFF  = time.Minute
+)
+
+var (
+	ErrQueueFull   = errors.New("queue is full")
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+type (
+	//Handler runs the job, if it returns an error the job is retried
+	Handler func(ctx context.Context, id int) error
+	//FailedHandler is called when a job failed every attempt, err is the last error (or
+	//ErrQueueFull if the queue had no room for a retry)
+	FailedHandler func(id int, err error)
+
+	Options struct {
+		Workers     int
+		Size        int
+		MaxAttempts int
+		MinBackoff  time.Duration
+		MaxBackoff  time.Duration
+	}
+)
+
+// A job is queued at most once: enqueuing an id that is already waiting (in the queue or
+// for a retry) does nothing. An id enqueued while its job is running is queued again.
+type Queue struct {
+	name     string
+	l        *logrus.Logger
+	handle   Handler
+	onFailed FailedHandler
+	opts     Options
+
+	jobs chan int
+
+	mu       sync.Mutex
+	waiting  map[int]bool
+	attempts map[int]int
+	retries  map[int]*time.Timer
+	closed   bool
+}
+
+func New(name string, l *logrus.Logger, handle Handler, onFailed FailedHandler, opts Options) *Queue {
+	if opts.Workers <= 0 {
+		opts.Workers = _DEFAULT_WORKERS
+	}
+	if opts.Size <= 0 {
+		opts.Size = _DEFAULT_SIZE
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = _DEFAULT_MAX_ATTEMPTS
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = _DEFAULT_MIN_BACKOFF
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = _DEFAULT_MAX_BACKOFF
+	}
+	return &Queue{
+		name:     name,
+		l:        l,
+		handle:   handle,
+		onFailed: onFailed,
+		opts:     opts,
+		jobs:     make(chan int, opts.Size),
+		waiting:  make(map[int]bool),
+		attempts: make(map[int]int),
+		retries:  make(map[int]*time.Timer),
+	}
+}
+
+// Enqueue adds the job to the queue, it never blocks: if the queue is full ErrQueueFull is returned
+func (q *Queue) Enqueue(id int) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	if q.waiting[id] {
+		return nil
+	}
+	select {
+	case q.jobs <- id:
+		q.waiting[id] = true
+		delete(q.attempts, id)
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// Len returns how many jobs are waiting to be run, including the ones waiting for a retry
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return len(q.waiting)
+}
+
+// Run starts the workers and blocks until the context is canceled and the running jobs are done.
+// The jobs still in the queue are dropped, the queue can't be used after Run returns.
+func (q *Queue) Run(ctx context.Context) {
+	wg := sync.WaitGroup{}
+	for i := 0; i < q.opts.Workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	<-ctx.Done()
+
+	q.mu.Lock()
+	q.closed = true
+	for _, t := range q.retries {
+		t.Stop()
+	}
+	q.mu.Unlock()
+	wg.Wait()
+}
+
+func (q *Queue) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-q.jobs:
+			q.run(ctx, id)
+		}
+	}
+}
+
+func (q *Queue) run(ctx context.Context, id int) {
+	q.mu.Lock()
+	delete(q.waiting, id)
+	q.attempts[id]++
+	attempts := q.attempts[id]
+	q.mu.Unlock()
+
+	err := q.handle(ctx, id)
+	if err == nil {
+		q.mu.Lock()
+		delete(q.attempts, id)
+		q.mu.Unlock()
+		return
+	}
+
+	if attempts >= q.opts.MaxAttempts {
+		q.l.Errorf("workqueue.%s: job %d failed %d times, giving up: %v", q.name, id, attempts, err)
+		q.mu.Lock()
+		delete(q.attempts, id)
+		q.mu.Unlock()
+		if q.onFailed != nil {
+			q.onFailed(id, err)
+		}
+		return
+	}
+
+	wait := backoff.Exponential(attempts-1, q.opts.MinBackoff, q.opts.MaxBackoff)
+	q.l.Warnf("workqueue.%s: job %d failed (attempt %d/%d), retrying in %s: %v", q.name, id, attempts, q.opts.MaxAttempts, wait, err)
+	q.retry(id, wait)
+}
+
+// retry puts the job back in the queue after the wait, keeping the count of the attempts
+func (q *Queue) retry(id int, wait time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed || q.waiting[id] {
+		//the job was enqueued again while running, that counts as a new job
+		return
+	}
+	q.waiting[id] = true
+	q.retries[id] = time.AfterFunc(wait, func() {
+		q.mu.Lock()
+		delete(q.retries, id)
+		if q.closed {
+			q.mu.Unlock()
+			return
+		}
+		select {
+		case q.jobs <- id:
+			q.mu.Unlock()
+			return
+		default:
+			delete(q.waiting, id)
+			delete(q.attempts, id)
+			q.mu.Unlock()
+		}
+		//the job failed like it had no attempts left, so whoever enqueued it can mark it
+		q.l.Errorf("workqueue.%s: queue full, dropping the retry of job %d", q.name, id)
+		if q.onFailed != nil {
+			q.onFailed(id, ErrQueueFull)
+		}
+	})
+}
@@ -0,0 +1,29 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+type (
+	//A blob storage stores opaque files (images, exports...) by key,
+	//keys are slash separated paths like "pfp/1/abc/128.png"
+	BlobStorer interface {
+		Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+		//Get returns ErrNotFound if there is no blob with the key, the caller must close the reader
+		Get(ctx context.Context, key string) (io.ReadCloser, *Info, error)
+		//Delete doesn't fail if the blob doesn't exist
+		Delete(ctx context.Context, key string) error
+	}
+
+	Info struct {
+		Size         int64
+		ContentType  string
+		ETag         string
+		LastModified time.Time
+	}
+)
@@ -0,0 +1,103 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/pkg/backoff"
+	"github.com/vano2903/service-template/repo"
+)
+
+const (
+	_DEFAULT_INTERVAL    = time.Second
+	_DEFAULT_BATCH_SIZE  = 100
+	_DEFAULT_MIN_BACKOFF = time.Second
+	_DEFAULT_MAX_BACKOFF = 5 * time.Minute
+)
+
+// The dispatcher polls the outbox and delivers the pending events to every sink.
+// An event is marked as delivered only when all the sinks accepted it, otherwise
+// it's retried with an exponential backoff, so the delivery is at-least-once.
+type Dispatcher struct {
+	outbox     repo.OutboxRepoer
+	sinks      []EventSinker
+	l          *logrus.Logger
+	interval   time.Duration
+	batchSize  int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+}
+
+func NewDispatcher(outbox repo.OutboxRepoer, l *logrus.Logger, interval time.Duration, batchSize int, sinks ...EventSinker) *Dispatcher {
+	if interval <= 0 {
+		interval = _DEFAULT_INTERVAL
+	}
+	if batchSize <= 0 {
+		batchSize = _DEFAULT_BATCH_SIZE
+	}
+	return &Dispatcher{
+		outbox:     outbox,
+		sinks:      sinks,
+		l:          l,
+		interval:   interval,
+		batchSize:  batchSize,
+		minBackoff: _DEFAULT_MIN_BACKOFF,
+		maxBackoff: _DEFAULT_MAX_BACKOFF,
+	}
+}
+
+// Run dispatches the pending events every interval until the context is canceled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := d.DispatchPending(ctx); err != nil {
+			d.l.Errorf("events.Dispatcher: unable to dispatch pending events: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchPending runs a single pass over the outbox and returns how many events were delivered
+func (d *Dispatcher) DispatchPending(ctx context.Context) (int, error) {
+	events, err := d.outbox.GetPendingEvents(time.Now(), d.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return delivered, ctx.Err()
+		}
+
+		var failures []string
+		for _, s := range d.sinks {
+			if err := s.Deliver(ctx, e); err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", s.Name(), err))
+			}
+		}
+
+		if len(failures) > 0 {
+			retryAt := time.Now().Add(backoff.Exponential(e.Attempts, d.minBackoff, d.maxBackoff))
+			d.l.Warnf("events.Dispatcher: event %d (%s) not delivered, retrying at %s: %s", e.ID, e.Type, retryAt.Format(time.RFC3339), strings.Join(failures, "; "))
+			if err := d.outbox.MarkEventFailed(e.ID, strings.Join(failures, "; "), retryAt); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := d.outbox.MarkEventDelivered(e.ID, time.Now()); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+	return delivered, nil
+}
@@ -0,0 +1,18 @@
+package events
+
+import (
+	"context"
+
+	"github.com/vano2903/service-template/model"
+)
+
+type (
+	//A sink is where the dispatcher delivers the domain events (a log, a message broker, webhooks...).
+	//Deliveries are at-least-once: the same event can be delivered more than once
+	//(for example if another sink failed and the event is retried) so sinks
+	//should be idempotent using the event ID.
+	EventSinker interface {
+		Name() string
+		Deliver(ctx context.Context, e *model.Event) error
+	}
+)
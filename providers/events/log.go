@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+)
+
+var _ EventSinker = new(LogSink)
+
+// LogSink just logs the events, useful in development to see what is published
+type LogSink struct {
+	l *logrus.Logger
+}
+
+func NewLogSink(l *logrus.Logger) *LogSink {
+	return &LogSink{
+		l: l,
+	}
+}
+
+func (s *LogSink) Name() string {
+	return "log"
+}
+
+func (s *LogSink) Deliver(_ context.Context, e *model.Event) error {
+	s.l.WithFields(logrus.Fields{
+		"event_id":   e.ID,
+		"event_type": e.Type,
+		"user_id":    e.UserID,
+	}).Info("domain event")
+	return nil
+}
@@ -0,0 +1,260 @@
+package logo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/backoff"
+	"github.com/vano2903/service-template/pkg/breaker"
+	"github.com/vano2903/service-template/pkg/tracing"
+)
+
+var _ LogoServicer = new(Client)
+
+var (
+	ErrInvalidResponse = errors.New("invalid response from the logo provider")
+	ErrUnauthorized    = errors.New("the logo provider refused the api key")
+)
+
+// Client calls the logo provider api:
+//
+//	POST <base_url>/logos
+//	Authorization: Bearer <api_key>
+//
+// that answers with {"url": "<url of the logo>"}.
+// Every attempt has a timeout, failed attempts (network errors, 429 and 5xx) are retried
+// with an exponential backoff with jitter and if the provider keeps failing the circuit
+// breaker opens so we stop waiting for a provider that is down.
+type Client struct {
+	baseUri    string
+	apiKey     string
+	http       *http.Client
+	maxRetries int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	breaker    *breaker.Breaker
+	metrics    *clientMetrics
+
+	//math/rand.Rand is not safe for concurrent use
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+type ClientOptions struct {
+	//timeout of a single attempt
+	Timeout    time.Duration
+	MaxRetries int
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+	//consecutive failed calls (after the retries) that open the circuit
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+	//where the metrics are registered, if nil prometheus.DefaultRegisterer is used
+	Registerer prometheus.Registerer
+}
+
+type providerResponse struct {
+	URL string `json:"url"`
+}
+
+// retryableError is an error worth another attempt
+type retryableError struct {
+	err        error
+	retryAfter time.Duration
+}
+
+func (e *retryableError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryableError) Unwrap() error {
+	return e.err
+}
+
+func NewClient(apiKey, baseUri string, opts ClientOptions) (*Client, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = 5 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.MinBackoff <= 0 {
+		opts.MinBackoff = 100 * time.Millisecond
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = 2 * time.Second
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = 30 * time.Second
+	}
+	if opts.Registerer == nil {
+		opts.Registerer = prometheus.DefaultRegisterer
+	}
+
+	metrics, err := newClientMetrics(opts.Registerer)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{
+		baseUri:    strings.TrimRight(baseUri, "/"),
+		apiKey:     apiKey,
+		http:       &http.Client{Timeout: opts.Timeout, Transport: tracing.Transport(nil)},
+		maxRetries: opts.MaxRetries,
+		minBackoff: opts.MinBackoff,
+		maxBackoff: opts.MaxBackoff,
+		breaker:    breaker.New(opts.BreakerThreshold, opts.BreakerCooldown),
+		metrics:    metrics,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	c.breaker.OnStateChange(func(_, to breaker.State) {
+		c.metrics.breakerState.Set(float64(to))
+	})
+	return c, nil
+}
+
+func (c *Client) GenerateLogo(ctx context.Context, _ *model.User) (string, error) {
+	if err := c.breaker.Allow(); err != nil {
+		c.metrics.calls.WithLabelValues("circuit_open").Inc()
+		return "", err
+	}
+
+	url, err := c.generateWithRetries(ctx)
+	if ctx.Err() != nil {
+		//the caller gave up, the call says nothing about the provider (and it can't close the circuit)
+		c.breaker.Cancel()
+	} else {
+		//an unauthorized error is our fault, not a sign that the provider is down
+		c.breaker.Done(err == nil || errors.Is(err, ErrUnauthorized))
+	}
+	if err != nil {
+		c.metrics.calls.WithLabelValues("error").Inc()
+		return "", err
+	}
+	c.metrics.calls.WithLabelValues("success").Inc()
+	return url, nil
+}
+
+func (c *Client) generateWithRetries(ctx context.Context) (string, error) {
+	var err error
+	for attempt := 0; attempt <= c.maxRetries; attempt++ {
+		if attempt > 0 {
+			c.metrics.retries.Inc()
+			wait := c.jitter(backoff.Exponential(attempt-1, c.minBackoff, c.maxBackoff))
+			//the provider told us how much to wait, but never more than the max backoff
+			var re *retryableError
+			if errors.As(err, &re) && re.retryAfter > wait {
+				wait = re.retryAfter
+				if wait > c.maxBackoff {
+					wait = c.maxBackoff
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return "", ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		var url string
+		url, err = c.generate(ctx)
+		if err == nil {
+			return url, nil
+		}
+		var re *retryableError
+		if !errors.As(err, &re) {
+			return "", err
+		}
+	}
+	return "", fmt.Errorf("logo provider still failing after %d attempts: %w", c.maxRetries+1, err)
+}
+
+// generate makes a single attempt
+func (c *Client) generate(ctx context.Context) (string, error) {
+	start := time.Now()
+	code := "error"
+	defer func() {
+		c.metrics.attemptDuration.WithLabelValues(code).Observe(time.Since(start).Seconds())
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseUri+"/logos", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", &retryableError{err: err}
+	}
+	defer resp.Body.Close()
+	code = strconv.Itoa(resp.StatusCode)
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return "", ErrUnauthorized
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		return "", &retryableError{
+			err:        fmt.Errorf("logo provider answered with status code %d", resp.StatusCode),
+			retryAfter: retryAfter(resp.Header.Get("Retry-After")),
+		}
+	case resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated:
+		return "", fmt.Errorf("%w: unexpected status code %d", ErrInvalidResponse, resp.StatusCode)
+	}
+
+	body := providerResponse{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64<<10)).Decode(&body); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidResponse, err)
+	}
+	if body.URL == "" {
+		return "", fmt.Errorf("%w: empty url", ErrInvalidResponse)
+	}
+	return body.URL, nil
+}
+
+// retryAfter reads the Retry-After header, either the seconds to wait or the date to wait for
+func retryAfter(value string) time.Duration {
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+func (c *Client) jitter(d time.Duration) time.Duration {
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	return backoff.Jitter(d, c.rand)
+}
+
+// BreakerState is the current state of the circuit breaker
+func (c *Client) BreakerState() breaker.State {
+	return c.breaker.State()
+}
+
+// Check reports the provider as down while the circuit breaker is open, it doesn't call
+// the provider as every call costs (and the breaker already knows how the calls are going)
+func (c *Client) Check(_ context.Context) error {
+	if state := c.breaker.State(); state == breaker.Open {
+		return fmt.Errorf("circuit breaker is %s", state)
+	}
+	return nil
+}
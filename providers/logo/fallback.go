@@ -0,0 +1,42 @@
+package logo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+)
+
+var _ LogoServicer = new(Fallback)
+
+// Fallback tries the providers in order and returns the first logo generated,
+// for example the http client first and the local avatars when the provider is down
+type Fallback struct {
+	providers []LogoServicer
+	l         *logrus.Logger
+}
+
+func NewFallback(l *logrus.Logger, providers ...LogoServicer) *Fallback {
+	return &Fallback{
+		providers: providers,
+		l:         l,
+	}
+}
+
+func (f *Fallback) GenerateLogo(ctx context.Context, u *model.User) (string, error) {
+	var errs []string
+	for i, p := range f.providers {
+		logo, err := p.GenerateLogo(ctx, u)
+		if err == nil {
+			if i > 0 {
+				f.l.Warnf("logo.Fallback: logo generated by the fallback provider %d (%T)", i, p)
+			}
+			return logo, nil
+		}
+		f.l.Warnf("logo.Fallback: provider %d (%T) failed: %v", i, p, err)
+		errs = append(errs, fmt.Sprintf("%T: %v", p, err))
+	}
+	return "", fmt.Errorf("every logo provider failed: %s", strings.Join(errs, "; "))
+}
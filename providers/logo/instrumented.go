@@ -0,0 +1,80 @@
+package logo
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vano2903/service-template/model"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/vano2903/service-template/providers/logo"
+
+var _ LogoServicer = new(Instrumented)
+
+// ServiceMetrics are shared by every instrumented provider, the provider is a label
+type ServiceMetrics struct {
+	duration *prometheus.HistogramVec
+	calls    *prometheus.CounterVec
+}
+
+func NewServiceMetrics(reg prometheus.Registerer) (*ServiceMetrics, error) {
+	m := &ServiceMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "logo_service",
+			Name:      "generate_duration_seconds",
+			Help:      "Duration of the logo generations by provider.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"provider"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logo_service",
+			Name:      "generate_total",
+			Help:      "Logo generations by provider and outcome (success, error).",
+		}, []string{"provider", "outcome"}),
+	}
+	for _, c := range []prometheus.Collector{m.duration, m.calls} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// Instrumented records the latency and the errors of the wrapped provider and traces
+// every generation with a span, the http calls made by the provider are its children
+type Instrumented struct {
+	next    LogoServicer
+	name    string
+	metrics *ServiceMetrics
+	tracer  trace.Tracer
+}
+
+func NewInstrumented(next LogoServicer, name string, metrics *ServiceMetrics) *Instrumented {
+	return &Instrumented{
+		next:    next,
+		name:    name,
+		metrics: metrics,
+		tracer:  otel.Tracer(instrumentationName),
+	}
+}
+
+func (i *Instrumented) GenerateLogo(ctx context.Context, u *model.User) (string, error) {
+	ctx, span := i.tracer.Start(ctx, "logo.GenerateLogo", trace.WithAttributes(attribute.String("logo.provider", i.name)))
+	defer span.End()
+
+	begin := time.Now()
+	logo, err := i.next.GenerateLogo(ctx, u)
+	i.metrics.duration.WithLabelValues(i.name).Observe(time.Since(begin).Seconds())
+	outcome := "success"
+	if err != nil {
+		outcome = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	i.metrics.calls.WithLabelValues(i.name, outcome).Inc()
+	return logo, err
+}
@@ -1,7 +1,15 @@
 package logo
 
+import (
+	"context"
+
+	"github.com/vano2903/service-template/model"
+)
+
 type (
 	LogoServicer interface {
-		GenerateLogo() (string, error)
+		//GenerateLogo returns the url of a new logo for the user,
+		//a provider can ignore the user or use it to personalize the logo
+		GenerateLogo(ctx context.Context, u *model.User) (string, error)
 	}
 )
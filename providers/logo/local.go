@@ -0,0 +1,57 @@
+package logo
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/avatar"
+)
+
+var _ LogoServicer = new(LocalLogo)
+
+// LocalLogo doesn't depend on any external service, it returns the url of an avatar
+// rendered by the service itself (see the avatars http handler).
+// The avatar only depends on the user's email (and name for the initials style) so
+// it never fails and regenerating it gives the same avatar.
+type LocalLogo struct {
+	//url where the service is reachable from the clients, for example https://users.example.com
+	publicUrl string
+	style     string
+	format    string
+}
+
+func NewLocalLogo(publicUrl, style, format string) (*LocalLogo, error) {
+	if style != avatar.StyleIdenticon && style != avatar.StyleInitials {
+		return nil, fmt.Errorf("unknown avatar style %q", style)
+	}
+	if format != "png" && format != "svg" {
+		return nil, fmt.Errorf("unknown avatar format %q", format)
+	}
+	return &LocalLogo{
+		publicUrl: strings.TrimRight(publicUrl, "/"),
+		style:     style,
+		format:    format,
+	}, nil
+}
+
+// AvatarPath returns the path (relative to the public url) of the avatar of the user:
+//
+//	/avatars/identicon/<hash>.<format>
+//	/avatars/initials/<initials>-<hash>.<format>
+func AvatarPath(u *model.User, style, format string) string {
+	seed := avatar.Hash(u.Email)
+	if style == avatar.StyleInitials {
+		seed = avatar.Initials(u.FirstName, u.LastName) + "-" + seed
+	}
+	return fmt.Sprintf("/avatars/%s/%s.%s", style, url.PathEscape(seed), format)
+}
+
+func (s *LocalLogo) GenerateLogo(_ context.Context, u *model.User) (string, error) {
+	if u == nil {
+		return "", fmt.Errorf("the local logo provider needs the user")
+	}
+	return s.publicUrl + AvatarPath(u, s.style, s.format), nil
+}
@@ -1,21 +1,34 @@
 package logo
 
 import (
+	"context"
 	"fmt"
 	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/vano2903/service-template/model"
 )
 
 var _ LogoServicer = new(ServiceLogo)
 
+// ServiceLogo doesn't call the provider, it just appends a random string to the base uri.
+// It's useful in development and tests where the real provider (see Client) is not available.
 type ServiceLogo struct {
 	apiKey  string
 	baseUri string
+
+	//the global source of math/rand is not seeded (so it would generate the same
+	//logos at every start) and rand.Rand is not safe for concurrent use
+	randMu sync.Mutex
+	rand   *rand.Rand
 }
 
 func NewServiceLogo(apiKey string, baseUri string) *ServiceLogo {
 	return &ServiceLogo{
 		apiKey:  apiKey,
 		baseUri: baseUri,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
 	}
 }
 
@@ -23,13 +36,15 @@ func (s *ServiceLogo) generateRandomString() string {
 	//generate a random string
 	const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
 
+	s.randMu.Lock()
+	defer s.randMu.Unlock()
 	b := make([]byte, 8)
 	for i := range b {
-		b[i] = letterBytes[rand.Intn(len(letterBytes))]
+		b[i] = letterBytes[s.rand.Intn(len(letterBytes))]
 	}
 	return string(b)
 }
 
-func (s *ServiceLogo) GenerateLogo() (string, error) {
+func (s *ServiceLogo) GenerateLogo(_ context.Context, _ *model.User) (string, error) {
 	return fmt.Sprintf("%s/%s", s.baseUri, s.generateRandomString()), nil
 }
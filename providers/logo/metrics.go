@@ -0,0 +1,70 @@
+package logo
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type clientMetrics struct {
+	calls           *prometheus.CounterVec
+	attemptDuration *prometheus.HistogramVec
+	retries         prometheus.Counter
+	breakerState    prometheus.Gauge
+}
+
+func newClientMetrics(reg prometheus.Registerer) (*clientMetrics, error) {
+	m := &clientMetrics{
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "logo_provider",
+			Name:      "calls_total",
+			Help:      "Calls to the logo provider by outcome (success, error, circuit_open), retries included in a single call.",
+		}, []string{"outcome"}),
+		attemptDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "logo_provider",
+			Name:      "attempt_duration_seconds",
+			Help:      "Duration of the single http attempts to the logo provider by status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"code"}),
+		retries: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "logo_provider",
+			Name:      "retries_total",
+			Help:      "Attempts to the logo provider that were retries of a failed attempt.",
+		}),
+		breakerState: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: "logo_provider",
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker of the logo provider (0 closed, 1 half-open, 2 open).",
+		}),
+	}
+
+	var err error
+	if m.calls, err = register(reg, m.calls); err != nil {
+		return nil, err
+	}
+	if m.attemptDuration, err = register(reg, m.attemptDuration); err != nil {
+		return nil, err
+	}
+	if m.retries, err = register(reg, m.retries); err != nil {
+		return nil, err
+	}
+	if m.breakerState, err = register(reg, m.breakerState); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// register returns the collector already registered with the same name if there is one,
+// so the client can be created again when the config is reloaded
+func register[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if err := reg.Register(c); err != nil {
+		are, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return c, err
+		}
+		existing, ok := are.ExistingCollector.(C)
+		if !ok {
+			return c, err
+		}
+		return existing, nil
+	}
+	return c, nil
+}
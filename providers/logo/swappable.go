@@ -0,0 +1,55 @@
+package logo
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/health"
+)
+
+var (
+	_ LogoServicer   = new(Swappable)
+	_ health.Checker = new(Swappable)
+)
+
+// Swappable forwards the calls to a provider that can be replaced at runtime
+// (when the config is reloaded) without recreating the components that use it
+type Swappable struct {
+	current atomic.Pointer[swapped]
+}
+
+// the provider and its check are swapped together
+type swapped struct {
+	provider LogoServicer
+	checker  health.Checker
+}
+
+// NewSwappable wraps the provider, checker checks the provider (the wrapped providers,
+// like Instrumented or Fallback, hide the check of the provider inside) and it can be nil
+func NewSwappable(provider LogoServicer, checker health.Checker) *Swappable {
+	s := &Swappable{}
+	s.Swap(provider, checker)
+	return s
+}
+
+// Swap replaces the provider, the calls already running finish with the old one
+func (s *Swappable) Swap(provider LogoServicer, checker health.Checker) {
+	s.current.Store(&swapped{provider: provider, checker: checker})
+}
+
+func (s *Swappable) Provider() LogoServicer {
+	return s.current.Load().provider
+}
+
+func (s *Swappable) GenerateLogo(ctx context.Context, u *model.User) (string, error) {
+	return s.Provider().GenerateLogo(ctx, u)
+}
+
+// Check checks the current provider, the providers without a check are always up
+func (s *Swappable) Check(ctx context.Context) error {
+	if checker := s.current.Load().checker; checker != nil {
+		return checker.Check(ctx)
+	}
+	return nil
+}
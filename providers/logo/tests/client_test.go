@@ -0,0 +1,179 @@
+package logo
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/vano2903/service-template/pkg/breaker"
+	"github.com/vano2903/service-template/providers/logo"
+	"gotest.tools/v3/assert"
+)
+
+const apiKey = "test-api-key"
+
+// provider is a stand-in for the logo provider api,
+// it fails the first failures requests with the given status code and Retry-After
+type provider struct {
+	calls      int32
+	failures   int32
+	status     int
+	retryAfter string
+	delay      time.Duration
+}
+
+func (p *provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	call := atomic.AddInt32(&p.calls, 1)
+	if r.Method != http.MethodPost || r.URL.Path != "/logos" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if r.Header.Get("Authorization") != "Bearer "+apiKey {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	time.Sleep(p.delay)
+	if call <= p.failures {
+		if p.retryAfter != "" {
+			w.Header().Set("Retry-After", p.retryAfter)
+		}
+		w.WriteHeader(p.status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write([]byte(`{"url": "https://cdn.example.com/logo.png"}`))
+}
+
+func newClient(t *testing.T, baseUri string, key string, opts logo.ClientOptions) (*logo.Client, *prometheus.Registry) {
+	reg := prometheus.NewRegistry()
+	opts.Registerer = reg
+	opts.MinBackoff = time.Millisecond
+	opts.MaxBackoff = 5 * time.Millisecond
+	c, err := logo.NewClient(key, baseUri, opts)
+	assert.NilError(t, err)
+	return c, reg
+}
+
+// logo client, cases:
+// [x] the api key is sent and the url is returned
+// [x] 5xx answers are retried
+// [x] the Retry-After is read in both forms and capped at the max backoff
+// [x] a wrong api key is not retried
+// [x] slow answers time out
+// [x] the circuit opens after consecutive failures and closes when the provider is back
+// [x] the calls canceled by the caller don't close the circuit
+func TestClient(t *testing.T) {
+	t.Run("generate logo", func(t *testing.T) {
+		p := &provider{}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, _ := newClient(t, srv.URL, apiKey, logo.ClientOptions{})
+
+		url, err := c.GenerateLogo(context.Background(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, url, "https://cdn.example.com/logo.png")
+	})
+
+	t.Run("retries", func(t *testing.T) {
+		p := &provider{failures: 2, status: http.StatusServiceUnavailable}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, reg := newClient(t, srv.URL, apiKey, logo.ClientOptions{MaxRetries: 2})
+
+		_, err := c.GenerateLogo(context.Background(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, atomic.LoadInt32(&p.calls), int32(3))
+
+		retries, err := testutil.GatherAndCount(reg, "logo_provider_retries_total")
+		assert.NilError(t, err)
+		assert.Equal(t, retries, 1)
+	})
+
+	t.Run("retry after", func(t *testing.T) {
+		p := &provider{failures: 1, status: http.StatusTooManyRequests, retryAfter: "3600"}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, _ := newClient(t, srv.URL, apiKey, logo.ClientOptions{MaxRetries: 1})
+
+		start := time.Now()
+		_, err := c.GenerateLogo(context.Background(), nil)
+		assert.NilError(t, err)
+		assert.Assert(t, time.Since(start) < time.Second, "waited %s", time.Since(start))
+
+		p = &provider{failures: 1, status: http.StatusTooManyRequests, retryAfter: time.Now().Add(2 * time.Second).UTC().Format(http.TimeFormat)}
+		srv = httptest.NewServer(p)
+		defer srv.Close()
+		c, err = logo.NewClient(apiKey, srv.URL, logo.ClientOptions{
+			Registerer: prometheus.NewRegistry(),
+			MaxRetries: 1,
+			MinBackoff: time.Millisecond,
+			MaxBackoff: 10 * time.Second,
+		})
+		assert.NilError(t, err)
+
+		start = time.Now()
+		_, err = c.GenerateLogo(context.Background(), nil)
+		assert.NilError(t, err)
+		assert.Assert(t, time.Since(start) > 500*time.Millisecond, "waited %s", time.Since(start))
+	})
+
+	t.Run("wrong api key", func(t *testing.T) {
+		p := &provider{}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, _ := newClient(t, srv.URL, "wrong", logo.ClientOptions{MaxRetries: 3})
+
+		_, err := c.GenerateLogo(context.Background(), nil)
+		assert.Assert(t, errors.Is(err, logo.ErrUnauthorized))
+		assert.Equal(t, atomic.LoadInt32(&p.calls), int32(1))
+	})
+
+	t.Run("timeout", func(t *testing.T) {
+		p := &provider{delay: 200 * time.Millisecond}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, _ := newClient(t, srv.URL, apiKey, logo.ClientOptions{Timeout: 20 * time.Millisecond})
+
+		_, err := c.GenerateLogo(context.Background(), nil)
+		assert.Assert(t, err != nil)
+	})
+
+	t.Run("circuit breaker", func(t *testing.T) {
+		p := &provider{failures: 2, status: http.StatusInternalServerError}
+		srv := httptest.NewServer(p)
+		defer srv.Close()
+		c, _ := newClient(t, srv.URL, apiKey, logo.ClientOptions{
+			BreakerThreshold: 2,
+			BreakerCooldown:  50 * time.Millisecond,
+		})
+
+		for i := 0; i < 2; i++ {
+			_, err := c.GenerateLogo(context.Background(), nil)
+			assert.Assert(t, err != nil)
+		}
+		assert.Equal(t, c.BreakerState(), breaker.Open)
+
+		//the provider is not called while the circuit is open
+		_, err := c.GenerateLogo(context.Background(), nil)
+		assert.Equal(t, err, breaker.ErrOpen)
+		assert.Equal(t, atomic.LoadInt32(&p.calls), int32(2))
+
+		time.Sleep(60 * time.Millisecond)
+		assert.Equal(t, c.BreakerState(), breaker.HalfOpen)
+		//a trial call canceled by the caller doesn't close the circuit
+		canceled, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err = c.GenerateLogo(canceled, nil)
+		assert.Assert(t, errors.Is(err, context.Canceled))
+		assert.Equal(t, c.BreakerState(), breaker.HalfOpen)
+		_, err = c.GenerateLogo(context.Background(), nil)
+		assert.NilError(t, err)
+		assert.Equal(t, c.BreakerState(), breaker.Closed)
+	})
+}
@@ -0,0 +1,78 @@
+package logo
+
+import (
+	"bytes"
+	"context"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/avatar"
+	"github.com/vano2903/service-template/providers/logo"
+	"gotest.tools/v3/assert"
+)
+
+// local avatars, cases:
+// [x] the same user always gets the same avatar, different users get different ones
+// [x] the email is case insensitive
+// [x] png and svg are rendered with the requested size
+// [x] the fallback chain uses the local avatars when the provider is down
+func TestLocalLogo(t *testing.T) {
+	u := &model.User{FirstName: "Davide", LastName: "Vanoncini", Email: "Davide@Example.com"}
+	other := &model.User{FirstName: "John", LastName: "Doe", Email: "john@example.com"}
+
+	t.Run("deterministic", func(t *testing.T) {
+		local, err := logo.NewLocalLogo("http://localhost:8080/", avatar.StyleInitials, "svg")
+		assert.NilError(t, err)
+
+		first, err := local.GenerateLogo(context.Background(), u)
+		assert.NilError(t, err)
+		second, err := local.GenerateLogo(context.Background(), &model.User{FirstName: "davide", LastName: "vanoncini", Email: "davide@example.com"})
+		assert.NilError(t, err)
+		assert.Equal(t, first, second)
+		assert.Assert(t, strings.HasPrefix(first, "http://localhost:8080/avatars/initials/DV-"))
+
+		otherLogo, err := local.GenerateLogo(context.Background(), other)
+		assert.NilError(t, err)
+		assert.Assert(t, first != otherLogo)
+	})
+
+	t.Run("rendering", func(t *testing.T) {
+		seed := avatar.Hash(u.Email)
+
+		buf := &bytes.Buffer{}
+		assert.NilError(t, png.Encode(buf, avatar.Identicon(seed, 64)))
+		decoded, err := png.Decode(buf)
+		assert.NilError(t, err)
+		assert.Equal(t, decoded.Bounds().Dx(), 64)
+
+		initials := avatar.InitialsImage("DV", seed, 100)
+		assert.Equal(t, initials.Bounds().Dy(), 100)
+
+		assert.Equal(t, avatar.IdenticonSVG(seed, 64), avatar.IdenticonSVG(seed, 64))
+		assert.Assert(t, avatar.IdenticonSVG(seed, 64) != avatar.IdenticonSVG(avatar.Hash(other.Email), 64))
+		assert.Assert(t, strings.Contains(avatar.InitialsSVG("<b>", seed, 64), "&lt;b&gt;"))
+	})
+
+	t.Run("fallback", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer srv.Close()
+
+		client, _ := newClient(t, srv.URL, apiKey, logo.ClientOptions{})
+		local, err := logo.NewLocalLogo("http://localhost:8080", avatar.StyleIdenticon, "png")
+		assert.NilError(t, err)
+
+		l := logrus.New()
+		l.SetOutput(&bytes.Buffer{})
+		chain := logo.NewFallback(l, client, local)
+		url, err := chain.GenerateLogo(context.Background(), u)
+		assert.NilError(t, err)
+		assert.Equal(t, url, "http://localhost:8080/avatars/identicon/"+avatar.Hash(u.Email)+".png")
+	})
+}
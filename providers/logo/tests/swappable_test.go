@@ -0,0 +1,51 @@
+package logo
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/logo"
+	"gotest.tools/v3/assert"
+)
+
+type down struct{}
+
+func (down) Check(context.Context) error {
+	return errors.New("down")
+}
+
+// swappable provider, cases:
+// [x] the calls go to the current provider
+// [x] the check is the one of the current provider, none means up
+// [x] the client can be created again with the same registry (config reload)
+func TestSwappable(t *testing.T) {
+	ctx := context.Background()
+	u := &model.User{FirstName: "Foo", LastName: "Bar", Email: "foo@bar.com"}
+	local, err := logo.NewLocalLogo("http://localhost:8080", "identicon", "svg")
+	assert.NilError(t, err)
+
+	s := logo.NewSwappable(local, down{})
+	url, err := s.GenerateLogo(ctx, u)
+	assert.NilError(t, err)
+	assert.Equal(t, url, "http://localhost:8080"+logo.AvatarPath(u, "identicon", "svg"))
+	assert.ErrorContains(t, s.Check(ctx), "down")
+
+	srv := httptest.NewServer(&provider{})
+	defer srv.Close()
+	reg := prometheus.NewRegistry()
+	_, err = logo.NewClient(apiKey, srv.URL, logo.ClientOptions{Registerer: reg})
+	assert.NilError(t, err)
+	client, err := logo.NewClient(apiKey, srv.URL, logo.ClientOptions{Registerer: reg})
+	assert.NilError(t, err)
+
+	s.Swap(client, nil)
+	assert.Equal(t, s.Provider(), logo.LogoServicer(client))
+	url, err = s.GenerateLogo(ctx, u)
+	assert.NilError(t, err)
+	assert.Equal(t, url, "https://cdn.example.com/logo.png")
+	assert.NilError(t, s.Check(ctx))
+}
@@ -0,0 +1,168 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/backoff"
+	"github.com/vano2903/service-template/pkg/tracing"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+)
+
+const (
+	_DEFAULT_INTERVAL     = time.Second
+	_DEFAULT_BATCH_SIZE   = 50
+	_DEFAULT_TIMEOUT      = 10 * time.Second
+	_DEFAULT_MAX_ATTEMPTS = 8
+	_DEFAULT_MIN_BACKOFF  = 5 * time.Second
+	_DEFAULT_MAX_BACKOFF  = time.Hour
+)
+
+// The sender sends the pending deliveries to the webhooks.
+// A delivery succeeds if the receiver answers with a 2xx status code, otherwise it's retried
+// with an exponential backoff. After maxAttempts failed attempts the delivery is dead and it's
+// not retried anymore until an admin asks for a manual redelivery.
+type Sender struct {
+	repo        repo.WebhookRepoer
+	client      *http.Client
+	l           *logrus.Logger
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+}
+
+func NewSender(repo repo.WebhookRepoer, l *logrus.Logger, timeout time.Duration, maxAttempts int) *Sender {
+	if timeout <= 0 {
+		timeout = _DEFAULT_TIMEOUT
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = _DEFAULT_MAX_ATTEMPTS
+	}
+	return &Sender{
+		repo:        repo,
+		client:      &http.Client{Timeout: timeout, Transport: tracing.Transport(nil)},
+		l:           l,
+		interval:    _DEFAULT_INTERVAL,
+		batchSize:   _DEFAULT_BATCH_SIZE,
+		maxAttempts: maxAttempts,
+		minBackoff:  _DEFAULT_MIN_BACKOFF,
+		maxBackoff:  _DEFAULT_MAX_BACKOFF,
+	}
+}
+
+// SetBackoff changes the wait between the attempts, mostly useful in tests
+func (s *Sender) SetBackoff(min, max time.Duration) {
+	s.minBackoff = min
+	s.maxBackoff = max
+}
+
+// Run sends the due deliveries every interval until the context is canceled
+func (s *Sender) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.SendDue(ctx); err != nil {
+			s.l.Errorf("webhook.Sender: unable to send due deliveries: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDue runs a single pass over the due deliveries and returns how many succeeded
+func (s *Sender) SendDue(ctx context.Context) (int, error) {
+	deliveries, err := s.repo.GetDueDeliveries(time.Now(), s.batchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	succeeded := 0
+	for _, d := range deliveries {
+		if ctx.Err() != nil {
+			return succeeded, ctx.Err()
+		}
+
+		w, err := s.repo.GetWebhook(d.WebhookID)
+		if err != nil {
+			if _, ok := err.(*mock.ErrWebhookNotFound); !ok {
+				return succeeded, err
+			}
+			//the webhook was deleted after the delivery was created, nobody is waiting for it anymore
+			d.Status = model.DeliveryDead
+			d.Attempts = append(d.Attempts, model.WebhookAttempt{At: time.Now(), Error: "webhook deleted"})
+			if err := s.repo.UpdateDelivery(d); err != nil {
+				return succeeded, err
+			}
+			continue
+		}
+
+		attempt := s.send(ctx, w, d)
+		d.Attempts = append(d.Attempts, attempt)
+		if attempt.Error == "" {
+			d.Status = model.DeliverySucceeded
+			succeeded++
+		} else {
+			d.FailedAttempts++
+			if d.FailedAttempts >= s.maxAttempts {
+				d.Status = model.DeliveryDead
+				s.l.Warnf("webhook.Sender: delivery %d to webhook %d is dead after %d attempts: %s", d.ID, w.ID, d.FailedAttempts, attempt.Error)
+			} else {
+				d.NextAttemptAt = time.Now().Add(backoff.Exponential(d.FailedAttempts-1, s.minBackoff, s.maxBackoff))
+				s.l.Debugf("webhook.Sender: delivery %d to webhook %d failed, retrying at %s: %s", d.ID, w.ID, d.NextAttemptAt.Format(time.RFC3339), attempt.Error)
+			}
+		}
+
+		if err := s.repo.UpdateDelivery(d); err != nil {
+			return succeeded, err
+		}
+	}
+	return succeeded, nil
+}
+
+// send makes a single attempt, the error of the attempt is empty if the receiver accepted the delivery
+func (s *Sender) send(ctx context.Context, w *model.Webhook, d *model.WebhookDelivery) model.WebhookAttempt {
+	attempt := model.WebhookAttempt{At: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		attempt.Error = fmt.Sprintf("invalid request: %v", err)
+		return attempt
+	}
+	timestamp := attempt.At.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "service-template-webhooks")
+	req.Header.Set(HeaderWebhookID, strconv.Itoa(w.ID))
+	req.Header.Set(HeaderDelivery, strconv.Itoa(d.ID))
+	req.Header.Set(HeaderEvent, d.EventType)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderSignature, Sign(w.Secret, timestamp, d.Payload))
+
+	resp, err := s.client.Do(req)
+	attempt.Duration = time.Since(attempt.At)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer resp.Body.Close()
+	//reading the body (up to a limit) allows the connection to be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	attempt.StatusCode = resp.StatusCode
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		attempt.Error = fmt.Sprintf("unexpected status code %d", resp.StatusCode)
+	}
+	return attempt
+}
@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/providers/events"
+	"github.com/vano2903/service-template/repo"
+)
+
+var _ events.EventSinker = new(Sink)
+
+// Body is what the receivers get, data is the payload of the event
+type Body struct {
+	EventID    int             `json:"event_id"`
+	EventType  string          `json:"event_type"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// Sink doesn't send anything, it fans out every event in a delivery for each
+// subscribed webhook and the Sender takes care of sending them.
+// This way a slow or broken receiver doesn't block the other sinks and webhooks.
+type Sink struct {
+	repo repo.WebhookRepoer
+}
+
+func NewSink(repo repo.WebhookRepoer) *Sink {
+	return &Sink{
+		repo: repo,
+	}
+}
+
+func (s *Sink) Name() string {
+	return "webhook"
+}
+
+func (s *Sink) Deliver(_ context.Context, e *model.Event) error {
+	body, err := json.Marshal(Body{
+		EventID:    e.ID,
+		EventType:  e.Type,
+		OccurredAt: e.OccurredAt,
+		Data:       json.RawMessage(e.Payload),
+	})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, w := range s.repo.GetAllWebhooks() {
+		if !w.Accepts(e.Type) {
+			continue
+		}
+		_, err := s.repo.AddDelivery(&model.WebhookDelivery{
+			WebhookID:     w.ID,
+			EventID:       e.ID,
+			EventType:     e.Type,
+			Payload:       body,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,79 @@
+package instrumented
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/instrumented"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// instrumented user repo, cases:
+// [x] every call is counted by operation and outcome
+// [x] a missing user is counted as not_found and the error is returned as is
+// [x] the calls made inside a transaction are counted
+func TestUserRepo(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	users, err := instrumented.NewUserRepo(mock.NewRepo(), reg, logrus.New())
+	assert.NilError(t, err)
+
+	id, err := users.Create(context.Background(), &model.User{Email: "metrics@test.com"})
+	assert.NilError(t, err)
+	_, err = users.Get(context.Background(), id)
+	assert.NilError(t, err)
+
+	_, err = users.Get(context.Background(), id+1)
+	_, ok := err.(*mock.ErrUserNotFound)
+	assert.Assert(t, ok)
+
+	err = users.WithTx(context.Background(), func(users repo.UserRepoer, _ repo.OutboxRepoer) error {
+		_, err := users.Get(context.Background(), id)
+		return err
+	})
+	assert.NilError(t, err)
+
+	expected := `
+# HELP repo_users_calls_total Calls to the user repo by operation and outcome (success, not_found, error).
+# TYPE repo_users_calls_total counter
+repo_users_calls_total{operation="create",outcome="success"} 1
+repo_users_calls_total{operation="get",outcome="not_found"} 1
+repo_users_calls_total{operation="get",outcome="success"} 2
+repo_users_calls_total{operation="with_tx",outcome="success"} 1
+`
+	assert.NilError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "repo_users_calls_total"))
+	assert.Equal(t, testutil.CollectAndCount(reg, "repo_users_call_duration_seconds"), 3)
+}
+
+// instrumented user repo logs, cases:
+// [x] a failed call is logged with the fields of the logger in the context
+// [x] a missing user is not logged
+func TestUserRepoLogs(t *testing.T) {
+	l, hook := test.NewNullLogger()
+	users, err := instrumented.NewUserRepo(mock.NewRepo(), prometheus.NewRegistry(), l)
+	assert.NilError(t, err)
+
+	ctx := logger.WithEntry(context.Background(), l.WithField("request_id", "req-1"))
+	id, err := users.Create(ctx, &model.User{Email: "logs@test.com", Role: model.RoleUnupdatable})
+	assert.NilError(t, err)
+
+	_, err = users.Get(ctx, id+1)
+	assert.Assert(t, err != nil)
+	assert.Equal(t, len(hook.AllEntries()), 0)
+
+	err = users.Update(ctx, &model.User{ID: id, Email: "changed@test.com", Role: model.RoleUnupdatable})
+	assert.Equal(t, err, mock.ErrUserUnapdatable)
+	entry := hook.LastEntry()
+	assert.Assert(t, entry != nil)
+	assert.Equal(t, entry.Level, logrus.ErrorLevel)
+	assert.Equal(t, entry.Data["request_id"], "req-1")
+	assert.Equal(t, entry.Data["operation"], "update")
+}
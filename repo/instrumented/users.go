@@ -0,0 +1,184 @@
+// Package instrumented has the decorators of the repos that record the latency and the errors
+// of every call, they can wrap any implementation (mock, mongo...).
+package instrumented
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/repo"
+	"github.com/vano2903/service-template/repo/mock"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/vano2903/service-template/repo/instrumented"
+
+var _ repo.UserRepoer = new(UserRepo)
+
+const (
+	outcomeSuccess  = "success"
+	outcomeNotFound = "not_found"
+	outcomeError    = "error"
+)
+
+type repoMetrics struct {
+	duration *prometheus.HistogramVec
+	calls    *prometheus.CounterVec
+}
+
+// UserRepo records every call to the wrapped repo and traces it with a span, the calls made inside
+// a transaction are recorded as well and their spans are children of the span of the transaction.
+// The failed calls are logged with the logger of the request
+type UserRepo struct {
+	next    repo.UserRepoer
+	metrics *repoMetrics
+	tracer  trace.Tracer
+	l       *logrus.Logger
+	//span of the transaction, nil outside of one
+	tx trace.Span
+}
+
+func NewUserRepo(next repo.UserRepoer, reg prometheus.Registerer, l *logrus.Logger) (*UserRepo, error) {
+	m := &repoMetrics{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "repo",
+			Subsystem: "users",
+			Name:      "call_duration_seconds",
+			Help:      "Duration of the calls to the user repo by operation.",
+			Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+		}, []string{"operation"}),
+		calls: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "repo",
+			Subsystem: "users",
+			Name:      "calls_total",
+			Help:      "Calls to the user repo by operation and outcome (success, not_found, error).",
+		}, []string{"operation", "outcome"}),
+	}
+	for _, c := range []prometheus.Collector{m.duration, m.calls} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return &UserRepo{
+		next:    next,
+		metrics: m,
+		tracer:  otel.Tracer(instrumentationName),
+		l:       l,
+	}, nil
+}
+
+func (r *UserRepo) startSpan(ctx context.Context, operation string) (context.Context, trace.Span) {
+	//the controller passes its own context to the calls made in the transaction,
+	//the span of the transaction is set as parent without losing the deadline of the context
+	if r.tx != nil {
+		ctx = trace.ContextWithSpan(ctx, r.tx)
+	}
+	return r.tracer.Start(ctx, "repo.users."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("repo.operation", operation)),
+	)
+}
+
+// observe records the metrics of the call and ends its span
+func (r *UserRepo) observe(ctx context.Context, span trace.Span, operation string, begin time.Time, err error) {
+	defer span.End()
+	elapsed := time.Since(begin)
+	r.metrics.duration.WithLabelValues(operation).Observe(elapsed.Seconds())
+	outcome := outcomeSuccess
+	if err != nil {
+		outcome = outcomeError
+		//not finding a user is part of the normal flow (checking if an email is taken...)
+		if _, ok := err.(*mock.ErrUserNotFound); ok {
+			outcome = outcomeNotFound
+		}
+	}
+	r.metrics.calls.WithLabelValues(operation, outcome).Inc()
+	span.SetAttributes(attribute.String("repo.outcome", outcome))
+	if outcome == outcomeError {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		//the error of a transaction is the one returned by the function run in it,
+		//it's logged by the caller and the failed calls made in it are already logged
+		if operation != "with_tx" {
+			logger.FromContext(ctx, r.l).WithFields(logrus.Fields{
+				"operation": operation,
+				"elapsed":   elapsed.String(),
+			}).Errorf("repo.users.%s: %v", operation, err)
+		}
+	}
+}
+
+func (r *UserRepo) Create(ctx context.Context, u *model.User) (int, error) {
+	ctx, span := r.startSpan(ctx, "create")
+	begin := time.Now()
+	id, err := r.next.Create(ctx, u)
+	r.observe(ctx, span, "create", begin, err)
+	return id, err
+}
+
+func (r *UserRepo) Get(ctx context.Context, id int) (*model.User, error) {
+	ctx, span := r.startSpan(ctx, "get")
+	begin := time.Now()
+	u, err := r.next.Get(ctx, id)
+	r.observe(ctx, span, "get", begin, err)
+	return u, err
+}
+
+func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
+	ctx, span := r.startSpan(ctx, "get_by_email")
+	begin := time.Now()
+	u, err := r.next.GetByEmail(ctx, email)
+	r.observe(ctx, span, "get_by_email", begin, err)
+	return u, err
+}
+
+func (r *UserRepo) Update(ctx context.Context, u *model.User) error {
+	ctx, span := r.startSpan(ctx, "update")
+	begin := time.Now()
+	err := r.next.Update(ctx, u)
+	r.observe(ctx, span, "update", begin, err)
+	return err
+}
+
+func (r *UserRepo) Delete(ctx context.Context, id int) error {
+	ctx, span := r.startSpan(ctx, "delete")
+	begin := time.Now()
+	err := r.next.Delete(ctx, id)
+	r.observe(ctx, span, "delete", begin, err)
+	return err
+}
+
+func (r *UserRepo) GetAll(ctx context.Context) []*model.User {
+	ctx, span := r.startSpan(ctx, "get_all")
+	begin := time.Now()
+	users := r.next.GetAll(ctx)
+	r.observe(ctx, span, "get_all", begin, nil)
+	return users
+}
+
+func (r *UserRepo) GetMany(ctx context.Context, ids []int) ([]*model.User, error) {
+	ctx, span := r.startSpan(ctx, "get_many")
+	span.SetAttributes(attribute.Int("repo.ids", len(ids)))
+	begin := time.Now()
+	users, err := r.next.GetMany(ctx, ids)
+	r.observe(ctx, span, "get_many", begin, err)
+	return users, err
+}
+
+// WithTx records the whole transaction as "with_tx" and the calls made in it with their operation
+func (r *UserRepo) WithTx(ctx context.Context, fn func(users repo.UserRepoer, outbox repo.OutboxRepoer) error) error {
+	ctx, span := r.startSpan(ctx, "with_tx")
+	begin := time.Now()
+	err := r.next.WithTx(ctx, func(users repo.UserRepoer, outbox repo.OutboxRepoer) error {
+		return fn(&UserRepo{next: users, metrics: r.metrics, tracer: r.tracer, l: r.l, tx: span}, outbox)
+	})
+	r.observe(ctx, span, "with_tx", begin, err)
+	return err
+}
@@ -1,6 +1,9 @@
 package repo
 
 import (
+	"context"
+	"time"
+
 	"github.com/vano2903/service-template/model"
 )
 
@@ -9,11 +12,63 @@ type (
 	//This interface has the methods declarations for the
 	//repo components.
 	UserRepoer interface {
-		Create(u *model.User) (id int, err error)
-		Get(id int) (*model.User, error)
-		GetByEmail(email string) (*model.User, error)
-		Update(u *model.User) error
-		Delete(id int) error
-		GetAll() []*model.User
+		//The context carries the deadline of the request and the trace,
+		//implementations with a database must pass it to the driver
+		Create(ctx context.Context, u *model.User) (id int, err error)
+		Get(ctx context.Context, id int) (*model.User, error)
+		GetByEmail(ctx context.Context, email string) (*model.User, error)
+		Update(ctx context.Context, u *model.User) error
+		Delete(ctx context.Context, id int) error
+		GetAll(ctx context.Context) []*model.User
+		//GetMany returns in a single query the users with the given ids, in no particular
+		//order; the ids without a user are skipped
+		GetMany(ctx context.Context, ids []int) ([]*model.User, error)
+
+		//WithTx runs fn in a transaction, every change made with the repos passed to fn
+		//is persisted only if fn returns nil, otherwise everything is rolled back.
+		//The error returned by fn is returned as is.
+		//Inside fn only the given repos must be used.
+		WithTx(ctx context.Context, fn func(users UserRepoer, outbox OutboxRepoer) error) error
+	}
+
+	//Repos holding resources (connections, pools, files...) implement Closer,
+	//they are closed at shutdown after every component using them is stopped
+	Closer interface {
+		Close(ctx context.Context) error
+	}
+
+	//Repos connected to a database implement Pinger, it's used by the readiness probe
+	Pinger interface {
+		Ping(ctx context.Context) error
+	}
+
+	//The outbox stores the domain events until they are delivered by the dispatcher.
+	//Events are added inside the same transaction of the change they describe (see UserRepoer.WithTx).
+	OutboxRepoer interface {
+		AddEvent(e *model.Event) (id int, err error)
+		//GetPendingEvents returns, ordered by id, at most limit events that are not delivered yet
+		//and that are due for a delivery attempt at the given time
+		GetPendingEvents(now time.Time, limit int) ([]*model.Event, error)
+		MarkEventDelivered(id int, at time.Time) error
+		MarkEventFailed(id int, reason string, retryAt time.Time) error
+	}
+
+	//Webhook subscriptions and their deliveries
+	WebhookRepoer interface {
+		CreateWebhook(w *model.Webhook) (id int, err error)
+		GetWebhook(id int) (*model.Webhook, error)
+		GetAllWebhooks() []*model.Webhook
+		DeleteWebhook(id int) error
+
+		//AddDelivery stores the delivery only if there isn't already one for the same webhook and event,
+		//in that case the id of the existing delivery is returned. This way delivering the same event twice
+		//(the dispatcher is at-least-once) doesn't send it twice to the webhook.
+		AddDelivery(d *model.WebhookDelivery) (id int, err error)
+		GetDelivery(id int) (*model.WebhookDelivery, error)
+		UpdateDelivery(d *model.WebhookDelivery) error
+		//GetDeliveries returns the deliveries of the webhook ordered by id, if status is empty every delivery is returned
+		GetDeliveries(webhookID int, status string) ([]*model.WebhookDelivery, error)
+		//GetDueDeliveries returns, ordered by id, at most limit pending deliveries due at the given time
+		GetDueDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error)
 	}
 )
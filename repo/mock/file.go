@@ -0,0 +1,105 @@
+package mock
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/migrate"
+)
+
+// snapshot is the content of the file of the repo
+type snapshot struct {
+	Users          []*model.User            `json:"users"`
+	LastID         int                      `json:"last_id"`
+	Events         []*model.Event           `json:"events"`
+	LastEventID    int                      `json:"last_event_id"`
+	Webhooks       []*model.Webhook         `json:"webhooks"`
+	LastWebhookID  int                      `json:"last_webhook_id"`
+	Deliveries     []*model.WebhookDelivery `json:"deliveries"`
+	LastDeliveryID int                      `json:"last_delivery_id"`
+	Migrations     []migrate.Applied        `json:"migrations"`
+}
+
+// Open returns a mock repo with the data of the file (if it exists), the data is saved
+// in the file when the repo is closed. This way the data survives the restarts and it's
+// shared by the cli commands, that must run while the service is stopped as the last
+// process closing the repo overwrites the file
+func Open(file string) (*RepoMock, error) {
+	r := NewRepo()
+	r.file = file
+	content, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the mock database: %w", err)
+	}
+
+	s := snapshot{}
+	if err := json.Unmarshal(content, &s); err != nil {
+		return nil, fmt.Errorf("invalid mock database %s: %w", file, err)
+	}
+	for _, u := range s.Users {
+		r.users[u.ID] = u
+	}
+	for _, e := range s.Events {
+		r.events[e.ID] = e
+	}
+	for _, w := range s.Webhooks {
+		r.webhooks[w.ID] = w
+	}
+	for _, d := range s.Deliveries {
+		r.deliveries[d.ID] = d
+	}
+	r.lastID, r.lastEventID, r.lastWebhookID, r.lastDeliveryID = s.LastID, s.LastEventID, s.LastWebhookID, s.LastDeliveryID
+	r.migrations = s.Migrations
+	return r, nil
+}
+
+// save writes the data in the file, it must be called with the lock held.
+// The file is replaced only when the new one is completely written
+func (r *RepoMock) save() error {
+	s := snapshot{
+		LastID:         r.lastID,
+		LastEventID:    r.lastEventID,
+		LastWebhookID:  r.lastWebhookID,
+		LastDeliveryID: r.lastDeliveryID,
+		Migrations:     r.migrations,
+	}
+	for _, u := range r.users {
+		s.Users = append(s.Users, u)
+	}
+	for _, e := range r.events {
+		s.Events = append(s.Events, e)
+	}
+	for _, w := range r.webhooks {
+		s.Webhooks = append(s.Webhooks, w)
+	}
+	for _, d := range r.deliveries {
+		s.Deliveries = append(s.Deliveries, d)
+	}
+	//sorted so the file doesn't change if the data doesn't
+	sort.Slice(s.Users, func(i, j int) bool { return s.Users[i].ID < s.Users[j].ID })
+	sort.Slice(s.Events, func(i, j int) bool { return s.Events[i].ID < s.Events[j].ID })
+	sort.Slice(s.Webhooks, func(i, j int) bool { return s.Webhooks[i].ID < s.Webhooks[j].ID })
+	sort.Slice(s.Deliveries, func(i, j int) bool { return s.Deliveries[i].ID < s.Deliveries[j].ID })
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(r.file), 0o755); err != nil {
+		return fmt.Errorf("unable to create the directory of the mock database: %w", err)
+	}
+	//the file has the passwords
+	tmp := r.file + ".tmp"
+	if err := os.WriteFile(tmp, content, 0o600); err != nil {
+		return fmt.Errorf("unable to save the mock database: %w", err)
+	}
+	return os.Rename(tmp, r.file)
+}
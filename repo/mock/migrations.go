@@ -0,0 +1,107 @@
+package mock
+
+import (
+	"context"
+	"sort"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/migrate"
+)
+
+// Migrations of the mock repo, a repo with a database would create and drop its tables
+// here. The mock has no schema so creating a "table" does nothing and dropping it deletes its data
+func (r *RepoMock) Migrations() []migrate.Migration {
+	return []migrate.Migration{
+		{
+			Version: 1,
+			Name:    "create_users",
+			Up:      func(context.Context) error { return nil },
+			Down: r.locked(func() {
+				r.users, r.lastID = make(map[int]*model.User), 0
+			}),
+		},
+		{
+			Version: 2,
+			Name:    "create_outbox",
+			Up:      func(context.Context) error { return nil },
+			Down: r.locked(func() {
+				r.events, r.lastEventID = make(map[int]*model.Event), 0
+			}),
+		},
+		{
+			Version: 3,
+			Name:    "create_webhooks",
+			Up:      func(context.Context) error { return nil },
+			Down: r.locked(func() {
+				r.webhooks, r.lastWebhookID = make(map[int]*model.Webhook), 0
+				r.deliveries, r.lastDeliveryID = make(map[int]*model.WebhookDelivery), 0
+			}),
+		},
+		{
+			//the users created before the background generation of the pfps have no status,
+			//the ones without a pfp get it generated at the next start (see ResumePendingPfps)
+			Version: 4,
+			Name:    "backfill_pfp_status",
+			Up: r.locked(func() {
+				for _, u := range r.users {
+					if u.PfpStatus != "" {
+						continue
+					}
+					u.PfpStatus = model.PfpStatusReady
+					if u.Pfp == "" {
+						u.PfpStatus = model.PfpStatusPending
+					}
+				}
+			}),
+			//the statuses are valid for the previous version too
+			Down: func(context.Context) error { return nil },
+		},
+		{
+			//the users without a locale follow the Accept-Language of the client, dropping
+			//the column forgets the preferences
+			Version: 5,
+			Name:    "add_user_locale",
+			Up:      func(context.Context) error { return nil },
+			Down: r.locked(func() {
+				for _, u := range r.users {
+					u.Locale = ""
+				}
+			}),
+		},
+	}
+}
+
+func (r *RepoMock) locked(fn func()) func(context.Context) error {
+	return func(context.Context) error {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		fn()
+		return nil
+	}
+}
+
+func (r *RepoMock) AppliedMigrations(_ context.Context) ([]migrate.Applied, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]migrate.Applied(nil), r.migrations...), nil
+}
+
+func (r *RepoMock) MarkApplied(_ context.Context, a migrate.Applied) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.migrations = append(r.migrations, a)
+	sort.Slice(r.migrations, func(i, j int) bool { return r.migrations[i].Version < r.migrations[j].Version })
+	return nil
+}
+
+func (r *RepoMock) MarkReverted(_ context.Context, version int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, a := range r.migrations {
+		if a.Version == version {
+			r.migrations = append(r.migrations[:i], r.migrations[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vano2903/service-template/model"
+)
+
+type ErrEventNotFound struct {
+	ID      int
+	Message string
+}
+
+func (e ErrEventNotFound) Error() string {
+	return e.Message
+}
+
+func copyEvent(e *model.Event) *model.Event {
+	c := *e
+	c.Payload = append([]byte(nil), e.Payload...)
+	return &c
+}
+
+func (r *RepoMock) AddEvent(e *model.Event) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addEvent(e)
+}
+
+func (r *RepoMock) addEvent(e *model.Event) (int, error) {
+	r.lastEventID++
+	e.ID = r.lastEventID
+	r.events[e.ID] = copyEvent(e)
+	return e.ID, nil
+}
+
+func (r *RepoMock) GetPendingEvents(now time.Time, limit int) ([]*model.Event, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getPendingEvents(now, limit)
+}
+
+func (r *RepoMock) getPendingEvents(now time.Time, limit int) ([]*model.Event, error) {
+	events := make([]*model.Event, 0)
+	for _, e := range r.events {
+		if e.IsDelivered() || e.NextAttemptAt.After(now) {
+			continue
+		}
+		events = append(events, copyEvent(e))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+func (r *RepoMock) MarkEventDelivered(id int, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.markEventDelivered(id, at)
+}
+
+func (r *RepoMock) markEventDelivered(id int, at time.Time) error {
+	e, ok := r.events[id]
+	if !ok {
+		return &ErrEventNotFound{
+			ID:      id,
+			Message: fmt.Sprintf("event with id %d not found", id),
+		}
+	}
+	e.Attempts++
+	e.LastError = ""
+	e.DeliveredAt = at
+	return nil
+}
+
+func (r *RepoMock) MarkEventFailed(id int, reason string, retryAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.markEventFailed(id, reason, retryAt)
+}
+
+func (r *RepoMock) markEventFailed(id int, reason string, retryAt time.Time) error {
+	e, ok := r.events[id]
+	if !ok {
+		return &ErrEventNotFound{
+			ID:      id,
+			Message: fmt.Sprintf("event with id %d not found", id),
+		}
+	}
+	e.Attempts++
+	e.LastError = reason
+	e.NextAttemptAt = retryAt
+	return nil
+}
+
+// GetEvents returns every event in the outbox ordered by id, delivered or not.
+// It's not part of the repo interface, it's used by the tests to check what was published.
+func (r *RepoMock) GetEvents() []*model.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]*model.Event, 0, len(r.events))
+	for _, e := range r.events {
+		events = append(events, copyEvent(e))
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].ID < events[j].ID
+	})
+	return events
+}
@@ -3,9 +3,12 @@
 package mock
 
 import (
+	"context"
 	"fmt"
+	"sync"
 
 	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/migrate"
 	"github.com/vano2903/service-template/repo"
 )
 
@@ -13,7 +16,12 @@ var (
 	// This step sould be the first step when creating a repo component, check the struct with the interface.
 	// To do so in golang you just write this: var _ Interface = new(Struct)
 	// If this line of code doesn't compile then your struct doesn't implement the interface.
-	_ repo.UserRepoer = new(RepoMock)
+	_ repo.UserRepoer    = new(RepoMock)
+	_ repo.OutboxRepoer  = new(RepoMock)
+	_ repo.WebhookRepoer = new(RepoMock)
+	_ repo.Closer        = new(RepoMock)
+	_ repo.Pinger        = new(RepoMock)
+	_ migrate.Storer     = new(RepoMock)
 
 	//In this example we are using a custom error statically defined
 	//and a custom error defined as a struct.
@@ -34,54 +42,102 @@ func (e ErrUserNotFound) Error() string {
 	return e.Message
 }
 
+// The mock is safe for concurrent use (the dispatcher and the http handlers run in different goroutines).
+// Users are always copied when stored and returned so that a caller can't change
+// the content of the repo without calling Update.
 type RepoMock struct {
+	mu     sync.Mutex
 	users  map[int]*model.User
 	lastID int
+
+	events      map[int]*model.Event
+	lastEventID int
+
+	webhooks       map[int]*model.Webhook
+	lastWebhookID  int
+	deliveries     map[int]*model.WebhookDelivery
+	lastDeliveryID int
+
+	migrations []migrate.Applied
+	//where the data is saved by Close, empty keeps it only in memory (see Open)
+	file string
 }
 
 // NewRepo returns a new mock repo.
 func NewRepo() *RepoMock {
 	return &RepoMock{
-		users: make(map[int]*model.User),
+		users:      make(map[int]*model.User),
+		events:     make(map[int]*model.Event),
+		webhooks:   make(map[int]*model.Webhook),
+		deliveries: make(map[int]*model.WebhookDelivery),
 	}
 }
 
-func (r *RepoMock) Create(u *model.User) (int, error) {
+func copyUser(u *model.User) *model.User {
+	c := *u
+	return &c
+}
+
+func (r *RepoMock) Create(_ context.Context, u *model.User) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.create(u)
+}
+
+func (r *RepoMock) create(u *model.User) (int, error) {
 	r.lastID++
 	u.ID = r.lastID
-	r.users[u.ID] = u
+	r.users[u.ID] = copyUser(u)
 	return int(r.lastID), nil
 }
 
-func (r *RepoMock) Get(id int) (*model.User, error) {
+func (r *RepoMock) Get(_ context.Context, id int) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.get(id)
+}
+
+func (r *RepoMock) get(id int) (*model.User, error) {
 	u, ok := r.users[id]
 	if !ok {
-		err := ErrUserNotFound{
+		err := &ErrUserNotFound{
 			ID:      id,
 			Message: fmt.Sprintf("user with id %d is not found", id),
 		}
 		return nil, err
 
 	}
-	return u, nil
+	return copyUser(u), nil
+}
+
+func (r *RepoMock) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getByEmail(email)
 }
 
-func (r *RepoMock) GetByEmail(email string) (*model.User, error) {
+func (r *RepoMock) getByEmail(email string) (*model.User, error) {
 	for _, u := range r.users {
 		if u.Email == email {
-			return u, nil
+			return copyUser(u), nil
 		}
 	}
-	err := ErrUserNotFound{
+	err := &ErrUserNotFound{
 		Message: fmt.Sprintf("user with email %s is not found", email),
 	}
 	return nil, err
 }
 
-func (r *RepoMock) Update(u *model.User) error {
+func (r *RepoMock) Update(_ context.Context, u *model.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.update(u)
+}
+
+func (r *RepoMock) update(u *model.User) error {
 	_, ok := r.users[u.ID]
 	if !ok {
-		err := ErrUserNotFound{
+		err := &ErrUserNotFound{
 			ID:      u.ID,
 			Message: fmt.Sprintf("user with id %d not found", u.ID),
 		}
@@ -90,14 +146,20 @@ func (r *RepoMock) Update(u *model.User) error {
 	if r.users[u.ID].Role == model.RoleUnupdatable {
 		return ErrUserUnapdatable
 	}
-	r.users[u.ID] = u
+	r.users[u.ID] = copyUser(u)
 	return nil
 }
 
-func (r *RepoMock) Delete(id int) error {
+func (r *RepoMock) Delete(_ context.Context, id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.delete(id)
+}
+
+func (r *RepoMock) delete(id int) error {
 	_, ok := r.users[id]
 	if !ok {
-		err := ErrUserNotFound{
+		err := &ErrUserNotFound{
 			ID:      id,
 			Message: fmt.Sprintf("user with id %d not found", id),
 		}
@@ -107,10 +169,47 @@ func (r *RepoMock) Delete(id int) error {
 	return nil
 }
 
-func (r *RepoMock) GetAll() []*model.User {
+func (r *RepoMock) GetAll(_ context.Context) []*model.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getAll()
+}
+
+func (r *RepoMock) getAll() []*model.User {
 	users := make([]*model.User, 0, len(r.users))
 	for _, u := range r.users {
-		users = append(users, u)
+		users = append(users, copyUser(u))
 	}
 	return users
 }
+
+func (r *RepoMock) GetMany(_ context.Context, ids []int) ([]*model.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.getMany(ids), nil
+}
+
+func (r *RepoMock) getMany(ids []int) []*model.User {
+	users := make([]*model.User, 0, len(ids))
+	for _, id := range ids {
+		if u, ok := r.users[id]; ok {
+			users = append(users, copyUser(u))
+		}
+	}
+	return users
+}
+
+// Close saves the data in the file of the repo if it has one, a real repo would close its connections here
+func (r *RepoMock) Close(_ context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == "" {
+		return nil
+	}
+	return r.save()
+}
+
+// Ping always succeeds as the mock is in memory
+func (r *RepoMock) Ping(_ context.Context) error {
+	return nil
+}
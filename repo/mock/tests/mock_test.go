@@ -0,0 +1,90 @@
+package mock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/pkg/migrate"
+	"github.com/vano2903/service-template/repo/mock"
+	"gotest.tools/v3/assert"
+)
+
+// file, cases:
+// [x] a missing file is an empty repo
+// [x] the data and the migrations are saved on close and loaded by open
+// [x] the ids continue from the saved ones
+// [x] an invalid file is an error
+func TestOpen(t *testing.T) {
+	ctx := context.Background()
+	file := filepath.Join(t.TempDir(), "data", "db.json")
+
+	r, err := mock.Open(file)
+	assert.NilError(t, err)
+	assert.Equal(t, len(r.GetAll(ctx)), 0)
+	m, err := migrate.New(r, r.Migrations())
+	assert.NilError(t, err)
+	_, err = m.Up(ctx, 0)
+	assert.NilError(t, err)
+	id, err := r.Create(ctx, &model.User{Email: "saved@mock.com", Role: model.RoleUser})
+	assert.NilError(t, err)
+	assert.NilError(t, r.Close(ctx))
+
+	info, err := os.Stat(file)
+	assert.NilError(t, err)
+	assert.Equal(t, info.Mode().Perm(), os.FileMode(0o600))
+
+	r, err = mock.Open(file)
+	assert.NilError(t, err)
+	u, err := r.Get(ctx, id)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Email, "saved@mock.com")
+	m, err = migrate.New(r, r.Migrations())
+	assert.NilError(t, err)
+	pending, err := m.Pending(ctx)
+	assert.NilError(t, err)
+	assert.Equal(t, pending, 0)
+	next, err := r.Create(ctx, &model.User{Email: "next@mock.com", Role: model.RoleUser})
+	assert.NilError(t, err)
+	assert.Assert(t, next > id)
+
+	assert.NilError(t, os.WriteFile(file, []byte("{"), 0o600))
+	_, err = mock.Open(file)
+	assert.ErrorContains(t, err, "invalid mock database")
+}
+
+// migrations, cases:
+// [x] the backfill sets the status of the pfps
+// [x] reverting the locales forgets the preferences
+// [x] reverting the creation of the users deletes them
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	r := mock.NewRepo()
+	withPfp, err := r.Create(ctx, &model.User{Email: "pfp@mock.com", Pfp: "http://pfp", Role: model.RoleUser})
+	assert.NilError(t, err)
+	withoutPfp, err := r.Create(ctx, &model.User{Email: "nopfp@mock.com", Role: model.RoleUser, Locale: "it"})
+	assert.NilError(t, err)
+
+	m, err := migrate.New(r, r.Migrations())
+	assert.NilError(t, err)
+	_, err = m.Up(ctx, 0)
+	assert.NilError(t, err)
+	u, err := r.Get(ctx, withPfp)
+	assert.NilError(t, err)
+	assert.Equal(t, u.PfpStatus, model.PfpStatusReady)
+	u, err = r.Get(ctx, withoutPfp)
+	assert.NilError(t, err)
+	assert.Equal(t, u.PfpStatus, model.PfpStatusPending)
+
+	_, err = m.Down(ctx, 1)
+	assert.NilError(t, err)
+	u, err = r.Get(ctx, withoutPfp)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Locale, "")
+
+	_, err = m.Down(ctx, 4)
+	assert.NilError(t, err)
+	assert.Equal(t, len(r.GetAll(ctx)), 0)
+}
@@ -0,0 +1,96 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/vano2903/service-template/model"
+	"github.com/vano2903/service-template/repo"
+)
+
+var (
+	_ repo.UserRepoer   = new(txMock)
+	_ repo.OutboxRepoer = new(txMock)
+)
+
+// WithTx holds the lock of the repo for the whole transaction, so transactions are serialized.
+// Before running fn we take a snapshot of the data and if fn fails we restore it.
+func (r *RepoMock) WithTx(_ context.Context, fn func(users repo.UserRepoer, outbox repo.OutboxRepoer) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	//stored users and events are never shared outside the repo so
+	//copying the maps is enough to have a snapshot
+	users := make(map[int]*model.User, len(r.users))
+	for id, u := range r.users {
+		users[id] = u
+	}
+	events := make(map[int]*model.Event, len(r.events))
+	for id, e := range r.events {
+		events[id] = copyEvent(e)
+	}
+	lastID, lastEventID := r.lastID, r.lastEventID
+
+	tx := &txMock{r: r}
+	if err := fn(tx, tx); err != nil {
+		r.users, r.events = users, events
+		r.lastID, r.lastEventID = lastID, lastEventID
+		return err
+	}
+	return nil
+}
+
+// txMock is the view of the repo given to the function run in a transaction,
+// it calls the methods without taking the lock as it's already held by WithTx
+type txMock struct {
+	r *RepoMock
+}
+
+func (t *txMock) Create(_ context.Context, u *model.User) (int, error) {
+	return t.r.create(u)
+}
+
+func (t *txMock) Get(_ context.Context, id int) (*model.User, error) {
+	return t.r.get(id)
+}
+
+func (t *txMock) GetByEmail(_ context.Context, email string) (*model.User, error) {
+	return t.r.getByEmail(email)
+}
+
+func (t *txMock) Update(_ context.Context, u *model.User) error {
+	return t.r.update(u)
+}
+
+func (t *txMock) Delete(_ context.Context, id int) error {
+	return t.r.delete(id)
+}
+
+func (t *txMock) GetAll(_ context.Context) []*model.User {
+	return t.r.getAll()
+}
+
+func (t *txMock) GetMany(_ context.Context, ids []int) ([]*model.User, error) {
+	return t.r.getMany(ids), nil
+}
+
+// nested transactions are just part of the outer one
+func (t *txMock) WithTx(_ context.Context, fn func(users repo.UserRepoer, outbox repo.OutboxRepoer) error) error {
+	return fn(t, t)
+}
+
+func (t *txMock) AddEvent(e *model.Event) (int, error) {
+	return t.r.addEvent(e)
+}
+
+func (t *txMock) GetPendingEvents(now time.Time, limit int) ([]*model.Event, error) {
+	return t.r.getPendingEvents(now, limit)
+}
+
+func (t *txMock) MarkEventDelivered(id int, at time.Time) error {
+	return t.r.markEventDelivered(id, at)
+}
+
+func (t *txMock) MarkEventFailed(id int, reason string, retryAt time.Time) error {
+	return t.r.markEventFailed(id, reason, retryAt)
+}
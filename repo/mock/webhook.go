@@ -0,0 +1,164 @@
+package mock
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vano2903/service-template/model"
+)
+
+type ErrWebhookNotFound struct {
+	ID      int
+	Message string
+}
+
+func (e ErrWebhookNotFound) Error() string {
+	return e.Message
+}
+
+type ErrDeliveryNotFound struct {
+	ID      int
+	Message string
+}
+
+func (e ErrDeliveryNotFound) Error() string {
+	return e.Message
+}
+
+func copyWebhook(w *model.Webhook) *model.Webhook {
+	c := *w
+	c.Events = append([]string(nil), w.Events...)
+	return &c
+}
+
+func copyDelivery(d *model.WebhookDelivery) *model.WebhookDelivery {
+	c := *d
+	c.Payload = append([]byte(nil), d.Payload...)
+	c.Attempts = append([]model.WebhookAttempt(nil), d.Attempts...)
+	return &c
+}
+
+func (r *RepoMock) CreateWebhook(w *model.Webhook) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lastWebhookID++
+	w.ID = r.lastWebhookID
+	r.webhooks[w.ID] = copyWebhook(w)
+	return w.ID, nil
+}
+
+func (r *RepoMock) GetWebhook(id int) (*model.Webhook, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	w, ok := r.webhooks[id]
+	if !ok {
+		return nil, &ErrWebhookNotFound{
+			ID:      id,
+			Message: fmt.Sprintf("webhook with id %d not found", id),
+		}
+	}
+	return copyWebhook(w), nil
+}
+
+func (r *RepoMock) GetAllWebhooks() []*model.Webhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	webhooks := make([]*model.Webhook, 0, len(r.webhooks))
+	for _, w := range r.webhooks {
+		webhooks = append(webhooks, copyWebhook(w))
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		return webhooks[i].ID < webhooks[j].ID
+	})
+	return webhooks
+}
+
+func (r *RepoMock) DeleteWebhook(id int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.webhooks[id]; !ok {
+		return &ErrWebhookNotFound{
+			ID:      id,
+			Message: fmt.Sprintf("webhook with id %d not found", id),
+		}
+	}
+	delete(r.webhooks, id)
+	return nil
+}
+
+func (r *RepoMock) AddDelivery(d *model.WebhookDelivery) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.deliveries {
+		if existing.WebhookID == d.WebhookID && existing.EventID == d.EventID {
+			d.ID = existing.ID
+			return existing.ID, nil
+		}
+	}
+	r.lastDeliveryID++
+	d.ID = r.lastDeliveryID
+	r.deliveries[d.ID] = copyDelivery(d)
+	return d.ID, nil
+}
+
+func (r *RepoMock) GetDelivery(id int) (*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	d, ok := r.deliveries[id]
+	if !ok {
+		return nil, &ErrDeliveryNotFound{
+			ID:      id,
+			Message: fmt.Sprintf("delivery with id %d not found", id),
+		}
+	}
+	return copyDelivery(d), nil
+}
+
+func (r *RepoMock) UpdateDelivery(d *model.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.deliveries[d.ID]; !ok {
+		return &ErrDeliveryNotFound{
+			ID:      d.ID,
+			Message: fmt.Sprintf("delivery with id %d not found", d.ID),
+		}
+	}
+	r.deliveries[d.ID] = copyDelivery(d)
+	return nil
+}
+
+func (r *RepoMock) GetDeliveries(webhookID int, status string) ([]*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.WebhookID != webhookID || (status != "" && d.Status != status) {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(d))
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	return deliveries, nil
+}
+
+func (r *RepoMock) GetDueDeliveries(now time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	deliveries := make([]*model.WebhookDelivery, 0)
+	for _, d := range r.deliveries {
+		if d.Status != model.DeliveryPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+		deliveries = append(deliveries, copyDelivery(d))
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].ID < deliveries[j].ID
+	})
+	if limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return deliveries, nil
+}
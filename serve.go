@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"reflect"
+
+	"github.com/labstack/echo/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/controller"
+	"github.com/vano2903/service-template/fixtures"
+	"github.com/vano2903/service-template/handlers/grpcserver"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/pkg/jwt"
+	"github.com/vano2903/service-template/pkg/lifecycle"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/pkg/migrate"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"github.com/vano2903/service-template/pkg/tracing"
+	"github.com/vano2903/service-template/pkg/workqueue"
+	"github.com/vano2903/service-template/providers/events"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/providers/webhook"
+	"github.com/vano2903/service-template/repo/instrumented"
+)
+
+// serve runs the service until it's stopped
+func serve(conf *config.Config) {
+	l, output, err := newLogger(conf.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	//every component has its own logger so its level can be changed at runtime
+	logs := logger.NewLevels(l)
+	l.Debug("initizalized logger")
+
+	//set up before anything creates spans
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		ServiceName:    conf.App.Name,
+		ServiceVersion: conf.App.Version,
+		Exporter:       conf.Tracing.Exporter,
+		Endpoint:       conf.Tracing.Endpoint,
+		Insecure:       conf.Tracing.Insecure,
+		File:           conf.Tracing.File,
+		SampleRatio:    conf.Tracing.SampleRatio,
+	})
+	if err != nil {
+		l.Fatalf("unable to set up the tracing: %v", err)
+	}
+
+	//every dependency registers its check for the readiness probe
+	checks := health.NewRegistry()
+
+	//creating the instances for the application
+	repo, migrator, err := newRepo(conf.Database)
+	if err != nil {
+		l.Fatalf("unable to create the repo: %v", err)
+	}
+	migrateOnStart(l, conf.Database, migrator)
+	checks.Register("repo", true, conf.Health.Timeout, repo.Ping)
+	users, err := instrumented.NewUserRepo(repo, prometheus.DefaultRegisterer, logs.Component("repo"))
+	if err != nil {
+		l.Fatalf("unable to instrument the repo: %v", err)
+	}
+	if err := controller.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+		l.Fatalf("unable to register the controller metrics: %v", err)
+	}
+
+	logoMetrics, err := logo.NewServiceMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		l.Fatalf("unable to register the logo metrics: %v", err)
+	}
+	provider, providerCheck, err := newLogoService(conf.Services.Logo, logs.Component("logo"), logoMetrics)
+	if err != nil {
+		l.Fatalf("unable to create the logo provider: %v", err)
+	}
+	//the provider is replaced when the config is reloaded
+	logoService := logo.NewSwappable(provider, providerCheck)
+	//the pfps are generated in background (or by the fallback), the service works without the provider
+	checks.RegisterChecker("logo provider", false, conf.Health.Timeout, logoService)
+
+	blobStorage, err := newBlobStorage(conf.Storage)
+	if err != nil {
+		l.Fatalf("unable to create the blob storage: %v", err)
+	}
+	if checker, ok := blobStorage.(health.Checker); ok {
+		//only the uploads need the storage, the rest of the service works without it
+		checks.RegisterChecker("blob storage", false, conf.Health.Timeout, checker)
+	}
+
+	//creating the controllers
+	controllerLog := logs.Component("controller")
+	c := controller.NewUserController(users, logoService, controllerLog)
+	wc := controller.NewWebhookController(users, repo, controllerLog)
+	pc := controller.NewPfpController(users, blobStorage, controllerLog, conf.HTTP.PublicUrl, conf.Uploads.PfpSizes, conf.Uploads.PfpMaxSize)
+	ac := controller.NewAdminController(users, logs, controllerLog)
+	c.SetPfpRemover(pc)
+
+	//components are started in order and stopped in reverse order,
+	//the logger, the tracing and the repo are stopped last as everything else uses them
+	lc := lifecycle.New(logs.Component("lifecycle"), conf.Shutdown.DrainTimeout)
+	lc.Add(lifecycle.Component{
+		Name: "logger",
+		Stop: func(context.Context) error {
+			if err := logger.Flush(l); err != nil {
+				return err
+			}
+			return output.Close()
+		},
+	})
+	lc.Add(lifecycle.Component{
+		Name: "tracing",
+		Stop: shutdownTracing,
+	})
+	lc.Add(lifecycle.Component{
+		Name: "repo",
+		Stop: repo.Close,
+	})
+
+	//limiting the requests of the clients, the limits are shared by the instances with the redis store
+	limiters, rateLimitStore, err := newRateLimiters(conf.RateLimit)
+	if err != nil {
+		l.Fatalf("unable to create the rate limiters: %v", err)
+	}
+	if redis, ok := rateLimitStore.(*ratelimit.Redis); ok {
+		//the requests are allowed when the store is down
+		checks.RegisterChecker("rate limit store", false, conf.Health.Timeout, redis)
+		lc.Add(lifecycle.Component{
+			Name: "rate limit store",
+			Stop: redis.Close,
+		})
+	}
+
+	//applying the changes of the config without a restart (on SIGHUP and when the files change)
+	reloader := config.NewReloader(conf, nil, logs.Component("config"))
+	reloader.Subscribe("logger", func(prev, next *config.Config) error {
+		if prev.Log.Level == next.Log.Level {
+			return nil
+		}
+		level, err := logrus.ParseLevel(next.Log.Level)
+		if err != nil {
+			return err
+		}
+		logs.SetConfigured(level)
+		return nil
+	})
+	reloader.Subscribe("logo provider", func(prev, next *config.Config) error {
+		if reflect.DeepEqual(prev.Services.Logo, next.Services.Logo) {
+			return nil
+		}
+		provider, providerCheck, err := newLogoService(next.Services.Logo, logs.Component("logo"), logoMetrics)
+		if err != nil {
+			return err
+		}
+		logoService.Swap(provider, providerCheck)
+		return nil
+	})
+	reloader.Subscribe("rate limits", func(prev, next *config.Config) error {
+		if reflect.DeepEqual(prev.RateLimit, next.RateLimit) {
+			return nil
+		}
+		return setRateLimits(limiters, next.RateLimit)
+	})
+	lc.Background("config reloader", reloader.Run)
+
+	//generating the profile pictures in background
+	if conf.PfpQueue.Enabled {
+		pfpQueue := workqueue.New("pfp", logs.Component("pfp_queue"), c.GeneratePfp, c.PfpGenerationFailed, workqueue.Options{
+			Workers:     conf.PfpQueue.Workers,
+			Size:        conf.PfpQueue.Size,
+			MaxAttempts: conf.PfpQueue.MaxAttempts,
+			MinBackoff:  conf.PfpQueue.MinBackoff,
+			MaxBackoff:  conf.PfpQueue.MaxBackoff,
+		})
+		c.SetPfpQueue(pfpQueue)
+		lc.Background("pfp queue", pfpQueue.Run)
+		if resumed := c.ResumePendingPfps(context.Background()); resumed > 0 {
+			l.Infof("resumed the generation of %d profile pictures", resumed)
+		}
+	}
+
+	if conf.Fixtures.OnStart {
+		seedOnStart(l, conf, c)
+	}
+
+	//delivering the domain events stored in the outbox
+	var sinks []events.EventSinker
+	if conf.Events.LogSink {
+		sinks = append(sinks, events.NewLogSink(logs.Component("events")))
+	}
+	if conf.Webhooks.Enabled {
+		sinks = append(sinks, webhook.NewSink(repo))
+		sender := webhook.NewSender(repo, logs.Component("webhook"), conf.Webhooks.Timeout, conf.Webhooks.MaxAttempts)
+		lc.Background("webhook sender", sender.Run)
+	}
+	dispatcher := events.NewDispatcher(repo, logs.Component("events"), conf.Events.DispatchInterval, conf.Events.BatchSize, sinks...)
+	lc.Background("events dispatcher", dispatcher.Run)
+
+	//creating the http server
+	e := echo.New()
+	e.HideBanner = true
+	httpserver.InitRouter(e, logs.Component("http"), httpserver.Controllers{
+		User:    c,
+		Webhook: wc,
+		Pfp:     pc,
+		Admin:   ac,
+	}, checks, conf, limiters)
+
+	//the readiness probe fails as soon as the shutdown begins so that the
+	//orchestrator stops sending new requests while the ones in flight are drained
+	go func() {
+		<-lc.Stopping()
+		checks.SetShuttingDown()
+	}()
+
+	//the grpc server has the same tokens of the http api, on its own port
+	if conf.GRPC.Enabled {
+		grpcServer := grpcserver.NewServer(logs.Component("grpc"), c, jwt.NewJWThandler(conf.HTTP.JWTSecret, conf.App.Name+":"+conf.App.Version), checks, grpcserver.Options{
+			Reflection: conf.GRPC.Reflection,
+		})
+		lc.Add(lifecycle.Component{
+			Name: "grpc server",
+			Start: func(context.Context) error {
+				listener, err := net.Listen("tcp", ":"+conf.GRPC.Port)
+				if err != nil {
+					return err
+				}
+				go func() {
+					if err := grpcServer.Serve(listener); err != nil {
+						lc.Fail("grpc server", err)
+					}
+				}()
+				l.Infof("grpc server listening on %s", listener.Addr())
+				return nil
+			},
+			Stop: grpcServer.Stop,
+		})
+	}
+
+	//the http server is the last to start and the first to stop: during the shutdown
+	//it stops accepting requests and waits for the ones in flight
+	lc.Add(lifecycle.Component{
+		Name: "http server",
+		Start: func(context.Context) error {
+			//listening here makes errors like "address already in use" stop the startup
+			listener, err := net.Listen("tcp", ":"+conf.HTTP.Port)
+			if err != nil {
+				return err
+			}
+			e.Listener = listener
+			go func() {
+				if err := e.Start(""); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					lc.Fail("http server", err)
+				}
+			}()
+			l.Infof("http server listening on %s", listener.Addr())
+			return nil
+		},
+		Stop: e.Shutdown,
+	})
+
+	if err := lc.Run(context.Background()); err != nil {
+		l.Errorf("service stopped with error: %v", err)
+		os.Exit(1)
+	}
+}
+
+// migrateOnStart applies the pending migrations if enabled, otherwise it only reports them
+func migrateOnStart(l *logrus.Logger, conf config.Database, migrator *migrate.Migrator) {
+	ctx := context.Background()
+	if !conf.MigrateOnStart {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			l.Fatalf("unable to read the migrations: %v", err)
+		}
+		if pending > 0 {
+			l.Warnf("%d migrations are pending, apply them with \"migrate up\"", pending)
+		}
+		return
+	}
+	applied, err := migrator.Up(ctx, 0)
+	for _, m := range applied {
+		l.Infof("applied migration %d (%s)", m.Version, m.Name)
+	}
+	if err != nil {
+		l.Fatalf("unable to apply the migrations: %v", err)
+	}
+}
+
+// seedOnStart upserts the users of the fixtures, a failure is logged as the service works without them
+func seedOnStart(l *logrus.Logger, conf *config.Config, c *controller.User) {
+	if err := fixtures.Allowed(conf.App.Profile); err != nil {
+		l.Errorf("fixtures not loaded: %v", err)
+		return
+	}
+	set, err := fixtures.Load(conf.Fixtures.Files...)
+	if err != nil {
+		l.Errorf("fixtures not loaded: %v", err)
+		return
+	}
+	result, err := set.Apply(context.Background(), c)
+	if err != nil {
+		l.Errorf("fixtures partially loaded (%s): %v", result, err)
+		return
+	}
+	l.Infof("fixtures loaded: %s", result)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"github.com/vano2903/service-template/config"
+	"github.com/vano2903/service-template/handlers/httpserver"
+	"github.com/vano2903/service-template/pkg/health"
+	"github.com/vano2903/service-template/pkg/logger"
+	"github.com/vano2903/service-template/pkg/migrate"
+	"github.com/vano2903/service-template/pkg/ratelimit"
+	"github.com/vano2903/service-template/providers/blob"
+	"github.com/vano2903/service-template/providers/logo"
+	"github.com/vano2903/service-template/repo/mock"
+)
+
+// the builders shared by serve and the cli commands, so they run with the same wiring
+
+// newLogger creates the root logger with its redaction and outputs, the output must be closed
+func newLogger(conf config.Log) (*logrus.Logger, *logger.Output, error) {
+	l := logger.NewLogger(conf.Level, conf.Type)
+	if conf.Redact.Enabled {
+		redact, err := logger.NewRedactHook(logger.RedactOptions{
+			Fields:   conf.Redact.Fields,
+			Patterns: conf.Redact.Patterns,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("unable to set up the redaction of the logs: %w", err)
+		}
+		l.AddHook(redact)
+	}
+	output, err := logger.NewOutput(logger.OutputOptions{
+		Outputs: conf.Outputs,
+		File: logger.FileOptions{
+			Path:       conf.File.Path,
+			MaxSizeMB:  conf.File.MaxSizeMB,
+			MaxAgeDays: conf.File.MaxAgeDays,
+			MaxBackups: conf.File.MaxBackups,
+			Compress:   conf.File.Compress,
+		},
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to set up the outputs of the logs: %w", err)
+	}
+	l.SetOutput(output)
+	return l, output, nil
+}
+
+// newRepo creates the repo of the configured driver and its migrator
+func newRepo(conf config.Database) (*mock.RepoMock, *migrate.Migrator, error) {
+	switch conf.Driver {
+	case "mock":
+		repo := mock.NewRepo()
+		if conf.File != "" {
+			var err error
+			if repo, err = mock.Open(conf.File); err != nil {
+				return nil, nil, err
+			}
+		}
+		migrator, err := migrate.New(repo, repo.Migrations())
+		if err != nil {
+			return nil, nil, err
+		}
+		return repo, migrator, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown database driver %q", conf.Driver)
+	}
+}
+
+// newLogoService creates the logo provider from the config, wrapping it in a fallback chain
+// if a fallback provider is configured. The check of the provider is nil if it has none
+func newLogoService(conf config.LogoService, l *logrus.Logger, metrics *logo.ServiceMetrics) (logo.LogoServicer, health.Checker, error) {
+	var provider logo.LogoServicer
+	var checker health.Checker
+	var err error
+	switch conf.Provider {
+	case "random":
+		provider = logo.NewServiceLogo(conf.ApiKey, conf.BaseUrl)
+	case "http":
+		provider, err = logo.NewClient(conf.ApiKey, conf.BaseUrl, logo.ClientOptions{
+			Timeout:          conf.Timeout,
+			MaxRetries:       conf.MaxRetries,
+			BreakerThreshold: conf.BreakerThreshold,
+			BreakerCooldown:  conf.BreakerCooldown,
+		})
+	case "local":
+		provider, err = logo.NewLocalLogo(conf.Local.PublicUrl, conf.Local.Style, conf.Local.Format)
+	default:
+		err = fmt.Errorf("unknown logo provider %q", conf.Provider)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	if c, ok := provider.(health.Checker); ok {
+		checker = c
+	}
+	provider = logo.NewInstrumented(provider, conf.Provider, metrics)
+
+	switch conf.Fallback {
+	case "":
+		return provider, checker, nil
+	case "local":
+		if conf.Provider == "local" {
+			return provider, checker, nil
+		}
+		local, err := logo.NewLocalLogo(conf.Local.PublicUrl, conf.Local.Style, conf.Local.Format)
+		if err != nil {
+			return nil, nil, err
+		}
+		return logo.NewFallback(l, provider, logo.NewInstrumented(local, "local", metrics)), checker, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown logo fallback provider %q", conf.Fallback)
+	}
+}
+
+func newBlobStorage(conf config.Storage) (blob.BlobStorer, error) {
+	switch conf.Driver {
+	case "local":
+		return blob.NewLocal(conf.Local.Path)
+	case "s3":
+		return blob.NewS3(blob.S3Options{
+			Endpoint:  conf.S3.Endpoint,
+			Region:    conf.S3.Region,
+			Bucket:    conf.S3.Bucket,
+			AccessKey: conf.S3.AccessKey,
+			SecretKey: conf.S3.SecretKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", conf.Driver)
+	}
+}
+
+// newRateLimiters creates the limiters of the route groups with their store
+func newRateLimiters(conf config.RateLimit) (httpserver.RateLimiters, ratelimit.Storer, error) {
+	var store ratelimit.Storer
+	switch conf.Store {
+	case "memory":
+		store = ratelimit.NewMemory()
+	case "redis":
+		store = ratelimit.NewRedis(ratelimit.RedisOptions{
+			Address:  conf.Redis.Address,
+			Password: conf.Redis.Password,
+			DB:       conf.Redis.DB,
+			Prefix:   conf.Redis.Prefix,
+			Timeout:  conf.Redis.Timeout,
+		})
+	default:
+		return httpserver.RateLimiters{}, nil, fmt.Errorf("unknown rate limit store %q", conf.Store)
+	}
+	auth, err := ratelimit.NewLimiter("auth", store, rateLimit(conf.Enabled, conf.Auth))
+	if err != nil {
+		return httpserver.RateLimiters{}, nil, err
+	}
+	api, err := ratelimit.NewLimiter("api", store, rateLimit(conf.Enabled, conf.API))
+	if err != nil {
+		return httpserver.RateLimiters{}, nil, err
+	}
+	return httpserver.RateLimiters{Auth: auth, API: api, APIKeys: apiKeys(conf.APIKeys)}, store, nil
+}
+
+// apiKeys splits the api keys of the config, separated by commas
+func apiKeys(keys string) []string {
+	split := []string{}
+	for _, key := range strings.Split(keys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			split = append(split, key)
+		}
+	}
+	return split
+}
+
+// setRateLimits applies the limits of the config to the limiters
+func setRateLimits(limiters httpserver.RateLimiters, conf config.RateLimit) error {
+	if err := limiters.Auth.SetLimit(rateLimit(conf.Enabled, conf.Auth)); err != nil {
+		return err
+	}
+	return limiters.API.SetLimit(rateLimit(conf.Enabled, conf.API))
+}
+
+// rateLimit returns the limit of the rule, a disabled one if the rate limits are disabled
+func rateLimit(enabled bool, rule config.RateLimitRule) ratelimit.Limit {
+	if !enabled {
+		return ratelimit.Limit{}
+	}
+	return ratelimit.Limit{
+		Algorithm: rule.Algorithm,
+		Requests:  rule.Requests,
+		Period:    rule.Period,
+		Burst:     rule.Burst,
+		Key:       rule.Key,
+	}
+}